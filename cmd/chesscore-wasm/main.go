@@ -0,0 +1,93 @@
+//go:build js && wasm
+
+// Command chesscore-wasm exposes package chesscore to JavaScript. Loaded with
+// Go's wasm_exec.js, it sets globalThis.chesscore to an object with:
+//
+//	legalTargets(fen, from) -> {ok, targets} // targets as in /api/games/{id}/legal
+//	decodeSAN(fen, san)     -> {ok, uci}
+//	play(fen, uci)          -> {ok, fen, san, status}
+//	parseStartFEN(fen)      -> {ok, fen}
+//
+// An empty fen is the standard starting position. Failures return
+// {ok: false, error}. Build it with `make wasm`.
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	"tinychess/pkg/chesscore"
+)
+
+func main() {
+	js.Global().Set("chesscore", js.ValueOf(map[string]any{
+		"legalTargets":  js.FuncOf(legalTargets),
+		"decodeSAN":     js.FuncOf(decodeSAN),
+		"play":          js.FuncOf(play),
+		"parseStartFEN": js.FuncOf(parseStartFEN),
+	}))
+	select {}
+}
+
+// arg returns the i'th argument as a string, or "" when it is missing or not
+// a string.
+func arg(args []js.Value, i int) string {
+	if i >= len(args) || args[i].Type() != js.TypeString {
+		return ""
+	}
+	return args[i].String()
+}
+
+// result converts v to a plain JavaScript object by way of JSON, so struct
+// tags match the server's responses.
+func result(v map[string]any) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return failure(err)
+	}
+	return js.Global().Get("JSON").Call("parse", string(b))
+}
+
+func failure(err error) any {
+	return js.ValueOf(map[string]any{"ok": false, "error": err.Error()})
+}
+
+func legalTargets(_ js.Value, args []js.Value) any {
+	g, err := chesscore.GameFromFEN(arg(args, 0))
+	if err != nil {
+		return failure(err)
+	}
+	from, err := chesscore.ParseSquare(arg(args, 1))
+	if err != nil {
+		return failure(err)
+	}
+	return result(map[string]any{"ok": true, "targets": chesscore.LegalTargets(g.Position(), from)})
+}
+
+func decodeSAN(_ js.Value, args []js.Value) any {
+	g, err := chesscore.GameFromFEN(arg(args, 0))
+	if err != nil {
+		return failure(err)
+	}
+	uci, err := chesscore.DecodeSAN(g.Position(), arg(args, 1))
+	if err != nil {
+		return failure(err)
+	}
+	return result(map[string]any{"ok": true, "uci": uci})
+}
+
+func play(_ js.Value, args []js.Value) any {
+	res, err := chesscore.Play(arg(args, 0), arg(args, 1))
+	if err != nil {
+		return failure(err)
+	}
+	return result(map[string]any{"ok": true, "fen": res.FEN, "san": res.SAN, "status": res.Status})
+}
+
+func parseStartFEN(_ js.Value, args []js.Value) any {
+	fen, err := chesscore.ParseStartFEN(arg(args, 0))
+	if err != nil {
+		return failure(err)
+	}
+	return result(map[string]any{"ok": true, "fen": fen})
+}
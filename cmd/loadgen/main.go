@@ -0,0 +1,53 @@
+// Command loadgen plays scripted games against a tinychess server to
+// generate realistic load.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tinychess/internal/sim"
+)
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:8080", "server base URL")
+	games := flag.Int("games", 100, "number of games to play")
+	concurrency := flag.Int("c", 10, "concurrent games")
+	flag.Parse()
+
+	script := sim.Script{
+		Moves:  []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1b5", "a7a6", "b5a4", "g8f6"},
+		Resign: true,
+	}
+	client := sim.NewClient(*baseURL, nil)
+
+	var failed atomic.Int64
+	var wg sync.WaitGroup
+	jobs := make(chan struct{})
+	start := time.Now()
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				if _, err := client.Play(context.Background(), script); err != nil {
+					failed.Add(1)
+					log.Printf("game failed: %v", err)
+				}
+			}
+		}()
+	}
+	for i := 0; i < *games; i++ {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	log.Printf("played %d games in %s (%.1f games/s, %d failed)",
+		*games, elapsed.Round(time.Millisecond), float64(*games)/elapsed.Seconds(), failed.Load())
+}
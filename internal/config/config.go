@@ -0,0 +1,336 @@
+// Package config loads the server's settings from an optional YAML file and
+// the environment. Environment variables override the file, so a shared file
+// can be adjusted per deployment; anything set in neither keeps its default.
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting of a tinychess server. The yaml keys are those
+// of the config file; the environment variable for each is noted beside it.
+type Config struct {
+	// Addr is the listen address (LISTEN_ADDR).
+	Addr string `yaml:"addr"`
+	TLS  TLS    `yaml:"tls"`
+	// DatabaseURL is the storage DSN (DATABASE_URL); games live only in
+	// memory when empty. RetentionDays deletes games idle for that long
+	// (RETENTION_DAYS).
+	DatabaseURL   string `yaml:"database_url"`
+	RetentionDays int    `yaml:"retention_days"`
+	// MoveShards splits the moves into that many tables by game, for
+	// instances with millions of moves (MOVE_SHARDS); 0 keeps one table.
+	MoveShards int `yaml:"move_shards"`
+	// DBMaintenanceInterval vacuums the database that often and logs what
+	// needs attention (DB_MAINTENANCE_INTERVAL); 0 leaves it to the admin
+	// API.
+	DBMaintenanceInterval time.Duration `yaml:"db_maintenance_interval"`
+	// SaveFile keeps the games of a server without a database across
+	// restarts, written every SaveInterval and read at startup (SAVE_FILE,
+	// SAVE_INTERVAL); an interval of 0 turns it off.
+	SaveFile     string        `yaml:"save_file"`
+	SaveInterval time.Duration `yaml:"save_interval"`
+	// AdminToken guards /admin (ADMIN_TOKEN).
+	AdminToken string `yaml:"admin_token"`
+	// InstanceName and BaseURL fill the page titles, PGN headers and links
+	// (INSTANCE_NAME, BASE_URL).
+	InstanceName string   `yaml:"instance_name"`
+	BaseURL      string   `yaml:"base_url"`
+	Branding     Branding `yaml:"branding"`
+	// AlternateURL is the deployment that takes over this one's games, such
+	// as the other half of a blue/green pair; event streams send clients
+	// there on shutdown and during maintenance (ALTERNATE_URL).
+	AlternateURL string `yaml:"alternate_url"`
+	// ShutdownGrace bounds how long shutdown waits for requests to finish
+	// (SHUTDOWN_GRACE).
+	ShutdownGrace time.Duration `yaml:"shutdown_grace"`
+	// EnginePath is a UCI engine binary (ENGINE_PATH) and TablebaseURL an
+	// endgame tablebase service (TABLEBASE_URL).
+	EnginePath   string `yaml:"engine_path"`
+	TablebaseURL string `yaml:"tablebase_url"`
+	// ImageCacheBytes sizes the board image cache (IMAGE_CACHE_BYTES); the
+	// hub's default is kept when unset.
+	ImageCacheBytes *int `yaml:"image_cache_bytes"`
+	// SeatSecret signs seat tokens (SEAT_SECRET).
+	SeatSecret string `yaml:"seat_secret"`
+	// TimeControls are the offered presets, e.g. "3+2,10+0" (TIME_CONTROLS).
+	TimeControls string `yaml:"time_controls"`
+	// WarmHours loads games active that recently at startup (WARM_HOURS).
+	WarmHours   int   `yaml:"warm_hours"`
+	LadderReach int   `yaml:"ladder_reach"`    // LADDER_REACH
+	APIQuota    int64 `yaml:"api_daily_quota"` // API_DAILY_QUOTA
+	// RateLimits throttle callers per endpoint group, e.g. {new: 10/m,
+	// move: 5/s}; RATE_LIMITS takes "new=10/m,move=5/s".
+	RateLimits map[string]string `yaml:"rate_limits"`
+	// TrustedProxies are the reverse proxies, as addresses or CIDR ranges,
+	// whose X-Forwarded-For is believed (TRUSTED_PROXIES, comma-separated).
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// CORSOrigins and FederationHosts are comma-separated in the
+	// environment (CORS_ORIGINS, FEDERATION_HOSTS).
+	CORSOrigins     []string  `yaml:"cors_origins"`
+	FederationHosts []string  `yaml:"federation_hosts"`
+	Fediverse       Fediverse `yaml:"fediverse"`
+	Cleanup         Cleanup   `yaml:"cleanup"`
+	Cooldowns       Cooldowns `yaml:"cooldowns"`
+	// Heartbeat is how often idle event streams send a keep-alive
+	// (SSE_HEARTBEAT).
+	Heartbeat time.Duration `yaml:"sse_heartbeat"`
+	// DisabledFeatures turns features off, e.g. [chat, ladder]
+	// (DISABLED_FEATURES).
+	DisabledFeatures []string `yaml:"disabled_features"`
+	// Coaches are the user ids that review games on request (COACHES,
+	// comma-separated).
+	Coaches []string `yaml:"coaches"`
+	// PostMortemSpectators lets spectators move in post-mortem rooms
+	// (POST_MORTEM_SPECTATORS=true).
+	PostMortemSpectators bool `yaml:"post_mortem_spectators"`
+}
+
+// TLS serves HTTPS from a certificate and key file (TLS_CERT, TLS_KEY).
+type TLS struct {
+	Cert string `yaml:"cert"`
+	Key  string `yaml:"key"`
+}
+
+// Branding customizes the pages beyond the instance name: a logo in place of
+// the pawn (BRAND_LOGO_URL), footer text (BRAND_FOOTER) and the theme
+// picker's accent colors, the first being the default (BRAND_ACCENTS,
+// comma-separated).
+type Branding struct {
+	LogoURL string   `yaml:"logo_url"`
+	Footer  string   `yaml:"footer"`
+	Accents []string `yaml:"accents"`
+}
+
+// Fediverse publishes finished games over ActivityPub (FEDIVERSE=true),
+// signing with the key at Key (FEDIVERSE_KEY).
+type Fediverse struct {
+	Enabled bool   `yaml:"enabled"`
+	Key     string `yaml:"key"`
+}
+
+// Cleanup drops games unseen for IdleAfter from memory every Interval
+// (CLEANUP_INTERVAL, IDLE_TTL).
+type Cleanup struct {
+	Interval  time.Duration `yaml:"interval"`
+	IdleAfter time.Duration `yaml:"idle_after"`
+}
+
+// Cooldowns are the pauses between a participant's reactions, chat messages
+// and hints (REACT_COOLDOWN, CHAT_COOLDOWN, HINT_COOLDOWN), and how long
+// reactions and chat stay locked after a decisive result
+// (POST_GAME_COOLDOWN, off when zero).
+type Cooldowns struct {
+	React    time.Duration `yaml:"react"`
+	Chat     time.Duration `yaml:"chat"`
+	Hint     time.Duration `yaml:"hint"`
+	PostGame time.Duration `yaml:"post_game"`
+}
+
+// Default returns the settings of a server started without any
+// configuration. Zero durations leave the built-in defaults in place.
+func Default() Config {
+	return Config{
+		Addr:          ":8080",
+		Fediverse:     Fediverse{Key: "fediverse-key.pem"},
+		ShutdownGrace: 10 * time.Second,
+		SaveFile:      "tinychess-games.json",
+		SaveInterval:  30 * time.Second,
+	}
+}
+
+// Load reads the YAML file at path, if path is not empty, over the defaults
+// and then applies the environment.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, err
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.validate()
+}
+
+// applyEnv overrides settings with the environment variables lookup finds.
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	env := envReader{lookup: lookup}
+	env.str("LISTEN_ADDR", &c.Addr)
+	env.str("TLS_CERT", &c.TLS.Cert)
+	env.str("TLS_KEY", &c.TLS.Key)
+	env.str("DATABASE_URL", &c.DatabaseURL)
+	env.integer("RETENTION_DAYS", &c.RetentionDays)
+	env.integer("MOVE_SHARDS", &c.MoveShards)
+	env.duration("DB_MAINTENANCE_INTERVAL", &c.DBMaintenanceInterval)
+	env.str("SAVE_FILE", &c.SaveFile)
+	env.duration("SAVE_INTERVAL", &c.SaveInterval)
+	env.str("ADMIN_TOKEN", &c.AdminToken)
+	env.str("INSTANCE_NAME", &c.InstanceName)
+	env.str("BASE_URL", &c.BaseURL)
+	env.str("BRAND_LOGO_URL", &c.Branding.LogoURL)
+	env.str("BRAND_FOOTER", &c.Branding.Footer)
+	env.list("BRAND_ACCENTS", &c.Branding.Accents)
+	env.str("ALTERNATE_URL", &c.AlternateURL)
+	env.duration("SHUTDOWN_GRACE", &c.ShutdownGrace)
+	env.str("ENGINE_PATH", &c.EnginePath)
+	env.str("TABLEBASE_URL", &c.TablebaseURL)
+	if v, ok := env.get("IMAGE_CACHE_BYTES"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			env.fail("IMAGE_CACHE_BYTES", v)
+		} else {
+			c.ImageCacheBytes = &n
+		}
+	}
+	env.str("SEAT_SECRET", &c.SeatSecret)
+	env.str("TIME_CONTROLS", &c.TimeControls)
+	env.integer("WARM_HOURS", &c.WarmHours)
+	env.integer("LADDER_REACH", &c.LadderReach)
+	if v, ok := env.get("API_DAILY_QUOTA"); ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			env.fail("API_DAILY_QUOTA", v)
+		} else {
+			c.APIQuota = n
+		}
+	}
+	if v, ok := env.get("RATE_LIMITS"); ok {
+		limits := make(map[string]string)
+		for _, pair := range strings.Split(v, ",") {
+			group, spec, ok := strings.Cut(pair, "=")
+			if !ok {
+				env.fail("RATE_LIMITS", v)
+				break
+			}
+			limits[strings.TrimSpace(group)] = strings.TrimSpace(spec)
+		}
+		c.RateLimits = limits
+	}
+	env.list("TRUSTED_PROXIES", &c.TrustedProxies)
+	env.list("CORS_ORIGINS", &c.CORSOrigins)
+	env.list("FEDERATION_HOSTS", &c.FederationHosts)
+	if v, ok := env.get("FEDIVERSE"); ok {
+		c.Fediverse.Enabled = v == "true"
+	}
+	env.str("FEDIVERSE_KEY", &c.Fediverse.Key)
+	env.duration("CLEANUP_INTERVAL", &c.Cleanup.Interval)
+	env.duration("IDLE_TTL", &c.Cleanup.IdleAfter)
+	env.duration("REACT_COOLDOWN", &c.Cooldowns.React)
+	env.duration("CHAT_COOLDOWN", &c.Cooldowns.Chat)
+	env.duration("HINT_COOLDOWN", &c.Cooldowns.Hint)
+	env.duration("POST_GAME_COOLDOWN", &c.Cooldowns.PostGame)
+	env.duration("SSE_HEARTBEAT", &c.Heartbeat)
+	env.list("DISABLED_FEATURES", &c.DisabledFeatures)
+	env.list("COACHES", &c.Coaches)
+	if v, ok := env.get("POST_MORTEM_SPECTATORS"); ok {
+		c.PostMortemSpectators = v == "true"
+	}
+	return env.err
+}
+
+// validate checks what can be checked without the packages that use the
+// settings; those validate the rest, such as time controls, as they start.
+func (c *Config) validate() error {
+	if (c.TLS.Cert == "") != (c.TLS.Key == "") {
+		return fmt.Errorf("tls needs both a cert and a key")
+	}
+	if c.AlternateURL != "" {
+		u, err := url.Parse(c.AlternateURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid alternate_url %q, want an http(s) URL", c.AlternateURL)
+		}
+	}
+	for name, n := range map[string]int{"retention_days": c.RetentionDays, "move_shards": c.MoveShards, "warm_hours": c.WarmHours, "ladder_reach": c.LadderReach} {
+		if n < 0 {
+			return fmt.Errorf("invalid %s: %d", name, n)
+		}
+	}
+	if c.ImageCacheBytes != nil && *c.ImageCacheBytes < 0 {
+		return fmt.Errorf("invalid image_cache_bytes: %d", *c.ImageCacheBytes)
+	}
+	if c.APIQuota < 0 {
+		return fmt.Errorf("invalid api_daily_quota: %d", c.APIQuota)
+	}
+	durations := map[string]time.Duration{
+		"cleanup.interval":        c.Cleanup.Interval,
+		"cleanup.idle_after":      c.Cleanup.IdleAfter,
+		"cooldowns.react":         c.Cooldowns.React,
+		"cooldowns.chat":          c.Cooldowns.Chat,
+		"cooldowns.hint":          c.Cooldowns.Hint,
+		"cooldowns.post_game":     c.Cooldowns.PostGame,
+		"sse_heartbeat":           c.Heartbeat,
+		"shutdown_grace":          c.ShutdownGrace,
+		"save_interval":           c.SaveInterval,
+		"db_maintenance_interval": c.DBMaintenanceInterval,
+	}
+	for name, d := range durations {
+		if d < 0 {
+			return fmt.Errorf("invalid %s: %v", name, d)
+		}
+	}
+	return nil
+}
+
+// envReader applies environment variables, keeping the first error.
+type envReader struct {
+	lookup func(string) (string, bool)
+	err    error
+}
+
+func (e *envReader) get(name string) (string, bool) {
+	v, ok := e.lookup(name)
+	return strings.TrimSpace(v), ok && strings.TrimSpace(v) != ""
+}
+
+func (e *envReader) fail(name, v string) {
+	if e.err == nil {
+		e.err = fmt.Errorf("invalid %s: %q", name, v)
+	}
+}
+
+func (e *envReader) str(name string, dst *string) {
+	if v, ok := e.get(name); ok {
+		*dst = v
+	}
+}
+
+func (e *envReader) integer(name string, dst *int) {
+	if v, ok := e.get(name); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			e.fail(name, v)
+			return
+		}
+		*dst = n
+	}
+}
+
+func (e *envReader) duration(name string, dst *time.Duration) {
+	if v, ok := e.get(name); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			e.fail(name, v)
+			return
+		}
+		*dst = d
+	}
+}
+
+func (e *envReader) list(name string, dst *[]string) {
+	if v, ok := e.get(name); ok {
+		*dst = strings.Split(v, ",")
+	}
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tinychess.yaml")
+	file := `
+addr: ":9000"
+database_url: sqlite:///tmp/chess.db
+retention_days: 90
+cors_origins: [https://club.example]
+cleanup:
+  idle_after: 6h
+cooldowns:
+  chat: 5s
+disabled_features: [ladder]
+`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	t.Setenv("LISTEN_ADDR", "127.0.0.1:8443")
+	t.Setenv("CHAT_COOLDOWN", "1s")
+	t.Setenv("FEDERATION_HOSTS", "a.example,b.example")
+	t.Setenv("RATE_LIMITS", "new=10/m, move=5/s")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.1,10.1.0.0/16")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Addr != "127.0.0.1:8443" || cfg.Cooldowns.Chat != time.Second {
+		t.Fatalf("expected the environment to win, got %q and %v", cfg.Addr, cfg.Cooldowns.Chat)
+	}
+	if cfg.DatabaseURL != "sqlite:///tmp/chess.db" || cfg.RetentionDays != 90 || cfg.Cleanup.IdleAfter != 6*time.Hour {
+		t.Fatalf("expected the file's settings, got %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.CORSOrigins, []string{"https://club.example"}) || !reflect.DeepEqual(cfg.DisabledFeatures, []string{"ladder"}) {
+		t.Fatalf("unexpected lists %v %v", cfg.CORSOrigins, cfg.DisabledFeatures)
+	}
+	if !reflect.DeepEqual(cfg.FederationHosts, []string{"a.example", "b.example"}) {
+		t.Fatalf("unexpected federation hosts %v", cfg.FederationHosts)
+	}
+	if want := map[string]string{"new": "10/m", "move": "5/s"}; !reflect.DeepEqual(cfg.RateLimits, want) {
+		t.Fatalf("unexpected rate limits %v", cfg.RateLimits)
+	}
+	if !reflect.DeepEqual(cfg.TrustedProxies, []string{"10.0.0.1", "10.1.0.0/16"}) {
+		t.Fatalf("unexpected trusted proxies %v", cfg.TrustedProxies)
+	}
+	if cfg.Fediverse.Key != "fediverse-key.pem" || cfg.ImageCacheBytes != nil {
+		t.Fatalf("expected defaults for unset settings, got %+v", cfg)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	cases := map[string]map[string]string{
+		"bad number":   {"RETENTION_DAYS": "ninety"},
+		"bad duration": {"HINT_COOLDOWN": "soon"},
+		"negative":     {"WARM_HOURS": "-1"},
+		"half tls":     {"TLS_CERT": "cert.pem"},
+		"bad limits":   {"RATE_LIMITS": "new:10/m"},
+		"relative url": {"ALTERNATE_URL": "green.example"},
+	}
+	for name, env := range cases {
+		cfg := Default()
+		err := cfg.applyEnv(func(k string) (string, bool) {
+			v, ok := env[k]
+			return v, ok
+		})
+		if err == nil {
+			err = cfg.validate()
+		}
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected a missing config file to fail")
+	}
+}
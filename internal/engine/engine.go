@@ -0,0 +1,71 @@
+// Package engine runs an external UCI chess engine, such as Stockfish, to
+// evaluate positions. Searches are serialized over a single engine process.
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/corentings/chess/v2/uci"
+)
+
+// DefaultMoveTime is the search time per evaluation.
+const DefaultMoveTime = 200 * time.Millisecond
+
+// Engine is a running UCI engine.
+type Engine struct {
+	// MoveTime bounds each search.
+	MoveTime time.Duration
+
+	mu  sync.Mutex
+	uci *uci.Engine
+}
+
+// Analysis is the engine's verdict on a position. Scores are from white's
+// point of view.
+type Analysis struct {
+	BestMove string // UCI, empty when there is no legal move
+	CP       int    // centipawns
+	Mate     int    // moves to mate, negative when black mates; 0 if none
+	Depth    int
+}
+
+// New starts the engine binary at path.
+func New(path string) (*Engine, error) {
+	e, err := uci.New(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.Run(uci.CmdUCI, uci.CmdIsReady, uci.CmdUCINewGame); err != nil {
+		_ = e.Close()
+		return nil, err
+	}
+	return &Engine{MoveTime: DefaultMoveTime, uci: e}, nil
+}
+
+// Analyze searches the given position.
+func (e *Engine) Analyze(pos *chess.Position) (Analysis, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.uci.Run(uci.CmdPosition{Position: pos}, uci.CmdGo{MoveTime: e.MoveTime}); err != nil {
+		return Analysis{}, err
+	}
+	res := e.uci.SearchResults()
+
+	a := Analysis{CP: res.Info.Score.CP, Mate: res.Info.Score.Mate, Depth: res.Info.Depth}
+	if pos.Turn() == chess.Black {
+		a.CP, a.Mate = -a.CP, -a.Mate
+	}
+	if res.BestMove != nil {
+		a.BestMove = chess.UCINotation{}.Encode(pos, res.BestMove)
+	}
+	return a, nil
+}
+
+// Close stops the engine process.
+func (e *Engine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.uci.Close()
+}
@@ -0,0 +1,205 @@
+// Package federation relays games hosted on other tinychess instances so they
+// can be spectated locally. Each remote game is read through a single upstream
+// SSE connection shared by every local viewer.
+package federation
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tinychess/internal/game"
+	"tinychess/internal/logging"
+)
+
+var (
+	// ErrHostNotAllowed is returned for hosts missing from the allowlist.
+	ErrHostNotAllowed = errors.New("federation: host not allowed")
+	// ErrBadGameID is returned when the remote game id is not a UUID.
+	ErrBadGameID = errors.New("federation: bad game id")
+)
+
+// RetryDelay is how long a feed waits before reconnecting to its upstream.
+var RetryDelay = 2 * time.Second
+
+// Relay proxies event streams from an allowlist of remote instances.
+type Relay struct {
+	HTTP *http.Client
+
+	hosts map[string]string // host[:port] -> base URL
+
+	mu    sync.Mutex
+	feeds map[string]*feed
+}
+
+type feed struct {
+	url      string
+	watchers map[chan []byte]struct{}
+	last     []byte
+	cancel   context.CancelFunc
+}
+
+// NewRelay returns a relay for the given instances. Entries are host names or
+// base URLs; bare hosts are reached over https.
+func NewRelay(allowed []string, httpClient *http.Client) (*Relay, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	r := &Relay{HTTP: httpClient, hosts: make(map[string]string), feeds: make(map[string]*feed)}
+	for _, entry := range allowed {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "://") {
+			entry = "https://" + entry
+		}
+		u, err := url.Parse(entry)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("federation: bad host %q", entry)
+		}
+		r.hosts[u.Host] = u.Scheme + "://" + u.Host + strings.TrimRight(u.Path, "/")
+	}
+	return r, nil
+}
+
+// Allowed reports whether host may be relayed.
+func (r *Relay) Allowed(host string) bool {
+	if r == nil {
+		return false
+	}
+	_, ok := r.hosts[host]
+	return ok
+}
+
+// Subscribe returns a channel of sanitized payloads for a remote game and a
+// function that ends the subscription. The latest state, if known, is sent
+// immediately.
+func (r *Relay) Subscribe(host, id string) (<-chan []byte, func(), error) {
+	if !r.Allowed(host) {
+		return nil, nil, ErrHostNotAllowed
+	}
+	gameID, err := game.ParseGameID(id)
+	if err != nil {
+		return nil, nil, ErrBadGameID
+	}
+	id = gameID.String()
+	key := host + "/" + id
+	ch := make(chan []byte, 16)
+
+	r.mu.Lock()
+	f, ok := r.feeds[key]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		f = &feed{
+			url:      r.hosts[host] + "/sse/" + id + "?spectate=1&schema=" + strconv.Itoa(game.SchemaVersion),
+			watchers: make(map[chan []byte]struct{}),
+			cancel:   cancel,
+		}
+		r.feeds[key] = f
+		go r.run(ctx, f)
+	}
+	f.watchers[ch] = struct{}{}
+	if f.last != nil {
+		ch <- f.last
+	}
+	r.mu.Unlock()
+
+	unsubscribe := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(f.watchers, ch)
+		if len(f.watchers) == 0 && r.feeds[key] == f {
+			f.cancel()
+			delete(r.feeds, key)
+		}
+	}
+	return ch, unsubscribe, nil
+}
+
+func (r *Relay) run(ctx context.Context, f *feed) {
+	for {
+		err := r.stream(ctx, f)
+		if ctx.Err() != nil {
+			return
+		}
+		logging.Debugf("federation: %s: %v", f.url, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(RetryDelay):
+		}
+	}
+}
+
+func (r *Relay) stream(ctx context.Context, f *feed) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	res, err := r.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", res.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok || data == "{}" {
+			continue
+		}
+		msg, kind := sanitize([]byte(data))
+		if msg == nil {
+			continue
+		}
+		r.mu.Lock()
+		if kind == "state" {
+			f.last = msg
+		}
+		for ch := range f.watchers {
+			select {
+			case ch <- msg:
+			default:
+			}
+		}
+		r.mu.Unlock()
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("stream closed")
+}
+
+// sanitize strips seat details from relayed state so local viewers are always
+// spectators and never adopt the relay's remote client id.
+func sanitize(data []byte) ([]byte, string) {
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, ""
+	}
+	kind, _ := m["kind"].(string)
+	if kind == "state" {
+		delete(m, "clientId")
+		delete(m, "color")
+		m["role"] = "spectator"
+	}
+	out, err := json.Marshal(m)
+	if err != nil {
+		return nil, ""
+	}
+	return out, kind
+}
@@ -0,0 +1,69 @@
+package federation
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRelaySanitizesRemoteState(t *testing.T) {
+	id := uuid.NewString()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sse/"+id || r.URL.Query().Get("spectate") != "1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"kind":"state","fen":"start","role":"player","color":"w","clientId":"abc"}`+"\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+
+	relay, err := NewRelay([]string{upstream.URL}, upstream.Client())
+	if err != nil {
+		t.Fatalf("new relay: %v", err)
+	}
+	host := strings.TrimPrefix(upstream.URL, "http://")
+
+	ch, unsubscribe, err := relay.Subscribe(host, id)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer unsubscribe()
+
+	select {
+	case msg := <-ch:
+		var st map[string]any
+		if err := json.Unmarshal(msg, &st); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if st["role"] != "spectator" || st["clientId"] != nil || st["color"] != nil {
+			t.Fatalf("expected sanitized spectator state, got %v", st)
+		}
+		if st["fen"] != "start" {
+			t.Fatalf("expected fen to pass through, got %v", st["fen"])
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no state relayed")
+	}
+}
+
+func TestRelayRejectsUnknownHost(t *testing.T) {
+	relay, err := NewRelay([]string{"chess.example.org"}, nil)
+	if err != nil {
+		t.Fatalf("new relay: %v", err)
+	}
+	if _, _, err := relay.Subscribe("evil.example.org", uuid.NewString()); err != ErrHostNotAllowed {
+		t.Fatalf("expected ErrHostNotAllowed, got %v", err)
+	}
+	if _, _, err := relay.Subscribe("chess.example.org", "../admin"); err != ErrBadGameID {
+		t.Fatalf("expected ErrBadGameID, got %v", err)
+	}
+}
@@ -0,0 +1,430 @@
+// Package fediverse publishes finished games as ActivityPub notes from a
+// single instance actor, so fediverse users can follow an instance's games.
+package fediverse
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"tinychess/internal/logging"
+)
+
+// ContentType is the media type for ActivityPub documents.
+const ContentType = `application/activity+json`
+
+// Username is the instance actor's preferred username, as in games@host.
+const Username = "games"
+
+const (
+	publicAudience = "https://www.w3.org/ns/activitystreams#Public"
+	maxOutbox      = 50
+	maxBody        = 1 << 20
+)
+
+// FollowerStore persists followers of the instance actor.
+type FollowerStore interface {
+	AddFollower(ctx context.Context, actor, inbox string) error
+	RemoveFollower(ctx context.Context, actor string) error
+	FollowerInboxes(ctx context.Context) ([]string, error)
+}
+
+// DeliveryKind is the outbox event kind of deliveries to follower inboxes.
+const DeliveryKind = "fediverse.deliver"
+
+// Queue holds deliveries for at-least-once sending, see package outbox.
+type Queue interface {
+	Publish(ctx context.Context, kind, target string, payload []byte) error
+}
+
+// FinishedGame describes a game to announce.
+type FinishedGame struct {
+	ID     string
+	Status string
+	At     time.Time
+}
+
+// Publisher is the instance actor. It answers WebFinger and actor lookups,
+// accepts follows and delivers a note for every finished game.
+type Publisher struct {
+	BaseURL string
+	Name    string
+	HTTP    *http.Client
+	// Queue, when set, holds deliveries so they are retried until they
+	// succeed; otherwise each is attempted once. The queue must hand
+	// DeliveryKind events to Deliver.
+	Queue Queue
+
+	key       *rsa.PrivateKey
+	followers FollowerStore
+
+	mu        sync.Mutex
+	notes     []map[string]any // newest first
+	published map[string]bool
+}
+
+// New returns a publisher for the instance at baseURL. Followers are kept in
+// memory when followers is nil.
+func New(baseURL, name string, key *rsa.PrivateKey, followers FollowerStore) *Publisher {
+	if followers == nil {
+		followers = &memoryFollowers{inboxes: make(map[string]string)}
+	}
+	return &Publisher{
+		BaseURL:   strings.TrimRight(baseURL, "/"),
+		Name:      name,
+		HTTP:      &http.Client{Timeout: 10 * time.Second},
+		key:       key,
+		followers: followers,
+		published: make(map[string]bool),
+	}
+}
+
+// LoadKey reads the actor's RSA key from a PEM file, generating and saving a
+// new key if the file does not exist yet.
+func LoadKey(path string) (*rsa.PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("fediverse: no PEM data in %s", path)
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+// ActorURL is the instance actor's id.
+func (p *Publisher) ActorURL() string { return p.BaseURL + "/ap/actor" }
+
+func (p *Publisher) keyID() string { return p.ActorURL() + "#main-key" }
+
+// Actor returns the actor document.
+func (p *Publisher) Actor() map[string]any {
+	pub, _ := x509.MarshalPKIXPublicKey(&p.key.PublicKey)
+	return map[string]any{
+		"@context":          []string{"https://www.w3.org/ns/activitystreams", "https://w3id.org/security/v1"},
+		"id":                p.ActorURL(),
+		"type":              "Service",
+		"preferredUsername": Username,
+		"name":              p.Name,
+		"summary":           "Finished games on " + html.EscapeString(p.Name),
+		"url":               p.BaseURL,
+		"inbox":             p.BaseURL + "/ap/inbox",
+		"outbox":            p.BaseURL + "/ap/outbox",
+		"followers":         p.BaseURL + "/ap/followers",
+		"publicKey": map[string]any{
+			"id":           p.keyID(),
+			"owner":        p.ActorURL(),
+			"publicKeyPem": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub})),
+		},
+	}
+}
+
+// WebFinger resolves acct:games@host to the actor. It reports false for any
+// other resource.
+func (p *Publisher) WebFinger(resource string) (map[string]any, bool) {
+	u, err := url.Parse(p.BaseURL)
+	if err != nil {
+		return nil, false
+	}
+	subject := "acct:" + Username + "@" + u.Host
+	if resource != subject && resource != p.ActorURL() {
+		return nil, false
+	}
+	return map[string]any{
+		"subject": subject,
+		"aliases": []string{p.ActorURL()},
+		"links": []map[string]string{
+			{"rel": "self", "type": ContentType, "href": p.ActorURL()},
+			{"rel": "http://webfinger.net/rel/profile-page", "type": "text/html", "href": p.BaseURL},
+		},
+	}, true
+}
+
+// Outbox returns the most recent notes as an ordered collection.
+func (p *Publisher) Outbox() map[string]any {
+	p.mu.Lock()
+	items := make([]map[string]any, 0, len(p.notes))
+	for _, note := range p.notes {
+		items = append(items, createActivity(p.ActorURL(), note))
+	}
+	p.mu.Unlock()
+	return map[string]any{
+		"@context":     "https://www.w3.org/ns/activitystreams",
+		"id":           p.BaseURL + "/ap/outbox",
+		"type":         "OrderedCollection",
+		"totalItems":   len(items),
+		"orderedItems": items,
+	}
+}
+
+// Followers returns the followers collection. Only the count is disclosed.
+func (p *Publisher) Followers(ctx context.Context) (map[string]any, error) {
+	inboxes, err := p.followers.FollowerInboxes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"@context":   "https://www.w3.org/ns/activitystreams",
+		"id":         p.BaseURL + "/ap/followers",
+		"type":       "OrderedCollection",
+		"totalItems": len(inboxes),
+	}, nil
+}
+
+// Note returns a published note by game id.
+func (p *Publisher) Note(id string) (map[string]any, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, note := range p.notes {
+		if note["id"] == p.noteURL(id) {
+			return note, true
+		}
+	}
+	return nil, false
+}
+
+func (p *Publisher) noteURL(id string) string { return p.BaseURL + "/ap/notes/" + id }
+
+// PublishGame announces a finished game to all followers. Each game is
+// published at most once.
+func (p *Publisher) PublishGame(ctx context.Context, g FinishedGame) {
+	p.mu.Lock()
+	if p.published[g.ID] {
+		p.mu.Unlock()
+		return
+	}
+	p.published[g.ID] = true
+	gameURL := p.BaseURL + "/" + g.ID
+	note := map[string]any{
+		"id":           p.noteURL(g.ID),
+		"type":         "Note",
+		"attributedTo": p.ActorURL(),
+		"published":    g.At.UTC().Format(time.RFC3339),
+		"to":           []string{publicAudience},
+		"cc":           []string{p.BaseURL + "/ap/followers"},
+		"url":          gameURL,
+		"content": fmt.Sprintf(`<p>%s</p><p><a href="%s">Replay</a> · <a href="%s">PGN</a></p>`,
+			html.EscapeString(g.Status), gameURL, p.BaseURL+"/api/games/"+g.ID+"/pgn"),
+		"attachment": []map[string]string{{
+			"type":      "Document",
+			"mediaType": "image/svg+xml",
+			"url":       p.BaseURL + "/api/games/" + g.ID + "/board.svg",
+			"name":      "Final position",
+		}},
+	}
+	p.notes = append([]map[string]any{note}, p.notes...)
+	if len(p.notes) > maxOutbox {
+		p.notes = p.notes[:maxOutbox]
+	}
+	p.mu.Unlock()
+
+	inboxes, err := p.followers.FollowerInboxes(ctx)
+	if err != nil {
+		logging.Debugf("fediverse: list followers: %v", err)
+		return
+	}
+	activity := createActivity(p.ActorURL(), note)
+	for _, inbox := range inboxes {
+		p.send(ctx, inbox, activity)
+	}
+}
+
+func createActivity(actor string, note map[string]any) map[string]any {
+	return map[string]any{
+		"@context":  "https://www.w3.org/ns/activitystreams",
+		"id":        note["id"].(string) + "/activity",
+		"type":      "Create",
+		"actor":     actor,
+		"published": note["published"],
+		"to":        note["to"],
+		"cc":        note["cc"],
+		"object":    note,
+	}
+}
+
+// activity is the subset of an incoming activity the inbox understands.
+type activity struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Actor  string          `json:"actor"`
+	Object json.RawMessage `json:"object"`
+}
+
+// HandleActivity processes an activity posted to the inbox. Follow and
+// Undo{Follow} are handled; everything else is ignored. The follower's inbox
+// is always read from its own actor document, so a forged Follow can at most
+// send public notes to the actor it names.
+func (p *Publisher) HandleActivity(ctx context.Context, body io.Reader) error {
+	var act activity
+	if err := json.NewDecoder(io.LimitReader(body, maxBody)).Decode(&act); err != nil {
+		return err
+	}
+	if act.Actor == "" {
+		return errors.New("fediverse: activity without actor")
+	}
+	switch act.Type {
+	case "Follow":
+		inbox, err := p.fetchInbox(ctx, act.Actor)
+		if err != nil {
+			return err
+		}
+		if err := p.followers.AddFollower(ctx, act.Actor, inbox); err != nil {
+			return err
+		}
+		accept := map[string]any{
+			"@context": "https://www.w3.org/ns/activitystreams",
+			"id":       p.ActorURL() + "#accept-" + fmt.Sprint(time.Now().UnixNano()),
+			"type":     "Accept",
+			"actor":    p.ActorURL(),
+			"object": map[string]string{
+				"id":     act.ID,
+				"type":   "Follow",
+				"actor":  act.Actor,
+				"object": p.ActorURL(),
+			},
+		}
+		if p.Queue != nil {
+			p.send(ctx, inbox, accept)
+		} else {
+			go p.send(context.Background(), inbox, accept)
+		}
+	case "Undo":
+		var inner activity
+		if err := json.Unmarshal(act.Object, &inner); err == nil && inner.Type == "Follow" {
+			return p.followers.RemoveFollower(ctx, act.Actor)
+		}
+	}
+	return nil
+}
+
+func (p *Publisher) fetchInbox(ctx context.Context, actor string) (string, error) {
+	u, err := url.Parse(actor)
+	if err != nil || u.Scheme != "https" && u.Scheme != "http" {
+		return "", fmt.Errorf("fediverse: bad actor %q", actor)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, actor, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", ContentType)
+	if err := p.sign(req, nil); err != nil {
+		return "", err
+	}
+	res, err := p.HTTP.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fediverse: fetch actor: status %d", res.StatusCode)
+	}
+	var doc struct {
+		Inbox string `json:"inbox"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxBody)).Decode(&doc); err != nil {
+		return "", err
+	}
+	if doc.Inbox == "" {
+		return "", errors.New("fediverse: actor has no inbox")
+	}
+	return doc.Inbox, nil
+}
+
+// send delivers an activity to inbox through the queue, or directly when
+// there is none.
+func (p *Publisher) send(ctx context.Context, inbox string, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		logging.Debugf("fediverse: encode activity: %v", err)
+		return
+	}
+	if p.Queue != nil {
+		err = p.Queue.Publish(ctx, DeliveryKind, inbox, body)
+	} else {
+		err = p.Deliver(ctx, inbox, body)
+	}
+	if err != nil {
+		logging.Debugf("fediverse: deliver to %s: %v", inbox, err)
+	}
+}
+
+// Deliver signs and posts an encoded activity to inbox.
+func (p *Publisher) Deliver(ctx context.Context, inbox string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	if err := p.sign(req, body); err != nil {
+		return err
+	}
+	res, err := p.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("status %d", res.StatusCode)
+	}
+	return nil
+}
+
+type memoryFollowers struct {
+	mu      sync.Mutex
+	inboxes map[string]string // actor -> inbox
+}
+
+func (m *memoryFollowers) AddFollower(_ context.Context, actor, inbox string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inboxes[actor] = inbox
+	return nil
+}
+
+func (m *memoryFollowers) RemoveFollower(_ context.Context, actor string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.inboxes, actor)
+	return nil
+}
+
+func (m *memoryFollowers) FollowerInboxes(context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := make(map[string]bool)
+	out := []string{}
+	for _, inbox := range m.inboxes {
+		if !seen[inbox] {
+			seen[inbox] = true
+			out = append(out, inbox)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,99 @@
+package fediverse
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFollowAndPublish(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	p := New("https://chess.example.org", "Example Chess", key, nil)
+
+	received := make(chan string, 4)
+	sigRE := regexp.MustCompile(`headers="([^"]+)",signature="([^"]+)"`)
+	var follower *httptest.Server
+	follower = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/actor":
+			_ = json.NewEncoder(w).Encode(map[string]string{"inbox": follower.URL + "/inbox"})
+		case "/inbox":
+			m := sigRE.FindStringSubmatch(r.Header.Get("Signature"))
+			if m == nil {
+				t.Errorf("missing signature")
+				return
+			}
+			sig, _ := base64.StdEncoding.DecodeString(m[2])
+			hash := sha256.Sum256([]byte(signingString(r, strings.Fields(m[1]))))
+			if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], sig); err != nil {
+				t.Errorf("bad signature: %v", err)
+			}
+			var act struct {
+				Type string `json:"type"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&act)
+			received <- act.Type
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer follower.Close()
+
+	follow := `{"id":"` + follower.URL + `/follow/1","type":"Follow","actor":"` + follower.URL + `/actor","object":"` + p.ActorURL() + `"}`
+	if err := p.HandleActivity(context.Background(), strings.NewReader(follow)); err != nil {
+		t.Fatalf("follow: %v", err)
+	}
+	expectActivity(t, received, "Accept")
+
+	g := FinishedGame{ID: "game-1", Status: "1-0 by checkmate", At: time.Now()}
+	p.PublishGame(context.Background(), g)
+	p.PublishGame(context.Background(), g)
+	expectActivity(t, received, "Create")
+	select {
+	case typ := <-received:
+		t.Fatalf("game published twice: %s", typ)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if _, ok := p.Note("game-1"); !ok {
+		t.Fatal("expected note to be served")
+	}
+	if out := p.Outbox(); out["totalItems"] != 1 {
+		t.Fatalf("expected one outbox item, got %v", out["totalItems"])
+	}
+}
+
+func TestWebFinger(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	p := New("https://chess.example.org/", "Example Chess", key, nil)
+	if _, ok := p.WebFinger("acct:games@chess.example.org"); !ok {
+		t.Fatal("expected instance actor to resolve")
+	}
+	if _, ok := p.WebFinger("acct:someone@chess.example.org"); ok {
+		t.Fatal("expected other accounts not to resolve")
+	}
+}
+
+func expectActivity(t *testing.T, ch <-chan string, want string) {
+	t.Helper()
+	select {
+	case got := <-ch:
+		if got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no %s delivered", want)
+	}
+}
@@ -0,0 +1,316 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+
+	"github.com/corentings/chess/v2"
+
+	"tinychess/internal/logging"
+	"tinychess/internal/tablebase"
+)
+
+// Centipawn losses at which a move is flagged.
+const (
+	InaccuracyLoss = 50
+	MistakeLoss    = 100
+	BlunderLoss    = 300
+)
+
+// mateScore is the centipawn value given to a forced mate; losses are
+// measured on scores capped at ±maxLossScore so one missed mate does not
+// swamp the accuracy figures.
+const (
+	mateScore    = 10000
+	maxLossScore = 1000
+)
+
+// AnalysisQueueSize bounds the number of finished games waiting for analysis.
+const AnalysisQueueSize = 64
+
+// MoveAnalysis is the engine's view of one ply. Scores are from white's point
+// of view; Loss is from the mover's.
+type MoveAnalysis struct {
+	Ply            int    `json:"ply"`
+	UCI            string `json:"uci"`
+	SAN            string `json:"san"`
+	Color          string `json:"color"`
+	CP             int    `json:"cp"`
+	Mate           int    `json:"mate,omitempty"`
+	BestMove       string `json:"bestMove"`
+	Loss           int    `json:"loss"`
+	Classification string `json:"classification,omitempty"`
+	// Tablebase is the exact verdict on endgame plies, when a tablebase is
+	// configured and covers the position.
+	Tablebase *TablebaseVerdict `json:"tablebase,omitempty"`
+}
+
+// TablebaseVerdict is the tablebase's outcome of a ply for the mover, before
+// and after the move, so a move that changes the result shows as such.
+type TablebaseVerdict struct {
+	Before tablebase.Category `json:"before"`
+	After  tablebase.Category `json:"after"`
+	// DTZ is the distance to zeroing before the move, see tablebase.Move.
+	DTZ      *int   `json:"dtz,omitempty"`
+	BestMove string `json:"bestMove,omitempty"`
+}
+
+// SideSummary counts the flagged moves of one side.
+type SideSummary struct {
+	Accuracy     float64 `json:"accuracy"`
+	Inaccuracies int     `json:"inaccuracies"`
+	Mistakes     int     `json:"mistakes"`
+	Blunders     int     `json:"blunders"`
+}
+
+// AnalysisReport is the post-game engine analysis of a finished game.
+type AnalysisReport struct {
+	Moves []MoveAnalysis `json:"moves"`
+	White SideSummary    `json:"white"`
+	Black SideSummary    `json:"black"`
+}
+
+// positionEval is an engine verdict on the position before a ply.
+type positionEval struct {
+	CP       int
+	Mate     int
+	BestMove string
+}
+
+// score folds mates into centipawns.
+func (e positionEval) score() int {
+	switch {
+	case e.Mate > 0:
+		return mateScore
+	case e.Mate < 0:
+		return -mateScore
+	}
+	return e.CP
+}
+
+// winChance maps a centipawn score to white's winning chances in percent.
+func winChance(cp int) float64 {
+	return 50 + 50*(2/(1+math.Exp(-0.00368208*float64(cp)))-1)
+}
+
+// moveAccuracy scores a move from the drop in the mover's winning chances.
+func moveAccuracy(before, after float64) float64 {
+	acc := 103.1668*math.Exp(-0.04354*(before-after)) - 3.1669
+	return math.Max(0, math.Min(100, acc))
+}
+
+func clampScore(cp int) int {
+	return max(-maxLossScore, min(maxLossScore, cp))
+}
+
+// classify names the kind of error a centipawn loss represents.
+func classify(loss int) string {
+	switch {
+	case loss >= BlunderLoss:
+		return "blunder"
+	case loss >= MistakeLoss:
+		return "mistake"
+	case loss >= InaccuracyLoss:
+		return "inaccuracy"
+	}
+	return ""
+}
+
+// buildReport turns evaluations of every position in a game, starting with
+// the initial one, into a report. evals must hold one more entry than uci.
+func buildReport(start string, uci []string, evals []positionEval) AnalysisReport {
+	report := AnalysisReport{Moves: make([]MoveAnalysis, 0, len(uci))}
+	san := FormatMoves(start, uci, NotationSAN)
+	blackFirst := newChessGame(start).Position().Turn() == chess.Black
+	var accWhite, accBlack []float64
+	for i, m := range uci {
+		before, after := evals[i], evals[i+1]
+		white := (i%2 == 0) != blackFirst
+		sign := 1
+		if !white {
+			sign = -1
+		}
+		loss := max(0, sign*(clampScore(before.score())-clampScore(after.score())))
+		winBefore, winAfter := winChance(sign*clampScore(before.score())), winChance(sign*clampScore(after.score()))
+		acc := moveAccuracy(winBefore, winAfter)
+
+		ma := MoveAnalysis{
+			Ply:            i + 1,
+			UCI:            m,
+			CP:             after.CP,
+			Mate:           after.Mate,
+			BestMove:       before.BestMove,
+			Loss:           loss,
+			Classification: classify(loss),
+		}
+		if i < len(san) {
+			ma.SAN = san[i]
+		}
+		side := &report.Black
+		ma.Color = chess.Black.String()
+		if white {
+			side = &report.White
+			ma.Color = chess.White.String()
+			accWhite = append(accWhite, acc)
+		} else {
+			accBlack = append(accBlack, acc)
+		}
+		switch ma.Classification {
+		case "blunder":
+			side.Blunders++
+		case "mistake":
+			side.Mistakes++
+		case "inaccuracy":
+			side.Inaccuracies++
+		}
+		report.Moves = append(report.Moves, ma)
+	}
+	report.White.Accuracy = mean(accWhite)
+	report.Black.Accuracy = mean(accBlack)
+	return report
+}
+
+// withTablebase attaches tablebase verdicts to the plies whose positions
+// before and after were both probed. probes holds one entry per position,
+// nil where there is no verdict.
+func withTablebase(report *AnalysisReport, probes []*tablebase.Result) {
+	for i := range report.Moves {
+		if i+1 >= len(probes) || probes[i] == nil || probes[i+1] == nil {
+			continue
+		}
+		before, after := probes[i], probes[i+1]
+		v := &TablebaseVerdict{Before: before.Category, After: after.Category.Opposite(), DTZ: before.DTZ}
+		if len(before.Moves) > 0 {
+			v.BestMove = before.Moves[0].UCI
+		}
+		report.Moves[i].Tablebase = v
+	}
+}
+
+// probeTablebase looks pos up in the hub's tablebase, returning nil when there
+// is none, it does not cover the position, or the probe fails.
+func (h *Hub) probeTablebase(ctx context.Context, pos *chess.Position) *tablebase.Result {
+	fen := pos.String()
+	if h.Tablebase == nil || tablebase.Pieces(fen) > tablebase.MaxPieces {
+		return nil
+	}
+	r, err := h.Tablebase.Probe(ctx, fen)
+	if err != nil {
+		logging.Debugf("tablebase probe failed: %v", err)
+		return nil
+	}
+	return &r
+}
+
+func mean(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return math.Round(sum/float64(len(xs))*10) / 10
+}
+
+// Analysis returns the game's post-game report, if one has been made.
+func (g *Game) Analysis() *AnalysisReport {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return g.analysis
+}
+
+// QueueAnalysis schedules a finished game for post-game analysis. It does
+// nothing without an engine, for unfinished games, games already analysed or
+// queued, or when the queue is full.
+func (h *Hub) QueueAnalysis(g *Game) {
+	if h.Engine == nil {
+		return
+	}
+	g.Mu.Lock()
+	skip := !g.overLocked() || g.analysis != nil || g.analysisQueued
+	if !skip {
+		g.analysisQueued = true
+	}
+	g.Mu.Unlock()
+	if skip {
+		return
+	}
+	select {
+	case h.analysisQueue <- g:
+	default:
+		logging.Debugf("analysis queue full, skipping %s", g.ID)
+		g.Mu.Lock()
+		g.analysisQueued = false
+		g.Mu.Unlock()
+	}
+}
+
+// RunAnalysis analyses queued games one at a time until ctx is done.
+func (h *Hub) RunAnalysis(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case g := <-h.analysisQueue:
+			if err := h.analyze(ctx, g); err != nil {
+				logging.Debugf("analyse %s failed: %v", g.ID, err)
+			}
+			g.Mu.Lock()
+			g.analysisQueued = false
+			g.Mu.Unlock()
+		}
+	}
+}
+
+// analyze evaluates every position of a finished game and stores the report.
+func (h *Hub) analyze(ctx context.Context, g *Game) error {
+	snap := g.Snapshot()
+	uci, start := snap.UCI, snap.StartFEN
+
+	replay := newChessGame(start)
+	evals := make([]positionEval, 0, len(uci)+1)
+	probes := make([]*tablebase.Result, 0, len(uci)+1)
+	for i := 0; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var e positionEval
+		switch {
+		case replay.Method() == chess.Checkmate && replay.Position().Turn() == chess.White:
+			e = positionEval{Mate: -1}
+		case replay.Method() == chess.Checkmate:
+			e = positionEval{Mate: 1}
+		default:
+			a, err := h.Engine.Analyze(replay.Position())
+			if err != nil {
+				return err
+			}
+			e = positionEval{CP: a.CP, Mate: a.Mate, BestMove: a.BestMove}
+		}
+		evals = append(evals, e)
+		probes = append(probes, h.probeTablebase(ctx, replay.Position()))
+		if i == len(uci) {
+			break
+		}
+		if err := replay.PushNotationMove(uci[i], chess.UCINotation{}, nil); err != nil {
+			return err
+		}
+	}
+
+	report := buildReport(start, uci, evals)
+	withTablebase(&report, probes)
+	g.Mu.Lock()
+	g.analysis = &report
+	g.Mu.Unlock()
+
+	if h.Store == nil {
+		return nil
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	return h.Store.SaveAnalysis(ctx, g.ID.UUID(), string(data))
+}
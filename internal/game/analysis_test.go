@@ -0,0 +1,58 @@
+package game
+
+import (
+	"testing"
+
+	"tinychess/internal/tablebase"
+)
+
+func TestBuildReportClassifiesLosses(t *testing.T) {
+	uci := []string{"e2e4", "e7e5", "d1h5", "g8f6"}
+	evals := []positionEval{
+		{CP: 20, BestMove: "e2e4"},
+		{CP: 25, BestMove: "e7e5"},
+		{CP: 30, BestMove: "g1f3"},
+		{CP: -40, BestMove: "b8c6"}, // white lost 70: inaccuracy
+		{CP: 500, BestMove: "h5e5"}, // black lost 540: blunder
+	}
+	report := buildReport("", uci, evals)
+
+	if len(report.Moves) != 4 {
+		t.Fatalf("expected 4 moves, got %d", len(report.Moves))
+	}
+	if got := report.Moves[2]; got.Classification != "inaccuracy" || got.Loss != 70 || got.SAN != "Qh5" || got.BestMove != "g1f3" {
+		t.Fatalf("unexpected analysis for Qh5: %+v", got)
+	}
+	if got := report.Moves[3]; got.Classification != "blunder" || got.Color != "b" {
+		t.Fatalf("unexpected analysis for Nf6: %+v", got)
+	}
+	if report.White.Inaccuracies != 1 || report.Black.Blunders != 1 {
+		t.Fatalf("unexpected summaries: %+v %+v", report.White, report.Black)
+	}
+	if report.White.Accuracy <= report.Black.Accuracy || report.White.Accuracy > 100 {
+		t.Fatalf("expected white to be more accurate: %v vs %v", report.White.Accuracy, report.Black.Accuracy)
+	}
+}
+
+func TestBuildReportCapsMateScores(t *testing.T) {
+	report := buildReport("", []string{"e2e4"}, []positionEval{{Mate: 3}, {CP: 0}})
+	if got := report.Moves[0].Loss; got != maxLossScore {
+		t.Fatalf("expected loss capped at %d, got %d", maxLossScore, got)
+	}
+}
+
+func TestWithTablebase(t *testing.T) {
+	dtz := 12
+	won := &tablebase.Result{Category: tablebase.Win, DTZ: &dtz, Moves: []tablebase.Move{{UCI: "a1a8"}}}
+	drawn := &tablebase.Result{Category: tablebase.Draw}
+	report := AnalysisReport{Moves: []MoveAnalysis{{Ply: 1}, {Ply: 2}, {Ply: 3}}}
+	withTablebase(&report, []*tablebase.Result{nil, won, drawn, nil})
+
+	if report.Moves[0].Tablebase != nil || report.Moves[2].Tablebase != nil {
+		t.Fatalf("expected plies without both probes to be skipped")
+	}
+	v := report.Moves[1].Tablebase
+	if v == nil || v.Before != tablebase.Win || v.After != tablebase.Draw || *v.DTZ != 12 || v.BestMove != "a1a8" {
+		t.Fatalf("got %+v", v)
+	}
+}
@@ -0,0 +1,41 @@
+package game
+
+import "testing"
+
+func benchmarkWatchers(b *testing.B, n int) *Game {
+	b.Helper()
+	g := newTestGame()
+	for _, uci := range []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1c4", "g8f6"} {
+		if err := g.MakeMove(uci); err != nil {
+			b.Fatalf("move %s: %v", uci, err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		ch := make(chan []byte, 1)
+		g.AddWatcher(ch)
+		go func() {
+			for range ch {
+			}
+		}()
+	}
+	return g
+}
+
+func BenchmarkBroadcast(b *testing.B) {
+	g := benchmarkWatchers(b, 500)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.Broadcast()
+	}
+}
+
+func BenchmarkBroadcastReaction(b *testing.B) {
+	g := benchmarkWatchers(b, 500)
+	payload := ReactionPayload{Schema: SchemaVersion, Kind: "emoji", Emoji: "🔥", Sender: "x"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.BroadcastReaction(payload)
+	}
+}
@@ -0,0 +1,170 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Chat limits. ChatHistoryLimit is how many recent messages are kept and
+// replayed to newly connected watchers; the pause between messages is
+// ChatCooldown.
+const (
+	MaxChatLength    = 280
+	ChatHistoryLimit = 50
+)
+
+// Chat message validation errors.
+var (
+	ErrChatEmpty   = errors.New("empty message")
+	ErrChatTooLong = errors.New("message too long")
+)
+
+// ChatRequest is a chat message sent by a client.
+type ChatRequest struct {
+	Text   string `json:"text"`
+	Sender string `json:"sender"`
+}
+
+// ChatPayload is a chat message broadcast to a game's watchers. Sender is
+// the sender's participant ID and Color their seat, empty for spectators.
+type ChatPayload struct {
+	Schema int    `json:"schema"`
+	Kind   string `json:"kind"`
+	ID     string `json:"id"`
+	Text   string `json:"text"`
+	At     int64  `json:"at"`
+	Sender string `json:"sender"`
+	Color  string `json:"color,omitempty"`
+}
+
+// CleanChat trims a chat message and drops control characters, rejecting
+// messages that end up empty or longer than MaxChatLength.
+func CleanChat(text string) (string, error) {
+	text = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, text))
+	if text == "" {
+		return "", ErrChatEmpty
+	}
+	if utf8.RuneCountInString(text) > MaxChatLength {
+		return "", ErrChatTooLong
+	}
+	return text, nil
+}
+
+// CanChat checks and starts the chat cooldown for a sender.
+func (g *Game) CanChat(sender string) (bool, int) {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+
+	now := time.Now()
+	if t, ok := g.lastChat[sender]; ok && now.Sub(t) < ChatCooldown {
+		wait := int((ChatCooldown - now.Sub(t)).Seconds())
+		return false, wait + 1
+	}
+	if g.lastChat == nil {
+		g.lastChat = make(map[string]time.Time)
+	}
+	g.lastChat[sender] = now
+	return true, 0
+}
+
+// ChatDeletePayload tells watchers to drop a chat message.
+type ChatDeletePayload struct {
+	Schema int    `json:"schema"`
+	Kind   string `json:"kind"`
+	ID     string `json:"id"`
+	At     int64  `json:"at"`
+}
+
+// PostChat adds a chat message from the client payload.Sender to the game's
+// history and sends it to all watchers, filling in its ID and the sender's
+// seat, and swapping the sender for their participant ID.
+func (g *Game) PostChat(payload ChatPayload) ChatPayload {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	if payload.ID == "" {
+		payload.ID = uuid.NewString()
+	}
+	if col, ok := g.Clients[payload.Sender]; ok {
+		payload.Color = col.String()
+	}
+	payload.Sender = g.chatterLocked(payload.Sender)
+	g.appendChatLocked(payload)
+	g.sendLocked(payload)
+	return payload
+}
+
+// chatterLocked returns the participant ID of a chat sender, remembering
+// who it stands for so the owner can still mute them once they have left
+// and their messages are gone. Callers must hold g.Mu.
+func (g *Game) chatterLocked(clientID string) string {
+	id := g.ParticipantID(clientID)
+	if g.chatters == nil {
+		g.chatters = make(map[string]string)
+	}
+	g.chatters[id] = clientID
+	return id
+}
+
+func (g *Game) appendChatLocked(payload ChatPayload) {
+	g.chat = append(g.chat, payload)
+	if len(g.chat) > ChatHistoryLimit {
+		g.chat = slices.Delete(g.chat, 0, len(g.chat)-ChatHistoryLimit)
+	}
+}
+
+// sendLocked broadcasts a payload to all watchers. Callers must hold g.Mu.
+func (g *Game) sendLocked(payload any) {
+	data, _ := json.Marshal(payload)
+	_, state := payload.(GameState)
+	g.deliverLocked(data, state)
+}
+
+// ChatHistory returns the game's recent chat messages, oldest first.
+func (g *Game) ChatHistory() []ChatPayload {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return slices.Clone(g.chat)
+}
+
+// DeleteChat removes a message from the history and tells watchers to drop
+// it. It reports whether the message was found.
+func (g *Game) DeleteChat(id string) bool {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	i := slices.IndexFunc(g.chat, func(m ChatPayload) bool { return m.ID == id })
+	if i < 0 {
+		return false
+	}
+	g.chat = slices.Delete(g.chat, i, i+1)
+	g.sendLocked(ChatDeletePayload{Schema: SchemaVersion, Kind: "chatDelete", ID: id, At: time.Now().UnixMilli()})
+	return true
+}
+
+// MuteChat silences a participant for the rest of the game.
+func (g *Game) MuteChat(clientID string) {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	if g.muted == nil {
+		g.muted = make(map[string]bool)
+	}
+	g.muted[clientID] = true
+}
+
+// ChatMuted reports whether a participant has been muted.
+func (g *Game) ChatMuted(clientID string) bool {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return g.muted[clientID]
+}
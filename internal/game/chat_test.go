@@ -0,0 +1,34 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCleanChat(t *testing.T) {
+	if got, err := CleanChat("  good\x07 game \n"); err != nil || got != "good game" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	if _, err := CleanChat(" \t"); err != ErrChatEmpty {
+		t.Fatalf("expected ErrChatEmpty, got %v", err)
+	}
+	if _, err := CleanChat(strings.Repeat("é", MaxChatLength)); err != nil {
+		t.Fatalf("message at the limit should pass: %v", err)
+	}
+	if _, err := CleanChat(strings.Repeat("a", MaxChatLength+1)); err != ErrChatTooLong {
+		t.Fatalf("expected ErrChatTooLong, got %v", err)
+	}
+}
+
+func TestCanChatCooldown(t *testing.T) {
+	g := newTestGame()
+	if ok, _ := g.CanChat("a"); !ok {
+		t.Fatalf("first message should be allowed")
+	}
+	if ok, wait := g.CanChat("a"); ok || wait < 1 {
+		t.Fatalf("second message should wait, got ok=%v wait=%d", ok, wait)
+	}
+	if ok, _ := g.CanChat("b"); !ok {
+		t.Fatalf("cooldown should be per sender")
+	}
+}
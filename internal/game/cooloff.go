@@ -0,0 +1,66 @@
+package game
+
+import (
+	"time"
+
+	"github.com/corentings/chess/v2"
+)
+
+// coolOffUntilLocked returns when the post-game cool-off that locks
+// reactions and chat after a decisive result ends, or the zero time when
+// they are open. Callers must hold g.Mu; a read lock is enough.
+func (g *Game) coolOffUntilLocked(now time.Time) time.Time {
+	// Post-mortem rooms are for talking the game over.
+	if PostGameCoolOff <= 0 || !g.postMortemOf.IsZero() {
+		return time.Time{}
+	}
+	if o := g.g.Outcome(); o != chess.WhiteWon && o != chess.BlackWon {
+		return time.Time{}
+	}
+	until := g.endedAtLocked().Add(PostGameCoolOff)
+	if !now.Before(until) {
+		return time.Time{}
+	}
+	return until
+}
+
+// endedAtLocked returns when the game ended as far as its record tells: the
+// later of its last move and the events that end games without one.
+func (g *Game) endedAtLocked() time.Time {
+	var at time.Time
+	if n := len(g.playedAt); n > 0 {
+		at = g.playedAt[n-1]
+	}
+	for _, e := range g.timeline {
+		switch e.Kind {
+		case EventResigned, EventAdjudicated, EventAbandoned, EventMoveLimit:
+			if t := time.UnixMilli(e.At); t.After(at) {
+				at = t
+			}
+		}
+	}
+	return at
+}
+
+// CoolOff returns how much longer reactions and chat stay locked after the
+// game's decisive result, 0 when they are open.
+func (g *Game) CoolOff() time.Duration {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	now := time.Now()
+	if until := g.coolOffUntilLocked(now); !until.IsZero() {
+		return until.Sub(now)
+	}
+	return 0
+}
+
+// scheduleReopenLocked broadcasts the state again once the cool-off ending
+// at until is over, so clients unlock reactions and chat without polling.
+// Callers must hold g.Mu.
+func (g *Game) scheduleReopenLocked(until time.Time) {
+	if until.IsZero() || g.reopenAt.Equal(until) {
+		return
+	}
+	g.reopenAt = until
+	time.AfterFunc(time.Until(until), g.Broadcast)
+}
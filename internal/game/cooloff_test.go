@@ -0,0 +1,56 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corentings/chess/v2"
+)
+
+func TestPostGameCoolOff(t *testing.T) {
+	defer func(d time.Duration) { PostGameCoolOff = d }(PostGameCoolOff)
+	PostGameCoolOff = time.Minute
+
+	g := newTestGame()
+	if ok, _ := g.CanReact("a"); !ok {
+		t.Fatalf("reactions should be open during the game")
+	}
+	if err := g.Resign(chess.White); err != nil {
+		t.Fatalf("resign: %v", err)
+	}
+	if ok, wait := g.CanReact("b"); ok || wait < 1 {
+		t.Fatalf("reactions should be locked after a decisive result, got ok=%v wait=%d", ok, wait)
+	}
+	if g.CoolOff() <= 0 {
+		t.Fatalf("expected the cool-off to be running")
+	}
+	g.Mu.RLock()
+	until := g.StateLocked().CoolOffUntil
+	g.Mu.RUnlock()
+	if until <= time.Now().UnixMilli() {
+		t.Fatalf("expected coolOffUntil in the future, got %d", until)
+	}
+
+	g.Mu.Lock()
+	for i := range g.timeline {
+		g.timeline[i].At = time.Now().Add(-2 * time.Minute).UnixMilli()
+	}
+	g.Mu.Unlock()
+	if ok, _ := g.CanReact("b"); !ok {
+		t.Fatalf("reactions should reopen once the cool-off is over")
+	}
+	g.Mu.RLock()
+	until = g.StateLocked().CoolOffUntil
+	g.Mu.RUnlock()
+	if until != 0 {
+		t.Fatalf("expected no coolOffUntil after the cool-off, got %d", until)
+	}
+
+	drawn := newTestGame()
+	if err := drawn.Adjudicate(true); err != nil {
+		t.Fatalf("adjudicate: %v", err)
+	}
+	if drawn.CoolOff() != 0 {
+		t.Fatalf("a draw should not lock reactions and chat")
+	}
+}
@@ -0,0 +1,327 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+
+	"tinychess/internal/tablebase"
+)
+
+// Tablebase probes endgame positions, see package tablebase.
+type Tablebase interface {
+	Probe(ctx context.Context, fen string) (tablebase.Result, error)
+}
+
+// ErrNoTablebase is returned when practice is requested without a configured
+// tablebase.
+var ErrNoTablebase = errors.New("tablebase unavailable")
+
+// ErrUnknownEnding is returned for endings the trainer does not offer.
+var ErrUnknownEnding = errors.New("unknown ending")
+
+// Endings are the material balances the trainer offers, the winning side
+// first.
+var Endings = []string{"KQvK", "KRvK", "KBBvK", "KBNvK", "KPvK", "KQvKR"}
+
+// TablebaseClientID holds the defender's seat in practice games.
+const TablebaseClientID = "tablebase"
+
+// trainingAttempts bounds the random positions tried for a winnable one.
+const trainingAttempts = 32
+
+// Training grades the trainee's moves in an endgame practice game, where the
+// trainee plays the winning side against the tablebase's best defence.
+type Training struct {
+	Ending string `json:"ending"`
+	// Moves counts the graded moves; Optimal those that kept the win at the
+	// shortest distance to zeroing (DTZ).
+	Moves   int `json:"moves"`
+	Optimal int `json:"optimal"`
+	// Accuracy is the percentage of DTZ-optimal moves.
+	Accuracy float64 `json:"accuracy"`
+	// Held is cleared once a move gives the win away.
+	Held bool `json:"held"`
+}
+
+// grade scores the move played from a position probed as verdict.
+func (t *Training) grade(verdict tablebase.Result, played tablebase.Move) {
+	t.Moves++
+	if -played.Category.WDL() < verdict.Category.WDL() {
+		t.Held = false
+	}
+	if len(verdict.Moves) > 0 {
+		best := verdict.Moves[0]
+		if played.Category == best.Category && sameDTZ(played.DTZ, best.DTZ) {
+			t.Optimal++
+		}
+	}
+	t.Accuracy = float64(t.Optimal) * 100 / float64(t.Moves)
+}
+
+func sameDTZ(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// Training returns a copy of the game's practice progress, or nil when it is
+// not a practice game.
+func (g *Game) Training() *Training {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return g.trainingLocked()
+}
+
+func (g *Game) trainingLocked() *Training {
+	if g.training == nil {
+		return nil
+	}
+	t := *g.training
+	return &t
+}
+
+// encodeTraining serializes practice progress for the store, "" for other
+// games.
+func encodeTraining(t *Training) string {
+	if t == nil {
+		return ""
+	}
+	data, _ := json.Marshal(t)
+	return string(data)
+}
+
+func decodeTraining(data string) *Training {
+	if data == "" {
+		return nil
+	}
+	var t Training
+	if err := json.Unmarshal([]byte(data), &t); err != nil || t.Ending == "" {
+		return nil
+	}
+	return &t
+}
+
+// seatTablebaseLocked gives the defender's seat to the tablebase.
+func (g *Game) seatTablebaseLocked() {
+	color := chess.White
+	if g.OwnerColor == chess.White {
+		color = chess.Black
+	}
+	g.Clients[TablebaseClientID] = color
+	if g.names == nil {
+		g.names = make(map[string]string)
+	}
+	g.names[TablebaseClientID] = "Tablebase"
+}
+
+// StartTraining creates a practice game for ending, owned by ownerID, who
+// plays the winning side from a random position the tablebase confirms is
+// won.
+func (h *Hub) StartTraining(ctx context.Context, ownerID, ending string) (GameID, chess.Color, error) {
+	if h.Tablebase == nil {
+		return GameID{}, chess.NoColor, ErrNoTablebase
+	}
+	strong, weak, ok := parseEnding(ending)
+	if !ok {
+		return GameID{}, chess.NoColor, ErrUnknownEnding
+	}
+	color := randomColor()
+	for i := 0; i < trainingAttempts; i++ {
+		fen := randomEndgame(strong, weak, color)
+		if fen == "" {
+			continue
+		}
+		verdict, err := h.Tablebase.Probe(ctx, fen)
+		if err != nil {
+			return GameID{}, chess.NoColor, err
+		}
+		if verdict.Category != tablebase.Win {
+			continue
+		}
+		return h.CreateGame(ctx, ownerID, GameOptions{StartFEN: fen, Color: color, Ending: ending})
+	}
+	return GameID{}, chess.NoColor, fmt.Errorf("no winning %s position found", ending)
+}
+
+// TrainingReply grades the trainee's move uci, played from the position
+// before, and answers with the tablebase's best defence. It returns the
+// reply in UCI, or "" when the game ended with the trainee's move.
+func (h *Hub) TrainingReply(ctx context.Context, g *Game, before, uci string) (string, error) {
+	if h.Tablebase == nil {
+		return "", ErrNoTablebase
+	}
+	verdict, err := h.Tablebase.Probe(ctx, before)
+	if err != nil {
+		return "", err
+	}
+	played, ok := verdict.Move(uci)
+	if !ok {
+		return "", fmt.Errorf("move %s not in tablebase verdict", uci)
+	}
+
+	g.Mu.Lock()
+	if g.training != nil {
+		g.training.grade(verdict, played)
+	}
+	after := g.g.Position().String()
+	over := g.overLocked()
+	g.Mu.Unlock()
+	if over {
+		return "", nil
+	}
+
+	defence, err := h.Tablebase.Probe(ctx, after)
+	if err != nil {
+		return "", err
+	}
+	if len(defence.Moves) == 0 {
+		return "", errors.New("no defence in tablebase verdict")
+	}
+	reply := defence.Moves[0].UCI
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	if g.g.Position().String() != after {
+		return "", errors.New("position changed while probing")
+	}
+	if err := g.applyMoveLocked(reply); err != nil {
+		return "", err
+	}
+	return reply, nil
+}
+
+// parseEnding splits an ending such as "KRvK" into the pieces of each side.
+func parseEnding(ending string) (strong, weak string, ok bool) {
+	for _, e := range Endings {
+		if e == ending {
+			strong, weak, _ = strings.Cut(ending, "v")
+			return strong, weak, true
+		}
+	}
+	return "", "", false
+}
+
+// randomEndgame places the pieces of strong for color and weak for the other
+// side on random squares, with color to move. It returns "" when the
+// placement is not a legal starting position.
+func randomEndgame(strong, weak string, color chess.Color) string {
+	var board [64]byte
+	place := func(piece byte) bool {
+		for tries := 0; tries < 64; tries++ {
+			sq := rand.Intn(64)
+			rank := sq / 8
+			if board[sq] != 0 || (piece|0x20 == 'p' && (rank == 0 || rank == 7)) {
+				continue
+			}
+			board[sq] = piece
+			return true
+		}
+		return false
+	}
+	for _, p := range []byte(strong) {
+		if !place(p) {
+			return ""
+		}
+	}
+	for _, p := range []byte(weak) {
+		if !place(p | 0x20) {
+			return ""
+		}
+	}
+	if kingsTouch(board) || (strings.Count(strong, "B") == 2 && !oppositeBishops(board)) {
+		return ""
+	}
+
+	var b strings.Builder
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			p := board[rank*8+file]
+			if p == 0 {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				b.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			b.WriteByte(p)
+		}
+		if empty > 0 {
+			b.WriteByte(byte('0' + empty))
+		}
+		if rank > 0 {
+			b.WriteByte('/')
+		}
+	}
+	fen := b.String() + " w - - 0 1"
+	if color == chess.Black {
+		fen = mirrorFEN(fen)
+	}
+	fen, err := ParseStartFEN(fen)
+	if err != nil {
+		return ""
+	}
+	return fen
+}
+
+// kingsTouch reports whether the kings stand next to each other, which
+// ParseStartFEN cannot see since neither king may capture the other.
+func kingsTouch(board [64]byte) bool {
+	var kings []int
+	for sq, p := range board {
+		if p == 'K' || p == 'k' {
+			kings = append(kings, sq)
+		}
+	}
+	if len(kings) != 2 {
+		return false
+	}
+	dr := kings[0]/8 - kings[1]/8
+	df := kings[0]%8 - kings[1]%8
+	return dr >= -1 && dr <= 1 && df >= -1 && df <= 1
+}
+
+// oppositeBishops reports whether the white bishops stand on squares of
+// different colors.
+func oppositeBishops(board [64]byte) bool {
+	seen := map[int]bool{}
+	for sq, p := range board {
+		if p == 'B' {
+			seen[(sq/8+sq%8)%2] = true
+		}
+	}
+	return len(seen) == 2
+}
+
+// mirrorFEN swaps the colors of a position without castling or en passant
+// rights, turning white's win into black's.
+func mirrorFEN(fen string) string {
+	fields := strings.Fields(fen)
+	ranks := strings.Split(fields[0], "/")
+	for i, j := 0, len(ranks)-1; i < j; i, j = i+1, j-1 {
+		ranks[i], ranks[j] = ranks[j], ranks[i]
+	}
+	swapped := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 0x20
+		case r >= 'A' && r <= 'Z':
+			return r + 0x20
+		}
+		return r
+	}, strings.Join(ranks, "/"))
+	fields[0] = swapped
+	if fields[1] == "w" {
+		fields[1] = "b"
+	} else {
+		fields[1] = "w"
+	}
+	return strings.Join(fields, " ")
+}
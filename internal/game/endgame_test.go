@@ -0,0 +1,141 @@
+package game
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+
+	"tinychess/internal/tablebase"
+)
+
+// fakeTablebase calls every position won for the side to move. The moves in
+// best are DTZ-optimal and listed first.
+type fakeTablebase struct {
+	best map[string]bool
+}
+
+func (f fakeTablebase) Probe(ctx context.Context, fen string) (tablebase.Result, error) {
+	g, err := GameFromFEN(fen)
+	if err != nil {
+		return tablebase.Result{}, err
+	}
+	fast, slow := -3, -9
+	r := tablebase.Result{Category: tablebase.Win}
+	var rest []tablebase.Move
+	for _, m := range g.ValidMoves() {
+		mv := tablebase.Move{UCI: chess.UCINotation{}.Encode(g.Position(), &m), Category: tablebase.Loss, DTZ: &slow}
+		if f.best[mv.UCI] {
+			mv.DTZ = &fast
+			r.Moves = append(r.Moves, mv)
+		} else {
+			rest = append(rest, mv)
+		}
+	}
+	r.Moves = append(r.Moves, rest...)
+	return r, nil
+}
+
+func TestRandomEndgame(t *testing.T) {
+	for _, ending := range Endings {
+		strong, weak, ok := parseEnding(ending)
+		if !ok {
+			t.Fatalf("%s: not parsed", ending)
+		}
+		for _, color := range []chess.Color{chess.White, chess.Black} {
+			var fen string
+			for i := 0; i < 100 && fen == ""; i++ {
+				fen = randomEndgame(strong, weak, color)
+			}
+			if fen == "" {
+				t.Fatalf("%s: no legal position", ending)
+			}
+			if got := tablebase.Pieces(fen); got != len(strong)+len(weak) {
+				t.Fatalf("%s: %q has %d pieces", ending, fen, got)
+			}
+			if turn := strings.Fields(fen)[1]; turn != color.String() {
+				t.Fatalf("%s: %q has %s to move, want %s", ending, fen, turn, color)
+			}
+		}
+	}
+	if _, _, ok := parseEnding("KKvK"); ok {
+		t.Fatalf("expected an unknown ending to be refused")
+	}
+}
+
+func TestMirrorFEN(t *testing.T) {
+	got := mirrorFEN("8/8/8/4k3/8/8/4P3/4K3 w - - 0 1")
+	if want := "4k3/4p3/8/8/4K3/8/8/8 b - - 0 1"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestTrainingReply(t *testing.T) {
+	h := NewHub(nil)
+	if _, _, err := h.StartTraining(context.Background(), uuid.NewString(), "KRvK"); err != ErrNoTablebase {
+		t.Fatalf("expected ErrNoTablebase, got %v", err)
+	}
+	h.Tablebase = fakeTablebase{}
+	if _, _, err := h.StartTraining(context.Background(), uuid.NewString(), "KKvK"); err != ErrUnknownEnding {
+		t.Fatalf("expected ErrUnknownEnding, got %v", err)
+	}
+
+	id, color, err := h.StartTraining(context.Background(), uuid.NewString(), "KRvK")
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	g, _ := h.Lookup(id)
+	if g.Clients[TablebaseClientID] != color.Other() {
+		t.Fatalf("tablebase not seated against the trainee")
+	}
+	if tr := g.Training(); tr == nil || tr.Ending != "KRvK" || !tr.Held {
+		t.Fatalf("got training %+v", tr)
+	}
+
+	h.Tablebase = fakeTablebase{best: map[string]bool{"a1a7": true, "e1d2": true}}
+	id, _, err = h.CreateGame(context.Background(), uuid.NewString(), GameOptions{
+		StartFEN: "4k3/8/8/8/8/8/8/R3K3 w - - 0 1",
+		Color:    chess.White,
+		Ending:   "KRvK",
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	g, _ = h.Lookup(id)
+	for _, uci := range []string{"a1a7", "e1e2"} {
+		before := g.g.Position().String()
+		if err := g.MakeMove(uci); err != nil {
+			t.Fatalf("move %s: %v", uci, err)
+		}
+		reply, err := h.TrainingReply(context.Background(), g, before, uci)
+		if err != nil {
+			t.Fatalf("reply to %s: %v", uci, err)
+		}
+		if got := g.MovesUCI(); reply == "" || got[len(got)-1] != reply {
+			t.Fatalf("reply %q not played, moves %v", reply, got)
+		}
+	}
+
+	tr := g.Training()
+	if tr.Moves != 2 || tr.Optimal != 1 || tr.Accuracy != 50 || !tr.Held {
+		t.Fatalf("got training %+v", tr)
+	}
+	g.Mu.RLock()
+	state := g.StateLocked()
+	g.Mu.RUnlock()
+	if state.Training == nil || state.Training.Moves != 2 || state.Black != "Tablebase" {
+		t.Fatalf("state training %+v, black %q", state.Training, state.Black)
+	}
+}
+
+func TestTrainingGrade(t *testing.T) {
+	zero, one := 0, 1
+	verdict := tablebase.Result{Category: tablebase.Win, Moves: []tablebase.Move{{UCI: "a1a8", Category: tablebase.Loss, DTZ: &one}}}
+	tr := Training{Held: true}
+	tr.grade(verdict, tablebase.Move{UCI: "a1a2", Category: tablebase.Draw, DTZ: &zero})
+	if tr.Held || tr.Optimal != 0 || tr.Moves != 1 {
+		t.Fatalf("got %+v", tr)
+	}
+}
@@ -0,0 +1,89 @@
+package game
+
+import (
+	"github.com/corentings/chess/v2"
+
+	"tinychess/internal/logging"
+)
+
+// pieceValues are the conventional material values in pawns.
+var pieceValues = map[chess.PieceType]int{
+	chess.Pawn:   1,
+	chess.Knight: 3,
+	chess.Bishop: 3,
+	chess.Rook:   5,
+	chess.Queen:  9,
+}
+
+// Material is the material on the board in pawns. Balance is white minus
+// black.
+type Material struct {
+	White   int `json:"white"`
+	Black   int `json:"black"`
+	Balance int `json:"balance"`
+}
+
+// Evaluation is an engine score for the current position, from white's point
+// of view.
+type Evaluation struct {
+	CP    int `json:"cp"`
+	Mate  int `json:"mate,omitempty"`
+	Depth int `json:"depth"`
+	// Ply is the position the score belongs to; stale scores are dropped.
+	Ply int `json:"-"`
+}
+
+// materialLocked counts the material on the board.
+func (g *Game) materialLocked() Material {
+	var m Material
+	for _, p := range g.g.Position().Board().SquareMap() {
+		switch p.Color() {
+		case chess.White:
+			m.White += pieceValues[p.Type()]
+		case chess.Black:
+			m.Black += pieceValues[p.Type()]
+		}
+	}
+	m.Balance = m.White - m.Black
+	return m
+}
+
+// evalLocked returns the engine score if it matches the current position.
+func (g *Game) evalLocked() *Evaluation {
+	if g.eval == nil || g.eval.Ply != len(g.g.Moves()) {
+		return nil
+	}
+	return g.eval
+}
+
+// Evaluate scores the game's current position with the hub's engine and
+// broadcasts the result. It does nothing without an engine or once the game
+// is over.
+func (h *Hub) Evaluate(g *Game) {
+	if h.Engine == nil {
+		return
+	}
+	g.Mu.RLock()
+	if g.overLocked() {
+		g.Mu.RUnlock()
+		return
+	}
+	pos := g.g.Position()
+	ply := len(g.g.Moves())
+	g.Mu.RUnlock()
+
+	a, err := h.Engine.Analyze(pos)
+	if err != nil {
+		logging.Debugf("evaluate %s failed: %v", g.ID, err)
+		return
+	}
+
+	g.Mu.Lock()
+	if len(g.g.Moves()) != ply {
+		g.Mu.Unlock()
+		return
+	}
+	g.eval = &Evaluation{CP: a.CP, Mate: a.Mate, Depth: a.Depth, Ply: ply}
+	g.Mu.Unlock()
+	g.Broadcast()
+}
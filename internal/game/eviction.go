@@ -0,0 +1,142 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/corentings/chess/v2"
+
+	"tinychess/internal/storage"
+)
+
+// EvictionStats counts the games the idle sweep has unloaded, see
+// CleanupInterval and IdleTTL.
+type EvictionStats struct {
+	// Loaded is how many games are in memory now.
+	Loaded int `json:"loaded"`
+	// Evicted counts the games unloaded, and Flushed those of them whose
+	// state was written to the store first. Failed counts the flushes that
+	// failed; those games stay loaded until a later sweep saves them.
+	Evicted   int64     `json:"evicted"`
+	Flushed   int64     `json:"flushed"`
+	Failed    int64     `json:"failed"`
+	LastSweep time.Time `json:"lastSweep"`
+}
+
+// Evictions reports the idle sweeps so far.
+func (h *Hub) Evictions() EvictionStats {
+	h.evictMu.Lock()
+	stats := h.evictions
+	h.evictMu.Unlock()
+	stats.Loaded = h.Len()
+	return stats
+}
+
+// evictIdle unloads the games nobody has touched for IdleTTL. Each game's
+// final state is written to the store first, so nothing kept only in memory
+// is lost; a game whose flush fails stays loaded, and so does one somebody
+// is still watching. Post-mortem rooms are never stored and are simply
+// dropped.
+func (h *Hub) evictIdle(ctx context.Context) {
+	var evicted, flushed, failed int64
+	for _, g := range h.loaded() {
+		g.Mu.RLock()
+		lastSeen := g.LastSeen
+		idle := time.Since(lastSeen)
+		room := !g.postMortemOf.IsZero()
+		watched := len(g.Watchers) > 0
+		var upd storage.GameStateUpdate
+		if idle > IdleTTL && !room && !watched {
+			upd = g.finalStateLocked()
+		}
+		g.Mu.RUnlock()
+		if idle <= IdleTTL || watched {
+			continue
+		}
+		saved := false
+		if h.Store != nil && !room {
+			if err := h.Store.SaveGameState(ctx, g.ID.UUID(), upd); err != nil {
+				log.Printf("evict game %s: keeping it loaded, saving its state failed: %v", g.ID, err)
+				failed++
+				continue
+			}
+			saved = true
+		}
+		if !h.unloadIdle(g, lastSeen) {
+			continue
+		}
+		evicted++
+		if saved {
+			flushed++
+		}
+		log.Printf("evicted game %s after %s idle (saved: %t)", g.ID, idle.Round(time.Second), saved)
+	}
+	h.evictMu.Lock()
+	h.evictions.Evicted += evicted
+	h.evictions.Flushed += flushed
+	h.evictions.Failed += failed
+	h.evictions.LastSweep = time.Now()
+	h.evictMu.Unlock()
+}
+
+// unloadIdle unloads g unless it was touched since lastSeen, when the sweep
+// looked at it, somebody has started watching it, or another game has taken
+// its place. It reports whether g
+// was unloaded.
+func (h *Hub) unloadIdle(g *Game, lastSeen time.Time) bool {
+	s := h.shard(g.ID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.games[g.ID] != g {
+		return false
+	}
+	g.Mu.RLock()
+	touched := !g.LastSeen.Equal(lastSeen) || len(g.Watchers) > 0
+	g.Mu.RUnlock()
+	if touched {
+		return false
+	}
+	delete(s.games, g.ID)
+	return true
+}
+
+// finalStateLocked is the game's state as the store keeps it, written
+// before the game is unloaded. Callers must hold g.Mu; a read lock is
+// enough.
+func (g *Game) finalStateLocked() storage.GameStateUpdate {
+	state := g.StateLocked()
+	plies := len(state.UCI)
+	active := !g.overLocked()
+	byWhite := strings.Join(state.Captured.ByWhite, "")
+	byBlack := strings.Join(state.Captured.ByBlack, "")
+	data, _ := json.Marshal(g.timeline)
+	timeline := string(data)
+	lastSeen := g.LastSeen
+	upd := storage.GameStateUpdate{
+		FEN:             &state.FEN,
+		PGN:             &state.PGN,
+		Status:          &state.Status,
+		Plies:           &plies,
+		Active:          &active,
+		LastSeen:        &lastSeen,
+		CapturedByWhite: &byWhite,
+		CapturedByBlack: &byBlack,
+		Timeline:        &timeline,
+	}
+	if !active {
+		if outcome := g.g.Outcome(); outcome != chess.NoOutcome {
+			result := outcome.String()
+			upd.Result = &result
+		}
+		if termination := state.Termination; termination != "" {
+			upd.Termination = &termination
+		}
+		if endedAt := g.endedAtLocked(); !endedAt.IsZero() {
+			upd.CompletedAt = &endedAt
+		}
+	}
+	return upd
+}
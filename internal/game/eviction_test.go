@@ -0,0 +1,93 @@
+package game
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestEvictIdleSavesFinalState(t *testing.T) {
+	store := newTestHubStore(t)
+	ctx := context.Background()
+	hub := NewHub(store)
+	idleID, _, err := hub.CreateGame(ctx, uuid.NewString(), GameOptions{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	busyID, _, err := hub.CreateGame(ctx, uuid.NewString(), GameOptions{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	idle, _ := hub.Lookup(idleID)
+	if err := idle.MakeMove("e2e4"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	idle.Mu.Lock()
+	idle.LastSeen = time.Now().Add(-IdleTTL - time.Minute)
+	fen := idle.g.Position().String()
+	idle.Mu.Unlock()
+
+	hub.evictIdle(ctx)
+	if _, ok := hub.Lookup(idleID); ok {
+		t.Fatalf("expected the idle game unloaded")
+	}
+	if _, ok := hub.Lookup(busyID); !ok {
+		t.Fatalf("expected the busy game kept")
+	}
+	stats := hub.Evictions()
+	if stats.Loaded != 1 || stats.Evicted != 1 || stats.Flushed != 1 || stats.Failed != 0 || stats.LastSweep.IsZero() {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	persisted, err := store.LoadGame(ctx, idleID.UUID())
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if persisted.Game.FEN != fen || persisted.Game.Plies != 1 || !persisted.Game.Active {
+		t.Fatalf("expected the final state saved, got %+v", persisted.Game)
+	}
+}
+
+func TestEvictIdleKeepsTouchedGame(t *testing.T) {
+	hub := NewHub(nil)
+	id, _, err := hub.CreateGame(context.Background(), uuid.NewString(), GameOptions{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	g, _ := hub.Lookup(id)
+	g.Mu.Lock()
+	stale := time.Now().Add(-IdleTTL - time.Minute)
+	g.LastSeen = stale
+	g.Mu.Unlock()
+	if hub.unloadIdle(g, stale.Add(-time.Second)) {
+		t.Fatalf("expected a game touched since the sweep looked kept")
+	}
+	if !hub.unloadIdle(g, stale) {
+		t.Fatalf("expected an untouched game unloaded")
+	}
+}
+
+func TestEvictIdleKeepsWatchedGame(t *testing.T) {
+	hub := NewHub(nil)
+	id, _, err := hub.CreateGame(context.Background(), uuid.NewString(), GameOptions{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	g, _ := hub.Lookup(id)
+	ch := make(chan []byte, 1)
+	g.AddWatcher(ch)
+	g.Mu.Lock()
+	g.LastSeen = time.Now().Add(-IdleTTL - time.Minute)
+	g.Mu.Unlock()
+
+	hub.evictIdle(context.Background())
+	if _, ok := hub.Lookup(id); !ok {
+		t.Fatalf("expected a watched game kept loaded")
+	}
+	g.RemoveWatcher(ch)
+	hub.evictIdle(context.Background())
+	if _, ok := hub.Lookup(id); ok {
+		t.Fatalf("expected the game unloaded once nobody watches it")
+	}
+}
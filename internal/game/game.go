@@ -6,6 +6,9 @@ import (
 	"time"
 
 	"github.com/corentings/chess/v2"
+
+	"tinychess/internal/i18n"
+	"tinychess/pkg/chesscore"
 )
 
 // Touch updates the last seen timestamp for a game and returns the timestamp.
@@ -21,7 +24,7 @@ func (g *Game) Touch() time.Time {
 func (g *Game) MovesUCI() []string {
 	ms := g.g.Moves()
 	out := make([]string, 0, len(ms))
-	tmp := chess.NewGame()
+	tmp := newChessGame(g.startFEN)
 	uci := chess.UCINotation{}
 	for _, m := range ms {
 		s := uci.Encode(tmp.Position(), m)
@@ -33,25 +36,122 @@ func (g *Game) MovesUCI() []string {
 	return out
 }
 
-// StateLocked returns the current game state (must be called with lock held)
+// StateLocked returns the current game state. Callers must hold g.Mu; a read
+// lock is enough.
 func (g *Game) StateLocked() GameState {
 	pos := g.g.Position()
 	fen := pos.String()
 	turn := pos.Turn().String()
 	status := ""
-	if g.g.Outcome() != chess.NoOutcome {
+	switch {
+	case g.g.Outcome() != chess.NoOutcome && g.Language != "":
+		reason := i18n.Reason(g.Language, g.terminationLocked())
+		status = i18n.T(g.Language, i18n.StatusEnded, g.g.Outcome().String(), reason)
+	case g.g.Outcome() != chess.NoOutcome && g.termination != "":
+		status = fmt.Sprintf("%s by %s", g.g.Outcome().String(), g.termination)
+	case g.g.Outcome() != chess.NoOutcome:
 		status = fmt.Sprintf("%s by %s", g.g.Outcome().String(), g.g.Method().String())
+	case g.termination == TerminationAbandonment:
+		status = i18n.T(g.Language, i18n.StatusAbandoned)
 	}
-	pgn := g.g.String()
+	pgn := g.PGNLocked(PGNHeaders{})
+	uci := g.MovesUCI()
+	white, black := g.playerNamesLocked()
+	moveTimes := g.moveTimesLocked(uci)
+	var coolOffUntil int64
+	if until := g.coolOffUntilLocked(time.Now()); !until.IsZero() {
+		coolOffUntil = until.UnixMilli()
+	}
+	postMortem, postMortemOf := g.postMortemLinksLocked()
 	return GameState{
-		Kind:     "state",
-		FEN:      fen,
-		Turn:     turn,
-		Status:   status,
-		PGN:      pgn,
-		UCI:      g.MovesUCI(),
-		LastSeen: g.LastSeen.UnixMilli(),
-		Watchers: len(g.Watchers),
+		Schema:         SchemaVersion,
+		Kind:           "state",
+		FEN:            fen,
+		Turn:           turn,
+		Status:         status,
+		Termination:    g.terminationLocked(),
+		TimeControl:    g.TimeControl,
+		StartFEN:       g.startFEN,
+		PGN:            pgn,
+		UCI:            uci,
+		Moves:          FormatMoves(g.startFEN, uci, NotationSAN),
+		Material:       g.materialLocked(),
+		Mobility:       g.mobilityLocked(),
+		Eval:           g.evalLocked(),
+		LastMove:       g.lastMove,
+		Captured:       g.capturedLocked(),
+		MoveTimes:      moveTimes,
+		Pace:           g.paceLocked(moveTimes, time.Now()),
+		LastSeen:       g.LastSeen.UnixMilli(),
+		Watchers:       len(g.Watchers),
+		White:          white,
+		Black:          black,
+		Participants:   g.participantsLocked(),
+		Training:       g.trainingLocked(),
+		HotSeat:        g.hotSeat,
+		HouseRules:     g.houseRulesLocked(),
+		CoolOffUntil:   coolOffUntil,
+		PostMortem:     postMortem,
+		PostMortemOf:   postMortemOf,
+		PostMortemOpen: !g.postMortemOf.IsZero() && g.postMortemOpen,
+	}
+}
+
+// replayLocked rebuilds the game by playing moves from the starting position.
+func (g *Game) replayLocked(moves []string) error {
+	g.g = newChessGame(g.startFEN)
+	g.lastMove = nil
+	g.captured = Captured{}
+	g.playedAt = nil
+	for i, uci := range moves {
+		if err := g.applyMoveLocked(uci); err != nil {
+			return fmt.Errorf("ply %d (%s): %w", i+1, uci, err)
+		}
+	}
+	return nil
+}
+
+// capturedLocked returns a copy of the captured lists.
+func (g *Game) capturedLocked() Captured {
+	return Captured{
+		ByWhite: append([]string{}, g.captured.ByWhite...),
+		ByBlack: append([]string{}, g.captured.ByBlack...),
+	}
+}
+
+// terminationLocked returns why the game ended, or "" while it is in progress.
+func (g *Game) terminationLocked() string {
+	if g.termination != "" {
+		return g.termination
+	}
+	return terminationOf(g.g.Method())
+}
+
+// overLocked reports whether the game has ended.
+func (g *Game) overLocked() bool {
+	return g.g.Outcome() != chess.NoOutcome || g.termination != ""
+}
+
+// terminationOf maps a chess outcome method to the termination reason stored
+// with finished games.
+func terminationOf(m chess.Method) string {
+	switch m {
+	case chess.Checkmate:
+		return TerminationCheckmate
+	case chess.Resignation:
+		return TerminationResignation
+	case chess.DrawOffer:
+		return TerminationDrawAgreement
+	case chess.Stalemate:
+		return TerminationStalemate
+	case chess.ThreefoldRepetition, chess.FivefoldRepetition:
+		return TerminationRepetition
+	case chess.FiftyMoveRule, chess.SeventyFiveMoveRule:
+		return TerminationFiftyMove
+	case chess.InsufficientMaterial:
+		return TerminationInsufficientMaterial
+	default:
+		return ""
 	}
 }
 
@@ -59,13 +159,9 @@ func (g *Game) StateLocked() GameState {
 func (g *Game) Broadcast() {
 	g.Mu.Lock()
 	state := g.StateLocked()
+	g.scheduleReopenLocked(g.coolOffUntilLocked(time.Now()))
 	data, _ := json.Marshal(state)
-	for ch := range g.Watchers {
-		select {
-		case ch <- data:
-		default:
-		}
-	}
+	g.deliverLocked(data, true)
 	g.Mu.Unlock()
 }
 
@@ -74,41 +170,106 @@ func (g *Game) MakeMove(uci string) error {
 	g.Mu.Lock()
 	defer g.Mu.Unlock()
 
-	mv, err := chess.UCINotation{}.Decode(g.g.Position(), uci)
+	if g.overLocked() {
+		return fmt.Errorf("game over")
+	}
+	if g.moveLimitReachedLocked() {
+		return ErrMoveLimit
+	}
+	if !g.frozenAt.IsZero() {
+		return ErrMaintenance
+	}
+	return g.applyMoveLocked(uci)
+}
+
+// applyMoveLocked validates and plays a UCI move, updating the last-move and
+// capture tracking.
+func (g *Game) applyMoveLocked(uci string) error {
+	legal, err := chesscore.LegalMove(g.g.Position(), uci)
 	if err != nil {
 		return err
 	}
-	valid := false
-	for _, m := range g.g.ValidMoves() {
-		if m.S1() == mv.S1() && m.S2() == mv.S2() && m.Promo() == mv.Promo() {
-			valid = true
-			break
-		}
+	mover := g.g.Position().Turn()
+	last := describeMove(g.g.Position(), legal)
+	if err := g.g.Move(legal, nil); err != nil {
+		return err
 	}
-	if !valid {
-		return fmt.Errorf("illegal move")
+	g.lastMove = last
+	g.playedAt = append(g.playedAt, time.Now())
+	if last.IsCapture {
+		g.captured.add(mover, last.CapturedPiece)
 	}
-	return g.g.Move(mv, nil)
+	return nil
 }
 
-// AddWatcher adds a new watcher channel
-func (g *Game) AddWatcher(ch chan []byte) {
+// Resign ends the game as a loss for the given color.
+func (g *Game) Resign(color chess.Color) error {
 	g.Mu.Lock()
-	g.Watchers[ch] = struct{}{}
-	g.Mu.Unlock()
+	defer g.Mu.Unlock()
+
+	if g.overLocked() {
+		return fmt.Errorf("game over")
+	}
+	if color == chess.NoColor {
+		return fmt.Errorf("not a player")
+	}
+	g.g.Resign(color)
+	g.recordEventLocked(EventResigned, color)
+	return nil
 }
 
-// RemoveWatcher removes a watcher channel
-func (g *Game) RemoveWatcher(ch chan []byte) {
+// Adjudicate ends an unfinished game by decision of the instance, either as a
+// draw or as abandoned without a result.
+func (g *Game) Adjudicate(draw bool) error {
 	g.Mu.Lock()
-	delete(g.Watchers, ch)
-	g.Mu.Unlock()
+	defer g.Mu.Unlock()
+
+	if g.overLocked() {
+		return fmt.Errorf("game over")
+	}
+	if draw {
+		if err := g.g.Draw(chess.DrawOffer); err != nil {
+			return err
+		}
+		g.termination = TerminationAdjudication
+		g.recordEventLocked(EventAdjudicated, chess.NoColor)
+		return nil
+	}
+	g.termination = TerminationAbandonment
+	g.recordEventLocked(EventAbandoned, chess.NoColor)
+	return nil
+}
+
+// restoreEnding reapplies a persisted result that is not implied by the
+// moves themselves, such as a resignation or adjudication.
+func (g *Game) restoreEnding(result, termination string) {
+	if g.g.Outcome() != chess.NoOutcome {
+		return
+	}
+	switch result {
+	case chess.WhiteWon.String():
+		g.g.Resign(chess.Black)
+	case chess.BlackWon.String():
+		g.g.Resign(chess.White)
+	case chess.Draw.String():
+		_ = g.g.Draw(chess.DrawOffer)
+	default:
+		if termination != TerminationAbandonment {
+			return
+		}
+	}
+	if termination != terminationOf(g.g.Method()) {
+		g.termination = termination
+	}
 }
 
 // RemoveClient removes a client from the game. If the client was the owner,
 // the owner slot is cleared so another client can claim it later.
 func (g *Game) RemoveClient(id string) {
 	g.Mu.Lock()
+	if col, ok := g.Clients[id]; ok {
+		g.recordEventLocked(EventReleased, col)
+	}
 	delete(g.Clients, id)
 	if g.OwnerID == id {
 		g.OwnerID = ""
@@ -122,8 +283,11 @@ func (g *Game) CanReact(sender string) (bool, int) {
 	defer g.Mu.Unlock()
 
 	now := time.Now()
-	if t, ok := g.LastReact[sender]; ok && now.Sub(t) < 5*time.Second {
-		wait := int(5 - now.Sub(t).Seconds())
+	if until := g.coolOffUntilLocked(now); !until.IsZero() {
+		return false, int(until.Sub(now).Seconds()) + 1
+	}
+	if t, ok := g.LastReact[sender]; ok && now.Sub(t) < ReactCooldown {
+		wait := int((ReactCooldown - now.Sub(t)).Seconds())
 		return false, wait
 	}
 
@@ -131,22 +295,48 @@ func (g *Game) CanReact(sender string) (bool, int) {
 	return true, 0
 }
 
-// BroadcastReaction sends a reaction to all watchers
+// BroadcastReaction sends a reaction to all watchers, under the sender's
+// participant ID.
 func (g *Game) BroadcastReaction(payload ReactionPayload) {
 	g.Mu.Lock()
+	payload.Sender = g.ParticipantID(payload.Sender)
 	data, _ := json.Marshal(payload)
-	for ch := range g.Watchers {
-		select {
-		case ch <- data:
-		default:
-		}
-	}
+	g.deliverLocked(data, false)
+	g.Mu.Unlock()
+}
+
+// BroadcastNotice sends a notice message to all watchers.
+func (g *Game) BroadcastNotice(message string) {
+	data, _ := json.Marshal(NoticePayload{
+		Schema:  SchemaVersion,
+		Kind:    "notice",
+		Message: message,
+		At:      time.Now().UnixMilli(),
+	})
+	g.Mu.Lock()
+	g.deliverLocked(data, false)
 	g.Mu.Unlock()
 }
 
+// BroadcastReview tells watchers that the review a player of color asked
+// for has been published.
+func (g *Game) BroadcastReview(reviewID string, color chess.Color) {
+	g.Mu.Lock()
+	g.sendLocked(ReviewPayload{Schema: SchemaVersion, Kind: "review", ReviewID: reviewID, Color: color.String()})
+	g.Mu.Unlock()
+}
+
+// Over reports whether the game has ended, including games abandoned without
+// a result.
+func (g *Game) Over() bool {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return g.overLocked()
+}
+
 // Outcome returns the game's current outcome.
 func (g *Game) Outcome() chess.Outcome {
-	g.Mu.Lock()
-	defer g.Mu.Unlock()
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
 	return g.g.Outcome()
 }
@@ -2,6 +2,7 @@ package game
 
 import (
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -12,7 +13,7 @@ import (
 func newTestGame() *Game {
 	return &Game{
 		g:         chess.NewGame(),
-		Watchers:  make(map[chan []byte]struct{}),
+		Watchers:  make(map[chan []byte]*watcher),
 		LastReact: make(map[string]time.Time),
 	}
 }
@@ -56,3 +57,306 @@ func TestCheckmateState(t *testing.T) {
 		t.Fatalf("expected checkmate in status, got %s", st.Status)
 	}
 }
+
+func TestTerminationRecorded(t *testing.T) {
+	g := newTestGame()
+	for _, m := range []string{"f2f3", "e7e5", "g2g4", "d8h4"} {
+		if err := g.MakeMove(m); err != nil {
+			t.Fatalf("move %s failed: %v", m, err)
+		}
+	}
+	g.Mu.Lock()
+	st := g.StateLocked()
+	g.Mu.Unlock()
+	if st.Termination != TerminationCheckmate {
+		t.Fatalf("expected checkmate termination, got %q", st.Termination)
+	}
+
+	g = newTestGame()
+	if err := g.Resign(chess.White); err != nil {
+		t.Fatalf("resign: %v", err)
+	}
+	g.Mu.Lock()
+	st = g.StateLocked()
+	g.Mu.Unlock()
+	if st.Termination != TerminationResignation {
+		t.Fatalf("expected resignation termination, got %q", st.Termination)
+	}
+}
+
+func TestAdjudicate(t *testing.T) {
+	g := newTestGame()
+	if err := g.Adjudicate(true); err != nil {
+		t.Fatalf("adjudicate: %v", err)
+	}
+	g.Mu.Lock()
+	st := g.StateLocked()
+	g.Mu.Unlock()
+	if st.Termination != TerminationAdjudication || !strings.HasPrefix(st.Status, "1/2-1/2") {
+		t.Fatalf("unexpected adjudicated state: %q %q", st.Status, st.Termination)
+	}
+	if err := g.MakeMove("e2e4"); err == nil {
+		t.Fatalf("expected move after adjudication to fail")
+	}
+
+	g = newTestGame()
+	if err := g.Adjudicate(false); err != nil {
+		t.Fatalf("abandon: %v", err)
+	}
+	g.Mu.Lock()
+	st = g.StateLocked()
+	g.Mu.Unlock()
+	if st.Status != "Abandoned" || st.Termination != TerminationAbandonment {
+		t.Fatalf("unexpected abandoned state: %q %q", st.Status, st.Termination)
+	}
+}
+
+func TestRestoreEnding(t *testing.T) {
+	g := newTestGame()
+	g.restoreEnding("0-1", TerminationResignation)
+	if g.Outcome() != chess.BlackWon {
+		t.Fatalf("expected black win, got %s", g.Outcome())
+	}
+	g.Mu.Lock()
+	st := g.StateLocked()
+	g.Mu.Unlock()
+	if st.Termination != TerminationResignation {
+		t.Fatalf("expected resignation, got %q", st.Termination)
+	}
+}
+
+func TestLocalizedStatus(t *testing.T) {
+	g := newTestGame()
+	g.Language = "de"
+	if err := g.Resign(chess.White); err != nil {
+		t.Fatalf("resign: %v", err)
+	}
+	g.Mu.Lock()
+	st := g.StateLocked()
+	g.Mu.Unlock()
+	if st.Status != "0-1 durch Aufgabe" {
+		t.Fatalf("expected German status, got %q", st.Status)
+	}
+	if st.Termination != TerminationResignation {
+		t.Fatalf("termination should stay machine-readable, got %q", st.Termination)
+	}
+}
+
+func TestMaterialInState(t *testing.T) {
+	g := newTestGame()
+	for _, mv := range []string{"e2e4", "d7d5", "e4d5"} {
+		if err := g.MakeMove(mv); err != nil {
+			t.Fatalf("move %s: %v", mv, err)
+		}
+	}
+	g.Mu.Lock()
+	st := g.StateLocked()
+	g.Mu.Unlock()
+	if st.Material.White != 39 || st.Material.Black != 38 || st.Material.Balance != 1 {
+		t.Fatalf("unexpected material %+v", st.Material)
+	}
+	if st.Eval != nil {
+		t.Fatalf("expected no eval without an engine, got %+v", st.Eval)
+	}
+}
+
+func TestLastMoveMetadata(t *testing.T) {
+	g := newTestGame()
+	for _, mv := range []string{"e2e4", "d7d5", "e4d5", "d8d5", "b1c3", "d5e5"} {
+		if err := g.MakeMove(mv); err != nil {
+			t.Fatalf("move %s: %v", mv, err)
+		}
+	}
+	g.Mu.Lock()
+	st := g.StateLocked()
+	g.Mu.Unlock()
+	lm := st.LastMove
+	if lm == nil || lm.From != "d5" || lm.To != "e5" || lm.SAN != "Qe5+" || !lm.IsCheck || lm.IsCapture {
+		t.Fatalf("unexpected last move %+v", lm)
+	}
+
+	if err := g.MakeMove("g1e2"); err != nil {
+		t.Fatalf("block: %v", err)
+	}
+	if err := g.MakeMove("e5c3"); err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	g.Mu.Lock()
+	st = g.StateLocked()
+	g.Mu.Unlock()
+	if lm := st.LastMove; !lm.IsCapture || lm.CapturedPiece != "n" {
+		t.Fatalf("expected knight capture, got %+v", lm)
+	}
+}
+
+func TestMoveTimes(t *testing.T) {
+	g := newTestGame()
+	g.CreatedAt = time.Now()
+	for _, uci := range []string{"e2e4", "e7e5"} {
+		if err := g.MakeMove(uci); err != nil {
+			t.Fatalf("move %s: %v", uci, err)
+		}
+	}
+	g.playedAt[0] = g.CreatedAt.Add(3 * time.Second)
+	g.playedAt[1] = g.CreatedAt.Add(10 * time.Second)
+
+	times := g.MoveTimes()
+	if len(times) != 2 || times[0].ThinkMs != 3000 || times[1].ThinkMs != 7000 || times[1].UCI != "e7e5" {
+		t.Fatalf("unexpected move times: %+v", times)
+	}
+}
+
+func TestPace(t *testing.T) {
+	g := newTestGame()
+	g.CreatedAt = time.Now()
+	for _, uci := range []string{"e2e4", "e7e5", "g1f3"} {
+		if err := g.MakeMove(uci); err != nil {
+			t.Fatalf("move %s: %v", uci, err)
+		}
+	}
+	g.playedAt[0] = g.CreatedAt.Add(2 * time.Second)
+	g.playedAt[1] = g.CreatedAt.Add(5 * time.Second)
+	g.playedAt[2] = g.CreatedAt.Add(9 * time.Second)
+
+	g.Mu.RLock()
+	pace := g.paceLocked(g.moveTimesLocked(g.MovesUCI()), g.CreatedAt.Add(20*time.Second))
+	g.Mu.RUnlock()
+	if pace.WhiteAvgMs != 3000 || pace.BlackAvgMs != 3000 {
+		t.Fatalf("unexpected averages: %+v", pace)
+	}
+	if pace.ThinkingSince != g.playedAt[2].UnixMilli() || pace.ThinkingMs != 11000 {
+		t.Fatalf("unexpected thinking time: %+v", pace)
+	}
+
+	if err := g.Resign(chess.Black); err != nil {
+		t.Fatalf("resign: %v", err)
+	}
+	g.Mu.RLock()
+	pace = g.paceLocked(g.moveTimesLocked(g.MovesUCI()), time.Now())
+	g.Mu.RUnlock()
+	if pace.ThinkingSince != 0 || pace.ThinkingMs != 0 {
+		t.Fatalf("expected no thinking time after the game ended: %+v", pace)
+	}
+}
+
+func TestMobility(t *testing.T) {
+	g := newTestGame()
+	if err := g.MakeMove("e2e4"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	g.Mu.RLock()
+	m := g.StateLocked().Mobility
+	g.Mu.RUnlock()
+	want := Mobility{
+		White: SideMobility{Moves: 30, PawnShield: 2},
+		Black: SideMobility{Moves: 20, PawnShield: 3},
+	}
+	if m == nil || *m != want {
+		t.Fatalf("got %+v, want %+v", m, want)
+	}
+
+	for _, uci := range []string{"f7f6", "d1h5"} {
+		if err := g.MakeMove(uci); err != nil {
+			t.Fatalf("move %s: %v", uci, err)
+		}
+	}
+	g.Mu.RLock()
+	m = g.StateLocked().Mobility
+	g.Mu.RUnlock()
+	if !m.Black.Check || m.Black.Moves != 1 || m.Black.KingAttacks != 2 || m.White.Check {
+		t.Fatalf("expected black in check with one reply, got %+v", m)
+	}
+
+	if err := g.Resign(chess.Black); err != nil {
+		t.Fatalf("resign: %v", err)
+	}
+	g.Mu.RLock()
+	m = g.StateLocked().Mobility
+	g.Mu.RUnlock()
+	if m != nil {
+		t.Fatalf("expected no mobility after the game ended, got %+v", m)
+	}
+}
+
+func TestHintCooldown(t *testing.T) {
+	g := newTestGame()
+	if ok, _ := g.CanHint("a"); !ok {
+		t.Fatalf("first hint should be allowed")
+	}
+	if ok, wait := g.CanHint("a"); ok || wait <= 0 {
+		t.Fatalf("second hint should wait, got ok=%v wait=%d", ok, wait)
+	}
+	if ok, _ := g.CanHint("b"); !ok {
+		t.Fatalf("cooldown should be per client")
+	}
+}
+
+func TestCapturedSurvivesPromotion(t *testing.T) {
+	g := newTestGame()
+	for _, mv := range []string{"h2h4", "g7g5", "h4g5", "h7h6", "g5h6", "f8g7", "h6g7", "g8f6", "g7h8q"} {
+		if err := g.MakeMove(mv); err != nil {
+			t.Fatalf("move %s: %v", mv, err)
+		}
+	}
+	g.Mu.Lock()
+	st := g.StateLocked()
+	g.Mu.Unlock()
+	want := []string{"p", "p", "b", "r"}
+	if strings.Join(st.Captured.ByWhite, "") != strings.Join(want, "") || len(st.Captured.ByBlack) != 0 {
+		t.Fatalf("unexpected captures %+v", st.Captured)
+	}
+
+	restored := ParseCaptured("ppbr", "")
+	if len(restored.ByWhite) != 4 || restored.ByBlack == nil {
+		t.Fatalf("unexpected restored captures %+v", restored)
+	}
+}
+
+func TestConcurrentStateReads(t *testing.T) {
+	g := newTestGame()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				g.Mu.RLock()
+				state := g.StateLocked()
+				g.Mu.RUnlock()
+				if state.PGN == "" {
+					t.Errorf("empty PGN")
+					return
+				}
+			}
+		}()
+	}
+	for _, uci := range []string{"e2e4", "e7e5", "g1f3", "b8c6"} {
+		if err := g.MakeMove(uci); err != nil {
+			t.Fatalf("move %s: %v", uci, err)
+		}
+	}
+	wg.Wait()
+}
+
+func TestConcurrentStateReadsAfterMove(t *testing.T) {
+	g := newTestGame()
+	// The move leaves a position nobody has listed the moves of yet, so the
+	// readers below are the first to.
+	if err := g.MakeMove("e2e4"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.Mu.RLock()
+			state := g.StateLocked()
+			g.Mu.RUnlock()
+			if state.Mobility == nil || state.Mobility.Black.Moves != 20 {
+				t.Errorf("unexpected mobility %+v", state.Mobility)
+			}
+		}()
+	}
+	wg.Wait()
+}
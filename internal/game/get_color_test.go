@@ -7,14 +7,15 @@ import (
 
 func TestGetReturnsAssignedColor(t *testing.T) {
 	h := NewHub(nil)
-	_, c1, err := h.Get(context.Background(), "g", "c1")
+	id := NewGameID()
+	_, c1, err := h.Get(context.Background(), id, "c1")
 	if err != nil {
 		t.Fatalf("get: %v", err)
 	}
 	if c1 == nil {
 		t.Fatalf("expected color for first client")
 	}
-	_, c2, err := h.Get(context.Background(), "g", "c2")
+	_, c2, err := h.Get(context.Background(), id, "c2")
 	if err != nil {
 		t.Fatalf("get: %v", err)
 	}
@@ -24,7 +25,7 @@ func TestGetReturnsAssignedColor(t *testing.T) {
 	if *c1 == *c2 {
 		t.Fatalf("expected different colors, got %v and %v", *c1, *c2)
 	}
-	_, c3, err := h.Get(context.Background(), "g", "c3")
+	_, c3, err := h.Get(context.Background(), id, "c3")
 	if err != nil {
 		t.Fatalf("get: %v", err)
 	}
@@ -0,0 +1,71 @@
+package game
+
+import (
+	"errors"
+	"time"
+
+	"github.com/corentings/chess/v2"
+)
+
+// ErrNoEngine is returned when hints are requested without a configured engine.
+var ErrNoEngine = errors.New("hints unavailable")
+
+// Hint is the engine's suggested move for the side to move.
+type Hint struct {
+	UCI  string     `json:"uci"`
+	SAN  string     `json:"san"`
+	Eval Evaluation `json:"eval"`
+}
+
+// CanHint checks and starts the hint cooldown for a client.
+func (g *Game) CanHint(clientID string) (bool, int) {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+
+	now := time.Now()
+	if t, ok := g.lastHint[clientID]; ok && now.Sub(t) < HintCooldown {
+		wait := int((HintCooldown - now.Sub(t)).Seconds())
+		return false, wait
+	}
+	if g.lastHint == nil {
+		g.lastHint = make(map[string]time.Time)
+	}
+	g.lastHint[clientID] = now
+	return true, 0
+}
+
+// Hint asks the hub's engine for the best move in the game's current
+// position. The search result also refreshes the game's evaluation.
+func (h *Hub) Hint(g *Game) (Hint, error) {
+	if h.Engine == nil {
+		return Hint{}, ErrNoEngine
+	}
+	g.Mu.RLock()
+	if g.overLocked() {
+		g.Mu.RUnlock()
+		return Hint{}, errors.New("game over")
+	}
+	pos := g.g.Position()
+	ply := len(g.g.Moves())
+	g.Mu.RUnlock()
+
+	a, err := h.Engine.Analyze(pos)
+	if err != nil {
+		return Hint{}, err
+	}
+	if a.BestMove == "" {
+		return Hint{}, errors.New("no legal move")
+	}
+	mv, err := chess.UCINotation{}.Decode(pos, a.BestMove)
+	if err != nil {
+		return Hint{}, err
+	}
+	eval := Evaluation{CP: a.CP, Mate: a.Mate, Depth: a.Depth, Ply: ply}
+
+	g.Mu.Lock()
+	if len(g.g.Moves()) == ply {
+		g.eval = &eval
+	}
+	g.Mu.Unlock()
+	return Hint{UCI: a.BestMove, SAN: chess.AlgebraicNotation{}.Encode(pos, mv), Eval: eval}, nil
+}
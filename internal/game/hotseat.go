@@ -0,0 +1,29 @@
+package game
+
+import "github.com/corentings/chess/v2"
+
+// HotSeat reports whether the game is played by two people sharing one
+// device: the owner moves for both colors and the other seat is never handed
+// out. Hot-seat games do not count towards the ladder.
+func (g *Game) HotSeat() bool {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return g.hotSeat
+}
+
+// MoverColor returns the color clientID moves when it is turn's move:
+// their seat's color, or turn itself for the owner of a hot-seat game and
+// for everyone who may move in a post-mortem room. ok is false for clients
+// without a seat.
+func (g *Game) MoverColor(clientID string, turn chess.Color) (color chess.Color, ok bool) {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	color, ok = g.Clients[clientID]
+	if ok && g.hotSeat && clientID == g.OwnerID {
+		return turn, true
+	}
+	if !g.postMortemOf.IsZero() && (ok || g.postMortemOpen) {
+		return turn, true
+	}
+	return color, ok
+}
@@ -0,0 +1,151 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/corentings/chess/v2"
+
+	"tinychess/internal/logging"
+)
+
+// Adjudication methods for games that reach their move limit.
+const (
+	// AdjudicateMaterial awards the game to the side ahead in material and
+	// draws it when material is level.
+	AdjudicateMaterial = "material"
+	// AdjudicateEval asks the engine instead, falling back to material when
+	// the instance has none.
+	AdjudicateEval = "eval"
+)
+
+// ErrMoveLimit refuses moves past a game's move limit while it is being
+// adjudicated.
+var ErrMoveLimit = errors.New("move limit reached")
+
+// MaxMoveLimit bounds the move limit a game may set.
+const MaxMoveLimit = 500
+
+// EvalMargin is the engine advantage, in centipawns, needed to win a game on
+// eval at the move limit; anything closer is a draw.
+const EvalMargin = 100
+
+// HouseRules are optional rules the server enforces on top of chess, for
+// casual games that should not run long.
+type HouseRules struct {
+	// MoveLimit ends the game once each side has made this many moves; 0
+	// means no limit.
+	MoveLimit int `json:"moveLimit,omitempty"`
+	// Adjudicate decides the result at the limit, AdjudicateMaterial when
+	// empty.
+	Adjudicate string `json:"adjudicate,omitempty"`
+}
+
+// ParseHouseRules validates the rules chosen for a new game. adjudicate
+// defaults to AdjudicateMaterial and is only accepted alongside a limit.
+func ParseHouseRules(moveLimit int, adjudicate string) (*HouseRules, error) {
+	if moveLimit < 0 || moveLimit > MaxMoveLimit {
+		return nil, fmt.Errorf("move limit must be between 1 and %d", MaxMoveLimit)
+	}
+	switch adjudicate {
+	case "", AdjudicateMaterial, AdjudicateEval:
+	default:
+		return nil, fmt.Errorf("unknown adjudication %q", adjudicate)
+	}
+	if moveLimit == 0 {
+		if adjudicate != "" {
+			return nil, fmt.Errorf("adjudication needs a move limit")
+		}
+		return nil, nil
+	}
+	if adjudicate == "" {
+		adjudicate = AdjudicateMaterial
+	}
+	return &HouseRules{MoveLimit: moveLimit, Adjudicate: adjudicate}, nil
+}
+
+func encodeHouseRules(r *HouseRules) string {
+	if r == nil {
+		return ""
+	}
+	data, _ := json.Marshal(r)
+	return string(data)
+}
+
+func decodeHouseRules(data string) *HouseRules {
+	if data == "" {
+		return nil
+	}
+	var r HouseRules
+	if err := json.Unmarshal([]byte(data), &r); err != nil || r.MoveLimit <= 0 {
+		return nil
+	}
+	return &r
+}
+
+// houseRulesLocked returns a copy of the game's house rules, nil if it has
+// none.
+func (g *Game) houseRulesLocked() *HouseRules {
+	if g.houseRules == nil {
+		return nil
+	}
+	r := *g.houseRules
+	return &r
+}
+
+// moveLimitReachedLocked reports whether the game has been played up to its
+// move limit.
+func (g *Game) moveLimitReachedLocked() bool {
+	return g.houseRules != nil && len(g.g.Moves()) >= 2*g.houseRules.MoveLimit
+}
+
+// EnforceHouseRules ends a game that has reached its move limit, scoring the
+// final position by material or with the hub's engine as its rules say. The
+// result is recorded with TerminationMoveLimit. It reports whether it ended
+// the game.
+func (h *Hub) EnforceHouseRules(g *Game) bool {
+	g.Mu.RLock()
+	if g.overLocked() || !g.moveLimitReachedLocked() {
+		g.Mu.RUnlock()
+		return false
+	}
+	rules := *g.houseRules
+	pos := g.g.Position()
+	ply := len(g.g.Moves())
+	score, margin := g.materialLocked().Balance*100, 1
+	g.Mu.RUnlock()
+
+	if rules.Adjudicate == AdjudicateEval && h.Engine != nil {
+		if a, err := h.Engine.Analyze(pos); err != nil {
+			logging.Debugf("adjudicate %s on eval failed, using material: %v", g.ID, err)
+		} else {
+			score, margin = a.CP, EvalMargin
+			switch {
+			case a.Mate > 0:
+				score = margin
+			case a.Mate < 0:
+				score = -margin
+			}
+		}
+	}
+
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	if g.overLocked() || len(g.g.Moves()) != ply {
+		return false
+	}
+	switch {
+	case score >= margin:
+		g.g.Resign(chess.Black)
+	case score <= -margin:
+		g.g.Resign(chess.White)
+	default:
+		if err := g.g.Draw(chess.DrawOffer); err != nil {
+			return false
+		}
+	}
+	g.termination = TerminationMoveLimit
+	g.recordEventLocked(EventMoveLimit, chess.NoColor)
+	return true
+}
@@ -0,0 +1,97 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/corentings/chess/v2"
+)
+
+func TestParseHouseRules(t *testing.T) {
+	if r, err := ParseHouseRules(0, ""); r != nil || err != nil {
+		t.Fatalf("expected no rules without a limit, got %v %v", r, err)
+	}
+	r, err := ParseHouseRules(40, "")
+	if err != nil || r.MoveLimit != 40 || r.Adjudicate != AdjudicateMaterial {
+		t.Fatalf("expected a 40 move limit decided by material, got %v %v", r, err)
+	}
+	for _, c := range []struct {
+		limit      int
+		adjudicate string
+	}{{-1, ""}, {MaxMoveLimit + 1, ""}, {40, "coin"}, {0, AdjudicateEval}} {
+		if _, err := ParseHouseRules(c.limit, c.adjudicate); err == nil {
+			t.Errorf("ParseHouseRules(%d, %q) accepted", c.limit, c.adjudicate)
+		}
+	}
+}
+
+func TestEnforceHouseRules(t *testing.T) {
+	ctx := context.Background()
+	store := newTestHubStore(t)
+	h := NewHub(store)
+	rules := &HouseRules{MoveLimit: 2, Adjudicate: AdjudicateMaterial}
+
+	// White is a pawn up after two moves each.
+	id, _, err := h.CreateGame(ctx, "00000000-0000-0000-0000-000000000001", GameOptions{HouseRules: rules})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	g, _ := h.Lookup(id)
+	for _, uci := range []string{"e2e4", "d7d5", "e4d5"} {
+		if err := g.MakeMove(uci); err != nil {
+			t.Fatalf("move %s: %v", uci, err)
+		}
+		if h.EnforceHouseRules(g) {
+			t.Fatalf("expected no adjudication before the limit, after %s", uci)
+		}
+	}
+	if err := g.MakeMove("g8f6"); err != nil {
+		t.Fatalf("move g8f6: %v", err)
+	}
+	if err := g.MakeMove("b1c3"); !errors.Is(err, ErrMoveLimit) {
+		t.Fatalf("expected moves past the limit to be refused, got %v", err)
+	}
+	if !h.EnforceHouseRules(g) {
+		t.Fatal("expected the game to be adjudicated at the limit")
+	}
+	if g.Outcome() != chess.WhiteWon {
+		t.Fatalf("expected white to win on material, got %v", g.Outcome())
+	}
+	g.Mu.RLock()
+	state := g.StateLocked()
+	g.Mu.RUnlock()
+	if state.Termination != TerminationMoveLimit || state.HouseRules == nil || state.HouseRules.MoveLimit != 2 {
+		t.Fatalf("unexpected state: termination %q, rules %v", state.Termination, state.HouseRules)
+	}
+	if events := g.Timeline(); events[len(events)-1].Kind != EventMoveLimit {
+		t.Fatalf("expected a move limit event, got %v", events)
+	}
+
+	// Level material is a draw.
+	id, _, err = h.CreateGame(ctx, "00000000-0000-0000-0000-000000000002", GameOptions{HouseRules: rules})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	g, _ = h.Lookup(id)
+	for _, uci := range []string{"e2e4", "e7e5", "g1f3", "b8c6"} {
+		if err := g.MakeMove(uci); err != nil {
+			t.Fatalf("move %s: %v", uci, err)
+		}
+	}
+	if !h.EnforceHouseRules(g) || g.Outcome() != chess.Draw {
+		t.Fatalf("expected a draw at the limit, got %v", g.Outcome())
+	}
+
+	// The rules survive a restart.
+	reloaded, _, err := NewHub(store).Get(ctx, id, "")
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	reloaded.Mu.RLock()
+	r := reloaded.houseRulesLocked()
+	reloaded.Mu.RUnlock()
+	if r == nil || *r != *rules {
+		t.Fatalf("expected the house rules after a restart, got %v", r)
+	}
+}
@@ -2,6 +2,7 @@ package game
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"math/rand"
 	"strings"
@@ -10,39 +11,37 @@ import (
 	"github.com/corentings/chess/v2"
 	"github.com/google/uuid"
 
+	"tinychess/internal/logging"
 	"tinychess/internal/storage"
 )
 
 // NewHub creates a new game hub with an optional backing store.
 func NewHub(store *storage.Store) *Hub {
-	h := &Hub{Games: make(map[string]*Game), Store: store}
+	h := &Hub{Store: store, analysisQueue: make(chan *Game, AnalysisQueueSize), Images: NewImageCache(DefaultImageCacheBytes), Seats: NewSeatSigner(nil), Pairings: NewPairings()}
+	for i := range h.shards {
+		h.shards[i].games = make(map[GameID]*Game)
+		h.shards[i].loading = make(map[GameID]*gameLoad)
+	}
 	go func() {
 		for {
-			time.Sleep(5 * time.Minute)
-			h.Mu.Lock()
-			for id, g := range h.Games {
-				g.Mu.Lock()
-				idle := time.Since(g.LastSeen) > 24*time.Hour
-				g.Mu.Unlock()
-				if idle {
-					delete(h.Games, id)
-				}
-			}
-			h.Mu.Unlock()
+			time.Sleep(CleanupInterval)
+			h.evictIdle(context.Background())
 		}
 	}()
 	return h
 }
 
-func newGameInstance(id string) *Game {
+func newGameInstance(id GameID) *Game {
 	color := randomColor()
+	now := time.Now()
 	return &Game{
 		ID:         id,
-		g:          chess.NewGame(),
-		Watchers:   make(map[chan []byte]struct{}),
+		g:          newChessGame(""),
+		Watchers:   make(map[chan []byte]*watcher),
 		LastReact:  make(map[string]time.Time),
 		Clients:    make(map[string]chess.Color),
-		LastSeen:   time.Now(),
+		LastSeen:   now,
+		CreatedAt:  now,
 		OwnerColor: color,
 	}
 }
@@ -65,9 +64,11 @@ func colorFromString(s string) chess.Color {
 	}
 }
 
-func (g *Game) assignColor(clientID string) *chess.Color {
-	if clientID == "" {
-		return nil
+// assignColor seats clientID if possible. joined reports whether the client
+// took a seat it did not already hold.
+func (g *Game) assignColor(clientID string) (color *chess.Color, joined bool) {
+	if clientID == "" || !ValidClientID(clientID) {
+		return nil, false
 	}
 	g.Mu.Lock()
 	defer g.Mu.Unlock()
@@ -78,7 +79,7 @@ func (g *Game) assignColor(clientID string) *chess.Color {
 			g.OwnerColor = col
 		}
 		c := col
-		return &c
+		return &c, false
 	}
 
 	if g.OwnerID == "" {
@@ -87,8 +88,20 @@ func (g *Game) assignColor(clientID string) *chess.Color {
 		}
 		g.OwnerID = clientID
 		g.Clients[clientID] = g.OwnerColor
+		g.recordEventLocked(EventJoined, g.OwnerColor)
+		c := g.OwnerColor
+		return &c, true
+	}
+
+	// Hot-seat games only ever seat their owner, who may be returning to a
+	// game reloaded from the store.
+	if g.hotSeat {
+		if clientID != g.OwnerID {
+			return nil, false
+		}
+		g.Clients[clientID] = g.OwnerColor
 		c := g.OwnerColor
-		return &c
+		return &c, false
 	}
 
 	if len(g.Clients) < 2 {
@@ -99,21 +112,19 @@ func (g *Game) assignColor(clientID string) *chess.Color {
 			color = chess.White
 		}
 		g.Clients[clientID] = color
+		g.recordEventLocked(EventJoined, color)
 		c := color
-		return &c
+		return &c, true
 	}
 
-	return nil
+	return nil, false
 }
 
 func (h *Hub) hydrateGame(ctx context.Context, g *Game) error {
 	if h.Store == nil {
 		return nil
 	}
-	gameID, err := uuid.Parse(g.ID)
-	if err != nil {
-		return nil
-	}
+	gameID := g.ID.UUID()
 	persisted, err := h.Store.LoadGame(ctx, gameID)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
@@ -122,13 +133,27 @@ func (h *Hub) hydrateGame(ctx context.Context, g *Game) error {
 		return err
 	}
 
-	if persisted.Game.FEN != "" {
-		if opt, err := chess.FEN(persisted.Game.FEN); err == nil {
-			g.g = chess.NewGame(opt)
+	g.startFEN = persisted.Game.StartFEN
+	// Replaying the move history restores repetition and fifty-move counting
+	// and the full PGN; the stored FEN is only a fallback.
+	if !h.replayHistory(ctx, g, gameID, persisted.Game.FEN) {
+		g.g = newChessGame(g.startFEN)
+		g.lastMove = nil
+		g.playedAt = nil
+		if persisted.Game.FEN != "" {
+			if restored, err := GameFromFEN(persisted.Game.FEN); err == nil {
+				g.g = restored
+			}
 		}
+		g.captured = ParseCaptured(persisted.Game.CapturedByWhite, persisted.Game.CapturedByBlack)
 	}
 
+	g.restoreEnding(persisted.Game.Result, persisted.Game.Termination)
+
 	g.LastSeen = persisted.Game.LastSeen
+	if !persisted.Game.CreatedAt.IsZero() {
+		g.CreatedAt = persisted.Game.CreatedAt
+	}
 	if g.LastSeen.IsZero() {
 		g.LastSeen = time.Now()
 	}
@@ -139,6 +164,41 @@ func (h *Hub) hydrateGame(ctx context.Context, g *Game) error {
 	if col := colorFromString(persisted.Game.OwnerColor); col != chess.NoColor {
 		g.OwnerColor = col
 	}
+	g.TimeControl = persisted.Game.TimeControl
+	g.Language = persisted.Game.Language
+	g.timeline = parseTimeline(persisted.Game.Timeline)
+	g.joinCodeHash = persisted.Game.JoinCodeHash
+	g.codeForSpectators = persisted.Game.CodeForSpectators
+	g.inviteOnly = persisted.Game.InviteOnly
+	g.hotSeat = persisted.Game.HotSeat
+	g.houseRules = decodeHouseRules(persisted.Game.HouseRules)
+	g.training = decodeTraining(persisted.Game.Training)
+	if data, err := h.Store.Analysis(ctx, gameID); err == nil {
+		var report AnalysisReport
+		if err := json.Unmarshal([]byte(data), &report); err == nil {
+			g.analysis = &report
+		}
+	}
+
+	if msgs, err := h.Store.RecentChat(ctx, gameID, ChatHistoryLimit); err == nil {
+		for _, m := range msgs {
+			g.appendChatLocked(ChatPayload{
+				Schema: SchemaVersion,
+				Kind:   "chat",
+				ID:     m.ID.String(),
+				Text:   m.Text,
+				At:     m.CreatedAt.UnixMilli(),
+				Sender: g.chatterLocked(m.UserID.String()),
+				Color:  m.Color,
+			})
+		}
+	}
+	if muted, err := h.Store.ChatMutes(ctx, gameID); err == nil && len(muted) > 0 {
+		g.muted = make(map[string]bool, len(muted))
+		for _, id := range muted {
+			g.muted[id.String()] = true
+		}
+	}
 
 	for _, player := range persisted.Players {
 		if !player.Active || player.UserID == uuid.Nil {
@@ -149,114 +209,217 @@ func (h *Hub) hydrateGame(ctx context.Context, g *Game) error {
 			continue
 		}
 		g.Clients[player.UserID.String()] = col
+		if player.Name != "" {
+			if g.names == nil {
+				g.names = make(map[string]string)
+			}
+			g.names[player.UserID.String()] = player.Name
+		}
 	}
 
 	if g.OwnerID == "" && persisted.Game.OwnerID != uuid.Nil {
 		g.OwnerID = persisted.Game.OwnerID.String()
 	}
+	if g.training != nil {
+		g.seatTablebaseLocked()
+	}
 
 	return nil
 }
 
+// replayHistory rebuilds g from its persisted moves. It reports false when
+// there are no moves, they do not replay, or they disagree with the stored
+// position.
+func (h *Hub) replayHistory(ctx context.Context, g *Game, gameID uuid.UUID, fen string) bool {
+	moves, err := h.Store.Moves(ctx, gameID)
+	if err != nil {
+		logging.Debugf("load moves for %s failed: %v", g.ID, err)
+		return false
+	}
+	if len(moves) == 0 {
+		return false
+	}
+	uci := make([]string, len(moves))
+	for i, m := range moves {
+		uci[i] = m.UCI
+	}
+	if err := g.replayLocked(uci); err != nil {
+		logging.Debugf("replay %s failed: %v", g.ID, err)
+		return false
+	}
+	if fen != "" && g.g.Position().String() != fen {
+		logging.Debugf("replay %s ended at %q, stored position is %q", g.ID, g.g.Position().String(), fen)
+		return false
+	}
+	for i, m := range moves {
+		g.playedAt[i] = m.CreatedAt
+	}
+	return true
+}
+
+// WarmLimit caps how many games are loaded when warming the hub at startup.
+const WarmLimit = 1000
+
+// Warm loads unfinished games seen within the last window from the store, so
+// the first request after a restart does not pay for hydration. At most limit
+// games are loaded when limit is positive. It returns how many were loaded.
+func (h *Hub) Warm(ctx context.Context, window time.Duration, limit int) (int, error) {
+	if h.Store == nil {
+		return 0, nil
+	}
+	ids, err := h.Store.ActiveGamesSince(ctx, time.Now().Add(-window), limit)
+	if err != nil {
+		return 0, err
+	}
+	loaded := 0
+	for _, gameID := range ids {
+		id := GameID(gameID)
+		if _, ok := h.Lookup(id); ok {
+			continue
+		}
+		if _, err := h.load(ctx, id); err != nil {
+			logging.Debugf("warm %s failed: %v", id, err)
+			continue
+		}
+		loaded++
+	}
+	return loaded, nil
+}
+
 // Get retrieves an existing game or creates a new in-memory copy. If a client ID
 // is provided, the player will be assigned a color (if available). The assigned
 // color is returned when applicable.
-func (h *Hub) Get(ctx context.Context, id, clientID string) (*Game, *chess.Color, error) {
-	h.Mu.Lock()
-	g, ok := h.Games[id]
-	if !ok {
-		g = newGameInstance(id)
-		if err := h.hydrateGame(ctx, g); err != nil {
-			h.Mu.Unlock()
-			return nil, nil, err
-		}
-		h.Games[id] = g
+func (h *Hub) Get(ctx context.Context, id GameID, clientID string) (*Game, *chess.Color, error) {
+	g, assigned, _, err := h.Join(ctx, id, clientID)
+	return g, assigned, err
+}
+
+// Join is Get that also reports whether clientID took its seat on this call,
+// rather than already holding it.
+func (h *Hub) Join(ctx context.Context, id GameID, clientID string) (g *Game, assigned *chess.Color, joined bool, err error) {
+	g, err = h.load(ctx, id)
+	if err != nil {
+		return nil, nil, false, err
 	}
-	h.Mu.Unlock()
 
-	var assigned *chess.Color
 	if clientID != "" {
-		assigned = g.assignColor(clientID)
+		assigned, joined = g.assignColor(clientID)
+		if joined {
+			if err := h.SaveTimeline(ctx, g); err != nil {
+				logging.Debugf("save timeline for %s failed: %v", id, err)
+			}
+		}
 		if assigned != nil && h.Store != nil {
-			gameUUID, err := uuid.Parse(id)
-			if err == nil {
-				userUUID, err := uuid.Parse(clientID)
-				if err == nil {
-					role := "player"
-					if g.OwnerID == clientID {
-						role = "owner"
-					}
-					now := time.Now()
-					if err := h.Store.EnsureUserSession(ctx, gameUUID, userUUID, assigned.String(), role, now); err != nil {
-						return g, assigned, err
-					}
+			if userUUID, err := uuid.Parse(clientID); err == nil {
+				role := "player"
+				if g.OwnerID == clientID {
+					role = "owner"
+				}
+				if err := h.Store.EnsureUserSession(ctx, id.UUID(), userUUID, assigned.String(), role, time.Now()); err != nil {
+					return g, assigned, joined, err
 				}
 			}
 		}
 	}
 
-	return g, assigned, nil
+	return g, assigned, joined, nil
 }
 
 // CreateGame creates a brand-new game, stores it if a backing store exists, and
-// returns the identifier and assigned owner color.
-func (h *Hub) CreateGame(ctx context.Context, ownerID string) (string, chess.Color, error) {
+// returns the identifier and assigned owner color. Options are expected to be
+// validated by the caller.
+func (h *Hub) CreateGame(ctx context.Context, ownerID string, opts GameOptions) (GameID, chess.Color, error) {
 	ownerID = strings.TrimSpace(ownerID)
 	if ownerID == "" {
-		return "", chess.NoColor, errors.New("missing owner id")
+		return GameID{}, chess.NoColor, errors.New("missing owner id")
 	}
 	ownerUUID, err := uuid.Parse(ownerID)
 	if err != nil {
-		return "", chess.NoColor, err
+		return GameID{}, chess.NoColor, err
+	}
+	if h.Maintenance().Active {
+		return GameID{}, chess.NoColor, ErrMaintenance
 	}
 
-	id := uuid.NewString()
+	id := NewGameID()
 	g := newGameInstance(id)
 	g.OwnerID = ownerID
+	if opts.Color != chess.NoColor {
+		g.OwnerColor = opts.Color
+	}
 	g.Clients[ownerID] = g.OwnerColor
+	g.TimeControl = opts.TimeControl
+	g.Language = opts.Language
+	if opts.StartFEN != "" {
+		g.startFEN = opts.StartFEN
+		g.g = newChessGame(opts.StartFEN)
+	}
+	if opts.JoinCode != "" {
+		hash, err := hashJoinCode(opts.JoinCode)
+		if err != nil {
+			return GameID{}, chess.NoColor, err
+		}
+		g.joinCodeHash = hash
+		g.codeForSpectators = opts.CodeForSpectators
+	}
+	g.inviteOnly = opts.InviteOnly
+	g.hotSeat = opts.HotSeat
+	g.houseRules = opts.HouseRules
+	g.recordEventLocked(EventJoined, g.OwnerColor)
+	if opts.Ending != "" {
+		g.training = &Training{Ending: opts.Ending, Held: true}
+		g.seatTablebaseLocked()
+	}
 
-	h.Mu.Lock()
-	h.Games[id] = g
-	h.Mu.Unlock()
+	h.add(g)
 
 	if h.Store != nil {
-		gameUUID, err := uuid.Parse(id)
-		if err != nil {
-			h.Mu.Lock()
-			delete(h.Games, id)
-			h.Mu.Unlock()
-			return "", chess.NoColor, err
-		}
+		gameUUID := id.UUID()
 		if err := h.Store.CreateGame(ctx, gameUUID, ownerUUID, g.OwnerColor.String(), g.LastSeen); err != nil {
-			h.Mu.Lock()
-			delete(h.Games, id)
-			h.Mu.Unlock()
-			return "", chess.NoColor, err
+			h.remove(id)
+			return GameID{}, chess.NoColor, err
 		}
 		if err := h.Store.EnsureUserSession(ctx, gameUUID, ownerUUID, g.OwnerColor.String(), "owner", g.LastSeen); err != nil {
-			h.Mu.Lock()
-			delete(h.Games, id)
-			h.Mu.Unlock()
-			return "", chess.NoColor, err
+			h.remove(id)
+			return GameID{}, chess.NoColor, err
 		}
 		g.Mu.Lock()
 		state := g.StateLocked()
+		timeline, _ := json.Marshal(g.timeline)
 		g.Mu.Unlock()
+		timelineJSON := string(timeline)
 		active := true
 		fen := state.FEN
 		pgn := state.PGN
 		status := state.Status
+		timeControl := g.TimeControl
+		language := g.Language
+		startFEN := g.startFEN
+		joinCodeHash := g.joinCodeHash
+		codeForSpectators := g.codeForSpectators
+		inviteOnly := g.inviteOnly
+		hotSeat := g.hotSeat
+		houseRules := encodeHouseRules(g.houseRules)
+		training := encodeTraining(state.Training)
 		if err := h.Store.SaveGameState(ctx, gameUUID, storage.GameStateUpdate{
-			FEN:      &fen,
-			PGN:      &pgn,
-			Status:   &status,
-			Active:   &active,
-			LastSeen: &g.LastSeen,
+			FEN:               &fen,
+			PGN:               &pgn,
+			Status:            &status,
+			TimeControl:       &timeControl,
+			Language:          &language,
+			StartFEN:          &startFEN,
+			JoinCodeHash:      &joinCodeHash,
+			CodeForSpectators: &codeForSpectators,
+			InviteOnly:        &inviteOnly,
+			HotSeat:           &hotSeat,
+			HouseRules:        &houseRules,
+			Training:          &training,
+			Timeline:          &timelineJSON,
+			Active:            &active,
+			LastSeen:          &g.LastSeen,
 		}); err != nil {
-			h.Mu.Lock()
-			delete(h.Games, id)
-			h.Mu.Unlock()
-			return "", chess.NoColor, err
+			h.remove(id)
+			return GameID{}, chess.NoColor, err
 		}
 	}
 
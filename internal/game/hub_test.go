@@ -2,29 +2,32 @@ package game
 
 import (
 	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+
+	"tinychess/internal/storage"
 )
 
-// runCleanup mimics the hub's cleanup routine for testing purposes.
-func runCleanup(h *Hub) {
-	h.Mu.Lock()
-	for id, game := range h.Games {
-		game.Mu.Lock()
-		idle := time.Since(game.LastSeen) > 24*time.Hour
-		game.Mu.Unlock()
-		if idle {
-			delete(h.Games, id)
-		}
+func newTestHubStore(t *testing.T) *storage.Store {
+	t.Helper()
+	db, err := storage.New("sqlite://"+filepath.Join(t.TempDir(), "test.db"), 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
 	}
-	h.Mu.Unlock()
+	return storage.NewStore(db)
 }
 
 func TestGamePersistenceBeforeCleanup(t *testing.T) {
 	h := NewHub(nil)
-	g, _, err := h.Get(context.Background(), "test", "")
+	id := NewGameID()
+	g, _, err := h.Get(context.Background(), id, "")
 	if err != nil {
 		t.Fatalf("get: %v", err)
 	}
@@ -34,11 +37,9 @@ func TestGamePersistenceBeforeCleanup(t *testing.T) {
 	g.LastSeen = time.Now().Add(-23 * time.Hour)
 	g.Mu.Unlock()
 
-	runCleanup(h)
+	h.evictIdle(context.Background())
 
-	h.Mu.Lock()
-	_, exists := h.Games["test"]
-	h.Mu.Unlock()
+	_, exists := h.Lookup(id)
 	if !exists {
 		t.Fatalf("game removed before 24 hours of inactivity")
 	}
@@ -48,11 +49,9 @@ func TestGamePersistenceBeforeCleanup(t *testing.T) {
 	g.LastSeen = time.Now().Add(-25 * time.Hour)
 	g.Mu.Unlock()
 
-	runCleanup(h)
+	h.evictIdle(context.Background())
 
-	h.Mu.Lock()
-	_, exists = h.Games["test"]
-	h.Mu.Unlock()
+	_, exists = h.Lookup(id)
 	if exists {
 		t.Fatalf("game not removed after 24 hours of inactivity")
 	}
@@ -60,7 +59,8 @@ func TestGamePersistenceBeforeCleanup(t *testing.T) {
 
 func TestOwnerAndClientColorAssignment(t *testing.T) {
 	h := NewHub(nil)
-	g, _, err := h.Get(context.Background(), "g1", "owner")
+	id := NewGameID()
+	g, _, err := h.Get(context.Background(), id, "owner")
 	if err != nil {
 		t.Fatalf("get: %v", err)
 	}
@@ -73,7 +73,7 @@ func TestOwnerAndClientColorAssignment(t *testing.T) {
 		t.Fatalf("owner not recorded with correct color")
 	}
 
-	g, _, err = h.Get(context.Background(), "g1", "client2")
+	g, _, err = h.Get(context.Background(), id, "client2")
 	if err != nil {
 		t.Fatalf("get: %v", err)
 	}
@@ -90,10 +90,11 @@ func TestOwnerAndClientColorAssignment(t *testing.T) {
 
 func TestTwoClientsReceiveOppositeColors(t *testing.T) {
 	h := NewHub(nil)
-	if _, _, err := h.Get(context.Background(), "g2", "c1"); err != nil {
+	id := NewGameID()
+	if _, _, err := h.Get(context.Background(), id, "c1"); err != nil {
 		t.Fatalf("get: %v", err)
 	}
-	g, _, err := h.Get(context.Background(), "g2", "c2")
+	g, _, err := h.Get(context.Background(), id, "c2")
 	if err != nil {
 		t.Fatalf("get: %v", err)
 	}
@@ -108,7 +109,7 @@ func TestTwoClientsReceiveOppositeColors(t *testing.T) {
 		t.Fatalf("expected clients to have opposite colors, both got %v", c1)
 	}
 
-	g, _, err = h.Get(context.Background(), "g2", "")
+	g, _, err = h.Get(context.Background(), id, "")
 	if err != nil {
 		t.Fatalf("get: %v", err)
 	}
@@ -119,13 +120,14 @@ func TestTwoClientsReceiveOppositeColors(t *testing.T) {
 
 func TestColorPersistsAfterOwnerLeaves(t *testing.T) {
 	h := NewHub(nil)
+	id := NewGameID()
 
 	// owner joins
-	if _, _, err := h.Get(context.Background(), "g3", "owner"); err != nil {
+	if _, _, err := h.Get(context.Background(), id, "owner"); err != nil {
 		t.Fatalf("get: %v", err)
 	}
 	// second player joins
-	g, _, err := h.Get(context.Background(), "g3", "player")
+	g, _, err := h.Get(context.Background(), id, "player")
 	if err != nil {
 		t.Fatalf("get: %v", err)
 	}
@@ -136,7 +138,7 @@ func TestColorPersistsAfterOwnerLeaves(t *testing.T) {
 	g.RemoveClient("owner")
 
 	// player refreshes (rejoins)
-	g, col, err := h.Get(context.Background(), "g3", "player")
+	g, col, err := h.Get(context.Background(), id, "player")
 	if err != nil {
 		t.Fatalf("get: %v", err)
 	}
@@ -153,7 +155,7 @@ func TestColorPersistsAfterOwnerLeaves(t *testing.T) {
 	}
 
 	// new client should receive opposite color
-	_, col2, err := h.Get(context.Background(), "g3", "newbie")
+	_, col2, err := h.Get(context.Background(), id, "newbie")
 	if err != nil {
 		t.Fatalf("get: %v", err)
 	}
@@ -161,3 +163,212 @@ func TestColorPersistsAfterOwnerLeaves(t *testing.T) {
 		t.Fatalf("expected new client to receive opposite color")
 	}
 }
+
+func TestHydrateReplaysMoveHistory(t *testing.T) {
+	store := newTestHubStore(t)
+	ctx := context.Background()
+	gameID, userID := uuid.New(), uuid.New()
+	if err := store.CreateGame(ctx, gameID, userID, "w", time.Now()); err != nil {
+		t.Fatalf("create game: %v", err)
+	}
+
+	played := newTestGame()
+	played.CreatedAt = time.Now().Add(-time.Minute)
+	moves := []string{"g1f3", "g8f6", "f3g1", "f6g8", "g1f3", "g8f6", "f3g1", "f6g8"}
+	for i, uci := range moves {
+		if err := played.MakeMove(uci); err != nil {
+			t.Fatalf("move %s: %v", uci, err)
+		}
+		color := "w"
+		if i%2 == 1 {
+			color = "b"
+		}
+		timing := played.MoveTimes()[i]
+		if err := store.RecordMove(ctx, gameID, userID, i+1, uci, color, "", time.UnixMilli(timing.At), timing.ThinkTime()); err != nil {
+			t.Fatalf("record move: %v", err)
+		}
+	}
+	fen := played.g.Position().String()
+	if err := store.SaveGameState(ctx, gameID, storage.GameStateUpdate{FEN: &fen}); err != nil {
+		t.Fatalf("save state: %v", err)
+	}
+
+	g, _, err := NewHub(store).Get(ctx, GameID(gameID), "")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	if got := g.MovesUCI(); !slices.Equal(got, moves) {
+		t.Fatalf("expected moves %v, got %v", moves, got)
+	}
+	if !slices.Contains(g.g.EligibleDraws(), chess.ThreefoldRepetition) {
+		t.Fatalf("expected threefold repetition to be claimable, got %v", g.g.EligibleDraws())
+	}
+	state := g.StateLocked()
+	if !strings.Contains(state.PGN, "1. Nf3 Nf6") || state.LastMove == nil || state.LastMove.UCI != "f6g8" {
+		t.Fatalf("unexpected state after hydrate: pgn=%q lastMove=%+v", state.PGN, state.LastMove)
+	}
+	if want := played.MoveTimes(); state.MoveTimes[7].At != want[7].At {
+		t.Fatalf("expected move times to survive hydrate, got %+v want %+v", state.MoveTimes, want)
+	}
+}
+
+func TestHydrateFallsBackToFEN(t *testing.T) {
+	store := newTestHubStore(t)
+	ctx := context.Background()
+	gameID, userID := uuid.New(), uuid.New()
+	if err := store.CreateGame(ctx, gameID, userID, "w", time.Now()); err != nil {
+		t.Fatalf("create game: %v", err)
+	}
+	fen := "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2"
+	if err := store.SaveGameState(ctx, gameID, storage.GameStateUpdate{FEN: &fen}); err != nil {
+		t.Fatalf("save state: %v", err)
+	}
+
+	g, _, err := NewHub(store).Get(ctx, GameID(gameID), "")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	if got := g.g.Position().String(); got != fen {
+		t.Fatalf("expected position %q, got %q", fen, got)
+	}
+}
+
+func TestHydrateCustomStart(t *testing.T) {
+	store := newTestHubStore(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	start := "8/8/8/4k3/8/8/4P3/4K3 w - - 0 1"
+
+	id, _, err := NewHub(store).CreateGame(ctx, userID.String(), GameOptions{StartFEN: start})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	gameID := id.UUID()
+	if err := store.RecordMove(ctx, gameID, userID, 1, "e2e4", "white", "", time.Now(), 0); err != nil {
+		t.Fatalf("record move: %v", err)
+	}
+	played := newChessGame(start)
+	if err := played.PushNotationMove("e2e4", chess.UCINotation{}, nil); err != nil {
+		t.Fatalf("play: %v", err)
+	}
+	fen := played.Position().String()
+	if err := store.SaveGameState(ctx, gameID, storage.GameStateUpdate{FEN: &fen}); err != nil {
+		t.Fatalf("save state: %v", err)
+	}
+
+	g, _, err := NewHub(store).Get(ctx, id, "")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	if g.StartFEN() != start || len(g.g.Moves()) != 1 {
+		t.Fatalf("expected replay from custom start, got start=%q moves=%d", g.StartFEN(), len(g.g.Moves()))
+	}
+}
+
+func TestWarmLoadsRecentGames(t *testing.T) {
+	store := newTestHubStore(t)
+	ctx := context.Background()
+	recent, stale, userID := uuid.New(), uuid.New(), uuid.New()
+	if err := store.CreateGame(ctx, recent, userID, "w", time.Now()); err != nil {
+		t.Fatalf("create game: %v", err)
+	}
+	if err := store.RecordMove(ctx, recent, userID, 1, "e2e4", "w", "", time.Now(), 0); err != nil {
+		t.Fatalf("record move: %v", err)
+	}
+	if err := store.CreateGame(ctx, stale, userID, "w", time.Now().Add(-48*time.Hour)); err != nil {
+		t.Fatalf("create game: %v", err)
+	}
+
+	hub := NewHub(store)
+	n, err := hub.Warm(ctx, 24*time.Hour, 0)
+	if err != nil || n != 1 {
+		t.Fatalf("expected one game warmed, got %d (%v)", n, err)
+	}
+	g, ok := hub.Lookup(GameID(recent))
+	if !ok {
+		t.Fatalf("expected recent game in memory")
+	}
+	if _, ok := hub.Lookup(GameID(stale)); ok {
+		t.Fatalf("stale game should not be warmed")
+	}
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	if got := g.MovesUCI(); !slices.Equal(got, []string{"e2e4"}) {
+		t.Fatalf("expected move history to be restored, got %v", got)
+	}
+	if n, _ := hub.Warm(ctx, 24*time.Hour, 0); n != 0 {
+		t.Fatalf("expected loaded games to be skipped, warmed %d", n)
+	}
+}
+
+func TestGetSharesOneLoad(t *testing.T) {
+	store := newTestHubStore(t)
+	ctx := context.Background()
+	gameID := uuid.New()
+	if err := store.CreateGame(ctx, gameID, uuid.New(), "w", time.Now()); err != nil {
+		t.Fatalf("create game: %v", err)
+	}
+	hub := NewHub(store)
+	id := GameID(gameID)
+	games := make([]*Game, 16)
+	var wg sync.WaitGroup
+	for i := range games {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			g, _, err := hub.Get(ctx, id, "")
+			if err != nil {
+				t.Errorf("get: %v", err)
+			}
+			games[i] = g
+		}(i)
+	}
+	wg.Wait()
+	for _, g := range games {
+		if g == nil || g != games[0] {
+			t.Fatalf("expected every request to get the same game")
+		}
+	}
+	if n := hub.Len(); n != 1 {
+		t.Fatalf("expected one game loaded, have %d", n)
+	}
+	if _, _, err := hub.Get(ctx, NewGameID(), ""); err != nil || hub.Len() != 2 {
+		t.Fatalf("expected another game loaded, have %d: %v", hub.Len(), err)
+	}
+}
+
+func TestPairings(t *testing.T) {
+	now := time.Now()
+	p := NewPairings()
+	p.now = func() time.Time { return now }
+	id := NewGameID()
+
+	first, _ := p.Issue(id)
+	code, expires := p.Issue(id)
+	if len(code) != 6 || !expires.Equal(now.Add(PairTTL)) {
+		t.Fatalf("unexpected code %q expiring %v", code, expires)
+	}
+	if first != code {
+		if _, ok := p.Claim(first); ok {
+			t.Fatal("expected a reissued code to replace the earlier one")
+		}
+	}
+	if got, ok := p.Claim(code); !ok || got != id {
+		t.Fatalf("Claim = %v, %v", got, ok)
+	}
+	if _, ok := p.Claim(code); ok {
+		t.Fatal("expected a code to be claimed only once")
+	}
+
+	code, _ = p.Issue(id)
+	now = now.Add(PairTTL)
+	if _, ok := p.Claim(code); ok {
+		t.Fatal("expected an expired code to be refused")
+	}
+}
@@ -0,0 +1,62 @@
+package game
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrBadGameID is returned by ParseGameID for anything that is not a game ID.
+var ErrBadGameID = errors.New("bad game id")
+
+// GameID identifies a game. Game IDs are UUIDs, validated once by
+// ParseGameID where they enter the server; past that point the hub and the
+// store can rely on every ID being persistable.
+type GameID uuid.UUID
+
+// NewGameID returns a fresh random game ID.
+func NewGameID() GameID {
+	return GameID(uuid.New())
+}
+
+// ParseGameID validates s as a game ID. Any UUID spelling accepted by
+// uuid.Parse is allowed and normalized; the nil UUID is not a game.
+func ParseGameID(s string) (GameID, error) {
+	u, err := uuid.Parse(strings.TrimSpace(s))
+	if err != nil || u == uuid.Nil {
+		return GameID{}, ErrBadGameID
+	}
+	return GameID(u), nil
+}
+
+// UUID returns the ID as the store's key type.
+func (id GameID) UUID() uuid.UUID {
+	return uuid.UUID(id)
+}
+
+// IsZero reports whether id is unset.
+func (id GameID) IsZero() bool {
+	return uuid.UUID(id) == uuid.Nil
+}
+
+// String returns the canonical hyphenated form of the ID.
+func (id GameID) String() string {
+	return uuid.UUID(id).String()
+}
+
+// MarshalText encodes the ID in its canonical form, so it appears in JSON as a
+// plain string.
+func (id GameID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText parses a game ID, rejecting malformed ones.
+func (id *GameID) UnmarshalText(b []byte) error {
+	parsed, err := ParseGameID(string(b))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
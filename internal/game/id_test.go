@@ -0,0 +1,30 @@
+package game
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseGameID(t *testing.T) {
+	id, err := ParseGameID(" 5F0C6A8E-2B1D-4C3E-9F7A-1B2C3D4E5F60 ")
+	if err != nil || id.String() != "5f0c6a8e-2b1d-4c3e-9f7a-1b2c3d4e5f60" {
+		t.Fatalf("expected normalized id, got %q (%v)", id, err)
+	}
+	for _, bad := range []string{"", "abc", "g1", "00000000-0000-0000-0000-000000000000"} {
+		if _, err := ParseGameID(bad); err != ErrBadGameID {
+			t.Fatalf("%q: expected ErrBadGameID, got %v", bad, err)
+		}
+	}
+
+	data, _ := json.Marshal(map[string]GameID{"id": id})
+	if string(data) != `{"id":"5f0c6a8e-2b1d-4c3e-9f7a-1b2c3d4e5f60"}` {
+		t.Fatalf("unexpected json %s", data)
+	}
+	var back struct{ ID GameID }
+	if err := json.Unmarshal([]byte(`{"ID":"5f0c6a8e-2b1d-4c3e-9f7a-1b2c3d4e5f60"}`), &back); err != nil || back.ID != id {
+		t.Fatalf("round trip failed: %v %v", back.ID, err)
+	}
+	if err := json.Unmarshal([]byte(`{"ID":"nope"}`), &back); err == nil {
+		t.Fatalf("expected bad id to fail to decode")
+	}
+}
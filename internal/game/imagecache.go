@@ -0,0 +1,102 @@
+package game
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultImageCacheBytes bounds the rendered board image cache unless
+// configured otherwise.
+const DefaultImageCacheBytes = 4 << 20
+
+// ImageCache is an in-memory LRU of rendered board images keyed by position
+// and render options, bounded by the total size of the images it holds. A nil
+// cache stores nothing.
+type ImageCache struct {
+	mu       sync.Mutex
+	maxBytes int
+	bytes    int
+	order    *list.List
+	entries  map[string]*list.Element
+	stats    ImageCacheStats
+}
+
+// ImageCacheStats reports cache effectiveness.
+type ImageCacheStats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Entries   int   `json:"entries"`
+	Bytes     int   `json:"bytes"`
+	MaxBytes  int   `json:"maxBytes"`
+}
+
+type imageEntry struct {
+	key  string
+	data []byte
+}
+
+// NewImageCache returns a cache holding at most maxBytes of images, or nil
+// when maxBytes is not positive.
+func NewImageCache(maxBytes int) *ImageCache {
+	if maxBytes <= 0 {
+		return nil
+	}
+	return &ImageCache{maxBytes: maxBytes, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Get returns the cached image for key and marks it recently used.
+func (c *ImageCache) Get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(el)
+	return el.Value.(*imageEntry).data, true
+}
+
+// Add stores an image, evicting the least recently used ones to stay within
+// the size limit. Images larger than the whole cache are not stored.
+func (c *ImageCache) Add(key string, data []byte) {
+	if c == nil || len(data) > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.bytes -= len(el.Value.(*imageEntry).data)
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+	for c.bytes+len(data) > c.maxBytes {
+		el := c.order.Back()
+		e := el.Value.(*imageEntry)
+		c.order.Remove(el)
+		delete(c.entries, e.key)
+		c.bytes -= len(e.data)
+		c.stats.Evictions++
+	}
+	c.entries[key] = c.order.PushFront(&imageEntry{key: key, data: data})
+	c.bytes += len(data)
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *ImageCache) Stats() ImageCacheStats {
+	if c == nil {
+		return ImageCacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Entries = len(c.entries)
+	s.Bytes = c.bytes
+	s.MaxBytes = c.maxBytes
+	return s
+}
@@ -0,0 +1,36 @@
+package game
+
+import "testing"
+
+func TestImageCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewImageCache(10)
+	c.Add("a", []byte("aaaa"))
+	c.Add("b", []byte("bbbb"))
+	if _, ok := c.Get("a"); !ok {
+		t.Fatalf("expected a to be cached")
+	}
+	c.Add("c", []byte("cccc"))
+	if _, ok := c.Get("b"); ok {
+		t.Fatalf("expected b to be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Fatalf("recently used a should survive")
+	}
+	c.Add("huge", make([]byte, 11))
+	if _, ok := c.Get("huge"); ok {
+		t.Fatalf("images larger than the cache should not be stored")
+	}
+
+	s := c.Stats()
+	if s.Hits != 2 || s.Misses != 2 || s.Evictions != 1 || s.Entries != 2 || s.Bytes != 8 || s.MaxBytes != 10 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if NewImageCache(0) != nil {
+		t.Fatalf("a zero size should disable the cache")
+	}
+	var disabled *ImageCache
+	disabled.Add("a", []byte("a"))
+	if _, ok := disabled.Get("a"); ok {
+		t.Fatalf("nil cache should store nothing")
+	}
+}
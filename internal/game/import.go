@@ -0,0 +1,68 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+
+	"tinychess/pkg/chesscore"
+)
+
+// MaxImportSize bounds the PGN accepted by an import, in bytes.
+const MaxImportSize = 256 << 10
+
+// ErrEmptyPGN is returned when an import holds no game.
+var ErrEmptyPGN = errors.New("no game in pgn")
+
+// ImportedPGN is a game read from PGN: its starting position and mainline.
+// Variations and comments are dropped.
+type ImportedPGN struct {
+	// StartFEN is the canonical custom starting position, see ParseStartFEN.
+	StartFEN string
+	UCI      []string
+}
+
+// ParsePGN reads the first game of pgn. Moves may use any notation the
+// chess package understands.
+func ParsePGN(pgn string) (ImportedPGN, error) {
+	if strings.TrimSpace(pgn) == "" {
+		return ImportedPGN{}, ErrEmptyPGN
+	}
+	parsed, err := chesscore.GameFromPGN(pgn)
+	if err != nil {
+		return ImportedPGN{}, fmt.Errorf("invalid pgn: %w", err)
+	}
+
+	start, err := ParseStartFEN(parsed.GetTagPair("FEN"))
+	if err != nil {
+		return ImportedPGN{}, err
+	}
+	imported := ImportedPGN{StartFEN: start}
+	tmp := newChessGame(start)
+	uci := chess.UCINotation{}
+	for _, m := range parsed.Moves() {
+		s := uci.Encode(tmp.Position(), m)
+		mv, err := uci.Decode(tmp.Position(), s)
+		if err != nil {
+			return ImportedPGN{}, fmt.Errorf("invalid pgn: ply %d (%s): %w", len(imported.UCI)+1, s, err)
+		}
+		if err := tmp.Move(mv, nil); err != nil {
+			return ImportedPGN{}, fmt.Errorf("invalid pgn: ply %d (%s): %w", len(imported.UCI)+1, s, err)
+		}
+		imported.UCI = append(imported.UCI, s)
+	}
+	return imported, nil
+}
+
+// LoadMoves plays moves on a game that has none yet, such as one just
+// created for an import.
+func (g *Game) LoadMoves(moves []string) error {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	if len(g.g.Moves()) > 0 {
+		return errors.New("game already has moves")
+	}
+	return g.replayLocked(moves)
+}
@@ -0,0 +1,44 @@
+package game
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParsePGN(t *testing.T) {
+	imp, err := ParsePGN("1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if want := []string{"e2e4", "e7e5", "d1h5", "b8c6", "f1c4", "g8f6", "h5f7"}; imp.StartFEN != "" || !slices.Equal(imp.UCI, want) {
+		t.Fatalf("got %+v", imp)
+	}
+
+	imp, err = ParsePGN("[SetUp \"1\"]\n[FEN \"8/8/8/4k3/8/8/4P3/4K3 w - - 0 1\"]\n\n1. e4 Kd4 *")
+	if err != nil {
+		t.Fatalf("parse custom start: %v", err)
+	}
+	if imp.StartFEN != "8/8/8/4k3/8/8/4P3/4K3 w - - 0 1" || !slices.Equal(imp.UCI, []string{"e2e4", "e5d4"}) {
+		t.Fatalf("got %+v", imp)
+	}
+
+	if _, err := ParsePGN("  "); err != ErrEmptyPGN {
+		t.Fatalf("expected ErrEmptyPGN, got %v", err)
+	}
+	if _, err := ParsePGN("1. e5 *"); err == nil {
+		t.Fatalf("expected an illegal move to be rejected")
+	}
+}
+
+func TestLoadMoves(t *testing.T) {
+	g := newGameInstance(NewGameID())
+	if err := g.LoadMoves([]string{"e2e4", "e7e5"}); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if err := g.LoadMoves([]string{"d2d4"}); err == nil {
+		t.Fatalf("expected a game with moves to be refused")
+	}
+	if got := g.MovesUCI(); !slices.Equal(got, []string{"e2e4", "e7e5"}) {
+		t.Fatalf("got %v", got)
+	}
+}
@@ -0,0 +1,97 @@
+package game
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+)
+
+// InviteRole is what an invite link admits its holder as.
+type InviteRole string
+
+const (
+	InviteWhite    InviteRole = "white"
+	InviteBlack    InviteRole = "black"
+	InviteSpectate InviteRole = "spectate"
+)
+
+// InviteRoleFor returns the play invite role for color c.
+func InviteRoleFor(c chess.Color) InviteRole {
+	if c == chess.Black {
+		return InviteBlack
+	}
+	return InviteWhite
+}
+
+// Invite returns the invite token admitting its holder to gameID as role.
+// Invites are links rather than credentials of a client, so anyone holding
+// one may use it until the seat it names is taken.
+func (s *SeatSigner) Invite(gameID GameID, role InviteRole) string {
+	return string(role) + "." + base64.RawURLEncoding.EncodeToString(s.sum(inviteTag, gameID, "", string(role)))
+}
+
+// ParseInvite checks an invite token for gameID and returns its role.
+func (s *SeatSigner) ParseInvite(gameID GameID, token string) (InviteRole, bool) {
+	role, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", false
+	}
+	switch InviteRole(role) {
+	case InviteWhite, InviteBlack, InviteSpectate:
+	default:
+		return "", false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, s.sum(inviteTag, gameID, "", role)) {
+		return "", false
+	}
+	return InviteRole(role), true
+}
+
+// OpenColor returns the color of the seat still open, or chess.NoColor when
+// both seats are taken or the game is hot-seat.
+func (g *Game) OpenColor() chess.Color {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	if len(g.Clients) >= 2 || g.hotSeat {
+		return chess.NoColor
+	}
+	if g.OwnerID == "" {
+		if g.OwnerColor == chess.NoColor {
+			return chess.White
+		}
+		return g.OwnerColor
+	}
+	return g.OwnerColor.Other()
+}
+
+// InviteOnly reports whether the open seat is reserved for play invites.
+func (g *Game) InviteOnly() bool {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return g.inviteOnly
+}
+
+// InviteAdmits reports whether clientID may take or keep a seat holding an
+// invite for role, or none when role is empty. Seated clients always keep
+// theirs; a play invite only seats its holder in the color it names, and a
+// spectator invite never seats anyone. Without an invite, only games that
+// are not invite-only hand out seats.
+func (g *Game) InviteAdmits(clientID string, role InviteRole) bool {
+	g.Mu.RLock()
+	_, seated := g.Clients[clientID]
+	inviteOnly := g.inviteOnly
+	g.Mu.RUnlock()
+	switch {
+	case clientID != "" && seated:
+		return true
+	case role == "":
+		return !inviteOnly
+	case role == InviteSpectate:
+		return false
+	}
+	open := g.OpenColor()
+	return open != chess.NoColor && role == InviteRoleFor(open)
+}
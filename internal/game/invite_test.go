@@ -0,0 +1,85 @@
+package game
+
+import (
+	"context"
+	"testing"
+
+	"github.com/corentings/chess/v2"
+)
+
+func TestInviteOnlySeating(t *testing.T) {
+	h := NewHub(nil)
+	owner := "00000000-0000-0000-0000-000000000001"
+	id, color, err := h.CreateGame(context.Background(), owner, GameOptions{InviteOnly: true})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	g, _ := h.Lookup(id)
+	open := InviteRoleFor(color.Other())
+	taken := InviteRoleFor(color)
+
+	role, ok := h.Seats.ParseInvite(id, h.Seats.Invite(id, open))
+	if !ok || role != open {
+		t.Fatalf("expected invite to round-trip, got %q %v", role, ok)
+	}
+	if _, ok := h.Seats.ParseInvite(NewGameID(), h.Seats.Invite(id, open)); ok {
+		t.Fatalf("invite must be bound to its game")
+	}
+	if _, ok := h.Seats.ParseInvite(id, string(taken)+h.Seats.Invite(id, open)[len(open):]); ok {
+		t.Fatalf("role must be covered by the signature")
+	}
+
+	if !g.InviteAdmits(owner, "") {
+		t.Fatalf("owner keeps the seat without an invite")
+	}
+	if g.InviteAdmits("stranger", "") || g.InviteAdmits("stranger", InviteSpectate) || g.InviteAdmits("stranger", taken) {
+		t.Fatalf("expected the open seat to need the play invite")
+	}
+	if !g.InviteAdmits("guest", open) {
+		t.Fatalf("expected the play invite to seat its holder")
+	}
+	if _, _, err := h.Get(context.Background(), id, "guest"); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if g.InviteAdmits("late", open) {
+		t.Fatalf("expected a used play invite to stop seating once the seat is taken")
+	}
+}
+
+func TestHotSeat(t *testing.T) {
+	ctx := context.Background()
+	store := newTestHubStore(t)
+	h := NewHub(store)
+	owner := "00000000-0000-0000-0000-000000000001"
+	id, color, err := h.CreateGame(ctx, owner, GameOptions{HotSeat: true})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	g, _ := h.Lookup(id)
+	if g.OpenColor() != chess.NoColor {
+		t.Fatal("expected a hot-seat game to have no open seat")
+	}
+	if _, assigned, err := h.Get(ctx, id, "00000000-0000-0000-0000-000000000002"); err != nil || assigned != nil {
+		t.Fatalf("expected a visitor to spectate, got %v %v", assigned, err)
+	}
+	for _, turn := range []chess.Color{chess.White, chess.Black} {
+		if got, ok := g.MoverColor(owner, turn); !ok || got != turn {
+			t.Fatalf("MoverColor(owner, %v) = %v, %v", turn, got, ok)
+		}
+	}
+	if got, _ := g.MoverColor("stranger", chess.Black); got != chess.NoColor {
+		t.Fatalf("expected no color for a stranger, got %v", got)
+	}
+
+	// The flag survives a restart, and the owner gets their seat back.
+	reloaded, _, err := NewHub(store).Get(ctx, id, owner)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !reloaded.HotSeat() {
+		t.Fatal("expected the reloaded game to be hot-seat")
+	}
+	if got, _ := reloaded.MoverColor(owner, color.Other()); got != color.Other() {
+		t.Fatalf("expected the owner to move the other color after a restart, got %v", got)
+	}
+}
@@ -0,0 +1,72 @@
+package game
+
+import "github.com/corentings/chess/v2"
+
+// LastMove describes the most recent move so clients can highlight it and
+// track captures without diffing positions.
+type LastMove struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	UCI       string `json:"uci"`
+	SAN       string `json:"san"`
+	IsCapture bool   `json:"isCapture"`
+	IsCheck   bool   `json:"isCheck"`
+	IsCastle  bool   `json:"isCastle"`
+	// CapturedPiece is the captured piece type ("p", "n", "b", "r", "q").
+	CapturedPiece string `json:"capturedPiece,omitempty"`
+	// Promotion is the piece type a pawn promoted to.
+	Promotion string `json:"promotion,omitempty"`
+}
+
+// Captured lists the piece types ("p", "n", "b", "r", "q") each side has
+// captured, in capture order.
+type Captured struct {
+	ByWhite []string `json:"byWhite"`
+	ByBlack []string `json:"byBlack"`
+}
+
+// add records a capture made by the given side.
+func (c *Captured) add(by chess.Color, piece string) {
+	if by == chess.White {
+		c.ByWhite = append(c.ByWhite, piece)
+	} else {
+		c.ByBlack = append(c.ByBlack, piece)
+	}
+}
+
+// ParseCaptured restores captured lists from their stored form, one letter
+// per piece.
+func ParseCaptured(byWhite, byBlack string) Captured {
+	c := Captured{ByWhite: []string{}, ByBlack: []string{}}
+	for _, r := range byWhite {
+		c.ByWhite = append(c.ByWhite, string(r))
+	}
+	for _, r := range byBlack {
+		c.ByBlack = append(c.ByBlack, string(r))
+	}
+	return c
+}
+
+// describeMove builds the LastMove for m played from pos.
+func describeMove(pos *chess.Position, m *chess.Move) *LastMove {
+	lm := &LastMove{
+		From:     m.S1().String(),
+		To:       m.S2().String(),
+		UCI:      chess.UCINotation{}.Encode(pos, m),
+		SAN:      chess.AlgebraicNotation{}.Encode(pos, m),
+		IsCheck:  m.HasTag(chess.Check),
+		IsCastle: m.HasTag(chess.KingSideCastle) || m.HasTag(chess.QueenSideCastle),
+	}
+	switch {
+	case m.HasTag(chess.EnPassant):
+		lm.IsCapture = true
+		lm.CapturedPiece = chess.Pawn.String()
+	case m.HasTag(chess.Capture):
+		lm.IsCapture = true
+		lm.CapturedPiece = pos.Board().Piece(m.S2()).Type().String()
+	}
+	if m.Promo() != chess.NoPieceType {
+		lm.Promotion = m.Promo().String()
+	}
+	return lm
+}
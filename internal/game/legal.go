@@ -0,0 +1,41 @@
+package game
+
+import (
+	"github.com/corentings/chess/v2"
+
+	"tinychess/pkg/chesscore"
+)
+
+// ErrBadSquare is returned for square names other than "a1" to "h8".
+var ErrBadSquare = chesscore.ErrBadSquare
+
+// Target is a square the piece on a given square may legally move to.
+type Target = chesscore.Target
+
+// ParseSquare parses a square name such as "e2".
+func ParseSquare(s string) (chess.Square, error) {
+	return chesscore.ParseSquare(s)
+}
+
+// LegalTargets lists where the piece on from may move in the current
+// position, sorted by square name. It is empty when the square is empty, holds a
+// piece of the side not to move, or the game is over. The moves are listed on
+// a copy of the position, see chesscore.Copy, so readers never share its move
+// cache.
+func (g *Game) LegalTargets(from chess.Square) []Target {
+	g.Mu.RLock()
+	over := g.overLocked()
+	pos, err := chesscore.Copy(g.g.Position())
+	g.Mu.RUnlock()
+	if over || err != nil {
+		return []Target{}
+	}
+	return chesscore.LegalTargets(pos, from)
+}
+
+// PieceAt returns the piece on sq in the current position, or chess.NoPiece.
+func (g *Game) PieceAt(sq chess.Square) chess.Piece {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return g.g.Position().Board().Piece(sq)
+}
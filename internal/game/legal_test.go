@@ -0,0 +1,75 @@
+package game
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestLegalTargets(t *testing.T) {
+	h := NewHub(nil)
+	id, _, err := h.CreateGame(context.Background(), uuid.NewString(), GameOptions{
+		StartFEN: "3r1n1k/4P3/8/3pP3/8/8/8/4K3 w - d6 0 1",
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	g, _ := h.Lookup(id)
+
+	targets := func(sq string) []Target {
+		t.Helper()
+		from, err := ParseSquare(sq)
+		if err != nil {
+			t.Fatalf("parse %s: %v", sq, err)
+		}
+		return g.LegalTargets(from)
+	}
+
+	want := []Target{
+		{To: "d8", Capture: true, Promotion: true},
+		{To: "e8", Promotion: true},
+		{To: "f8", Capture: true, Promotion: true},
+	}
+	if got := targets("e7"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("e7: got %+v, want %+v", got, want)
+	}
+	want = []Target{{To: "d6", Capture: true}, {To: "e6"}}
+	if got := targets("e5"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("e5 with en passant: got %+v, want %+v", got, want)
+	}
+	if got := targets("d5"); len(got) != 0 {
+		t.Fatalf("expected no targets for the side not to move, got %+v", got)
+	}
+	if got := targets("a4"); len(got) != 0 {
+		t.Fatalf("expected no targets from an empty square, got %+v", got)
+	}
+
+	for _, bad := range []string{"", "i1", "a9", "e22"} {
+		if _, err := ParseSquare(bad); err != ErrBadSquare {
+			t.Errorf("ParseSquare(%q) = %v, want ErrBadSquare", bad, err)
+		}
+	}
+}
+
+func TestLegalTargetsConcurrent(t *testing.T) {
+	g := newTestGame()
+	if err := g.MakeMove("e2e4"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	from, _ := ParseSquare("g8")
+	want := []Target{{To: "f6"}, {To: "h6"}}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := g.LegalTargets(from); !reflect.DeepEqual(got, want) {
+				t.Errorf("g8: got %+v, want %+v", got, want)
+			}
+		}()
+	}
+	wg.Wait()
+}
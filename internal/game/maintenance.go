@@ -0,0 +1,100 @@
+package game
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrMaintenance refuses moves and new games while the instance is in
+// maintenance mode.
+var ErrMaintenance = errors.New("the instance is in maintenance mode")
+
+// Maintenance is the instance's maintenance mode, sent to watchers as a
+// "maintenance" event whenever it changes. While it is active games are
+// read-only: moves are refused, no games are created and clocks stand still.
+type Maintenance struct {
+	Schema  int    `json:"schema"`
+	Kind    string `json:"kind"`
+	Active  bool   `json:"active"`
+	Message string `json:"message,omitempty"`
+	// Since is when maintenance began, in Unix milliseconds.
+	Since int64 `json:"since,omitempty"`
+}
+
+// Maintenance returns the instance's maintenance mode.
+func (h *Hub) Maintenance() Maintenance {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.maintenanceLocked()
+}
+
+func (h *Hub) maintenanceLocked() Maintenance {
+	m := Maintenance{Schema: SchemaVersion, Kind: "maintenance", Active: h.maintenance.Active}
+	if m.Active {
+		m.Message = h.maintenance.Message
+		m.Since = h.maintenance.Since
+	}
+	return m
+}
+
+// SetMaintenance turns maintenance mode on, or updates its message, or turns
+// it off, and tells the watchers of every loaded game. Clocks are paused for
+// as long as it lasts; the pauses are kept in memory only.
+func (h *Hub) SetMaintenance(active bool, message string) Maintenance {
+	h.toggling.Lock()
+	defer h.toggling.Unlock()
+	now := time.Now()
+	h.mu.Lock()
+	switch {
+	case active && !h.maintenance.Active:
+		h.maintenance = Maintenance{Active: true, Since: now.UnixMilli()}
+	case !active:
+		h.maintenance = Maintenance{}
+	}
+	h.maintenance.Message = message
+	m := h.maintenanceLocked()
+	h.mu.Unlock()
+	// Games loaded from here on see the new mode in admit.
+	for _, g := range h.loaded() {
+		g.Mu.Lock()
+		if active {
+			g.freezeLocked(now)
+		} else {
+			g.thawLocked(now)
+		}
+		g.sendLocked(m)
+		g.sendLocked(g.StateLocked())
+		g.Mu.Unlock()
+	}
+	return m
+}
+
+// admit freezes a game loaded into the hub during maintenance.
+func (h *Hub) admit(g *Game) {
+	m := h.Maintenance()
+	if m.Active {
+		g.Mu.Lock()
+		g.freezeLocked(time.UnixMilli(m.Since))
+		g.Mu.Unlock()
+	}
+}
+
+// freezeLocked stops the game's clock at now.
+func (g *Game) freezeLocked(now time.Time) {
+	if g.frozenAt.IsZero() && !g.overLocked() {
+		g.frozenAt = now
+	}
+}
+
+// thawLocked restarts the game's clock, leaving the time it stood still out
+// of the current move's think time.
+func (g *Game) thawLocked(now time.Time) {
+	if g.frozenAt.IsZero() {
+		return
+	}
+	if g.paused == nil {
+		g.paused = make(map[int]time.Duration)
+	}
+	g.paused[len(g.g.Moves())] += now.Sub(g.frozenAt)
+	g.frozenAt = time.Time{}
+}
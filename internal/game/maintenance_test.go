@@ -0,0 +1,78 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMaintenance(t *testing.T) {
+	ctx := context.Background()
+	h := NewHub(nil)
+	owner := "00000000-0000-0000-0000-000000000001"
+	id, _, err := h.CreateGame(ctx, owner, GameOptions{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	g, _ := h.Lookup(id)
+	ch := make(chan []byte, 4)
+	g.AddWatcher(ch)
+
+	m := h.SetMaintenance(true, "Back in five")
+	if !m.Active || m.Message != "Back in five" || m.Since == 0 {
+		t.Fatalf("unexpected maintenance %+v", m)
+	}
+	var event Maintenance
+	if err := json.Unmarshal(<-ch, &event); err != nil || event.Kind != "maintenance" || !event.Active {
+		t.Fatalf("expected a maintenance event, got %+v %v", event, err)
+	}
+	if err := g.MakeMove("e2e4"); !errors.Is(err, ErrMaintenance) {
+		t.Fatalf("expected moves to be refused, got %v", err)
+	}
+	if _, _, err := h.CreateGame(ctx, owner, GameOptions{}); !errors.Is(err, ErrMaintenance) {
+		t.Fatalf("expected new games to be refused, got %v", err)
+	}
+	g.Mu.RLock()
+	pace := g.StateLocked().Pace
+	g.Mu.RUnlock()
+	if !pace.Paused || pace.ThinkingSince != 0 {
+		t.Fatalf("expected a paused clock, got %+v", pace)
+	}
+
+	if m := h.SetMaintenance(false, ""); m.Active {
+		t.Fatalf("expected maintenance to end, got %+v", m)
+	}
+	if err := g.MakeMove("e2e4"); err != nil {
+		t.Fatalf("move after maintenance: %v", err)
+	}
+}
+
+func TestMaintenancePausesClock(t *testing.T) {
+	g := newTestGame()
+	g.CreatedAt = time.Now().Add(-time.Minute)
+	if err := g.MakeMove("e2e4"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	g.playedAt[0] = g.CreatedAt.Add(2 * time.Second)
+
+	// Black thinks for 3s, maintenance stops the clock for 20s, then black
+	// thinks for 4s more.
+	g.Mu.Lock()
+	g.freezeLocked(g.CreatedAt.Add(5 * time.Second))
+	g.thawLocked(g.CreatedAt.Add(25 * time.Second))
+	pace := g.paceLocked(g.moveTimesLocked(g.MovesUCI()), g.CreatedAt.Add(27*time.Second))
+	g.Mu.Unlock()
+	if pace.ThinkingMs != 5000 || pace.ThinkingSince != g.CreatedAt.Add(22*time.Second).UnixMilli() {
+		t.Fatalf("expected the pause left out of the thinking time, got %+v", pace)
+	}
+
+	if err := g.MakeMove("e7e5"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	g.playedAt[1] = g.CreatedAt.Add(29 * time.Second)
+	if times := g.MoveTimes(); times[1].ThinkMs != 7000 {
+		t.Fatalf("expected 7s of think time, got %+v", times[1])
+	}
+}
@@ -0,0 +1,155 @@
+package game
+
+import (
+	"github.com/corentings/chess/v2"
+
+	"tinychess/pkg/chesscore"
+)
+
+// Mobility gives commentary tools simple indicators for each side that need
+// no engine: how many moves it has and how exposed its king is.
+type Mobility struct {
+	White SideMobility `json:"white"`
+	Black SideMobility `json:"black"`
+}
+
+// SideMobility is one side's part of Mobility.
+type SideMobility struct {
+	// Moves is the number of legal moves the side has, or would have if it
+	// were its turn.
+	Moves int `json:"moves"`
+	// Check is set while the side's king is in check.
+	Check bool `json:"check,omitempty"`
+	// KingAttacks counts the king's square and its neighbours that the other
+	// side attacks.
+	KingAttacks int `json:"kingAttacks"`
+	// PawnShield counts the side's pawns one or two ranks in front of the
+	// king, on its file or the files either side.
+	PawnShield int `json:"pawnShield"`
+}
+
+// mobilityLocked computes the mobility indicators for the current position,
+// or nil once the game is over. Moves are counted on a copy of the position,
+// since listing them fills the position's move cache and callers may only
+// hold a read lock.
+func (g *Game) mobilityLocked() *Mobility {
+	if g.overLocked() {
+		return nil
+	}
+	pos, err := chesscore.Copy(g.g.Position())
+	if err != nil {
+		return nil
+	}
+	board := pos.Board()
+	return &Mobility{
+		White: sideMobility(pos, board, chess.White),
+		Black: sideMobility(pos, board, chess.Black),
+	}
+}
+
+func sideMobility(pos *chess.Position, board *chess.Board, c chess.Color) SideMobility {
+	var m SideMobility
+	if pos.Turn() == c {
+		m.Moves = len(pos.ValidMoves())
+	} else if other, err := chesscore.WithTurn(pos, c); err == nil {
+		for _, mv := range other.ValidMoves() {
+			// The side to move may be in check; taking its king is no move.
+			if board.Piece(mv.S2()).Type() != chess.King {
+				m.Moves++
+			}
+		}
+	}
+
+	king := chess.NoSquare
+	for sq, p := range board.SquareMap() {
+		if p.Type() == chess.King && p.Color() == c {
+			king = sq
+		}
+	}
+	if king == chess.NoSquare {
+		return m
+	}
+	attacked := attackedSquares(board, c.Other())
+	m.Check = attacked[king]
+	ahead := 1
+	if c == chess.Black {
+		ahead = -1
+	}
+	for df := -1; df <= 1; df++ {
+		for dr := -1; dr <= 1; dr++ {
+			if sq, ok := offset(king, df, dr); ok && attacked[sq] {
+				m.KingAttacks++
+			}
+		}
+		for _, dr := range []int{ahead, 2 * ahead} {
+			if sq, ok := offset(king, df, dr); ok && board.Piece(sq) == chess.NewPiece(chess.Pawn, c) {
+				m.PawnShield++
+			}
+		}
+	}
+	return m
+}
+
+// Steps are {files, ranks} offsets; bishops, rooks and queens repeat theirs
+// until they reach a piece.
+var (
+	knightSteps = [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingSteps   = [][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	bishopRays  = [][2]int{{1, 1}, {-1, 1}, {-1, -1}, {1, -1}}
+	rookRays    = [][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+)
+
+// attackedSquares returns the squares the pieces of color c attack,
+// whether or not the attack could legally be carried out.
+func attackedSquares(board *chess.Board, c chess.Color) map[chess.Square]bool {
+	attacked := make(map[chess.Square]bool)
+	steps := func(from chess.Square, deltas [][2]int, slide bool) {
+		for _, d := range deltas {
+			for sq, ok := offset(from, d[0], d[1]); ok; sq, ok = offset(sq, d[0], d[1]) {
+				attacked[sq] = true
+				if !slide || board.Piece(sq) != chess.NoPiece {
+					break
+				}
+			}
+		}
+	}
+	for from, p := range board.SquareMap() {
+		if p.Color() != c {
+			continue
+		}
+		switch p.Type() {
+		case chess.Pawn:
+			ahead := 1
+			if c == chess.Black {
+				ahead = -1
+			}
+			for _, df := range []int{-1, 1} {
+				if sq, ok := offset(from, df, ahead); ok {
+					attacked[sq] = true
+				}
+			}
+		case chess.Knight:
+			steps(from, knightSteps, false)
+		case chess.King:
+			steps(from, kingSteps, false)
+		case chess.Bishop:
+			steps(from, bishopRays, true)
+		case chess.Rook:
+			steps(from, rookRays, true)
+		case chess.Queen:
+			steps(from, bishopRays, true)
+			steps(from, rookRays, true)
+		}
+	}
+	return attacked
+}
+
+// offset returns the square df files and dr ranks away from sq, if it is on
+// the board.
+func offset(sq chess.Square, df, dr int) (chess.Square, bool) {
+	f, r := int(sq.File())+df, int(sq.Rank())+dr
+	if f < 0 || f > 7 || r < 0 || r > 7 {
+		return chess.NoSquare, false
+	}
+	return chess.NewSquare(chess.File(f), chess.Rank(r)), true
+}
@@ -0,0 +1,68 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MoveIDHistory is how many recent move ids a game remembers.
+const MoveIDHistory = 32
+
+// MaxMoveIDLength bounds client-chosen move ids.
+const MaxMoveIDLength = 64
+
+// ErrDuplicateMove is returned by MakeMoveOnce for a move id that was
+// already played.
+var ErrDuplicateMove = errors.New("duplicate move")
+
+func moveIDKey(clientID, moveID string) string {
+	return clientID + " " + moveID
+}
+
+// MovePlayed reports whether clientID already made a move with moveID.
+func (g *Game) MovePlayed(clientID, moveID string) bool {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return g.movePlayedLocked(moveIDKey(clientID, moveID))
+}
+
+func (g *Game) movePlayedLocked(key string) bool {
+	for _, k := range g.moveIDs {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// MakeMoveOnce is MakeMove for moves carrying a client-chosen id: a move
+// whose id clientID has already played is refused with ErrDuplicateMove, so
+// a retried request does not play twice. An empty id is not checked.
+func (g *Game) MakeMoveOnce(uci, clientID, moveID string) error {
+	if moveID == "" {
+		return g.MakeMove(uci)
+	}
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	key := moveIDKey(clientID, moveID)
+	if g.movePlayedLocked(key) {
+		return ErrDuplicateMove
+	}
+	if g.overLocked() {
+		return fmt.Errorf("game over")
+	}
+	if g.moveLimitReachedLocked() {
+		return ErrMoveLimit
+	}
+	if !g.frozenAt.IsZero() {
+		return ErrMaintenance
+	}
+	if err := g.applyMoveLocked(uci); err != nil {
+		return err
+	}
+	g.moveIDs = append(g.moveIDs, key)
+	if len(g.moveIDs) > MoveIDHistory {
+		g.moveIDs = g.moveIDs[len(g.moveIDs)-MoveIDHistory:]
+	}
+	return nil
+}
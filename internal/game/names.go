@@ -0,0 +1,76 @@
+package game
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/corentings/chess/v2"
+)
+
+// MaxNameLength bounds a display name, in characters.
+const MaxNameLength = 32
+
+// ErrNameTooLong is returned for display names over MaxNameLength.
+var ErrNameTooLong = errors.New("name too long")
+
+// CleanName normalizes a display name: control characters are dropped and
+// runs of whitespace collapse to one space. An empty result clears the name.
+func CleanName(name string) (string, error) {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, name)
+	name = strings.Join(strings.Fields(name), " ")
+	if utf8.RuneCountInString(name) > MaxNameLength {
+		return "", ErrNameTooLong
+	}
+	return name, nil
+}
+
+// SetName sets the display name of a seated client and reports whether it
+// is seated. Names of spectators are not kept.
+func (g *Game) SetName(clientID, name string) bool {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	if _, ok := g.Clients[clientID]; !ok {
+		return false
+	}
+	if g.names == nil {
+		g.names = make(map[string]string)
+	}
+	if name == "" {
+		delete(g.names, clientID)
+	} else {
+		g.names[clientID] = name
+	}
+	return true
+}
+
+// playerNamesLocked returns the display names of the seated players.
+func (g *Game) playerNamesLocked() (white, black string) {
+	for id, col := range g.Clients {
+		switch col {
+		case chess.White:
+			white = g.names[id]
+		case chess.Black:
+			black = g.names[id]
+		}
+	}
+	return white, black
+}
+
+// withNames fills the player tags of extra from the display names, unless the
+// caller set them.
+func withNames(extra PGNHeaders, white, black string) PGNHeaders {
+	if extra.White == "" {
+		extra.White = white
+	}
+	if extra.Black == "" {
+		extra.Black = black
+	}
+	return extra
+}
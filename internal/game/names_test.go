@@ -0,0 +1,46 @@
+package game
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/corentings/chess/v2"
+)
+
+func TestCleanName(t *testing.T) {
+	if name, err := CleanName("  Magnus \t\n Carlsen\x00 "); err != nil || name != "Magnus Carlsen" {
+		t.Fatalf("got %q, %v", name, err)
+	}
+	if _, err := CleanName(strings.Repeat("é", MaxNameLength+1)); err != ErrNameTooLong {
+		t.Fatalf("expected ErrNameTooLong, got %v", err)
+	}
+}
+
+func TestPlayerNames(t *testing.T) {
+	g := newGameInstance(NewGameID())
+	g.Clients["w1"] = chess.White
+	g.Clients["b1"] = chess.Black
+	if g.SetName("spectator", "Nobody") {
+		t.Fatalf("spectators must not get a name")
+	}
+	g.SetName("w1", `Ann "the rook"`)
+	g.SetName("b1", "Bob")
+
+	g.Mu.RLock()
+	state := g.StateLocked()
+	g.Mu.RUnlock()
+	if state.White != `Ann "the rook"` || state.Black != "Bob" {
+		t.Fatalf("unexpected names %q %q", state.White, state.Black)
+	}
+	if !strings.Contains(state.PGN, `[White "Ann \"the rook\""]`) || !strings.Contains(state.PGN, `[Black "Bob"]`) {
+		t.Fatalf("expected escaped player tags, got %s", state.PGN)
+	}
+	if pgn := g.Snapshot().PGN(PGNHeaders{Black: "Override"}); !strings.Contains(pgn, `[Black "Override"]`) {
+		t.Fatalf("explicit headers must win, got %s", pgn)
+	}
+
+	g.SetName("b1", "")
+	if !strings.Contains(g.Snapshot().PGN(PGNHeaders{}), `[White "Ann`) || strings.Contains(g.Snapshot().PGN(PGNHeaders{}), "Bob") {
+		t.Fatalf("expected the snapshot to follow name changes")
+	}
+}
@@ -0,0 +1,163 @@
+package game
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+
+	"tinychess/pkg/chesscore"
+)
+
+// Notation selects how move lists are written for a reader.
+type Notation string
+
+const (
+	// NotationSAN is standard algebraic notation, e.g. "Nf3".
+	NotationSAN Notation = "san"
+	// NotationLAN is long algebraic notation with both squares, e.g. "Ng1f3".
+	NotationLAN Notation = "lan"
+	// NotationFigurine is SAN with piece glyphs, e.g. "♘f3".
+	NotationFigurine Notation = "figurine"
+	// NotationLocal is SAN with the piece letters of the game's language,
+	// see Notation.In; it falls back to SAN where that is unknown.
+	NotationLocal Notation = "local"
+)
+
+// pieceLetters translates SAN piece letters for localized notations, named
+// "san-" and the language, e.g. "san-de" writes "Sf3" for "Nf3".
+var pieceLetters = map[string]*strings.Replacer{
+	"de": strings.NewReplacer("K", "K", "Q", "D", "R", "T", "B", "L", "N", "S"),
+	"es": strings.NewReplacer("K", "R", "Q", "D", "R", "T", "B", "A", "N", "C"),
+	"fr": strings.NewReplacer("K", "R", "Q", "D", "R", "T", "B", "F", "N", "C"),
+	"it": strings.NewReplacer("K", "R", "Q", "D", "R", "T", "B", "A", "N", "C"),
+	"nl": strings.NewReplacer("K", "K", "Q", "D", "R", "T", "B", "L", "N", "P"),
+}
+
+// LocalizedSAN returns the SAN notation with language's piece letters, and
+// false when there are none for it.
+func LocalizedSAN(language string) (Notation, bool) {
+	if _, ok := pieceLetters[language]; !ok {
+		return "", false
+	}
+	return Notation("san-" + language), true
+}
+
+func (n Notation) pieceLetters() *strings.Replacer {
+	lang, ok := strings.CutPrefix(string(n), "san-")
+	if !ok {
+		return nil
+	}
+	return pieceLetters[lang]
+}
+
+// ParseNotation validates a notation name. Empty input selects SAN.
+func ParseNotation(s string) (Notation, bool) {
+	switch n := Notation(strings.ToLower(strings.TrimSpace(s))); n {
+	case "":
+		return NotationSAN, true
+	case NotationSAN, NotationLAN, NotationFigurine, NotationLocal:
+		return n, true
+	default:
+		if lang, ok := strings.CutPrefix(string(n), "san-"); ok {
+			return LocalizedSAN(lang)
+		}
+		return "", false
+	}
+}
+
+// In resolves NotationLocal for a game in language, empty for English. Other
+// notations are returned unchanged.
+func (n Notation) In(language string) Notation {
+	if n != NotationLocal {
+		return n
+	}
+	if local, ok := LocalizedSAN(language); ok {
+		return local
+	}
+	return NotationSAN
+}
+
+// DecodeSAN converts a move in standard algebraic notation, such as "Nf3",
+// "O-O" or "exd8=Q+", to UCI in the game's current position. Castling may
+// also be written with zeros. Decoding lists the legal moves, so it works
+// on a copy of the position, as LegalTargets does.
+func (g *Game) DecodeSAN(san string) (string, error) {
+	g.Mu.RLock()
+	pos, err := chesscore.Copy(g.g.Position())
+	g.Mu.RUnlock()
+	if err != nil {
+		return "", err
+	}
+	return chesscore.DecodeSAN(pos, san)
+}
+
+var figurines = strings.NewReplacer("K", "♔", "Q", "♕", "R", "♖", "B", "♗", "N", "♘")
+
+// FormatMoves replays UCI moves from the starting position, or from start
+// when it is a FEN, and writes each in the given notation. Replay stops at
+// the first move that does not apply.
+func FormatMoves(start string, uci []string, n Notation) []string {
+	out := make([]string, 0, len(uci))
+	g := newChessGame(start)
+	dec := chess.UCINotation{}
+	for _, s := range uci {
+		pos := g.Position()
+		m, err := dec.Decode(pos, s)
+		if err != nil {
+			break
+		}
+		san := chess.AlgebraicNotation{}.Encode(pos, m)
+		switch n {
+		case NotationLAN:
+			out = append(out, chess.LongAlgebraicNotation{}.Encode(pos, m))
+		case NotationFigurine:
+			out = append(out, figurines.Replace(san))
+		default:
+			if letters := n.pieceLetters(); letters != nil {
+				san = letters.Replace(san)
+			}
+			out = append(out, san)
+		}
+		if err := g.Move(m, nil); err != nil {
+			break
+		}
+	}
+	return out
+}
+
+// ApplyNotation rewrites the moves list of an encoded state payload in the
+// given notation. Other payloads, and SAN, are returned unchanged.
+func ApplyNotation(data []byte, n Notation) []byte {
+	if n == NotationSAN || n == "" {
+		return data
+	}
+	var p map[string]any
+	if err := json.Unmarshal(data, &p); err != nil || p["kind"] != "state" {
+		return data
+	}
+	raw, _ := p["uci"].([]any)
+	uci := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if s, ok := v.(string); ok {
+			uci = append(uci, s)
+		}
+	}
+	start, _ := p["startFen"].(string)
+	p["moves"] = FormatMoves(start, uci, n)
+	out, err := json.Marshal(p)
+	if err != nil {
+		return data
+	}
+	return out
+}
+
+// PGNWithNotation rewrites the movetext of a PGN export in the given notation,
+// keeping its tag pairs and result. Moves are replayed from start, a FEN or
+// empty for the standard position. SAN exports are returned unchanged.
+func PGNWithNotation(pgn, start string, uci []string, n Notation) string {
+	if n == NotationSAN || n == "" {
+		return pgn
+	}
+	return rewriteMovetext(pgn, FormatMoves(start, uci, n), nil)
+}
@@ -0,0 +1,135 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFormatMoves(t *testing.T) {
+	uci := []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1c4", "g8f6", "e1g1"}
+	cases := map[Notation][]string{
+		NotationSAN:      {"e4", "e5", "Nf3", "Nc6", "Bc4", "Nf6", "O-O"},
+		NotationFigurine: {"e4", "e5", "♘f3", "♘c6", "♗c4", "♘f6", "O-O"},
+	}
+	for n, want := range cases {
+		if got := FormatMoves("", uci, n); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", n, got, want)
+		}
+	}
+	lan := FormatMoves("", uci, NotationLAN)
+	if len(lan) != len(uci) || !strings.Contains(lan[2], "g1") || !strings.Contains(lan[2], "f3") {
+		t.Fatalf("expected long algebraic moves, got %v", lan)
+	}
+}
+
+func TestApplyNotation(t *testing.T) {
+	data := []byte(`{"kind":"state","uci":["g1f3"],"moves":["Nf3"]}`)
+	var st struct {
+		Moves []string `json:"moves"`
+	}
+	if err := json.Unmarshal(ApplyNotation(data, NotationFigurine), &st); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(st.Moves) != 1 || st.Moves[0] != "♘f3" {
+		t.Fatalf("expected figurine move, got %v", st.Moves)
+	}
+	if string(ApplyNotation(data, NotationSAN)) != string(data) {
+		t.Fatalf("SAN payload should be unchanged")
+	}
+}
+
+func TestPGNWithNotation(t *testing.T) {
+	pgn := "[Event \"Tiny Chess\"]\n[Result \"*\"]\n\n1. Nf3 *"
+	got := PGNWithNotation(pgn, "", []string{"g1f3"}, NotationFigurine)
+	if got != "[Event \"Tiny Chess\"]\n[Result \"*\"]\n\n1. ♘f3 *" {
+		t.Fatalf("unexpected PGN %q", got)
+	}
+}
+
+func TestParseNotation(t *testing.T) {
+	if n, ok := ParseNotation(""); !ok || n != NotationSAN {
+		t.Fatalf("empty notation should default to SAN")
+	}
+	if _, ok := ParseNotation("descriptive"); ok {
+		t.Fatalf("expected unknown notation to be rejected")
+	}
+	if n, ok := ParseNotation("SAN-DE"); !ok || n != "san-de" {
+		t.Fatalf("expected German SAN, got %q", n)
+	}
+	if _, ok := ParseNotation("san-xx"); ok {
+		t.Fatalf("expected a language without piece letters to be rejected")
+	}
+}
+
+func TestLocalizedSAN(t *testing.T) {
+	uci := []string{"g1f3", "d7d5", "b1c3", "c8g4", "e2e4", "d5d4", "f1e2", "d4c3", "e1g1", "c3b2", "d1e1", "b2a1q"}
+	de, _ := LocalizedSAN("de")
+	got := FormatMoves("", uci, de)
+	want := []string{"Sf3", "d5", "Sc3", "Lg4", "e4", "d4", "Le2", "dxc3", "O-O", "cxb2", "De1", "bxa1=D"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("German SAN: got %v, want %v", got, want)
+	}
+	if fr := FormatMoves("", uci[:4], NotationLocal.In("fr")); !reflect.DeepEqual(fr, []string{"Cf3", "d5", "Cc3", "Fg4"}) {
+		t.Fatalf("French SAN: got %v", fr)
+	}
+	if n := NotationLocal.In(""); n != NotationSAN {
+		t.Fatalf("expected English games to keep SAN, got %q", n)
+	}
+	if n := NotationFigurine.In("de"); n != NotationFigurine {
+		t.Fatalf("expected an explicit notation to be kept, got %q", n)
+	}
+}
+
+func TestDecodeSAN(t *testing.T) {
+	h := NewHub(nil)
+	id, _, err := h.CreateGame(context.Background(), uuid.NewString(), GameOptions{
+		StartFEN: "3r3k/4P3/8/8/8/8/8/R3K2R w KQ - 0 1",
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	g, _ := h.Lookup(id)
+
+	cases := map[string]string{
+		"O-O":     "e1g1",
+		"0-0":     "e1g1",
+		"exd8=Q+": "e7d8q",
+		"e8=N":    "e7e8n",
+		"Ra2":     "a1a2",
+	}
+	for san, want := range cases {
+		if got, err := g.DecodeSAN(san); err != nil || got != want {
+			t.Errorf("DecodeSAN(%q) = %q, %v; want %q", san, got, err, want)
+		}
+	}
+	// The rook on d8 bars castling queenside.
+	for _, san := range []string{"O-O-O", "Nf3", "e4e5", ""} {
+		if got, err := g.DecodeSAN(san); err == nil {
+			t.Errorf("DecodeSAN(%q) = %q, want an error", san, got)
+		}
+	}
+}
+
+func TestDecodeSANConcurrent(t *testing.T) {
+	g := newTestGame()
+	if err := g.MakeMove("e2e4"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := g.DecodeSAN("Nf6"); err != nil || got != "g8f6" {
+				t.Errorf("DecodeSAN(Nf6) = %q, %v", got, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,79 @@
+package game
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// PairTTL is how long a pairing code can be claimed.
+const PairTTL = 5 * time.Minute
+
+// pairCodes is the number of possible pairing codes, which have six digits.
+const pairCodes = 1_000_000
+
+// Pairings hands out short numeric codes that seat a second device in a
+// game, for two phones in the same room: one shows the code or its QR and
+// the other claims it. Codes expire after PairTTL and can be claimed once;
+// a game has at most one code at a time.
+type Pairings struct {
+	mu    sync.Mutex
+	codes map[string]pairing
+	now   func() time.Time
+}
+
+type pairing struct {
+	game    GameID
+	expires time.Time
+}
+
+// NewPairings returns an empty set of pairing codes.
+func NewPairings() *Pairings {
+	return &Pairings{codes: make(map[string]pairing), now: time.Now}
+}
+
+// Issue returns a fresh code for id and when it expires, replacing any
+// earlier code for the same game.
+func (p *Pairings) Issue(id GameID) (string, time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	for code, pr := range p.codes {
+		if pr.game == id || !now.Before(pr.expires) {
+			delete(p.codes, code)
+		}
+	}
+	code := randomPairCode()
+	for _, taken := p.codes[code]; taken; _, taken = p.codes[code] {
+		code = randomPairCode()
+	}
+	expires := now.Add(PairTTL)
+	p.codes[code] = pairing{game: id, expires: expires}
+	return code, expires
+}
+
+// Claim consumes code and returns the game it pairs with. It fails for
+// unknown, expired and already claimed codes.
+func (p *Pairings) Claim(code string) (GameID, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pr, ok := p.codes[code]
+	if !ok {
+		return GameID{}, false
+	}
+	delete(p.codes, code)
+	if !p.now().Before(pr.expires) {
+		return GameID{}, false
+	}
+	return pr.game, true
+}
+
+func randomPairCode() string {
+	n, err := rand.Int(rand.Reader, big.NewInt(pairCodes))
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%06d", n.Int64())
+}
@@ -0,0 +1,144 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/corentings/chess/v2"
+)
+
+// Instance identifies this server in generated PGN headers.
+type Instance struct {
+	Name    string
+	BaseURL string
+}
+
+var (
+	instanceMu sync.RWMutex
+	instance   = Instance{Name: "Tiny Chess"}
+)
+
+// SetInstance configures the instance metadata used for PGN Event and Site tags.
+func SetInstance(name, baseURL string) {
+	instanceMu.Lock()
+	defer instanceMu.Unlock()
+	if name != "" {
+		instance.Name = name
+	}
+	instance.BaseURL = strings.TrimRight(baseURL, "/")
+}
+
+// CurrentInstance returns the configured instance metadata.
+func CurrentInstance() Instance {
+	instanceMu.RLock()
+	defer instanceMu.RUnlock()
+	return instance
+}
+
+// PGNHeaders carries optional tags that come from outside the game itself,
+// such as tournament rounds or player ratings. Empty values are omitted.
+type PGNHeaders struct {
+	Round    string
+	White    string
+	Black    string
+	WhiteElo string
+	BlackElo string
+}
+
+// pgnTermination maps a stored termination reason to the PGN Termination tag.
+func pgnTermination(termination string) string {
+	switch termination {
+	case "":
+		return ""
+	case TerminationTimeout:
+		return "time forfeit"
+	case TerminationAbandonment:
+		return "abandoned"
+	case TerminationAdjudication, TerminationMoveLimit:
+		return "adjudication"
+	default:
+		return "normal"
+	}
+}
+
+// setUpTag is the PGN SetUp tag value for games from a custom position.
+func setUpTag(startFEN string) string {
+	if startFEN == "" {
+		return ""
+	}
+	return "1"
+}
+
+// pgnTagEscaper escapes tag values as the PGN standard requires, since
+// values such as display names come from users.
+var pgnTagEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// sevenTagRoster is the PGN standard's required tag order; other tags follow
+// alphabetically.
+var sevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// PGNLocked builds the game's PGN with the standard seven tag roster filled
+// from instance metadata and the players' display names, plus any extra
+// headers. It only reads the game, so a read lock is enough.
+func (g *Game) PGNLocked(extra PGNHeaders) string {
+	white, black := g.playerNamesLocked()
+	return buildPGN(g.g, g.ID, g.startFEN, g.terminationLocked(), g.CreatedAt, withNames(extra, white, black))
+}
+
+// buildPGN writes the PGN of cg with its tags. It never mutates cg.
+func buildPGN(cg *chess.Game, id GameID, startFEN, termination string, createdAt time.Time, extra PGNHeaders) string {
+	inst := CurrentInstance()
+	site := inst.BaseURL
+	if site != "" && !id.IsZero() {
+		site += "/" + id.String()
+	}
+	date := ""
+	if !createdAt.IsZero() {
+		date = createdAt.UTC().Format("2006.01.02")
+	}
+	tags := map[string]string{
+		"Event":       inst.Name,
+		"Site":        site,
+		"Date":        date,
+		"Round":       extra.Round,
+		"White":       extra.White,
+		"Black":       extra.Black,
+		"WhiteElo":    extra.WhiteElo,
+		"BlackElo":    extra.BlackElo,
+		"Result":      cg.Outcome().String(),
+		"Termination": pgnTermination(termination),
+		"SetUp":       setUpTag(startFEN),
+		"FEN":         startFEN,
+	}
+	keys := make([]string, 0, len(tags))
+	for k, v := range tags {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		ia, ib := slices.Index(sevenTagRoster, a), slices.Index(sevenTagRoster, b)
+		switch {
+		case ia >= 0 && ib >= 0:
+			return ia - ib
+		case ia >= 0:
+			return -1
+		case ib >= 0:
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+
+	// The tags are written here rather than set on the chess game, so that
+	// building a PGN never mutates shared state.
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "[%s \"%s\"]\n", k, pgnTagEscaper.Replace(tags[k]))
+	}
+	b.WriteString("\n")
+	b.WriteString(cg.String())
+	return b.String()
+}
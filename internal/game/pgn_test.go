@@ -0,0 +1,44 @@
+package game
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPGNLockedHeaders(t *testing.T) {
+	SetInstance("Club Night", "https://chess.example.org/")
+	defer SetInstance("Tiny Chess", "")
+
+	g := newTestGame()
+	g.ID = GameID(uuid.MustParse("5f0c6a8e-2b1d-4c3e-9f7a-1b2c3d4e5f60"))
+	g.CreatedAt = time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)
+	for _, m := range []string{"f2f3", "e7e5", "g2g4", "d8h4"} {
+		if err := g.MakeMove(m); err != nil {
+			t.Fatalf("move %s failed: %v", m, err)
+		}
+	}
+
+	g.Mu.Lock()
+	pgn := g.PGNLocked(PGNHeaders{Round: "3", WhiteElo: "1500"})
+	g.Mu.Unlock()
+
+	for _, want := range []string{
+		`[Event "Club Night"]`,
+		`[Site "https://chess.example.org/5f0c6a8e-2b1d-4c3e-9f7a-1b2c3d4e5f60"]`,
+		`[Date "2024.03.09"]`,
+		`[Round "3"]`,
+		`[Result "0-1"]`,
+		`[WhiteElo "1500"]`,
+		`[Termination "normal"]`,
+	} {
+		if !strings.Contains(pgn, want) {
+			t.Fatalf("expected %s in PGN:\n%s", want, pgn)
+		}
+	}
+	if strings.Contains(pgn, "BlackElo") {
+		t.Fatalf("empty headers should be omitted:\n%s", pgn)
+	}
+}
@@ -0,0 +1,122 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotPostMortem is returned when a post-mortem action is asked of an
+// ordinary game.
+var ErrNotPostMortem = errors.New("not a post-mortem room")
+
+// OpenPostMortem links a finished game to its post-mortem room, a shared
+// analysis board seeded with the game where its players explore lines
+// together: they move for either side and rewind to any ply, and everyone
+// watching sees the board follow. Spectators move too when the hub's
+// PostMortemSpectators is set. Rooms live in memory only; a room that was
+// unloaded is opened again under the same id. It returns the room's id,
+// and false for games that are not over or are rooms themselves.
+func (h *Hub) OpenPostMortem(g *Game) (GameID, bool) {
+	g.Mu.RLock()
+	if !g.overLocked() || !g.postMortemOf.IsZero() {
+		g.Mu.RUnlock()
+		return GameID{}, false
+	}
+	id := g.postMortem
+	if !id.IsZero() {
+		if _, ok := h.Lookup(id); ok {
+			g.Mu.RUnlock()
+			return id, true
+		}
+	} else {
+		id = NewGameID()
+	}
+	room := newGameInstance(id)
+	room.postMortemOf = g.ID
+	room.postMortemOpen = h.PostMortemSpectators
+	room.startFEN = g.startFEN
+	room.OwnerID = g.OwnerID
+	room.OwnerColor = g.OwnerColor
+	for client, col := range g.Clients {
+		room.Clients[client] = col
+	}
+	if len(g.names) > 0 {
+		room.names = make(map[string]string, len(g.names))
+		for client, name := range g.names {
+			room.names[client] = name
+		}
+	}
+	room.Language = g.Language
+	err := room.replayLocked(g.MovesUCI())
+	g.Mu.RUnlock()
+	if err != nil {
+		return GameID{}, false
+	}
+
+	if !h.add(room) {
+		// Another request opened it first.
+		return id, true
+	}
+	g.Mu.Lock()
+	g.postMortem = id
+	g.Mu.Unlock()
+	return id, true
+}
+
+// PostMortemOf returns the game a post-mortem room analyses; ok is false
+// for ordinary games.
+func (g *Game) PostMortemOf() (id GameID, ok bool) {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return g.postMortemOf, !g.postMortemOf.IsZero()
+}
+
+// PostMortemOpen reports whether spectators may move in a post-mortem room.
+func (g *Game) PostMortemOpen() bool {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return !g.postMortemOf.IsZero() && g.postMortemOpen
+}
+
+// ClaimAnalyst reports whether clientID is joining an open post-mortem room
+// as an analyst for the first time. Later connections naming the same client
+// must prove the seat some other way.
+func (g *Game) ClaimAnalyst(clientID string) bool {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	if g.postMortemOf.IsZero() || !g.postMortemOpen || g.analysts[clientID] {
+		return false
+	}
+	if g.analysts == nil {
+		g.analysts = make(map[string]bool)
+	}
+	g.analysts[clientID] = true
+	return true
+}
+
+// Rewind takes a post-mortem room back to the position after ply plies,
+// so another line can be explored from there.
+func (g *Game) Rewind(ply int) error {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	if g.postMortemOf.IsZero() {
+		return ErrNotPostMortem
+	}
+	uci := g.MovesUCI()
+	if ply < 0 || ply > len(uci) {
+		return fmt.Errorf("ply %d out of range", ply)
+	}
+	return g.replayLocked(uci[:ply])
+}
+
+// postMortemLinksLocked returns the state's post-mortem links: the room of a
+// finished game, and the game a room analyses.
+func (g *Game) postMortemLinksLocked() (room, of string) {
+	if !g.postMortem.IsZero() {
+		room = g.postMortem.String()
+	}
+	if !g.postMortemOf.IsZero() {
+		of = g.postMortemOf.String()
+	}
+	return room, of
+}
@@ -0,0 +1,99 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/corentings/chess/v2"
+)
+
+func TestPostMortemRoom(t *testing.T) {
+	hub := NewHub(nil)
+	g := newGameInstance(NewGameID())
+	g.OwnerID, g.OwnerColor = "a", chess.White
+	g.Clients["a"], g.Clients["b"] = chess.White, chess.Black
+	hub.add(g)
+	if _, ok := hub.OpenPostMortem(g); ok {
+		t.Fatalf("a game in progress should have no post-mortem room")
+	}
+	for _, uci := range []string{"e2e4", "e7e5"} {
+		if err := g.MakeMove(uci); err != nil {
+			t.Fatalf("move %s: %v", uci, err)
+		}
+	}
+	if err := g.Resign(chess.Black); err != nil {
+		t.Fatalf("resign: %v", err)
+	}
+
+	id, ok := hub.OpenPostMortem(g)
+	if !ok {
+		t.Fatalf("expected a post-mortem room for the finished game")
+	}
+	if again, _ := hub.OpenPostMortem(g); again != id {
+		t.Fatalf("expected the same room again, got %s and %s", id, again)
+	}
+	room, ok := hub.Lookup(id)
+	if !ok {
+		t.Fatalf("expected the room loaded")
+	}
+	g.Mu.RLock()
+	state := g.StateLocked()
+	g.Mu.RUnlock()
+	room.Mu.RLock()
+	roomState := room.StateLocked()
+	room.Mu.RUnlock()
+	if state.PostMortem != id.String() || roomState.PostMortemOf != g.ID.String() {
+		t.Fatalf("expected the game and room linked, got %q and %q", state.PostMortem, roomState.PostMortemOf)
+	}
+	if len(roomState.UCI) != 2 || roomState.Status != "" {
+		t.Fatalf("expected the room seeded with the game and open, got %v %q", roomState.UCI, roomState.Status)
+	}
+	if _, ok := hub.OpenPostMortem(room); ok {
+		t.Fatalf("rooms should not get rooms of their own")
+	}
+
+	if col, ok := room.MoverColor("a", chess.Black); !ok || col != chess.Black {
+		t.Fatalf("players should move either side in the room, got %v %v", col, ok)
+	}
+	if _, ok := room.MoverColor("spectator", chess.White); ok {
+		t.Fatalf("spectators should not move unless the room is open")
+	}
+	if err := room.MakeMove("g1f3"); err != nil {
+		t.Fatalf("move in room: %v", err)
+	}
+	if err := room.Rewind(1); err != nil {
+		t.Fatalf("rewind: %v", err)
+	}
+	if uci := room.MovesUCI(); len(uci) != 1 || uci[0] != "e2e4" {
+		t.Fatalf("expected the room back at ply 1, got %v", uci)
+	}
+	if err := room.Rewind(3); err == nil {
+		t.Fatalf("expected an error rewinding past the room's moves")
+	}
+	if err := g.Rewind(0); err != ErrNotPostMortem {
+		t.Fatalf("expected ErrNotPostMortem for an ordinary game, got %v", err)
+	}
+	if uci := g.MovesUCI(); len(uci) != 2 {
+		t.Fatalf("the game itself should be untouched, got %v", uci)
+	}
+
+	hub.remove(id)
+	if reopened, ok := hub.OpenPostMortem(g); !ok || reopened != id {
+		t.Fatalf("expected an unloaded room opened again under its id, got %s %v", reopened, ok)
+	}
+
+	hub.PostMortemSpectators = true
+	other := newGameInstance(NewGameID())
+	other.Clients["a"] = chess.White
+	hub.add(other)
+	if err := other.Resign(chess.White); err != nil {
+		t.Fatalf("resign: %v", err)
+	}
+	openID, _ := hub.OpenPostMortem(other)
+	open, _ := hub.Lookup(openID)
+	if !open.PostMortemOpen() {
+		t.Fatalf("expected an open room")
+	}
+	if col, ok := open.MoverColor("spectator", chess.White); !ok || col != chess.White {
+		t.Fatalf("spectators should move in an open room, got %v %v", col, ok)
+	}
+}
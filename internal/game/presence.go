@@ -0,0 +1,155 @@
+package game
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/corentings/chess/v2"
+)
+
+// Participant roles in the live participant list.
+const (
+	RolePlayer    = "player"
+	RoleSpectator = "spectator"
+)
+
+// participantIDs signs the IDs participants are listed under. Its key is
+// random, so the IDs only last as long as the process; clients pick up
+// the new ones when their event stream reconnects.
+var participantIDs = NewSeatSigner(nil)
+
+// Participant is an entry in a game's live participant list. Players are
+// listed whether or not they are connected, so their opponent can tell when
+// they have gone; spectators only while they watch. ID is the participant's
+// opaque ID, see Game.ParticipantID.
+type Participant struct {
+	ID        string `json:"id"`
+	Role      string `json:"role"`
+	Color     string `json:"color,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Connected bool   `json:"connected"`
+}
+
+// PresencePayload announces that a participant connected or disconnected,
+// along with the updated participant list.
+type PresencePayload struct {
+	Schema int    `json:"schema"`
+	Kind   string `json:"kind"`
+	Participant
+	At           int64         `json:"at"`
+	Participants []Participant `json:"participants"`
+}
+
+// Connect records an event stream opened by clientID. Presence is announced
+// when the client had no other stream open, so extra tabs stay quiet.
+func (g *Game) Connect(clientID string) {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	if g.connected == nil {
+		g.connected = make(map[string]int)
+	}
+	g.connected[clientID]++
+	if g.connected[clientID] == 1 {
+		g.sendLocked(g.presenceLocked(clientID, true))
+	}
+}
+
+// Disconnect records that one of clientID's event streams closed, announcing
+// it once the client's last stream is gone.
+func (g *Game) Disconnect(clientID string) {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	if g.connected[clientID] == 0 {
+		return
+	}
+	g.connected[clientID]--
+	if g.connected[clientID] == 0 {
+		delete(g.connected, clientID)
+		g.sendLocked(g.presenceLocked(clientID, false))
+	}
+}
+
+// ParticipantID returns the ID clientID is known by to the game's other
+// watchers, in the participant list, chat and reactions. A client ID is
+// what its seat token is checked against and the key for its settings, so
+// it is never sent to anyone but its own client.
+func (g *Game) ParticipantID(clientID string) string {
+	return participantIDs.ParticipantID(g.ID, clientID)
+}
+
+// ParticipantClient resolves a participant ID back to the client behind it,
+// among the game's players, connected spectators and chat senders.
+func (g *Game) ParticipantClient(id string) (string, bool) {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	if clientID, ok := g.chatters[id]; ok {
+		return clientID, true
+	}
+	for clientID := range g.Clients {
+		if g.ParticipantID(clientID) == id {
+			return clientID, true
+		}
+	}
+	for clientID := range g.connected {
+		if g.ParticipantID(clientID) == id {
+			return clientID, true
+		}
+	}
+	return "", false
+}
+
+// Participants returns the live participant list.
+func (g *Game) Participants() []Participant {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return g.participantsLocked()
+}
+
+func (g *Game) presenceLocked(clientID string, connected bool) PresencePayload {
+	return PresencePayload{
+		Schema:       SchemaVersion,
+		Kind:         "presence",
+		Participant:  g.participantLocked(clientID, connected),
+		At:           time.Now().UnixMilli(),
+		Participants: g.participantsLocked(),
+	}
+}
+
+func (g *Game) participantLocked(clientID string, connected bool) Participant {
+	p := Participant{ID: g.ParticipantID(clientID), Role: RoleSpectator, Connected: connected}
+	if col, ok := g.Clients[clientID]; ok {
+		p.Role = RolePlayer
+		p.Color = col.String()
+		p.Name = g.names[clientID]
+	}
+	return p
+}
+
+// participantsLocked lists players, white first, then connected spectators
+// in ID order.
+func (g *Game) participantsLocked() []Participant {
+	out := make([]Participant, 0, len(g.Clients)+len(g.connected))
+	for id := range g.Clients {
+		out = append(out, g.participantLocked(id, g.connected[id] > 0))
+	}
+	for id, n := range g.connected {
+		if _, seated := g.Clients[id]; !seated && n > 0 {
+			out = append(out, g.participantLocked(id, true))
+		}
+	}
+	white := chess.White.String()
+	slices.SortFunc(out, func(a, b Participant) int {
+		if a.Role != b.Role {
+			return strings.Compare(a.Role, b.Role)
+		}
+		if a.Color != b.Color {
+			if a.Color == white {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return out
+}
@@ -0,0 +1,105 @@
+package game
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/corentings/chess/v2"
+)
+
+func TestPresence(t *testing.T) {
+	g := newGameInstance(NewGameID())
+	g.Clients["w1"] = chess.White
+	g.Clients["b1"] = chess.Black
+	ch := make(chan []byte, 8)
+	g.AddWatcher(ch)
+
+	next := func() *PresencePayload {
+		select {
+		case data := <-ch:
+			var p PresencePayload
+			if err := json.Unmarshal(data, &p); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			return &p
+		default:
+			return nil
+		}
+	}
+
+	g.Connect("b1")
+	g.Connect("b1")
+	g.Connect("s1")
+	p := next()
+	if p == nil || p.Kind != "presence" || p.ID != g.ParticipantID("b1") || p.Role != RolePlayer || p.Color != "b" || !p.Connected {
+		t.Fatalf("unexpected presence %+v", p)
+	}
+	if p := next(); p == nil || p.ID != g.ParticipantID("s1") || p.Role != RoleSpectator {
+		t.Fatalf("expected spectator presence, got %+v", p)
+	}
+	if p := next(); p != nil {
+		t.Fatalf("a second stream must not be announced, got %+v", p)
+	}
+
+	want := []Participant{
+		{ID: g.ParticipantID("w1"), Role: RolePlayer, Color: "w"},
+		{ID: g.ParticipantID("b1"), Role: RolePlayer, Color: "b", Connected: true},
+		{ID: g.ParticipantID("s1"), Role: RoleSpectator, Connected: true},
+	}
+	got := g.Participants()
+	if len(got) != len(want) {
+		t.Fatalf("participants %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("participant %d: got %+v want %+v", i, got[i], want[i])
+		}
+	}
+
+	g.Disconnect("b1")
+	if p := next(); p != nil {
+		t.Fatalf("client with a stream left must stay connected, got %+v", p)
+	}
+	g.Disconnect("b1")
+	if p := next(); p == nil || p.ID != g.ParticipantID("b1") || p.Connected {
+		t.Fatalf("expected disconnect presence, got %+v", p)
+	}
+	g.Disconnect("s1")
+	next()
+	if got := g.Participants(); len(got) != 2 || got[1].Connected {
+		t.Fatalf("expected players only, both away, got %+v", got)
+	}
+}
+
+func TestParticipantIDsHideClientIDs(t *testing.T) {
+	g := newGameInstance(NewGameID())
+	g.Clients["w1"] = chess.White
+	g.Connect("s1")
+
+	for _, p := range g.Participants() {
+		if p.ID == "w1" || p.ID == "s1" {
+			t.Fatalf("participant list leaks a client ID: %+v", p)
+		}
+	}
+	if g.ParticipantID("w1") == newGameInstance(NewGameID()).ParticipantID("w1") {
+		t.Fatal("participant IDs must differ between games")
+	}
+	for _, id := range []string{"w1", "s1"} {
+		if got, ok := g.ParticipantClient(g.ParticipantID(id)); !ok || got != id {
+			t.Fatalf("resolve %s: got %q %v", id, got, ok)
+		}
+	}
+
+	// Chat senders stay resolvable after they leave.
+	msg := g.PostChat(ChatPayload{Schema: SchemaVersion, Kind: "chat", Text: "hi", Sender: "c1"})
+	if msg.Sender != g.ParticipantID("c1") {
+		t.Fatalf("chat sender %q is not the participant ID", msg.Sender)
+	}
+	g.DeleteChat(msg.ID)
+	if got, ok := g.ParticipantClient(msg.Sender); !ok || got != "c1" {
+		t.Fatalf("resolve chat sender: got %q %v", got, ok)
+	}
+	if _, ok := g.ParticipantClient("w1"); ok {
+		t.Fatal("a client ID must not resolve as a participant ID")
+	}
+}
@@ -0,0 +1,58 @@
+package game
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MaxJoinCodeLength bounds the join code of a private game.
+const MaxJoinCodeLength = 64
+
+// ErrJoinCodeTooLong is returned for join codes over MaxJoinCodeLength.
+var ErrJoinCodeTooLong = errors.New("join code too long")
+
+// CleanJoinCode trims a join code and checks its length. An empty result
+// means the game is open.
+func CleanJoinCode(code string) (string, error) {
+	code = strings.TrimSpace(code)
+	if len(code) > MaxJoinCodeLength {
+		return "", ErrJoinCodeTooLong
+	}
+	return code, nil
+}
+
+func hashJoinCode(code string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// Private reports whether the game needs a join code to take a seat.
+func (g *Game) Private() bool {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return g.joinCodeHash != ""
+}
+
+// CanJoin reports whether clientID may take or keep a seat given code. Open
+// games admit everyone, and clients already seated never need the code again.
+func (g *Game) CanJoin(clientID, code string) bool {
+	g.Mu.RLock()
+	hash := g.joinCodeHash
+	_, seated := g.Clients[clientID]
+	g.Mu.RUnlock()
+	if hash == "" || (clientID != "" && seated) {
+		return true
+	}
+	return code != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(code)) == nil
+}
+
+// CanView reports whether clientID may watch the game given code. Only
+// private games that extend their code to spectators restrict viewing.
+func (g *Game) CanView(clientID, code string) bool {
+	g.Mu.RLock()
+	restricted := g.codeForSpectators
+	g.Mu.RUnlock()
+	return !restricted || g.CanJoin(clientID, code)
+}
@@ -0,0 +1,51 @@
+package game
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPrivateGameJoinCode(t *testing.T) {
+	ctx := context.Background()
+	h := NewHub(nil)
+	owner := "00000000-0000-0000-0000-000000000001"
+	id, _, err := h.CreateGame(ctx, owner, GameOptions{JoinCode: "sekrit"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	g, _ := h.Lookup(id)
+	if !g.Private() || g.joinCodeHash == "sekrit" {
+		t.Fatalf("expected a private game with a hashed code")
+	}
+	if !g.CanJoin(owner, "") {
+		t.Fatalf("seated owner should not need the code")
+	}
+	if g.CanJoin("guest", "") || g.CanJoin("guest", "wrong") {
+		t.Fatalf("expected guest without the code to be refused a seat")
+	}
+	if !g.CanJoin("guest", "sekrit") {
+		t.Fatalf("expected guest with the code to be seated")
+	}
+	if !g.CanView("guest", "") {
+		t.Fatalf("spectators need no code unless the game asks for it")
+	}
+
+	id, _, err = h.CreateGame(ctx, owner, GameOptions{JoinCode: "sekrit", CodeForSpectators: true})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	g, _ = h.Lookup(id)
+	if g.CanView("guest", "") || !g.CanView("guest", "sekrit") {
+		t.Fatalf("expected spectators to need the code")
+	}
+}
+
+func TestCleanJoinCode(t *testing.T) {
+	if code, err := CleanJoinCode("  abc "); err != nil || code != "abc" {
+		t.Fatalf("got %q, %v", code, err)
+	}
+	if _, err := CleanJoinCode(strings.Repeat("x", MaxJoinCodeLength+1)); err != ErrJoinCodeTooLong {
+		t.Fatalf("expected ErrJoinCodeTooLong, got %v", err)
+	}
+}
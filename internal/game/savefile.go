@@ -0,0 +1,225 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/corentings/chess/v2"
+
+	"tinychess/internal/logging"
+)
+
+// savedGame is a game as kept in the save file, see Hub.SaveGames. Colors
+// are "w" or "b".
+type savedGame struct {
+	ID          GameID            `json:"id"`
+	StartFEN    string            `json:"startFen,omitempty"`
+	FEN         string            `json:"fen"`
+	UCI         []string          `json:"uci"`
+	PlayedAt    []time.Time       `json:"playedAt,omitempty"`
+	Result      string            `json:"result"`
+	Termination string            `json:"termination,omitempty"`
+	OwnerID     string            `json:"ownerId,omitempty"`
+	OwnerColor  string            `json:"ownerColor"`
+	Clients     map[string]string `json:"clients"`
+	Names       map[string]string `json:"names,omitempty"`
+	TimeControl string            `json:"timeControl,omitempty"`
+	Language    string            `json:"language,omitempty"`
+	Timeline    []TimelineEvent   `json:"timeline,omitempty"`
+	// JoinCodeHash keeps private games private; the code itself is never
+	// stored.
+	JoinCodeHash      string      `json:"joinCodeHash,omitempty"`
+	CodeForSpectators bool        `json:"codeForSpectators,omitempty"`
+	InviteOnly        bool        `json:"inviteOnly,omitempty"`
+	HotSeat           bool        `json:"hotSeat,omitempty"`
+	HouseRules        *HouseRules `json:"houseRules,omitempty"`
+	Training          *Training   `json:"training,omitempty"`
+	CreatedAt         time.Time   `json:"createdAt"`
+	LastSeen          time.Time   `json:"lastSeen"`
+}
+
+// saveFile is the save file's content.
+type saveFile struct {
+	SavedAt time.Time   `json:"savedAt"`
+	Games   []savedGame `json:"games"`
+}
+
+// saveLocked captures the game for the save file. Callers must hold g.Mu; a
+// read lock is enough.
+func (g *Game) saveLocked() savedGame {
+	clients := make(map[string]string, len(g.Clients))
+	for id, col := range g.Clients {
+		clients[id] = col.String()
+	}
+	return savedGame{
+		ID:                g.ID,
+		StartFEN:          g.startFEN,
+		FEN:               g.g.Position().String(),
+		UCI:               g.MovesUCI(),
+		PlayedAt:          append([]time.Time(nil), g.playedAt...),
+		Result:            g.g.Outcome().String(),
+		Termination:       g.termination,
+		OwnerID:           g.OwnerID,
+		OwnerColor:        g.OwnerColor.String(),
+		Clients:           clients,
+		Names:             g.names,
+		TimeControl:       g.TimeControl,
+		Language:          g.Language,
+		Timeline:          g.timeline,
+		JoinCodeHash:      g.joinCodeHash,
+		CodeForSpectators: g.codeForSpectators,
+		InviteOnly:        g.inviteOnly,
+		HotSeat:           g.hotSeat,
+		HouseRules:        g.houseRules,
+		Training:          g.training,
+		CreatedAt:         g.CreatedAt,
+		LastSeen:          g.LastSeen,
+	}
+}
+
+// restore rebuilds a game from the save file. As when hydrating from the
+// store, the moves are replayed and the saved position is only a fallback.
+func (s savedGame) restore() *Game {
+	g := newGameInstance(s.ID)
+	g.startFEN = s.StartFEN
+	if err := g.replayLocked(s.UCI); err != nil || g.g.Position().String() != s.FEN {
+		logging.Debugf("replay saved game %s failed, restoring its position: %v", s.ID, err)
+		g.g = newChessGame(g.startFEN)
+		g.lastMove = nil
+		g.playedAt = nil
+		if restored, err := GameFromFEN(s.FEN); err == nil {
+			g.g = restored
+		}
+	} else if len(s.PlayedAt) == len(g.playedAt) {
+		copy(g.playedAt, s.PlayedAt)
+	}
+	g.restoreEnding(s.Result, s.Termination)
+	g.OwnerID = s.OwnerID
+	if col := savedColor(s.OwnerColor); col != chess.NoColor {
+		g.OwnerColor = col
+	}
+	for id, c := range s.Clients {
+		if col := savedColor(c); col != chess.NoColor {
+			g.Clients[id] = col
+		}
+	}
+	g.names = s.Names
+	g.TimeControl = s.TimeControl
+	g.Language = s.Language
+	g.timeline = s.Timeline
+	g.joinCodeHash = s.JoinCodeHash
+	g.codeForSpectators = s.CodeForSpectators
+	g.inviteOnly = s.InviteOnly
+	g.hotSeat = s.HotSeat
+	g.houseRules = s.HouseRules
+	g.training = s.Training
+	if !s.CreatedAt.IsZero() {
+		g.CreatedAt = s.CreatedAt
+	}
+	if !s.LastSeen.IsZero() {
+		g.LastSeen = s.LastSeen
+	}
+	return g
+}
+
+func savedColor(s string) chess.Color {
+	switch s {
+	case chess.White.String():
+		return chess.White
+	case chess.Black.String():
+		return chess.Black
+	}
+	return chess.NoColor
+}
+
+// SaveGames writes the games loaded in the hub to the JSON file at path, so
+// a deployment without a database can bring them back with LoadGames after
+// a restart. The file is replaced in one step, so a crash mid-write leaves
+// the previous save. It returns how many games were saved.
+func (h *Hub) SaveGames(path string) (int, error) {
+	games := h.loaded()
+	file := saveFile{SavedAt: time.Now(), Games: make([]savedGame, 0, len(games))}
+	for _, g := range games {
+		g.Mu.RLock()
+		// Post-mortem rooms are opened again from their games.
+		if g.postMortemOf.IsZero() {
+			file.Games = append(file.Games, g.saveLocked())
+		}
+		g.Mu.RUnlock()
+	}
+	data, err := json.Marshal(file)
+	if err != nil {
+		return 0, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	return len(file.Games), nil
+}
+
+// LoadGames adds the games saved at path by SaveGames to the hub, keeping
+// any game already loaded. A missing file loads nothing. It returns how
+// many games were loaded.
+func (h *Hub) LoadGames(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var file saveFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return 0, err
+	}
+	loaded := 0
+	for _, s := range file.Games {
+		if s.ID.IsZero() {
+			continue
+		}
+		if _, ok := h.Lookup(s.ID); ok {
+			continue
+		}
+		if h.add(s.restore()) {
+			loaded++
+		}
+	}
+	return loaded, nil
+}
+
+// RunSaves saves the hub's games to path every interval until ctx is done,
+// and once more then, so a clean shutdown loses nothing.
+func (h *Hub) RunSaves(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if _, err := h.SaveGames(path); err != nil {
+				logging.Debugf("save games failed: %v", err)
+			}
+			return
+		case <-ticker.C:
+			if _, err := h.SaveGames(path); err != nil {
+				logging.Debugf("save games failed: %v", err)
+			}
+		}
+	}
+}
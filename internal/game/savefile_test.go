@@ -0,0 +1,80 @@
+package game
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+)
+
+func TestSaveGamesRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	hub := NewHub(nil)
+	owner, opponent := uuid.NewString(), uuid.NewString()
+	playing, _, err := hub.CreateGame(ctx, owner, GameOptions{Color: chess.White, TimeControl: "5+0"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	g, _, err := hub.Get(ctx, playing, opponent)
+	if err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	for _, uci := range []string{"e2e4", "e7e5", "g1f3"} {
+		if err := g.MakeMove(uci); err != nil {
+			t.Fatalf("move %s: %v", uci, err)
+		}
+	}
+	resigned, _, err := hub.CreateGame(ctx, owner, GameOptions{Color: chess.Black})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	r, _ := hub.Lookup(resigned)
+	if err := r.Resign(chess.Black); err != nil {
+		t.Fatalf("resign: %v", err)
+	}
+
+	g.Mu.RLock()
+	want := g.StateLocked()
+	g.Mu.RUnlock()
+
+	path := filepath.Join(t.TempDir(), "games.json")
+	if n, err := hub.SaveGames(path); err != nil || n != 2 {
+		t.Fatalf("save: %d %v", n, err)
+	}
+	restarted := NewHub(nil)
+	if n, err := restarted.LoadGames(path); err != nil || n != 2 {
+		t.Fatalf("load: %d %v", n, err)
+	}
+
+	got, ok := restarted.Lookup(playing)
+	if !ok {
+		t.Fatal("expected the game in progress to be loaded")
+	}
+	got.Mu.RLock()
+	state := got.StateLocked()
+	owned, ownerColor, clients := got.OwnerID, got.OwnerColor, len(got.Clients)
+	opponentColor := got.Clients[opponent]
+	got.Mu.RUnlock()
+	if state.FEN != want.FEN || !slices.Equal(state.UCI, want.UCI) || state.TimeControl != "5+0" {
+		t.Fatalf("unexpected restored state %q %v %q", state.FEN, state.UCI, state.TimeControl)
+	}
+	if owned != owner || ownerColor != chess.White || clients != 2 || opponentColor != chess.Black {
+		t.Fatalf("unexpected seats: owner %q %v, %d clients, opponent %v", owned, ownerColor, clients, opponentColor)
+	}
+	if len(state.MoveTimes) != 3 {
+		t.Fatalf("expected move times kept, got %v", state.MoveTimes)
+	}
+
+	over, _ := restarted.Lookup(resigned)
+	if outcome := over.Outcome(); outcome != chess.WhiteWon {
+		t.Fatalf("expected the resignation kept, got %v", outcome)
+	}
+
+	// A missing file is a first start, not an error.
+	if n, err := NewHub(nil).LoadGames(filepath.Join(t.TempDir(), "none.json")); err != nil || n != 0 {
+		t.Fatalf("load missing: %d %v", n, err)
+	}
+}
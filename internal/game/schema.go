@@ -0,0 +1,172 @@
+package game
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+)
+
+// SchemaVersion is the current version of the state and event payloads.
+//
+// Version history:
+//
+//	1: original payloads (no schema field, PGN without tag pairs)
+//	2: adds schema, termination and timeControl; PGN carries tag pairs
+//	3: adds moves, the move list in the reader's preferred notation
+//	4: adds material and eval
+//	5: adds lastMove
+//	6: adds captured
+//	7: adds moveTimes
+//	8: adds startFen
+//	9: adds owner to client state
+//	10: adds seatToken to client state
+//	11: adds participants and presence events
+//	12: adds white and black display names, and participant names
+//	13: adds training
+//	14: adds pace
+//	15: adds mobility
+//	16: adds coolOffUntil
+//	17: adds postMortem, postMortemOf and postMortemOpen
+//	18: adds hotSeat
+//	19: adds houseRules
+//	20: participants, chat and reactions carry participant IDs rather than
+//	    client IDs; adds participantId to client state
+const SchemaVersion = 20
+
+// MinSchemaVersion is the oldest payload version the server can still produce.
+const MinSchemaVersion = 1
+
+// NegotiateSchema resolves the schema version requested by a client. Missing
+// or invalid values get the current version; values are clamped to the
+// supported range.
+func NegotiateSchema(requested string) int {
+	v, err := strconv.Atoi(requested)
+	if err != nil || v > SchemaVersion {
+		return SchemaVersion
+	}
+	if v < MinSchemaVersion {
+		return MinSchemaVersion
+	}
+	return v
+}
+
+var pgnTagLine = regexp.MustCompile(`(?m)^\[.*\]\s*\n`)
+
+// downgrades convert a decoded payload from version v+1 to version v.
+var downgrades = map[int]func(map[string]any){
+	19: func(p map[string]any) {
+		p["schema"] = 19
+		delete(p, "participantId")
+	},
+	18: func(p map[string]any) {
+		p["schema"] = 18
+		delete(p, "houseRules")
+	},
+	17: func(p map[string]any) {
+		p["schema"] = 17
+		delete(p, "hotSeat")
+	},
+	16: func(p map[string]any) {
+		p["schema"] = 16
+		delete(p, "postMortem")
+		delete(p, "postMortemOf")
+		delete(p, "postMortemOpen")
+	},
+	15: func(p map[string]any) {
+		p["schema"] = 15
+		delete(p, "coolOffUntil")
+	},
+	14: func(p map[string]any) {
+		p["schema"] = 14
+		delete(p, "mobility")
+	},
+	13: func(p map[string]any) {
+		p["schema"] = 13
+		delete(p, "pace")
+	},
+	12: func(p map[string]any) {
+		p["schema"] = 12
+		delete(p, "training")
+	},
+	11: func(p map[string]any) {
+		p["schema"] = 11
+		delete(p, "white")
+		delete(p, "black")
+		delete(p, "name")
+		if list, ok := p["participants"].([]any); ok {
+			for _, e := range list {
+				if m, ok := e.(map[string]any); ok {
+					delete(m, "name")
+				}
+			}
+		}
+	},
+	10: func(p map[string]any) {
+		p["schema"] = 10
+		delete(p, "participants")
+	},
+	9: func(p map[string]any) {
+		p["schema"] = 9
+		delete(p, "seatToken")
+	},
+	8: func(p map[string]any) {
+		p["schema"] = 8
+		delete(p, "owner")
+	},
+	7: func(p map[string]any) {
+		p["schema"] = 7
+		delete(p, "startFen")
+	},
+	6: func(p map[string]any) {
+		p["schema"] = 6
+		delete(p, "moveTimes")
+	},
+	5: func(p map[string]any) {
+		p["schema"] = 5
+		delete(p, "captured")
+	},
+	4: func(p map[string]any) {
+		p["schema"] = 4
+		delete(p, "lastMove")
+	},
+	3: func(p map[string]any) {
+		p["schema"] = 3
+		delete(p, "material")
+		delete(p, "eval")
+	},
+	2: func(p map[string]any) {
+		p["schema"] = 2
+		delete(p, "moves")
+	},
+	1: func(p map[string]any) {
+		delete(p, "schema")
+		delete(p, "termination")
+		delete(p, "timeControl")
+		if pgn, ok := p["pgn"].(string); ok {
+			p["pgn"] = pgnTagLine.ReplaceAllString(pgn, "")
+		}
+	},
+}
+
+// ConvertPayload rewrites an encoded payload for an older schema version.
+// Payloads that are already current, or that cannot be decoded as an object,
+// are returned unchanged.
+func ConvertPayload(data []byte, schema int) []byte {
+	if schema >= SchemaVersion {
+		return data
+	}
+	var p map[string]any
+	if err := json.Unmarshal(data, &p); err != nil || p["kind"] == nil {
+		return data
+	}
+	for v := SchemaVersion - 1; v >= schema; v-- {
+		if fn := downgrades[v]; fn != nil {
+			fn(p)
+		}
+	}
+	out, err := json.Marshal(p)
+	if err != nil {
+		return data
+	}
+	return out
+}
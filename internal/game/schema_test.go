@@ -0,0 +1,80 @@
+package game
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/corentings/chess/v2"
+)
+
+func TestNegotiateSchema(t *testing.T) {
+	cases := map[string]int{
+		"":    SchemaVersion,
+		"abc": SchemaVersion,
+		"1":   1,
+		"0":   MinSchemaVersion,
+		"99":  SchemaVersion,
+	}
+	for in, want := range cases {
+		if got := NegotiateSchema(in); got != want {
+			t.Fatalf("NegotiateSchema(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestConvertPayloadToV1(t *testing.T) {
+	g := newTestGame()
+	if err := g.Resign(chess.White); err != nil {
+		t.Fatalf("resign: %v", err)
+	}
+	g.Mu.Lock()
+	data, _ := json.Marshal(g.StateLocked())
+	g.Mu.Unlock()
+
+	var v1 map[string]any
+	if err := json.Unmarshal(ConvertPayload(data, 1), &v1); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	for _, k := range []string{"schema", "termination"} {
+		if _, ok := v1[k]; ok {
+			t.Fatalf("v1 payload should not contain %s", k)
+		}
+	}
+	if pgn := v1["pgn"].(string); strings.Contains(pgn, "[") {
+		t.Fatalf("v1 PGN should not contain tag pairs: %q", pgn)
+	}
+
+	if got := ConvertPayload(data, SchemaVersion); string(got) != string(data) {
+		t.Fatalf("current schema payload should be unchanged")
+	}
+	if got := ConvertPayload([]byte("{}"), 1); string(got) != "{}" {
+		t.Fatalf("heartbeat should be unchanged, got %s", got)
+	}
+}
+
+func TestConvertPayloadDropsNewerFields(t *testing.T) {
+	g := newTestGame()
+	g.hotSeat = true
+	g.houseRules = &HouseRules{MoveLimit: 40}
+	g.Mu.Lock()
+	data, _ := json.Marshal(ClientState{GameState: g.StateLocked(), ParticipantID: g.ParticipantID("c1")})
+	g.Mu.Unlock()
+
+	cases := map[string]int{"hotSeat": 17, "houseRules": 18, "participantId": 19}
+	for field, older := range cases {
+		var current, old map[string]any
+		if err := json.Unmarshal(ConvertPayload(data, older+1), &current); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if _, ok := current[field]; !ok {
+			t.Fatalf("schema %d payload should contain %s", older+1, field)
+		}
+		if err := json.Unmarshal(ConvertPayload(data, older), &old); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if _, ok := old[field]; ok || old["schema"] != float64(older) {
+			t.Fatalf("schema %d payload should not contain %s, got %v", older, field, old)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package game
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// SeatSigner issues and checks seat tokens. A token is an HMAC over the game
+// and client IDs, handed to a client when it is seated; requests that act on
+// a seat must present it, so knowing someone's client ID is not enough to
+// move or release for them.
+type SeatSigner struct {
+	key []byte
+}
+
+// NewSeatSigner returns a signer using key. A random key is generated when key
+// is empty, in which case tokens do not survive a restart and clients pick up
+// fresh ones when their event stream reconnects.
+func NewSeatSigner(key []byte) *SeatSigner {
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+	}
+	return &SeatSigner{key: key}
+}
+
+// Token kinds. Each MAC starts with its kind, so a token of one kind never
+// passes for another, whatever the client ID.
+const (
+	seatTag        = "seat"
+	apiTag         = "api"
+	inviteTag      = "invite"
+	participantTag = "participant"
+)
+
+// ValidClientID reports whether id may hold a seat. Client IDs are signed
+// into tokens, where NUL bytes separate the fields, so they may not contain
+// one.
+func ValidClientID(id string) bool {
+	return !strings.ContainsRune(id, 0)
+}
+
+// Token returns the seat token for clientID in gameID.
+func (s *SeatSigner) Token(gameID GameID, clientID string) string {
+	return base64.RawURLEncoding.EncodeToString(s.sum(seatTag, gameID, clientID))
+}
+
+// Valid reports whether token was issued to clientID in gameID.
+func (s *SeatSigner) Valid(gameID GameID, clientID, token string) bool {
+	mac, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !ValidClientID(clientID) {
+		return false
+	}
+	return hmac.Equal(mac, s.sum(seatTag, gameID, clientID))
+}
+
+// ParticipantID returns the opaque ID clientID is shown to other watchers of
+// gameID under. It differs from game to game, so it cannot be used to
+// follow a client around, and it proves nothing on its own.
+func (s *SeatSigner) ParticipantID(gameID GameID, clientID string) string {
+	return base64.RawURLEncoding.EncodeToString(s.sum(participantTag, gameID, clientID)[:12])
+}
+
+func (s *SeatSigner) sum(tag string, gameID GameID, clientID string, extra ...string) []byte {
+	m := hmac.New(sha256.New, s.key)
+	m.Write([]byte(tag))
+	m.Write([]byte{0})
+	m.Write(gameID[:])
+	m.Write([]byte(clientID))
+	for _, e := range extra {
+		m.Write([]byte{0})
+		m.Write([]byte(e))
+	}
+	return m.Sum(nil)
+}
+
+// Scope is what an API token may do in its game.
+type Scope string
+
+const (
+	// ScopeRead lets an integration such as an overlay or dashboard follow
+	// the game, private or not, but never take a seat or act on one.
+	ScopeRead Scope = "read"
+	// ScopePlay additionally acts for the seat of the player who issued it.
+	ScopePlay Scope = "play"
+)
+
+// ParseScope validates a scope name.
+func ParseScope(s string) (Scope, bool) {
+	switch Scope(s) {
+	case ScopeRead, ScopePlay:
+		return Scope(s), true
+	}
+	return "", false
+}
+
+// Allows reports whether a token of scope s may be used where want is
+// required.
+func (s Scope) Allows(want Scope) bool {
+	return s == want || s == ScopePlay
+}
+
+// APIGrant is what a valid API token carries: the client that issued it and
+// its scope.
+type APIGrant struct {
+	ClientID string
+	Scope    Scope
+}
+
+// APIToken returns a token for gameID with the given scope, issued by
+// clientID. Unlike seat tokens, API tokens are presented on their own as a
+// bearer credential, so the issuer travels inside the token.
+func (s *SeatSigner) APIToken(gameID GameID, clientID string, scope Scope) string {
+	enc := base64.RawURLEncoding
+	return string(scope) + "." + enc.EncodeToString([]byte(clientID)) + "." + enc.EncodeToString(s.sum(apiTag, gameID, clientID, string(scope)))
+}
+
+// ParseAPIToken checks an API token for gameID and returns its grant.
+func (s *SeatSigner) ParseAPIToken(gameID GameID, token string) (APIGrant, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return APIGrant{}, false
+	}
+	scope, ok := ParseScope(parts[0])
+	if !ok {
+		return APIGrant{}, false
+	}
+	enc := base64.RawURLEncoding
+	clientID, err := enc.DecodeString(parts[1])
+	if err != nil || !ValidClientID(string(clientID)) {
+		return APIGrant{}, false
+	}
+	mac, err := enc.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(mac, s.sum(apiTag, gameID, string(clientID), string(scope))) {
+		return APIGrant{}, false
+	}
+	return APIGrant{ClientID: string(clientID), Scope: scope}, true
+}
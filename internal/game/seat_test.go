@@ -0,0 +1,75 @@
+package game
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/corentings/chess/v2"
+)
+
+func TestSeatSigner(t *testing.T) {
+	s := NewSeatSigner([]byte("secret"))
+	g1, g2 := NewGameID(), NewGameID()
+	tok := s.Token(g1, "c1")
+	if !s.Valid(g1, "c1", tok) {
+		t.Fatalf("expected token to be valid")
+	}
+	if s.Valid(g1, "c2", tok) || s.Valid(g2, "c1", tok) {
+		t.Fatalf("token must be bound to game and client")
+	}
+	if s.Valid(g1, "c1", "") || s.Valid(g1, "c1", "!!") {
+		t.Fatalf("expected malformed tokens to be rejected")
+	}
+	if NewSeatSigner([]byte("other")).Valid(g1, "c1", tok) {
+		t.Fatalf("expected token from another key to be rejected")
+	}
+	if NewSeatSigner([]byte("secret")).Token(g1, "c1") != tok {
+		t.Fatalf("expected tokens to be stable for a key")
+	}
+}
+
+func TestAPIToken(t *testing.T) {
+	s := NewSeatSigner([]byte("secret"))
+	g1, g2 := NewGameID(), NewGameID()
+	tok := s.APIToken(g1, "c1", ScopeRead)
+	grant, ok := s.ParseAPIToken(g1, tok)
+	if !ok || grant.ClientID != "c1" || grant.Scope != ScopeRead {
+		t.Fatalf("unexpected grant %+v (%v)", grant, ok)
+	}
+	if _, ok := s.ParseAPIToken(g2, tok); ok {
+		t.Fatalf("token must be bound to its game")
+	}
+	if _, ok := s.ParseAPIToken(g1, "play"+strings.TrimPrefix(tok, "read")); ok {
+		t.Fatalf("scope must be covered by the signature")
+	}
+	if !ScopePlay.Allows(ScopeRead) || ScopeRead.Allows(ScopePlay) {
+		t.Fatalf("unexpected scope ordering")
+	}
+}
+
+func TestTokenKindsAreSeparate(t *testing.T) {
+	s := NewSeatSigner([]byte("secret"))
+	id := NewGameID()
+	// Under a shared layout these client IDs would sign the same bytes as
+	// the white invite and c1's play token.
+	invite := s.Invite(id, InviteWhite)
+	if _, sig, _ := strings.Cut(invite, "."); s.Valid(id, "\x00invite\x00white", sig) || s.Valid(id, "\x00white", sig) {
+		t.Fatalf("expected an invite to fail as a seat token")
+	}
+	play := s.APIToken(id, "c1", ScopePlay)
+	sig := play[strings.LastIndex(play, ".")+1:]
+	if s.Valid(id, "c1\x00play", sig) {
+		t.Fatalf("expected an API token to fail as a seat token")
+	}
+	if role, ok := s.ParseInvite(id, "white."+s.Token(id, "")); ok {
+		t.Fatalf("expected a seat token to fail as an invite, got %s", role)
+	}
+	if s.Valid(id, "c\x00", s.Token(id, "c\x00")) {
+		t.Fatalf("expected client IDs with NUL bytes refused")
+	}
+	g := newTestGame()
+	g.Clients = make(map[string]chess.Color)
+	if color, _ := g.assignColor("c\x00"); color != nil {
+		t.Fatalf("expected a client ID with a NUL byte not seated")
+	}
+}
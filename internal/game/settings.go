@@ -0,0 +1,26 @@
+package game
+
+import "time"
+
+// Tunables read while games are served. They may be changed at startup, see
+// the config package, but not once the hub is running.
+var (
+	// CleanupInterval is how often the hub drops idle games from memory,
+	// and IdleTTL how long a game must go unseen first. Dropped games are
+	// loaded again from storage on their next visit.
+	CleanupInterval = 5 * time.Minute
+	IdleTTL         = 24 * time.Hour
+
+	// ReactCooldown, ChatCooldown and HintCooldown are how long a
+	// participant waits between reactions, chat messages and hints in the
+	// same game.
+	ReactCooldown = 5 * time.Second
+	ChatCooldown  = 2 * time.Second
+	HintCooldown  = 30 * time.Second
+
+	// PostGameCoolOff locks reactions and chat for everyone in a game for
+	// this long after a decisive result, so a loss is not followed by a
+	// burst of spam; they reopen for the post-mortem afterwards. 0 turns
+	// it off.
+	PostGameCoolOff time.Duration
+)
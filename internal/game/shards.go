@@ -0,0 +1,134 @@
+package game
+
+import (
+	"context"
+	"sync"
+)
+
+// hubShards is how many parts the hub's games are split into. Each part has
+// its own lock, so requests for different games rarely wait on each other.
+const hubShards = 32
+
+// hubShard holds the games whose ids fall into one part of the hub.
+type hubShard struct {
+	mu    sync.RWMutex
+	games map[GameID]*Game
+	// loading holds the games being hydrated from the store, so concurrent
+	// requests for one game share a single load.
+	loading map[GameID]*gameLoad
+}
+
+// gameLoad is one hydration in progress; g and err are set before done is
+// closed.
+type gameLoad struct {
+	done chan struct{}
+	g    *Game
+	err  error
+}
+
+func (h *Hub) shard(id GameID) *hubShard {
+	return &h.shards[id[len(id)-1]%hubShards]
+}
+
+// Lookup returns a game only if it is currently loaded in memory.
+func (h *Hub) Lookup(id GameID) (*Game, bool) {
+	s := h.shard(id)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	g, ok := s.games[id]
+	return g, ok
+}
+
+// Len returns how many games are loaded in memory.
+func (h *Hub) Len() int {
+	n := 0
+	for i := range h.shards {
+		s := &h.shards[i]
+		s.mu.RLock()
+		n += len(s.games)
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+// loaded returns the games loaded in memory.
+func (h *Hub) loaded() []*Game {
+	var games []*Game
+	for i := range h.shards {
+		s := &h.shards[i]
+		s.mu.RLock()
+		for _, g := range s.games {
+			games = append(games, g)
+		}
+		s.mu.RUnlock()
+	}
+	return games
+}
+
+// add loads g into the hub unless a game with its id already is. It reports
+// whether g was added.
+func (h *Hub) add(g *Game) bool {
+	s := h.shard(g.ID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.games[g.ID]; ok {
+		return false
+	}
+	h.admit(g)
+	s.games[g.ID] = g
+	return true
+}
+
+// remove unloads the game id.
+func (h *Hub) remove(id GameID) {
+	s := h.shard(id)
+	s.mu.Lock()
+	delete(s.games, id)
+	s.mu.Unlock()
+}
+
+// load returns the game id, hydrating it from the store when it is not
+// loaded yet. The store is only asked once however many requests arrive
+// while it answers, and no lock is held meanwhile, so a slow load holds up
+// nobody but the requests for that game.
+func (h *Hub) load(ctx context.Context, id GameID) (*Game, error) {
+	if g, ok := h.Lookup(id); ok {
+		return g, nil
+	}
+	s := h.shard(id)
+	s.mu.Lock()
+	if g, ok := s.games[id]; ok {
+		s.mu.Unlock()
+		return g, nil
+	}
+	if l, ok := s.loading[id]; ok {
+		s.mu.Unlock()
+		select {
+		case <-l.done:
+			return l.g, l.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	l := &gameLoad{done: make(chan struct{})}
+	s.loading[id] = l
+	s.mu.Unlock()
+
+	g := newGameInstance(id)
+	err := h.hydrateGame(ctx, g)
+
+	s.mu.Lock()
+	delete(s.loading, id)
+	if err != nil {
+		g = nil
+	} else if existing, ok := s.games[id]; ok {
+		g = existing
+	} else {
+		h.admit(g)
+		s.games[id] = g
+	}
+	s.mu.Unlock()
+	l.g, l.err = g, err
+	close(l.done)
+	return g, err
+}
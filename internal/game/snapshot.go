@@ -0,0 +1,126 @@
+package game
+
+import (
+	"io"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/corentings/chess/v2/image"
+)
+
+// Snapshot is an immutable copy of a game for long-running readers such as
+// exports, image rendering and analysis, so they never hold the game lock
+// while they work. Snapshots are cached and only retaken after the game
+// changes.
+type Snapshot struct {
+	ID          GameID
+	StartFEN    string
+	Termination string
+	CreatedAt   time.Time
+	UCI         []string
+	Timeline    []TimelineEvent
+	// White and Black are the players' display names.
+	White string
+	Black string
+
+	key  snapshotKey
+	game *chess.Game
+}
+
+// snapshotKey identifies the game state a snapshot was taken from.
+type snapshotKey struct {
+	ply         int
+	events      int
+	outcome     chess.Outcome
+	termination string
+	white       string
+	black       string
+}
+
+func (g *Game) snapshotKeyLocked() snapshotKey {
+	white, black := g.playerNamesLocked()
+	return snapshotKey{
+		ply:         len(g.g.Moves()),
+		events:      len(g.timeline),
+		outcome:     g.g.Outcome(),
+		termination: g.terminationLocked(),
+		white:       white,
+		black:       black,
+	}
+}
+
+// Snapshot returns a copy of the game as it is now. Callers must not hold
+// g.Mu.
+func (g *Game) Snapshot() *Snapshot {
+	g.Mu.RLock()
+	key := g.snapshotKeyLocked()
+	if s := g.snapshot.Load(); s != nil && s.key == key {
+		g.Mu.RUnlock()
+		return s
+	}
+	s := &Snapshot{
+		ID:          g.ID,
+		StartFEN:    g.startFEN,
+		Termination: key.termination,
+		CreatedAt:   g.CreatedAt,
+		UCI:         g.MovesUCI(),
+		Timeline:    append([]TimelineEvent{}, g.timeline...),
+		White:       key.white,
+		Black:       key.black,
+		key:         key,
+		game:        g.g.Clone(),
+	}
+	g.Mu.RUnlock()
+	g.snapshot.Store(s)
+	return s
+}
+
+// PGN builds the snapshot's PGN, as Game.PGNLocked does.
+func (s *Snapshot) PGN(extra PGNHeaders) string {
+	return buildPGN(s.game, s.ID, s.StartFEN, s.Termination, s.CreatedAt, withNames(extra, s.White, s.Black))
+}
+
+// Position returns the snapshot's final position.
+func (s *Snapshot) Position() *chess.Position {
+	return s.game.Position()
+}
+
+// WriteBoardSVG renders the snapshot's final position as SVG.
+func (s *Snapshot) WriteBoardSVG(w io.Writer) error {
+	return image.SVG(w, s.Position().Board())
+}
+
+// BoardImageKey identifies the rendered SVG of the snapshot's final position
+// in an ImageCache.
+func (s *Snapshot) BoardImageKey() string {
+	return "svg " + s.Position().Board().String()
+}
+
+// PlyPosition is the board after a number of plies, for links that open a
+// game at a given moment.
+type PlyPosition struct {
+	Ply int `json:"ply"`
+	// Plies is the length of the whole game, so viewers can tell how far
+	// back the position is.
+	Plies int    `json:"plies" doc:"Length of the whole game."`
+	FEN   string `json:"fen"`
+	// UCI and SAN are the move that led to the position, empty at ply 0.
+	UCI string `json:"uci,omitempty"`
+	SAN string `json:"san,omitempty"`
+}
+
+// AtPly returns the position after the first ply moves of the snapshot, or
+// false when the game is shorter.
+func (s *Snapshot) AtPly(ply int) (PlyPosition, bool) {
+	positions := s.game.Positions()
+	if ply < 0 || ply >= len(positions) {
+		return PlyPosition{}, false
+	}
+	p := PlyPosition{Ply: ply, Plies: len(positions) - 1, FEN: positions[ply].String()}
+	if ply > 0 {
+		move := s.game.Moves()[ply-1]
+		p.UCI = s.UCI[ply-1]
+		p.SAN = chess.AlgebraicNotation{}.Encode(positions[ply-1], move)
+	}
+	return p, true
+}
@@ -0,0 +1,61 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/corentings/chess/v2"
+)
+
+func TestSnapshotIsolation(t *testing.T) {
+	g := newTestGame()
+	if err := g.MakeMove("e2e4"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	snap := g.Snapshot()
+	if g.Snapshot() != snap {
+		t.Fatalf("unchanged game should reuse its snapshot")
+	}
+	g.Mu.RLock()
+	want := g.PGNLocked(PGNHeaders{})
+	g.Mu.RUnlock()
+	if got := snap.PGN(PGNHeaders{}); got != want {
+		t.Fatalf("snapshot PGN %q, game PGN %q", got, want)
+	}
+
+	if err := g.MakeMove("e7e5"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if err := g.Resign(chess.White); err != nil {
+		t.Fatalf("resign: %v", err)
+	}
+	if len(snap.UCI) != 1 || snap.PGN(PGNHeaders{}) != want || snap.Position().Turn() != chess.Black {
+		t.Fatalf("snapshot changed after the game moved on")
+	}
+	next := g.Snapshot()
+	if next == snap || len(next.UCI) != 2 || next.Termination != TerminationResignation {
+		t.Fatalf("expected a fresh snapshot, got %+v", next)
+	}
+}
+
+func TestSnapshotAtPly(t *testing.T) {
+	g := newTestGame()
+	for _, uci := range []string{"e2e4", "e7e5", "g1f3"} {
+		if err := g.MakeMove(uci); err != nil {
+			t.Fatalf("move %s: %v", uci, err)
+		}
+	}
+	snap := g.Snapshot()
+	p, ok := snap.AtPly(2)
+	if !ok || p.Plies != 3 || p.UCI != "e7e5" || p.SAN != "e5" ||
+		p.FEN != "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2" {
+		t.Fatalf("unexpected position at ply 2: %+v", p)
+	}
+	if p, ok := snap.AtPly(0); !ok || p.UCI != "" || p.FEN != "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1" {
+		t.Fatalf("expected the starting position at ply 0, got %+v", p)
+	}
+	for _, ply := range []int{-1, 4} {
+		if _, ok := snap.AtPly(ply); ok {
+			t.Errorf("AtPly(%d) should not exist", ply)
+		}
+	}
+}
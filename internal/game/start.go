@@ -0,0 +1,36 @@
+package game
+
+import (
+	"github.com/corentings/chess/v2"
+
+	"tinychess/pkg/chesscore"
+)
+
+// GameFromFEN returns a new chess game from fen, or from the standard
+// position when fen is empty. Use it instead of chess.FEN and chess.NewGame,
+// which are not safe to call concurrently.
+func GameFromFEN(fen string) (*chess.Game, error) {
+	return chesscore.GameFromFEN(fen)
+}
+
+// newChessGame starts a game from the standard position, or from fen when it
+// is set. fen must already be valid, see ParseStartFEN.
+func newChessGame(fen string) *chess.Game {
+	g, err := GameFromFEN(fen)
+	if err != nil {
+		g, _ = GameFromFEN("")
+	}
+	return g
+}
+
+// ParseStartFEN validates a custom starting position and returns it in
+// canonical form; see chesscore.ParseStartFEN.
+func ParseStartFEN(fen string) (string, error) {
+	return chesscore.ParseStartFEN(fen)
+}
+
+// StartFEN returns the game's custom starting position, or "" for the
+// standard one. It is fixed when the game is created.
+func (g *Game) StartFEN() string {
+	return g.startFEN
+}
@@ -0,0 +1,52 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStartFEN(t *testing.T) {
+	cases := map[string]bool{
+		"": true,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1": true,
+		"8/8/8/4k3/8/8/4P3/4K3 w - - 0 1":                          true,
+		"not a fen":                                                false,
+		"8/8/8/8/8/8/4P3/4K3 w - - 0 1":                            false, // no black king
+		"4k3/8/8/8/8/8/4R3/4K3 w - - 0 1":                          false, // black in check with white to move
+		"7k/5Q2/6K1/8/8/8/8/8 b - - 0 1":                           false, // stalemate
+	}
+	for fen, ok := range cases {
+		got, err := ParseStartFEN(fen)
+		if (err == nil) != ok {
+			t.Fatalf("ParseStartFEN(%q) error = %v, want ok=%v", fen, err, ok)
+		}
+		if ok && strings.HasPrefix(fen, "rnbqkbnr") && got != "" {
+			t.Fatalf("standard position should normalize to empty, got %q", got)
+		}
+	}
+}
+
+func TestCustomStartPosition(t *testing.T) {
+	start := "8/8/8/4k3/8/8/4P3/4K3 b - - 0 1"
+	g := newTestGame()
+	g.startFEN = start
+	g.g = newChessGame(start)
+	for _, uci := range []string{"e5d5", "e2e4"} {
+		if err := g.MakeMove(uci); err != nil {
+			t.Fatalf("move %s: %v", uci, err)
+		}
+	}
+
+	g.Mu.Lock()
+	state := g.StateLocked()
+	g.Mu.Unlock()
+	if state.StartFEN != start || len(state.UCI) != 2 || state.UCI[0] != "e5d5" {
+		t.Fatalf("unexpected state: %+v", state)
+	}
+	if len(state.Moves) != 2 || state.Moves[1] != "e4+" {
+		t.Fatalf("expected SAN from the custom start, got %v", state.Moves)
+	}
+	if !strings.Contains(state.PGN, `[FEN "`+start+`"]`) || !strings.Contains(state.PGN, `[SetUp "1"]`) {
+		t.Fatalf("expected SetUp and FEN tags, got %q", state.PGN)
+	}
+}
@@ -0,0 +1,121 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TimeControl describes a clock setting offered by the instance. Correspondence
+// controls use Days per move instead of a base time.
+type TimeControl struct {
+	Name      string `json:"name"`
+	Category  string `json:"category"`
+	Initial   int    `json:"initial"`   // seconds
+	Increment int    `json:"increment"` // seconds per move
+	Days      int    `json:"days,omitempty"`
+}
+
+// String returns the compact form used in requests, e.g. "5+3" or "3d".
+func (tc TimeControl) String() string {
+	if tc.Days > 0 {
+		return fmt.Sprintf("%dd", tc.Days)
+	}
+	return fmt.Sprintf("%d+%d", tc.Initial/60, tc.Increment)
+}
+
+// DefaultTimeControls are the presets offered when the instance does not
+// configure its own.
+var DefaultTimeControls = []TimeControl{
+	{Name: "1+0", Category: "bullet", Initial: 60},
+	{Name: "2+1", Category: "bullet", Initial: 120, Increment: 1},
+	{Name: "3+0", Category: "blitz", Initial: 180},
+	{Name: "3+2", Category: "blitz", Initial: 180, Increment: 2},
+	{Name: "5+0", Category: "blitz", Initial: 300},
+	{Name: "5+3", Category: "blitz", Initial: 300, Increment: 3},
+	{Name: "10+0", Category: "rapid", Initial: 600},
+	{Name: "10+5", Category: "rapid", Initial: 600, Increment: 5},
+	{Name: "15+10", Category: "rapid", Initial: 900, Increment: 10},
+	{Name: "30+0", Category: "classical", Initial: 1800},
+	{Name: "30+20", Category: "classical", Initial: 1800, Increment: 20},
+	{Name: "1 day", Category: "correspondence", Days: 1},
+	{Name: "3 days", Category: "correspondence", Days: 3},
+	{Name: "7 days", Category: "correspondence", Days: 7},
+}
+
+// ParseTimeControl parses "minutes+increment" or "Nd" into a TimeControl and
+// assigns its category from the estimated game duration.
+func ParseTimeControl(s string) (TimeControl, error) {
+	s = strings.TrimSpace(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return TimeControl{}, fmt.Errorf("bad time control %q", s)
+		}
+		return TimeControl{Name: s, Category: "correspondence", Days: n}, nil
+	}
+	mins, inc, ok := strings.Cut(s, "+")
+	if !ok {
+		return TimeControl{}, fmt.Errorf("bad time control %q", s)
+	}
+	m, err := strconv.Atoi(mins)
+	if err != nil || m <= 0 {
+		return TimeControl{}, fmt.Errorf("bad time control %q", s)
+	}
+	i, err := strconv.Atoi(inc)
+	if err != nil || i < 0 {
+		return TimeControl{}, fmt.Errorf("bad time control %q", s)
+	}
+	tc := TimeControl{Name: s, Initial: m * 60, Increment: i}
+	// Estimated duration for 40 moves, matching the usual category bounds.
+	switch est := tc.Initial + 40*tc.Increment; {
+	case est < 180:
+		tc.Category = "bullet"
+	case est < 480:
+		tc.Category = "blitz"
+	case est < 1500:
+		tc.Category = "rapid"
+	default:
+		tc.Category = "classical"
+	}
+	return tc, nil
+}
+
+// ParseTimeControls parses a comma-separated preset list.
+func ParseTimeControls(s string) ([]TimeControl, error) {
+	var out []TimeControl
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		tc, err := ParseTimeControl(part)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, tc)
+	}
+	return out, nil
+}
+
+// TimeControls returns the presets offered by the hub.
+func (h *Hub) TimeControls() []TimeControl {
+	if len(h.Presets) == 0 {
+		return DefaultTimeControls
+	}
+	return h.Presets
+}
+
+// ValidateTimeControl resolves a submitted time control against the hub's
+// presets and returns the matching preset.
+func (h *Hub) ValidateTimeControl(s string) (TimeControl, error) {
+	tc, err := ParseTimeControl(s)
+	if err != nil {
+		return TimeControl{}, err
+	}
+	for _, p := range h.TimeControls() {
+		if p.Initial == tc.Initial && p.Increment == tc.Increment && p.Days == tc.Days {
+			return p, nil
+		}
+	}
+	return TimeControl{}, fmt.Errorf("time control %s is not offered", s)
+}
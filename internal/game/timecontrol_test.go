@@ -0,0 +1,48 @@
+package game
+
+import "testing"
+
+func TestParseTimeControl(t *testing.T) {
+	cases := map[string]string{
+		"1+0":   "bullet",
+		"3+2":   "blitz",
+		"10+5":  "rapid",
+		"30+20": "classical",
+		"3d":    "correspondence",
+	}
+	for in, want := range cases {
+		tc, err := ParseTimeControl(in)
+		if err != nil {
+			t.Fatalf("parse %s: %v", in, err)
+		}
+		if tc.Category != want {
+			t.Fatalf("%s: expected %s, got %s", in, want, tc.Category)
+		}
+		if tc.String() != in {
+			t.Fatalf("%s: round trip gave %s", in, tc.String())
+		}
+	}
+	for _, bad := range []string{"", "5", "a+b", "0+0", "-1d"} {
+		if _, err := ParseTimeControl(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestValidateTimeControlAgainstPresets(t *testing.T) {
+	h := NewHub(nil)
+	if _, err := h.ValidateTimeControl("5+3"); err != nil {
+		t.Fatalf("expected default preset to validate: %v", err)
+	}
+	if _, err := h.ValidateTimeControl("7+7"); err == nil {
+		t.Fatalf("expected unknown preset to be rejected")
+	}
+
+	h.Presets = []TimeControl{{Name: "7+7", Initial: 420, Increment: 7}}
+	if _, err := h.ValidateTimeControl("7+7"); err != nil {
+		t.Fatalf("expected configured preset to validate: %v", err)
+	}
+	if _, err := h.ValidateTimeControl("5+3"); err == nil {
+		t.Fatalf("expected default preset to be rejected once configured")
+	}
+}
@@ -0,0 +1,145 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/corentings/chess/v2"
+
+	"tinychess/internal/storage"
+)
+
+// Timeline event kinds.
+const (
+	EventJoined      = "joined"
+	EventReleased    = "released"
+	EventResigned    = "resigned"
+	EventAdjudicated = "adjudicated"
+	EventAbandoned   = "abandoned"
+	EventMoveLimit   = "move-limit"
+)
+
+// TimelineEvent is something that happened in a game other than a move. Ply
+// is the number of moves played when it happened.
+type TimelineEvent struct {
+	Kind  string `json:"kind"`
+	Color string `json:"color,omitempty"`
+	Ply   int    `json:"ply"`
+	At    int64  `json:"at"`
+}
+
+// Comment describes the event for a PGN comment.
+func (e TimelineEvent) Comment() string {
+	side := ""
+	switch e.Color {
+	case chess.White.String():
+		side = "White "
+	case chess.Black.String():
+		side = "Black "
+	}
+	switch e.Kind {
+	case EventJoined:
+		return side + "joined"
+	case EventReleased:
+		return side + "seat released"
+	case EventResigned:
+		return side + "resigned"
+	case EventAdjudicated:
+		return "Game adjudicated"
+	case EventAbandoned:
+		return "Game abandoned"
+	case EventMoveLimit:
+		return "Move limit reached"
+	}
+	return side + e.Kind
+}
+
+// recordEventLocked appends an event at the current ply.
+func (g *Game) recordEventLocked(kind string, color chess.Color) {
+	e := TimelineEvent{Kind: kind, At: time.Now().UnixMilli()}
+	if g.g != nil {
+		e.Ply = len(g.g.Moves())
+	}
+	if color != chess.NoColor {
+		e.Color = color.String()
+	}
+	g.timeline = append(g.timeline, e)
+}
+
+// Timeline returns a copy of the game's non-move events in order.
+func (g *Game) Timeline() []TimelineEvent {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return append([]TimelineEvent{}, g.timeline...)
+}
+
+// SaveTimeline persists the game's timeline.
+func (h *Hub) SaveTimeline(ctx context.Context, g *Game) error {
+	if h.Store == nil {
+		return nil
+	}
+	data, err := json.Marshal(g.Timeline())
+	if err != nil {
+		return err
+	}
+	timeline := string(data)
+	return h.Store.SaveGameState(ctx, g.ID.UUID(), storage.GameStateUpdate{Timeline: &timeline})
+}
+
+// parseTimeline restores a timeline from its stored JSON form.
+func parseTimeline(data string) []TimelineEvent {
+	var events []TimelineEvent
+	if data == "" || json.Unmarshal([]byte(data), &events) != nil {
+		return nil
+	}
+	return events
+}
+
+// PGNWithTimeline rewrites the movetext of a PGN export in the given notation
+// with each timeline event as a comment after the move it followed. Events
+// before the first move lead the movetext.
+func PGNWithTimeline(pgn, start string, uci []string, n Notation, events []TimelineEvent) string {
+	comments := make(map[int][]string)
+	for _, e := range events {
+		comments[e.Ply] = append(comments[e.Ply], e.Comment())
+	}
+	return rewriteMovetext(pgn, FormatMoves(start, uci, n), comments)
+}
+
+// rewriteMovetext replaces the movetext of pgn with moves, keeping its tag
+// pairs and result. comments are placed after the ply they are keyed by.
+func rewriteMovetext(pgn string, moves []string, comments map[int][]string) string {
+	tags, movetext, ok := strings.Cut(pgn, "\n\n")
+	if !ok {
+		tags, movetext = "", pgn
+	}
+	result := "*"
+	if fields := strings.Fields(movetext); len(fields) > 0 {
+		result = fields[len(fields)-1]
+	}
+
+	var b strings.Builder
+	writeComments := func(ply int) {
+		for _, c := range comments[ply] {
+			b.WriteString("{" + strings.ReplaceAll(c, "}", "") + "} ")
+		}
+	}
+	writeComments(0)
+	for i, m := range moves {
+		if i%2 == 0 {
+			b.WriteString(strconv.Itoa(i/2+1) + ". ")
+		} else if len(comments[i]) > 0 {
+			b.WriteString(strconv.Itoa(i/2+1) + "... ")
+		}
+		b.WriteString(m + " ")
+		writeComments(i + 1)
+	}
+	b.WriteString(result)
+	if tags == "" {
+		return b.String()
+	}
+	return tags + "\n\n" + b.String()
+}
@@ -0,0 +1,68 @@
+package game
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestTimelinePersistsAndExports(t *testing.T) {
+	store := newTestHubStore(t)
+	ctx := context.Background()
+	h := NewHub(store)
+	owner, guest := uuid.NewString(), uuid.NewString()
+	id, ownerColor, err := h.CreateGame(ctx, owner, GameOptions{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	g, _, err := h.Get(ctx, id, guest)
+	if err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	if _, _, err := h.Get(ctx, id, guest); err != nil {
+		t.Fatalf("rejoin: %v", err)
+	}
+	if err := g.MakeMove("e2e4"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if err := g.Resign(ownerColor.Other()); err != nil {
+		t.Fatalf("resign: %v", err)
+	}
+	if err := h.SaveTimeline(ctx, g); err != nil {
+		t.Fatalf("save timeline: %v", err)
+	}
+
+	reloaded, _, err := NewHub(store).Get(ctx, id, "")
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	events := reloaded.Timeline()
+	var kinds []string
+	for _, e := range events {
+		kinds = append(kinds, e.Kind+":"+e.Color)
+	}
+	o, p := ownerColor.String(), ownerColor.Other().String()
+	if got, want := strings.Join(kinds, " "), "joined:"+o+" joined:"+p+" resigned:"+p; got != want {
+		t.Fatalf("unexpected timeline %q", got)
+	}
+	if events[2].Ply != 1 {
+		t.Fatalf("resignation should follow ply 1, got %d", events[2].Ply)
+	}
+
+	pgn := "[Result \"1-0\"]\n\n1. e4 1-0"
+	got := PGNWithTimeline(pgn, "", []string{"e2e4"}, NotationSAN, events)
+	want := "[Result \"1-0\"]\n\n{" + events[0].Comment() + "} {" + events[1].Comment() + "} 1. e4 {" + events[2].Comment() + "} 1-0"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestPGNWithTimelineAfterWhiteMove(t *testing.T) {
+	events := []TimelineEvent{{Kind: EventReleased, Color: "b", Ply: 1}}
+	got := PGNWithTimeline("1. e4 e5 *", "", []string{"e2e4", "e7e5"}, NotationSAN, events)
+	if got != "1. e4 {Black seat released} 1... e5 *" {
+		t.Fatalf("unexpected movetext %q", got)
+	}
+}
@@ -0,0 +1,111 @@
+package game
+
+import (
+	"strings"
+	"time"
+)
+
+// PaceWindow is the number of each side's latest moves averaged in Pace.
+const PaceWindow = 10
+
+// MoveTime records when a ply was played and how long the mover took, measured
+// from the previous ply or, for the first ply, from the game's creation.
+type MoveTime struct {
+	Ply     int    `json:"ply"`
+	UCI     string `json:"uci"`
+	At      int64  `json:"at"`
+	ThinkMs int64  `json:"thinkMs"`
+}
+
+// moveTimesLocked returns the timing of every ply in the mainline. Plies
+// without a recorded timestamp report zero.
+func (g *Game) moveTimesLocked(uci []string) []MoveTime {
+	out := make([]MoveTime, 0, len(uci))
+	prev := g.CreatedAt
+	for i, m := range uci {
+		mt := MoveTime{Ply: i + 1, UCI: m}
+		if i < len(g.playedAt) && !g.playedAt[i].IsZero() {
+			at := g.playedAt[i]
+			mt.At = at.UnixMilli()
+			if think := at.Sub(prev) - g.paused[i]; !prev.IsZero() && think > 0 {
+				mt.ThinkMs = think.Milliseconds()
+			}
+			prev = at
+		}
+		out = append(out, mt)
+	}
+	return out
+}
+
+// MoveTimes returns the timing of every ply in the mainline.
+func (g *Game) MoveTimes() []MoveTime {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return g.moveTimesLocked(g.MovesUCI())
+}
+
+// ThinkTime converts a MoveTime's think time to a duration.
+func (m MoveTime) ThinkTime() time.Duration {
+	return time.Duration(m.ThinkMs) * time.Millisecond
+}
+
+// Pace is the players' move timing for overlays and TV views: a rolling
+// average per side and how long the side to move has been thinking.
+type Pace struct {
+	// WhiteAvgMs and BlackAvgMs average each side's last PaceWindow timed
+	// moves; zero before a side has one.
+	WhiteAvgMs int64 `json:"whiteAvgMs"`
+	BlackAvgMs int64 `json:"blackAvgMs"`
+	// ThinkingSince is when the side to move started thinking, in Unix
+	// milliseconds and moved forward by any pause, so clients can keep
+	// counting; ThinkingMs is the time spent when the state was built. Both
+	// are zero once the game is over.
+	ThinkingSince int64 `json:"thinkingSince,omitempty"`
+	ThinkingMs    int64 `json:"thinkingMs"`
+	// Paused is set while maintenance stops the clock; ThinkingSince is
+	// left out so clients stop counting.
+	Paused bool `json:"paused,omitempty"`
+}
+
+// paceLocked computes the game's Pace from its move timings.
+func (g *Game) paceLocked(times []MoveTime, now time.Time) Pace {
+	blackFirst := len(strings.Fields(g.startFEN)) > 1 && strings.Fields(g.startFEN)[1] == "b"
+	var sums, counts [2]int64
+	for i := len(times) - 1; i >= 0; i-- {
+		side := i % 2
+		if blackFirst {
+			side = 1 - side
+		}
+		if times[i].ThinkMs <= 0 || counts[side] == PaceWindow {
+			continue
+		}
+		sums[side] += times[i].ThinkMs
+		counts[side]++
+	}
+	var p Pace
+	if counts[0] > 0 {
+		p.WhiteAvgMs = sums[0] / counts[0]
+	}
+	if counts[1] > 0 {
+		p.BlackAvgMs = sums[1] / counts[1]
+	}
+	if g.overLocked() {
+		return p
+	}
+	since := g.CreatedAt
+	if n := len(times); n > 0 && times[n-1].At > 0 {
+		since = time.UnixMilli(times[n-1].At)
+	}
+	if since.IsZero() {
+		return p
+	}
+	since = since.Add(g.paused[len(times)])
+	if !g.frozenAt.IsZero() {
+		p.Paused = true
+		p.ThinkingMs = max(0, g.frozenAt.Sub(since).Milliseconds())
+		return p
+	}
+	p.ThinkingSince = since.UnixMilli()
+	p.ThinkingMs = max(0, now.Sub(since).Milliseconds())
+	return p
+}
@@ -2,36 +2,184 @@ package game
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/corentings/chess/v2"
+	"tinychess/internal/engine"
 	"tinychess/internal/storage"
 )
 
 // Hub manages all active chess games
 type Hub struct {
-	Mu    sync.Mutex
-	Games map[string]*Game
-	Store *storage.Store
+	// shards hold the loaded games, see Lookup and Get.
+	shards [hubShards]hubShard
+	Store  *storage.Store
+	// Presets are the time controls offered by this instance; DefaultTimeControls
+	// are used when empty.
+	Presets []TimeControl
+	// Engine scores positions after each move when configured.
+	Engine *engine.Engine
+	// Images caches rendered board images; nil disables caching.
+	Images *ImageCache
+	// Seats signs the tokens that prove a client holds its seat.
+	Seats *SeatSigner
+	// Tablebase answers endgame practice games when configured.
+	Tablebase Tablebase
+	// Pairings holds the codes that seat a second device, see Pairings.
+	Pairings *Pairings
+	// PostMortemSpectators lets spectators move in post-mortem rooms, not
+	// just the players; see OpenPostMortem.
+	PostMortemSpectators bool
+
+	// mu guards maintenance, the instance's maintenance mode, and
+	// toggling serializes SetMaintenance.
+	mu          sync.RWMutex
+	toggling    sync.Mutex
+	maintenance Maintenance
+
+	// analysisQueue feeds finished games to RunAnalysis.
+	analysisQueue chan *Game
+
+	// evictMu guards evictions, the idle sweeps' counts.
+	evictMu   sync.Mutex
+	evictions EvictionStats
 }
 
 // Game represents a single chess game with its state and watchers
 type Game struct {
-	ID         string
-	Mu         sync.Mutex
+	ID         GameID
+	Mu         sync.RWMutex
 	g          *chess.Game
-	Watchers   map[chan []byte]struct{}
+	Watchers   map[chan []byte]*watcher
 	LastReact  map[string]time.Time
 	LastSeen   time.Time
+	CreatedAt  time.Time
 	OwnerID    string
 	OwnerColor chess.Color
 	Clients    map[string]chess.Color // clientId -> color
+	// TimeControl is the compact time control chosen at creation, if any.
+	TimeControl string
+	// Language localizes server-generated text for everyone in the game. Empty
+	// keeps the default English wording.
+	Language string
+	// frozenAt is when maintenance stopped the game's clock, zero while it
+	// runs; paused is how long it stood still during each ply, by index,
+	// which is left out of that ply's think time.
+	frozenAt time.Time
+	paused   map[int]time.Duration
+	// startFEN is the custom starting position, empty for the standard one.
+	startFEN string
+	// termination overrides the reason derived from the chess outcome for games
+	// ended by the instance (adjudicated or abandoned).
+	termination string
+	// eval is the latest engine score, see Hub.Evaluate.
+	eval *Evaluation
+	// lastMove describes the latest move made through MakeMove.
+	lastMove *LastMove
+	// captured tracks captures per side as moves are made, so promotions do
+	// not skew counts derived from the board.
+	captured Captured
+	// timeline holds non-move events such as joins and resignations.
+	timeline []TimelineEvent
+	// snapshot caches the latest Snapshot for long-running readers.
+	snapshot atomic.Pointer[Snapshot]
+	// analysis is the post-game report, see Hub.QueueAnalysis.
+	analysis       *AnalysisReport
+	analysisQueued bool
+	// lastHint applies HintCooldown per client.
+	lastHint map[string]time.Time
+	// lastChat applies ChatCooldown per sender.
+	lastChat map[string]time.Time
+	// chat holds the last ChatHistoryLimit messages for replay.
+	chat []ChatPayload
+	// chatters maps the participant IDs of chat senders to their client
+	// IDs, see chatterLocked.
+	chatters map[string]string
+	// muted lists participants the owner has silenced.
+	muted map[string]bool
+	// joinCodeHash is the bcrypt hash of a private game's join code.
+	joinCodeHash string
+	// codeForSpectators makes spectators present the join code too.
+	codeForSpectators bool
+	// inviteOnly seats the second player only through a play invite.
+	inviteOnly bool
+	// hotSeat lets the owner move both colors, see HotSeat.
+	hotSeat bool
+	// houseRules are the game's optional house rules, nil for plain chess.
+	houseRules *HouseRules
+	// names holds the display names of seated clients.
+	names map[string]string
+	// connected counts open event streams per client, see Connect.
+	connected map[string]int
+	// playedAt holds when each mainline ply was played.
+	playedAt []time.Time
+	// training is set for endgame practice games, see Training.
+	training *Training
+	// moveIDs holds the ids of the latest moves made with MakeMoveOnce.
+	moveIDs []string
+	// reopenAt is the end of the cool-off a broadcast is scheduled for,
+	// see scheduleReopenLocked.
+	reopenAt time.Time
+	// postMortem is the post-mortem room of a finished game, and
+	// postMortemOf the game a room analyses; see OpenPostMortem.
+	// postMortemOpen lets a room's spectators move too.
+	postMortem     GameID
+	postMortemOf   GameID
+	postMortemOpen bool
+	// analysts holds the clients already handed a seat token as analysts
+	// of an open post-mortem room; see ClaimAnalyst.
+	analysts map[string]bool
+}
+
+// GameOptions holds settings chosen when a game is created.
+type GameOptions struct {
+	TimeControl string
+	Language    string
+	// StartFEN is a custom starting position, see ParseStartFEN.
+	StartFEN string
+	// JoinCode makes the game private: seats are only handed to clients that
+	// present it, and to spectators too when CodeForSpectators is set.
+	JoinCode          string
+	CodeForSpectators bool
+	// InviteOnly keeps the open seat for whoever holds the play invite, so
+	// the spectator invite can be shared publicly.
+	InviteOnly bool
+	// HotSeat is pass-and-play on one device: the owner moves both colors.
+	HotSeat bool
+	// HouseRules adds server-enforced rules such as a move limit, see
+	// ParseHouseRules.
+	HouseRules *HouseRules
+	// Color seats the owner on that side; NoColor picks one at random.
+	Color chess.Color
+	// Ending makes the game endgame practice against the tablebase, see
+	// Hub.StartTraining.
+	Ending string
 }
 
-// MoveRequest represents a move request from a client
+// MoveRequest represents a move request from a client. Promotion optionally
+// names the piece a pawn promotes to ("q", "r", "b" or "n"); it may also be
+// given as the fifth character of UCI.
 type MoveRequest struct {
-	UCI      string `json:"uci"`
-	ClientID string `json:"clientId"`
+	UCI string `json:"uci" example:"g1f3"`
+	// SAN is the move in standard algebraic notation, e.g. "Nf3", sent
+	// instead of UCI.
+	SAN       string `json:"san,omitempty" example:"Nf3"`
+	ClientID  string `json:"clientId" required:"true"`
+	Promotion string `json:"promotion,omitempty" enum:"q,r,b,n"`
+	SeatToken string `json:"seatToken"`
+	// MoveID is chosen by the client so a move retried after a lost
+	// response, or replayed from an offline queue, is played only once.
+	MoveID string `json:"moveId,omitempty" maxLength:"64" doc:"Chosen by the client; a retry with the same id is answered with duplicate set instead of playing twice. May also be sent as the Idempotency-Key header."`
+}
+
+// ValidPromotion reports whether p names a piece a pawn may promote to.
+func ValidPromotion(p string) bool {
+	switch p {
+	case "q", "r", "b", "n":
+		return true
+	}
+	return false
 }
 
 // ReactionRequest represents a reaction request from a client
@@ -42,14 +190,90 @@ type ReactionRequest struct {
 
 // GameState represents the current state of a game
 type GameState struct {
-	Kind     string   `json:"kind"`
-	FEN      string   `json:"fen"`
-	Turn     string   `json:"turn"`
-	Status   string   `json:"status"`
-	PGN      string   `json:"pgn"`
-	UCI      []string `json:"uci"`
-	LastSeen int64    `json:"lastSeen"`
-	Watchers int      `json:"watchers"`
+	Schema      int      `json:"schema"`
+	Kind        string   `json:"kind"`
+	FEN         string   `json:"fen"`
+	Turn        string   `json:"turn" enum:"w,b"`
+	Status      string   `json:"status"`
+	Termination string   `json:"termination,omitempty"`
+	TimeControl string   `json:"timeControl,omitempty"`
+	StartFEN    string   `json:"startFen,omitempty"`
+	PGN         string   `json:"pgn"`
+	UCI         []string `json:"uci"`
+	Moves       []string `json:"moves"`
+	Material    Material `json:"material"`
+	// Mobility is left out once the game is over.
+	Mobility  *Mobility   `json:"mobility,omitempty"`
+	Eval      *Evaluation `json:"eval,omitempty"`
+	LastMove  *LastMove   `json:"lastMove,omitempty"`
+	Captured  Captured    `json:"captured"`
+	MoveTimes []MoveTime  `json:"moveTimes"`
+	Pace      Pace        `json:"pace"`
+	LastSeen  int64       `json:"lastSeen"`
+	Watchers  int         `json:"watchers"`
+	// White and Black are the players' display names, if they set one.
+	White string `json:"white,omitempty"`
+	Black string `json:"black,omitempty"`
+	// Participants is the live participant list, see Participant.
+	Participants []Participant `json:"participants"`
+	// Training is the trainee's progress in endgame practice games.
+	Training *Training `json:"training,omitempty"`
+	// HotSeat is set for pass-and-play games, see Game.HotSeat.
+	HotSeat bool `json:"hotSeat,omitempty"`
+	// HouseRules are the game's house rules, absent for plain chess.
+	HouseRules *HouseRules `json:"houseRules,omitempty"`
+	// CoolOffUntil is when reactions and chat reopen after a decisive
+	// result, in Unix milliseconds; absent while they are open.
+	CoolOffUntil int64 `json:"coolOffUntil,omitempty"`
+	// PostMortem is the id of a finished game's post-mortem room, and
+	// PostMortemOf, in a room, the id of the game it analyses.
+	PostMortem   string `json:"postMortem,omitempty" format:"uuid"`
+	PostMortemOf string `json:"postMortemOf,omitempty" format:"uuid"`
+	// PostMortemOpen is set in rooms where spectators move too.
+	PostMortemOpen bool `json:"postMortemOpen,omitempty"`
+}
+
+// Termination reasons recorded for finished games.
+const (
+	TerminationCheckmate            = "checkmate"
+	TerminationResignation          = "resignation"
+	TerminationDrawAgreement        = "agreement"
+	TerminationStalemate            = "stalemate"
+	TerminationRepetition           = "repetition"
+	TerminationFiftyMove            = "fifty-move"
+	TerminationInsufficientMaterial = "insufficient-material"
+	TerminationTimeout              = "timeout"
+	TerminationAbandonment          = "abandonment"
+	TerminationAdjudication         = "adjudication"
+	TerminationMoveLimit            = "move-limit"
+)
+
+// NoticePayload is a human-readable message broadcast to a game's watchers.
+type NoticePayload struct {
+	Schema  int    `json:"schema"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	At      int64  `json:"at"`
+}
+
+// ReconnectPayload asks a client to reconnect, to URL when set, because the
+// instance is shutting down or handing its games to another deployment.
+type ReconnectPayload struct {
+	Schema  int    `json:"schema"`
+	Kind    string `json:"kind"`
+	URL     string `json:"url,omitempty"`
+	RetryMs int64  `json:"retryMs"`
+}
+
+// ReviewPayload tells a game's watchers that a coach published a review of
+// the game. Color is the side of the player who asked for it, "w" or "b" as
+// in ClientState, so only their page offers it; the review itself is fetched
+// from the API.
+type ReviewPayload struct {
+	Schema   int    `json:"schema"`
+	Kind     string `json:"kind"`
+	ReviewID string `json:"reviewId"`
+	Color    string `json:"color"`
 }
 
 // ClientState represents the state sent to a specific client, including their color
@@ -58,10 +282,19 @@ type ClientState struct {
 	Color    *string `json:"color"`
 	Role     string  `json:"role"`
 	ClientID string  `json:"clientId"`
+	// ParticipantID is the ID the client is listed under, see
+	// Game.ParticipantID.
+	ParticipantID string `json:"participantId,omitempty"`
+	// Owner is set for the game's owner, who may moderate chat.
+	Owner bool `json:"owner,omitempty"`
+	// SeatToken must accompany moves and seat changes made by a seated
+	// client. It is only sent to that client.
+	SeatToken string `json:"seatToken,omitempty"`
 }
 
 // ReactionPayload represents a reaction broadcast
 type ReactionPayload struct {
+	Schema int    `json:"schema"`
 	Kind   string `json:"kind"`
 	Emoji  string `json:"emoji"`
 	At     int64  `json:"at"`
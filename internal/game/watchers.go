@@ -0,0 +1,110 @@
+package game
+
+import (
+	"encoding/json"
+
+	"tinychess/internal/logging"
+)
+
+// MaxWatcherDrops is how many messages in a row a watcher may miss, because
+// its channel stayed full, before it is evicted. Its stream then ends and
+// the client reconnects to a fresh state.
+const MaxWatcherDrops = 32
+
+// watcher is what the game knows about one subscriber's delivery.
+type watcher struct {
+	// drops counts the messages missed in a row.
+	drops int
+	// stale is set once a message was missed, until the watcher has been
+	// sent the current state again.
+	stale bool
+	// evicted is closed when the watcher is evicted.
+	evicted chan struct{}
+}
+
+// AddWatcher adds a new watcher channel
+func (g *Game) AddWatcher(ch chan []byte) {
+	g.Mu.Lock()
+	g.Watchers[ch] = &watcher{evicted: make(chan struct{})}
+	g.Mu.Unlock()
+}
+
+// RemoveWatcher removes a watcher channel
+func (g *Game) RemoveWatcher(ch chan []byte) {
+	g.Mu.Lock()
+	delete(g.Watchers, ch)
+	g.Mu.Unlock()
+}
+
+// Evicted returns a channel that is closed when the watcher ch is evicted
+// for not keeping up, see MaxWatcherDrops. Streams stop when it closes. It
+// is closed already for channels that are not watching.
+func (g *Game) Evicted(ch chan []byte) <-chan struct{} {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	if w, ok := g.Watchers[ch]; ok {
+		return w.evicted
+	}
+	closed := make(chan struct{})
+	close(closed)
+	return closed
+}
+
+// Resync sends the current state to the watcher ch if it missed messages
+// and has room for it again, so a watcher that fell behind in a quiet game
+// need not wait for the next broadcast to catch up.
+func (g *Game) Resync(ch chan []byte) {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	if w, ok := g.Watchers[ch]; ok && w.stale {
+		g.resyncLocked(ch, w, nil)
+	}
+}
+
+// deliverLocked sends data to every watcher without blocking. A watcher that
+// missed messages is first sent the current state, once it has room again;
+// state says whether data is that state already. Watchers that miss
+// MaxWatcherDrops messages in a row are evicted. Callers must hold g.Mu for
+// writing.
+func (g *Game) deliverLocked(data []byte, state bool) {
+	var snapshot []byte
+	for ch, w := range g.Watchers {
+		if w.stale && !state {
+			snapshot = g.resyncLocked(ch, w, snapshot)
+		}
+		select {
+		case ch <- data:
+			w.drops = 0
+			if state {
+				w.stale = false
+			}
+		default:
+			w.drops++
+			w.stale = true
+			if w.drops >= MaxWatcherDrops {
+				logging.Debugf("game %s: evicting a watcher that missed %d messages", g.ID, w.drops)
+				close(w.evicted)
+				delete(g.Watchers, ch)
+			}
+		}
+	}
+}
+
+// resyncLocked sends the current state to a stale watcher if it has room,
+// encoding it unless snapshot already holds it, and returns the encoded
+// state.
+func (g *Game) resyncLocked(ch chan []byte, w *watcher, snapshot []byte) []byte {
+	if len(ch) >= cap(ch) {
+		return snapshot
+	}
+	if snapshot == nil {
+		snapshot, _ = json.Marshal(g.StateLocked())
+	}
+	select {
+	case ch <- snapshot:
+		w.stale = false
+		w.drops = 0
+	default:
+	}
+	return snapshot
+}
@@ -0,0 +1,73 @@
+package game
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSlowWatcherResyncs(t *testing.T) {
+	g := newTestGame()
+	ch := make(chan []byte, 2)
+	g.AddWatcher(ch)
+	g.BroadcastNotice("one")
+	g.BroadcastNotice("two")
+	g.BroadcastNotice("missed")
+	<-ch
+	<-ch
+	g.BroadcastNotice("next")
+	var state GameState
+	if err := json.Unmarshal(<-ch, &state); err != nil || state.Kind != "state" {
+		t.Fatalf("expected a state snapshot after missing a message, got %+v %v", state, err)
+	}
+	var notice NoticePayload
+	if err := json.Unmarshal(<-ch, &notice); err != nil || notice.Message != "next" {
+		t.Fatalf("expected the next notice after the snapshot, got %+v %v", notice, err)
+	}
+	g.BroadcastNotice("again")
+	if err := json.Unmarshal(<-ch, &notice); err != nil || notice.Message != "again" {
+		t.Fatalf("expected no second snapshot once caught up, got %+v %v", notice, err)
+	}
+}
+
+func TestSlowWatcherResyncOnRequest(t *testing.T) {
+	g := newTestGame()
+	ch := make(chan []byte, 1)
+	g.AddWatcher(ch)
+	g.BroadcastNotice("one")
+	g.BroadcastNotice("missed")
+	<-ch
+	g.Resync(ch)
+	var state GameState
+	if err := json.Unmarshal(<-ch, &state); err != nil || state.Kind != "state" {
+		t.Fatalf("expected Resync to send a snapshot, got %+v %v", state, err)
+	}
+	g.Resync(ch)
+	if len(ch) != 0 {
+		t.Fatalf("expected no snapshot for a watcher that is caught up")
+	}
+}
+
+func TestStuckWatcherEvicted(t *testing.T) {
+	g := newTestGame()
+	ch := make(chan []byte, 1)
+	g.AddWatcher(ch)
+	evicted := g.Evicted(ch)
+	// The first message fills the channel; the rest are dropped.
+	for i := 0; i <= MaxWatcherDrops; i++ {
+		g.BroadcastNotice("hello")
+	}
+	select {
+	case <-evicted:
+	default:
+		t.Fatalf("expected a watcher that never drains to be evicted")
+	}
+	if len(g.Watchers) != 0 {
+		t.Fatalf("expected the evicted watcher removed, got %d", len(g.Watchers))
+	}
+	g.RemoveWatcher(ch)
+	select {
+	case <-g.Evicted(ch):
+	default:
+		t.Fatalf("expected Evicted closed for a channel no longer watching")
+	}
+}
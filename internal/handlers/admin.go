@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tinychess/internal/game"
+	"tinychess/internal/i18n"
+	"tinychess/internal/logging"
+	"tinychess/internal/storage"
+)
+
+// RequireAdmin rejects requests that do not carry the configured admin token
+// as a bearer token.
+func (h *Handler) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if h.AdminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.AdminToken)) != 1 {
+			WriteJSON(w, http.StatusUnauthorized, map[string]any{"ok": false, "error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HandleAdminRetention reports on (GET) or applies (POST) the retention policy
+// for inactive anonymous users. The inactivity period defaults to the
+// configured retention age and may be overridden with ?days=N.
+func (h *Handler) HandleAdminRetention(w http.ResponseWriter, r *http.Request) {
+	age := h.RetentionAge
+	if v := r.URL.Query().Get("days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days <= 0 {
+			WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad days"})
+			return
+		}
+		age = time.Duration(days) * 24 * time.Hour
+	}
+	if age <= 0 {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "retention disabled"})
+		return
+	}
+
+	dryRun := r.Method != http.MethodPost || r.URL.Query().Get("dryRun") == "true"
+	report, err := h.Store.ApplyRetention(r.Context(), time.Now().Add(-age), dryRun)
+	if err != nil {
+		logging.Debugf("retention failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "retention failed"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "report": report})
+}
+
+// HandleAdminDatabase reports on the database's tables and indexes (GET), or
+// vacuums it first (POST), the job DB_MAINTENANCE_INTERVAL schedules.
+func (h *Handler) HandleAdminDatabase(w http.ResponseWriter, r *http.Request) {
+	if h.Store == nil {
+		WriteJSON(w, http.StatusServiceUnavailable, map[string]any{"ok": false, "error": "no database"})
+		return
+	}
+	report, err := h.Store.MaintainDatabase(r.Context(), r.Method == http.MethodPost)
+	if err != nil {
+		logging.Debugf("database maintenance failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "database maintenance failed"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "report": report})
+}
+
+// HandleAdminMoveHistory returns every recorded move for a game, including
+// revoked plies, for auditing.
+func (h *Handler) HandleAdminMoveHistory(w http.ResponseWriter, r *http.Request) {
+	moves, err := h.Store.MoveHistory(r.Context(), requestGameID(r).UUID())
+	if err != nil {
+		logging.Debugf("move history failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not load moves"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "moves": moves})
+}
+
+// HandleAdminAdjudicate ends stale games in bulk as draws or abandoned games.
+// Games idle for at least idleDays match, optionally narrowed by owner, ply
+// count or time control. Dry runs only list the matching games.
+func (h *Handler) HandleAdminAdjudicate(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		IdleDays    int    `json:"idleDays"`
+		Decision    string `json:"decision"`
+		OwnerID     string `json:"ownerId"`
+		MaxPlies    int    `json:"maxPlies"`
+		TimeControl string `json:"timeControl"`
+		Limit       int    `json:"limit"`
+		DryRun      bool   `json:"dryRun"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return
+	}
+	if body.IdleDays <= 0 {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "idleDays must be positive"})
+		return
+	}
+	var draw bool
+	switch body.Decision {
+	case "draw":
+		draw = true
+	case "abandon":
+	default:
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": `decision must be "draw" or "abandon"`})
+		return
+	}
+	filter := storage.AdjudicationFilter{
+		IdleSince:   time.Now().Add(-time.Duration(body.IdleDays) * 24 * time.Hour),
+		MaxPlies:    body.MaxPlies,
+		TimeControl: body.TimeControl,
+		Limit:       body.Limit,
+	}
+	if body.OwnerID != "" {
+		ownerID, err := uuid.Parse(body.OwnerID)
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad owner id"})
+			return
+		}
+		filter.OwnerID = ownerID
+	}
+
+	games, err := h.Store.StaleGames(r.Context(), filter)
+	if err != nil {
+		logging.Debugf("stale games failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not list games"})
+		return
+	}
+	if body.DryRun {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "dryRun": true, "games": games})
+		return
+	}
+
+	status, result, termination := "Abandoned", "", game.TerminationAbandonment
+	notice := i18n.NoticeAbandoned
+	if draw {
+		status, result, termination = "1/2-1/2 by "+game.TerminationAdjudication, "1/2-1/2", game.TerminationAdjudication
+		notice = i18n.NoticeAdjudicateDraw
+	}
+	now := time.Now()
+	adjudicated := make([]storage.StaleGame, 0, len(games))
+	for _, sg := range games {
+		if g, ok := h.Hub.Lookup(game.GameID(sg.ID)); ok {
+			if err := g.Adjudicate(draw); err != nil {
+				continue
+			}
+			g.BroadcastNotice(i18n.T(g.Language, notice))
+			go g.Broadcast()
+			h.announceFinished(game.GameID(sg.ID), status, g.Outcome())
+			h.Hub.QueueAnalysis(g)
+		}
+		if err := h.Store.AdjudicateGame(r.Context(), sg.ID, status, result, termination, now); err != nil {
+			logging.Debugf("adjudicate %s failed: %v", sg.ID, err)
+			continue
+		}
+		if g, ok := h.Hub.Lookup(game.GameID(sg.ID)); ok {
+			if err := h.Hub.SaveTimeline(r.Context(), g); err != nil {
+				logging.Debugf("save timeline for %s failed: %v", sg.ID, err)
+			}
+		}
+		if _, err := h.Store.ResolveChallenge(r.Context(), sg.ID, uuid.Nil, now); err != nil {
+			logging.Debugf("resolve ladder challenge %s failed: %v", sg.ID, err)
+		}
+		adjudicated = append(adjudicated, sg)
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "dryRun": false, "games": adjudicated})
+}
+
+// HandleAdminOutbox reports the outgoing deliveries still waiting to be
+// sent, and those given up on after too many failed attempts.
+func (h *Handler) HandleAdminOutbox(w http.ResponseWriter, r *http.Request) {
+	pending, dead, err := h.Store.OutboxCounts(r.Context())
+	if err != nil {
+		logging.Debugf("count outbox events failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not count events"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "pending": pending, "dead": dead})
+}
+
+// HandleAdminEvictions reports how many games are loaded and how many the
+// idle sweep has unloaded, saved first or kept after a failed save.
+func (h *Handler) HandleAdminEvictions(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "evictions": h.Hub.Evictions()})
+}
+
+// HandleAdminImages reports board image cache hits, misses and size.
+func (h *Handler) HandleAdminImages(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "cache": h.Hub.Images.Stats()})
+}
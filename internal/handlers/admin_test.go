@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tinychess/internal/game"
+	"tinychess/internal/storage"
+)
+
+func TestRequireAdminRejectsMissingToken(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+	h.AdminToken = "secret"
+
+	req := httptest.NewRequest("GET", "/admin/retention", nil)
+	w := httptest.NewRecorder()
+	h.RequireAdmin(http.HandlerFunc(h.HandleAdminRetention)).ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestRequireAdminDisabledWithoutToken(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+
+	req := httptest.NewRequest("GET", "/admin/retention", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	h.RequireAdmin(http.HandlerFunc(h.HandleAdminRetention)).ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestHandleAdminRetentionDryRun(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+	h.AdminToken = "secret"
+	h.RetentionAge = 24 * time.Hour
+
+	req := httptest.NewRequest("GET", "/admin/retention?days=30", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	h.RequireAdmin(http.HandlerFunc(h.HandleAdminRetention)).ServeHTTP(w, req)
+
+	var resp struct {
+		OK     bool `json:"ok"`
+		Report struct {
+			DryRun bool `json:"dryRun"`
+		} `json:"report"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !resp.OK || !resp.Report.DryRun {
+		t.Fatalf("expected ok dry run report, got %+v", resp)
+	}
+}
+
+func TestHandleAdminMaintenance(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+	h.AdminToken = "secret"
+	mux := h.Routes()
+
+	req := httptest.NewRequest("POST", "/admin/maintenance", strings.NewReader(`{"active":true,"message":"Deploying"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !h.Hub.Maintenance().Active {
+		t.Fatalf("expected maintenance to start, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/new", strings.NewReader(`{"userId":"00000000-0000-0000-0000-000000000001"}`)))
+	var resp struct {
+		OK          bool             `json:"ok"`
+		Code        string           `json:"code"`
+		Maintenance game.Maintenance `json:"maintenance"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if w.Code != http.StatusServiceUnavailable || resp.Code != "maintenance" || resp.Maintenance.Message != "Deploying" {
+		t.Fatalf("expected new games to be refused, got %d %+v", w.Code, resp)
+	}
+
+	req = httptest.NewRequest("POST", "/admin/maintenance", strings.NewReader(`{"active":false}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/new", strings.NewReader(`{"userId":"00000000-0000-0000-0000-000000000001"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected new games after maintenance, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleAdminDatabase(t *testing.T) {
+	db, err := storage.New("sqlite://"+filepath.Join(t.TempDir(), "test.db"), 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	store := storage.NewStore(db)
+	h := NewHandler(game.NewHub(store), store)
+	h.AdminToken = "secret"
+
+	for _, method := range []string{"GET", "POST"} {
+		req := httptest.NewRequest(method, "/admin/database", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, req)
+		var resp struct {
+			OK     bool                   `json:"ok"`
+			Report storage.DatabaseReport `json:"report"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if !resp.OK || resp.Report.Vacuumed != (method == "POST") || len(resp.Report.Tables) == 0 {
+			t.Fatalf("%s: unexpected response %+v", method, resp)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h = NewHandler(game.NewHub(nil), nil)
+	h.AdminToken = "secret"
+	req := httptest.NewRequest("GET", "/admin/database", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	h.Routes().ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without a database, got %d", w.Code)
+	}
+}
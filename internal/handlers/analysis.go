@@ -0,0 +1,28 @@
+package handlers
+
+import "net/http"
+
+// HandleAnalysis returns the post-game engine report of a finished game.
+// Finished games without a report are queued and report "pending", which
+// also picks up games whose analysis was lost to a restart.
+func (h *Handler) HandleAnalysis(w http.ResponseWriter, r *http.Request) {
+	g, _, err := h.Hub.Get(r.Context(), requestGameID(r), "")
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
+		return
+	}
+	if report := g.Analysis(); report != nil {
+		WriteJSON(w, http.StatusOK, AnalysisResponse{OK: true, Status: "done", Analysis: report})
+		return
+	}
+	if h.Hub.Engine == nil {
+		WriteJSON(w, http.StatusNotFound, map[string]any{"ok": false, "error": "analysis unavailable"})
+		return
+	}
+	if !g.Over() {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "game in progress"})
+		return
+	}
+	h.Hub.QueueAnalysis(g)
+	WriteJSON(w, http.StatusOK, AnalysisResponse{OK: true, Status: "pending"})
+}
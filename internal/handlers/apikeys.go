@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tinychess/internal/game"
+	"tinychess/internal/logging"
+	"tinychess/internal/storage"
+)
+
+type keyUserKey struct{}
+
+// requestAPIKey returns the API key sent as a bearer credential or in the
+// X-API-Key header, if any.
+func requestAPIKey(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get("X-API-Key")); key != "" {
+		return key
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer "+storage.APIKeyPrefix) {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return ""
+}
+
+// Authenticate resolves an API key to the user it was issued to. The user
+// then stands in for ?userId= and X-User-ID; moves and other seat actions
+// still need seat tokens. Requests without a key pass through unchanged; an
+// unknown or revoked key is refused with 401.
+func (h *Handler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := requestAPIKey(r)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, err := h.Store.APIKeyUser(r.Context(), key, time.Now())
+		if err != nil {
+			if !errors.Is(err, storage.ErrUnknownKey) {
+				logging.Debugf("check api key failed: %v", err)
+			}
+			WriteJSON(w, http.StatusUnauthorized, map[string]any{"ok": false, "error": "bad api key"})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), keyUserKey{}, userID.String())))
+	})
+}
+
+// keyUser returns the user authenticated by an API key, if any.
+func keyUser(r *http.Request) (string, bool) {
+	id, ok := r.Context().Value(keyUserKey{}).(string)
+	return id, ok
+}
+
+// APIKeyRequest is the body of POST /api/keys. Callers without a key prove
+// who they are with the seat token from one of their games.
+type APIKeyRequest struct {
+	UserID    string `json:"userId" format:"uuid"`
+	GameID    string `json:"gameId"`
+	SeatToken string `json:"seatToken"`
+	Name      string `json:"name" maxLength:"64"`
+}
+
+// keyOwner returns the user whose keys the request may manage: the user of
+// the API key it carries, or else userID, vouched for by a seat token it
+// holds in game gameID.
+func (h *Handler) keyOwner(r *http.Request, userID, gameID, seatToken string) (uuid.UUID, bool) {
+	if id, ok := keyUser(r); ok {
+		user, err := uuid.Parse(id)
+		return user, err == nil
+	}
+	user, err := uuid.Parse(userID)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	id, err := game.ParseGameID(gameID)
+	if err != nil || seatToken == "" {
+		return uuid.Nil, false
+	}
+	return user, h.seatAuthorized(r, id, user.String(), seatToken)
+}
+
+// HandleAPIKeys lists (GET) or issues (POST, {"userId", "gameId",
+// "seatToken", "name"}) the caller's API keys. Without a key, GET takes the
+// game and seat token as ?gameId= and ?seatToken=. A new key is returned
+// once, in the response that creates it; only its hash is stored.
+func (h *Handler) HandleAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if h.Store == nil {
+		WriteJSON(w, http.StatusServiceUnavailable, map[string]any{"ok": false, "error": "api keys unavailable"})
+		return
+	}
+	var body APIKeyRequest
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+			return
+		}
+	}
+	raw := requestUserID(r)
+	if raw == "" {
+		raw = strings.TrimSpace(body.UserID)
+	}
+	if raw == "" {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing user id"})
+		return
+	}
+	gameID, token := body.GameID, body.SeatToken
+	if r.Method == http.MethodGet {
+		gameID, token = r.URL.Query().Get("gameId"), r.URL.Query().Get("seatToken")
+	}
+	userID, ok := h.keyOwner(r, raw, gameID, token)
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "api key or seat token required"})
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		keys, err := h.Store.APIKeys(r.Context(), userID)
+		if err != nil {
+			logging.Debugf("list api keys failed: %v", err)
+			WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not load keys"})
+			return
+		}
+		WriteJSON(w, http.StatusOK, APIKeysResponse{OK: true, Keys: keys})
+		return
+	}
+
+	name := strings.TrimSpace(body.Name)
+	if len(name) > 64 {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "name too long"})
+		return
+	}
+	key, k, err := h.Store.CreateAPIKey(r.Context(), userID, name)
+	if err != nil {
+		logging.Debugf("create api key failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not create key"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, NewAPIKeyResponse{OK: true, Key: key, APIKey: k})
+}
+
+// HandleRevokeAPIKey deletes one of the caller's API keys. Like listing, it
+// takes the caller's API key, or ?gameId= and ?seatToken= in its place.
+func (h *Handler) HandleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if h.Store == nil {
+		WriteJSON(w, http.StatusServiceUnavailable, map[string]any{"ok": false, "error": "api keys unavailable"})
+		return
+	}
+	raw := requestUserID(r)
+	if raw == "" {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing user id"})
+		return
+	}
+	userID, ok := h.keyOwner(r, raw, r.URL.Query().Get("gameId"), r.URL.Query().Get("seatToken"))
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "api key or seat token required"})
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("keyId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	found, err := h.Store.RevokeAPIKey(r.Context(), userID, id)
+	if err != nil {
+		logging.Debugf("revoke api key failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not revoke key"})
+		return
+	}
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
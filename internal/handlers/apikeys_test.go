@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+
+	"tinychess/internal/game"
+	"tinychess/internal/storage"
+)
+
+func TestAPIKeys(t *testing.T) {
+	db, err := storage.New("sqlite://"+filepath.Join(t.TempDir(), "test.db"), 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	store := storage.NewStore(db)
+	hub := game.NewHub(store)
+	h := NewHandler(hub, store)
+	user := uuid.NewString()
+
+	do := func(method, path, key, body string) (int, map[string]any) {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			r.Header.Set("Authorization", "Bearer "+key)
+		}
+		w := httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, r)
+		var resp map[string]any
+		_ = json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+
+	id, _, err := hub.CreateGame(context.Background(), user, game.GameOptions{Color: chess.White})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	token := h.seatToken(id, user)
+
+	// Knowing a user's ID is not enough to manage their keys.
+	if code, resp := do("POST", "/api/keys", "", `{"userId":"`+user+`","name":"my bot"}`); code != http.StatusForbidden {
+		t.Fatalf("expected a key without proof to be refused, got %d %v", code, resp)
+	}
+	if code, resp := do("POST", "/api/keys", "", `{"userId":"`+user+`","gameId":"`+id.String()+`","seatToken":"bad","name":"my bot"}`); code != http.StatusForbidden {
+		t.Fatalf("expected a bad seat token to be refused, got %d %v", code, resp)
+	}
+	if code, resp := do("GET", "/api/keys?userId="+user, "", ""); code != http.StatusForbidden {
+		t.Fatalf("expected listing without proof to be refused, got %d %v", code, resp)
+	}
+
+	code, resp := do("POST", "/api/keys", "", `{"userId":"`+user+`","gameId":"`+id.String()+`","seatToken":"`+token+`","name":"my bot"}`)
+	if code != http.StatusOK || resp["ok"] != true {
+		t.Fatalf("issue key: %d %v", code, resp)
+	}
+	key := resp["key"].(string)
+	keyID := resp["apiKey"].(map[string]any)["id"].(string)
+
+	// The key identifies the user without ?userId=, but does not stand in
+	// for a seat token.
+	if code, resp := do("POST", "/move/"+id.String(), key, `{"clientId":"`+user+`","san":"e4"}`); code != http.StatusForbidden {
+		t.Fatalf("expected a move with only a key to be refused, got %d %v", code, resp)
+	}
+	if code, resp := do("POST", "/move/"+id.String(), key, `{"clientId":"`+user+`","seatToken":"`+token+`","san":"e4"}`); code != http.StatusOK || resp["ok"] != true {
+		t.Fatalf("move with key and seat token: %d %v", code, resp)
+	}
+	code, resp = do("GET", "/api/keys", key, "")
+	if keys, _ := resp["keys"].([]any); code != http.StatusOK || len(keys) != 1 {
+		t.Fatalf("list keys: %d %v", code, resp)
+	}
+	if _, ok := resp["keys"].([]any)[0].(map[string]any)["hash"]; ok {
+		t.Fatal("key hashes must not be listed")
+	}
+
+	if code, _ := do("DELETE", "/api/keys/"+keyID+"?userId="+user, "", ""); code != http.StatusForbidden {
+		t.Fatalf("expected a revoke without proof to be refused, got %d", code)
+	}
+	other := uuid.NewString()
+	otherGame, _, err := hub.CreateGame(context.Background(), other, game.GameOptions{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if code, _ := do("DELETE", "/api/keys/"+keyID+"?userId="+other+"&gameId="+otherGame.String()+"&seatToken="+h.seatToken(otherGame, other), "", ""); code != http.StatusNotFound {
+		t.Fatalf("expected another user's revoke to miss, got %d", code)
+	}
+	if code, _ := do("DELETE", "/api/keys/"+keyID, key, ""); code != http.StatusOK {
+		t.Fatalf("revoke: %d", code)
+	}
+	if code, _ := do("GET", "/api/games/"+id.String(), key, ""); code != http.StatusUnauthorized {
+		t.Fatalf("expected a revoked key to be refused, got %d", code)
+	}
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tinychess/internal/game"
+	"tinychess/internal/logging"
+	"tinychess/internal/storage"
+)
+
+// HandleChat relays a chat message from a player or spectator to everyone
+// watching the game and keeps it in the game's history.
+func (h *Handler) HandleChat(w http.ResponseWriter, r *http.Request) {
+	id := requestGameID(r)
+	g, _, err := h.Hub.Get(r.Context(), id, "")
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
+		return
+	}
+
+	var body game.ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return
+	}
+	sender := strings.TrimSpace(body.Sender)
+	if sender == "" {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing client id"})
+		return
+	}
+	text, err := game.CleanChat(body.Text)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+		return
+	}
+
+	if g.ChatMuted(sender) {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "muted"})
+		return
+	}
+	if wait := g.CoolOff(); wait > 0 {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": fmt.Sprintf("cooldown %ds", int(wait.Seconds())+1)})
+		return
+	}
+	canChat, wait := g.CanChat(sender)
+	if !canChat {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": fmt.Sprintf("cooldown %ds", wait)})
+		return
+	}
+
+	msg := g.PostChat(game.ChatPayload{
+		Schema: game.SchemaVersion,
+		Kind:   "chat",
+		Text:   text,
+		At:     time.Now().UnixMilli(),
+		Sender: sender,
+	})
+	if err := h.saveChat(r.Context(), id, sender, msg); err != nil {
+		logging.Debugf("save chat failed: %v", err)
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "id": msg.ID})
+}
+
+// HandleChatDelete lets the game's owner remove a chat message.
+func (h *Handler) HandleChatDelete(w http.ResponseWriter, r *http.Request) {
+	g, body, ok := h.chatModeration(w, r)
+	if !ok {
+		return
+	}
+	if !g.DeleteChat(body.MessageID) {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "unknown message"})
+		return
+	}
+	if h.Store != nil {
+		if msgID, err := uuid.Parse(body.MessageID); err == nil {
+			if err := h.Store.DeleteChat(r.Context(), g.ID.UUID(), msgID, time.Now()); err != nil {
+				logging.Debugf("delete chat failed: %v", err)
+			}
+		}
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// HandleChatMute lets the game's owner silence a participant for the rest of
+// the game.
+func (h *Handler) HandleChatMute(w http.ResponseWriter, r *http.Request) {
+	g, body, ok := h.chatModeration(w, r)
+	if !ok {
+		return
+	}
+	target := strings.TrimSpace(body.TargetID)
+	if target == "" {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing target id"})
+		return
+	}
+	// Chat only shows participant IDs, so that is what the owner sends.
+	if clientID, ok := g.ParticipantClient(target); ok {
+		target = clientID
+	}
+	if target == body.ClientID {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "cannot mute owner"})
+		return
+	}
+	g.MuteChat(target)
+	if h.Store != nil {
+		if userID, err := uuid.Parse(target); err == nil {
+			if err := h.Store.MuteChat(r.Context(), g.ID.UUID(), userID, time.Now()); err != nil {
+				logging.Debugf("mute chat failed: %v", err)
+			}
+		}
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+type chatModerationRequest struct {
+	ClientID  string `json:"clientId"`
+	SeatToken string `json:"seatToken"`
+	MessageID string `json:"messageId"`
+	TargetID  string `json:"targetId"`
+}
+
+// chatModeration loads the game and checks that the request comes from its
+// owner, holding the owner's seat, writing the error response when it does
+// not.
+func (h *Handler) chatModeration(w http.ResponseWriter, r *http.Request) (*game.Game, chatModerationRequest, bool) {
+	var body chatModerationRequest
+	g, _, err := h.Hub.Get(r.Context(), requestGameID(r), "")
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
+		return nil, body, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return nil, body, false
+	}
+	body.ClientID = strings.TrimSpace(body.ClientID)
+	if body.ClientID == "" {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing client id"})
+		return nil, body, false
+	}
+	g.Mu.RLock()
+	owner := g.OwnerID
+	g.Mu.RUnlock()
+	if body.ClientID != owner {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "not owner"})
+		return nil, body, false
+	}
+	if !h.seatAuthorized(r, g.ID, body.ClientID, body.SeatToken) {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "bad seat token"})
+		return nil, body, false
+	}
+	return g, body, true
+}
+
+func (h *Handler) saveChat(ctx context.Context, id game.GameID, sender string, msg game.ChatPayload) error {
+	if h.Store == nil {
+		return nil
+	}
+	msgID, err := uuid.Parse(msg.ID)
+	if err != nil {
+		return err
+	}
+	// Senders without a UUID client ID are stored anonymously.
+	userID, _ := uuid.Parse(sender)
+	return h.Store.SaveChat(ctx, storage.ChatMessage{
+		ID:        msgID,
+		GameID:    id.UUID(),
+		UserID:    userID,
+		Color:     msg.Color,
+		Text:      msg.Text,
+		CreatedAt: time.UnixMilli(msg.At),
+	})
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tinychess/internal/game"
+	"tinychess/internal/storage"
+)
+
+func TestHandleChatBroadcasts(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	id := game.NewGameID()
+	g, color, err := hub.Get(context.Background(), id, "player")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	ch := make(chan []byte, 1)
+	g.AddWatcher(ch)
+
+	req := httptest.NewRequest("POST", "/chat/"+id.String(), strings.NewReader(`{"text":" gl hf ","sender":"player"}`))
+	w := httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"ok":true`) {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	var msg game.ChatPayload
+	if err := json.Unmarshal(<-ch, &msg); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if msg.Kind != "chat" || msg.Text != "gl hf" || msg.Sender != g.ParticipantID("player") || msg.Color != color.String() {
+		t.Fatalf("unexpected chat payload %+v", msg)
+	}
+
+	w = httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, httptest.NewRequest("POST", "/chat/"+id.String(), strings.NewReader(`{"text":"again","sender":"player"}`)))
+	if !strings.Contains(w.Body.String(), "cooldown") {
+		t.Fatalf("expected cooldown, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, httptest.NewRequest("POST", "/chat/"+id.String(), strings.NewReader(`{"text":"  ","sender":"other"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for empty message, got %d", w.Code)
+	}
+}
+
+func TestChatLockedAfterDecisiveResult(t *testing.T) {
+	defer func(d time.Duration) { game.PostGameCoolOff = d }(game.PostGameCoolOff)
+	game.PostGameCoolOff = time.Minute
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	id := game.NewGameID()
+	g, color, err := hub.Get(context.Background(), id, "player")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if err := g.Resign(*color); err != nil {
+		t.Fatalf("resign: %v", err)
+	}
+	w := httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, httptest.NewRequest("POST", "/chat/"+id.String(), strings.NewReader(`{"text":"gg","sender":"player"}`)))
+	if !strings.Contains(w.Body.String(), `"ok":false`) || !strings.Contains(w.Body.String(), "cooldown") {
+		t.Fatalf("expected chat locked after the result, got %s", w.Body.String())
+	}
+}
+
+func TestChatModerationPersists(t *testing.T) {
+	db, err := storage.New("sqlite://"+filepath.Join(t.TempDir(), "test.db"), 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	store := storage.NewStore(db)
+	hub := game.NewHub(store)
+	h := NewHandler(hub, store)
+	mux := h.Routes()
+
+	post := func(path, body string) map[string]any {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		var resp map[string]any
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+		return resp
+	}
+
+	owner, guest := uuid.NewString(), uuid.NewString()
+	id, _, err := hub.CreateGame(context.Background(), owner, game.GameOptions{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	kept := post("/chat/"+id.String(), `{"text":"hello","sender":"`+owner+`"}`)
+	spam := post("/chat/"+id.String(), `{"text":"spam","sender":"`+guest+`"}`)
+	if kept["ok"] != true || spam["ok"] != true {
+		t.Fatalf("chat failed: %v %v", kept, spam)
+	}
+	if resp := post("/chat/"+id.String()+"/delete", `{"clientId":"`+guest+`","messageId":"`+kept["id"].(string)+`"}`); resp["error"] != "not owner" {
+		t.Fatalf("expected non-owner delete to be refused, got %v", resp)
+	}
+	if resp := post("/chat/"+id.String()+"/delete", `{"clientId":"`+owner+`","messageId":"`+kept["id"].(string)+`"}`); resp["error"] != "bad seat token" {
+		t.Fatalf("expected a delete without the owner's seat token to be refused, got %v", resp)
+	}
+	seat := `,"seatToken":"` + h.seatToken(id, owner) + `"`
+	if resp := post("/chat/"+id.String()+"/delete", `{"clientId":"`+owner+`"`+seat+`,"messageId":"`+spam["id"].(string)+`"}`); resp["ok"] != true {
+		t.Fatalf("delete failed: %v", resp)
+	}
+	// The owner only sees the guest's participant ID, and the guest's
+	// message is gone by now.
+	g, _ := hub.Lookup(id)
+	if resp := post("/chat/"+id.String()+"/mute", `{"clientId":"`+owner+`"`+seat+`,"targetId":"`+g.ParticipantID(guest)+`"}`); resp["ok"] != true {
+		t.Fatalf("mute failed: %v", resp)
+	}
+
+	// A fresh hub restores the history without the deleted message, and the
+	// mute still applies.
+	reloaded, _, err := game.NewHub(store).Get(context.Background(), id, "")
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	history := reloaded.ChatHistory()
+	if len(history) != 1 || history[0].Text != "hello" || history[0].ID != kept["id"] {
+		t.Fatalf("unexpected history %+v", history)
+	}
+	if !reloaded.ChatMuted(guest) {
+		t.Fatalf("mute should survive a reload")
+	}
+}
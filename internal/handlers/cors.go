@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// corsPrefixes are the paths other sites may call when their origin is
+// allowed, see CORS.
+var corsPrefixes = []string{"/api/", "/move/", "/sse/"}
+
+// corsMaxAge is how long, in seconds, browsers may cache a preflight.
+const corsMaxAge = "600"
+
+// ParseOrigins parses a comma-separated list of origins allowed to make
+// cross-origin requests, such as "https://example.com,http://localhost:3000".
+// "*" allows any origin.
+func ParseOrigins(s string) ([]string, error) {
+	var origins []string
+	for _, o := range strings.Split(s, ",") {
+		o = strings.TrimSpace(o)
+		if o == "" {
+			continue
+		}
+		if o == "*" {
+			origins = append(origins, o)
+			continue
+		}
+		u, err := url.Parse(o)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+			return nil, fmt.Errorf("invalid origin %q", o)
+		}
+		origins = append(origins, u.Scheme+"://"+strings.ToLower(u.Host))
+	}
+	return origins, nil
+}
+
+// corsPath reports whether path is one other sites may call.
+func corsPath(path string) bool {
+	for _, p := range corsPrefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS lets the origins in CORSOrigins read the responses of requests they
+// make, and exposes the rate limit headers to them. Preflights also need
+// allowMethods, which lists the methods and headers. Requests from other
+// origins get no CORS headers, so browsers keep refusing them.
+func (h *Handler) CORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(h.CORSOrigins) > 0 {
+			w.Header().Add("Vary", "Origin")
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && h.allowOrigin(origin) {
+			if slices.Contains(h.CORSOrigins, "*") {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			w.Header().Set("Access-Control-Expose-Headers", "Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset")
+			if r.Method == http.MethodOptions {
+				w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) allowOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, o := range h.CORSOrigins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math/rand"
+	"time"
+
+	"tinychess/internal/game"
+)
+
+// ReconnectRetry is the reconnection delay draining streams suggest. Each
+// stream adds up to as much again at random, so clients do not all come
+// back at once.
+const ReconnectRetry = 2 * time.Second
+
+// Drain ends every open event stream, and any opened until Undrain, with a
+// retry directive and a "reconnect" event naming AlternateURL. It is used
+// on shutdown and, when an alternate deployment is configured, for
+// maintenance.
+func (h *Handler) Drain() {
+	h.drainMu.Lock()
+	defer h.drainMu.Unlock()
+	if h.drainCh == nil {
+		h.drainCh = make(chan struct{})
+	}
+	if !h.draining {
+		h.draining = true
+		close(h.drainCh)
+	}
+}
+
+// Undrain lets event streams stay open again.
+func (h *Handler) Undrain() {
+	h.drainMu.Lock()
+	defer h.drainMu.Unlock()
+	if h.draining {
+		h.draining = false
+		h.drainCh = make(chan struct{})
+	}
+}
+
+// drained returns a channel that is closed when the streams are drained.
+func (h *Handler) drained() <-chan struct{} {
+	h.drainMu.Lock()
+	defer h.drainMu.Unlock()
+	if h.drainCh == nil {
+		h.drainCh = make(chan struct{})
+	}
+	return h.drainCh
+}
+
+// writeReconnect queues the frames that end a drained stream.
+func (h *Handler) writeReconnect(stream *eventStream, schema int) {
+	retry := ReconnectRetry + time.Duration(rand.Int63n(int64(ReconnectRetry)))
+	stream.Retry(retry)
+	data, _ := json.Marshal(game.ReconnectPayload{
+		Schema:  game.SchemaVersion,
+		Kind:    "reconnect",
+		URL:     h.AlternateURL,
+		RetryMs: retry.Milliseconds(),
+	})
+	stream.Event(game.ConvertPayload(data, schema))
+}
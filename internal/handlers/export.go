@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"tinychess/internal/game"
+)
+
+// HandleGame returns the game's current state, the same payload its event
+// stream carries, for scripts and bots that poll instead of streaming. It
+// honours ?schema= and ?notation= like the stream.
+func (h *Handler) HandleGame(w http.ResponseWriter, r *http.Request) {
+	g, _, err := h.Hub.Get(r.Context(), requestGameID(r), "")
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
+		return
+	}
+	g.Mu.RLock()
+	state := g.StateLocked()
+	g.Mu.RUnlock()
+	w.Header().Set("Cache-Control", "no-store")
+	WriteJSON(w, http.StatusOK, stateForClient(r, state, h.notationFor(r, requestUserID(r)).In(g.Language)))
+}
+
+// HandlePGN exports the game as a PGN file with instance headers. The
+// movetext follows the caller's notation preference; only SAN exports are
+// standard PGN. With ?timeline=1 joins, seat releases and resignations are
+// included as comments.
+func (h *Handler) HandlePGN(w http.ResponseWriter, r *http.Request) {
+	id := requestGameID(r)
+	g, _, err := h.Hub.Get(r.Context(), id, "")
+	if err != nil {
+		http.Error(w, "game unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	snap := g.Snapshot()
+	pgn := snap.PGN(game.PGNHeaders{})
+	notation := h.notationFor(r, requestUserID(r)).In(g.Language)
+	if withTimeline, _ := strconv.ParseBool(r.URL.Query().Get("timeline")); withTimeline {
+		pgn = game.PGNWithTimeline(pgn, snap.StartFEN, snap.UCI, notation, snap.Timeline)
+	} else {
+		pgn = game.PGNWithNotation(pgn, snap.StartFEN, snap.UCI, notation)
+	}
+
+	w.Header().Set("Content-Type", "application/x-chess-pgn; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="tinychess-`+id.String()+`.pgn"`)
+	_, _ = w.Write([]byte(pgn + "\n"))
+}
+
+// HandlePly returns the position after {ply} moves, so a shared link can
+// open the game at that moment.
+func (h *Handler) HandlePly(w http.ResponseWriter, r *http.Request) {
+	ply, err := strconv.Atoi(r.PathValue("ply"))
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad ply"})
+		return
+	}
+	g, _, err := h.Hub.Get(r.Context(), requestGameID(r), "")
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
+		return
+	}
+	pos, ok := g.Snapshot().AtPly(ply)
+	if !ok {
+		WriteJSON(w, http.StatusNotFound, map[string]any{"ok": false, "error": "no such ply"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, PlyResponse{OK: true, Position: pos})
+}
+
+// HandleMoves returns the game's mainline with the time each ply was played
+// and how long the mover took.
+func (h *Handler) HandleMoves(w http.ResponseWriter, r *http.Request) {
+	g, _, err := h.Hub.Get(r.Context(), requestGameID(r), "")
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "moves": g.MoveTimes()})
+}
+
+// HandleLegal lists the squares the piece on ?from= may legally move to, and
+// whether each move captures or promotes, so clients can highlight them
+// without knowing the rules.
+func (h *Handler) HandleLegal(w http.ResponseWriter, r *http.Request) {
+	from := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("from")))
+	sq, err := game.ParseSquare(from)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+		return
+	}
+	g, _, err := h.Hub.Get(r.Context(), requestGameID(r), "")
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, LegalResponse{OK: true, From: from, Targets: g.LegalTargets(sq)})
+}
+
+// HandleTimeline returns the game's non-move events, such as joins and
+// resignations, keyed by the ply they followed.
+func (h *Handler) HandleTimeline(w http.ResponseWriter, r *http.Request) {
+	g, _, err := h.Hub.Get(r.Context(), requestGameID(r), "")
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "events": g.Timeline()})
+}
+
+// HandleBoardSVG renders the game's current position as an SVG image.
+// Renders are shared through the hub's image cache, so many games or crawler
+// hits on the same position render it once.
+func (h *Handler) HandleBoardSVG(w http.ResponseWriter, r *http.Request) {
+	g, _, err := h.Hub.Get(r.Context(), requestGameID(r), "")
+	if err != nil {
+		http.Error(w, "game unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	snap := g.Snapshot()
+	key := snap.BoardImageKey()
+	data, ok := h.Hub.Images.Get(key)
+	if !ok {
+		var buf bytes.Buffer
+		if err := snap.WriteBoardSVG(&buf); err != nil {
+			http.Error(w, "render failed", http.StatusInternalServerError)
+			return
+		}
+		data = buf.Bytes()
+		h.Hub.Images.Add(key, data)
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Features an operator can turn off. Their endpoints stay routed but answer
+// 404, so clients can tell a disabled feature from a bad request.
+const (
+	FeatureChat       = "chat"
+	FeatureReactions  = "reactions"
+	FeatureHints      = "hints"
+	FeatureLadder     = "ladder"
+	FeaturePairing    = "pairing"
+	FeatureImport     = "import"
+	FeaturePostMortem = "postmortem"
+)
+
+var features = []string{FeatureChat, FeatureReactions, FeatureHints, FeatureLadder, FeaturePairing, FeatureImport, FeaturePostMortem}
+
+// ParseFeatures validates a list of feature names, such as the features to
+// disable, and returns them as a set.
+func ParseFeatures(names []string) (map[string]bool, error) {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, f := range features {
+			known = known || f == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown feature %q, want one of %s", name, strings.Join(features, ", "))
+		}
+		set[name] = true
+	}
+	return set, nil
+}
+
+// feature serves fn unless the named feature is disabled.
+func (h *Handler) feature(name string, fn http.HandlerFunc) http.HandlerFunc {
+	if !h.Disabled[name] {
+		return fn
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusNotFound, map[string]any{"ok": false, "error": name + " is disabled on this instance"})
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/corentings/chess/v2"
+
+	"tinychess/internal/fediverse"
+	"tinychess/internal/game"
+	"tinychess/internal/logging"
+)
+
+// writeActivity writes an ActivityPub document.
+func writeActivity(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", fediverse.ContentType)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// HandleWebFinger resolves the instance actor's acct: address.
+func (h *Handler) HandleWebFinger(w http.ResponseWriter, r *http.Request) {
+	if h.Fediverse == nil {
+		http.NotFound(w, r)
+		return
+	}
+	doc, ok := h.Fediverse.WebFinger(r.URL.Query().Get("resource"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/jrd+json")
+	_ = json.NewEncoder(w).Encode(doc)
+}
+
+// HandleActor serves the instance actor document.
+func (h *Handler) HandleActor(w http.ResponseWriter, r *http.Request) {
+	if h.Fediverse == nil {
+		http.NotFound(w, r)
+		return
+	}
+	writeActivity(w, h.Fediverse.Actor())
+}
+
+// HandleOutbox lists recently published games.
+func (h *Handler) HandleOutbox(w http.ResponseWriter, r *http.Request) {
+	if h.Fediverse == nil {
+		http.NotFound(w, r)
+		return
+	}
+	writeActivity(w, h.Fediverse.Outbox())
+}
+
+// HandleFollowers serves the followers collection.
+func (h *Handler) HandleFollowers(w http.ResponseWriter, r *http.Request) {
+	if h.Fediverse == nil {
+		http.NotFound(w, r)
+		return
+	}
+	doc, err := h.Fediverse.Followers(r.Context())
+	if err != nil {
+		logging.Debugf("list followers failed: %v", err)
+		http.Error(w, "followers unavailable", http.StatusInternalServerError)
+		return
+	}
+	writeActivity(w, doc)
+}
+
+// HandleNote serves a published game note.
+func (h *Handler) HandleNote(w http.ResponseWriter, r *http.Request) {
+	if h.Fediverse == nil {
+		http.NotFound(w, r)
+		return
+	}
+	note, ok := h.Fediverse.Note(r.PathValue("id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeActivity(w, note)
+}
+
+// HandleInbox accepts follow requests for the instance actor.
+func (h *Handler) HandleInbox(w http.ResponseWriter, r *http.Request) {
+	if h.Fediverse == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := h.Fediverse.HandleActivity(r.Context(), r.Body); err != nil {
+		logging.Debugf("inbox activity rejected: %v", err)
+		http.Error(w, "bad activity", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// announceFinished publishes a decided game to fediverse followers.
+func (h *Handler) announceFinished(id game.GameID, status string, outcome chess.Outcome) {
+	if h.Fediverse == nil || outcome == chess.NoOutcome {
+		return
+	}
+	go h.Fediverse.PublishGame(context.Background(), fediverse.FinishedGame{ID: id.String(), Status: status, At: time.Now()})
+}
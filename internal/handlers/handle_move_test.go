@@ -3,6 +3,8 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
@@ -16,15 +18,16 @@ import (
 func TestHandleMoveWrongColor(t *testing.T) {
 	hub := game.NewHub(nil)
 	h := NewHandler(hub, nil)
-	g, _, err := hub.Get(context.Background(), "g1", "")
+	id := game.NewGameID()
+	g, _, err := hub.Get(context.Background(), id, "")
 	if err != nil {
 		t.Fatalf("get game: %v", err)
 	}
 	g.Clients["c1"] = chess.White
 
-	req := httptest.NewRequest("POST", "/move/g1", strings.NewReader(`{"uci":"a7a6","clientId":"c1"}`))
+	req := httptest.NewRequest("POST", "/move/"+id.String(), strings.NewReader(fmt.Sprintf(`{"uci":"a7a6","clientId":"c1","seatToken":%q}`, hub.Seats.Token(id, "c1"))))
 	w := httptest.NewRecorder()
-	h.HandleMove(w, req)
+	h.Routes().ServeHTTP(w, req)
 
 	var resp map[string]any
 	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
@@ -39,15 +42,16 @@ func TestHandleMoveWrongColor(t *testing.T) {
 func TestHandleMoveNotYourTurn(t *testing.T) {
 	hub := game.NewHub(nil)
 	h := NewHandler(hub, nil)
-	g, _, err := hub.Get(context.Background(), "g2", "")
+	id := game.NewGameID()
+	g, _, err := hub.Get(context.Background(), id, "")
 	if err != nil {
 		t.Fatalf("get game: %v", err)
 	}
 	g.Clients["c2"] = chess.Black
 
-	req := httptest.NewRequest("POST", "/move/g2", strings.NewReader(`{"uci":"a7a6","clientId":"c2"}`))
+	req := httptest.NewRequest("POST", "/move/"+id.String(), strings.NewReader(fmt.Sprintf(`{"uci":"a7a6","clientId":"c2","seatToken":%q}`, hub.Seats.Token(id, "c2"))))
 	w := httptest.NewRecorder()
-	h.HandleMove(w, req)
+	h.Routes().ServeHTTP(w, req)
 
 	var resp map[string]any
 	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
@@ -62,15 +66,16 @@ func TestHandleMoveNotYourTurn(t *testing.T) {
 func TestHandleMoveSuccess(t *testing.T) {
 	hub := game.NewHub(nil)
 	h := NewHandler(hub, nil)
-	g, _, err := hub.Get(context.Background(), "g3", "")
+	id := game.NewGameID()
+	g, _, err := hub.Get(context.Background(), id, "")
 	if err != nil {
 		t.Fatalf("get game: %v", err)
 	}
 	g.Clients["c1"] = chess.White
 
-	req := httptest.NewRequest("POST", "/move/g3", strings.NewReader(`{"uci":"e2e4","clientId":"c1"}`))
+	req := httptest.NewRequest("POST", "/move/"+id.String(), strings.NewReader(fmt.Sprintf(`{"uci":"e2e4","clientId":"c1","seatToken":%q}`, hub.Seats.Token(id, "c1"))))
 	w := httptest.NewRecorder()
-	h.HandleMove(w, req)
+	h.Routes().ServeHTTP(w, req)
 
 	var resp map[string]any
 	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
@@ -80,3 +85,224 @@ func TestHandleMoveSuccess(t *testing.T) {
 		t.Fatalf("expected move to succeed")
 	}
 }
+
+// Test that the requested promotion piece is used instead of a queen.
+func TestHandleMoveUnderpromotion(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	id := game.NewGameID()
+	g, _, err := hub.Get(context.Background(), id, "")
+	if err != nil {
+		t.Fatalf("get game: %v", err)
+	}
+	g.Clients["c1"] = chess.White
+	for _, mv := range []string{"h2h4", "g7g5", "h4g5", "h7h6", "g5h6", "f8g7", "h6g7", "g8f6"} {
+		if err := g.MakeMove(mv); err != nil {
+			t.Fatalf("setup move %s: %v", mv, err)
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/move/"+id.String(), strings.NewReader(fmt.Sprintf(`{"uci":"g7h8","promotion":"k","clientId":"c1","seatToken":%q}`, hub.Seats.Token(id, "c1"))))
+	w := httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, req)
+	if w.Code != 400 {
+		t.Fatalf("expected king promotion to be rejected, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/move/"+id.String(), strings.NewReader(fmt.Sprintf(`{"uci":"g7h8","promotion":"n","clientId":"c1","seatToken":%q}`, hub.Seats.Token(id, "c1"))))
+	w = httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, req)
+
+	var resp struct {
+		OK    bool           `json:"ok"`
+		State game.GameState `json:"state"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !resp.OK || !strings.HasPrefix(resp.State.FEN, "rnbqk2N/") {
+		t.Fatalf("expected knight promotion, got ok=%v fen=%q", resp.OK, resp.State.FEN)
+	}
+}
+
+// Test that a move made with another player's client ID but no valid seat
+// token is refused.
+func TestHandleMoveRequiresSeatToken(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	id := game.NewGameID()
+	g, _, err := hub.Get(context.Background(), id, "")
+	if err != nil {
+		t.Fatalf("get game: %v", err)
+	}
+	g.Clients["c1"] = chess.White
+
+	for _, token := range []string{"", "bogus", hub.Seats.Token(game.NewGameID(), "c1"), hub.Seats.Token(id, "c2")} {
+		body := fmt.Sprintf(`{"uci":"e2e4","clientId":"c1","seatToken":%q}`, token)
+		w := httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, httptest.NewRequest("POST", "/move/"+id.String(), strings.NewReader(body)))
+		if w.Code != 403 {
+			t.Fatalf("token %q: expected 403, got %d", token, w.Code)
+		}
+	}
+	if len(g.MovesUCI()) != 0 {
+		t.Fatalf("expected no moves to be played")
+	}
+}
+
+// Test that moves may be sent in SAN instead of UCI.
+func TestHandleMoveSAN(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	id := game.NewGameID()
+	g, _, err := hub.Get(context.Background(), id, "")
+	if err != nil {
+		t.Fatalf("get game: %v", err)
+	}
+	g.Clients["c1"] = chess.White
+	token := hub.Seats.Token(id, "c1")
+
+	post := func(body string) (int, map[string]any) {
+		req := httptest.NewRequest("POST", "/move/"+id.String(), strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, req)
+		var resp map[string]any
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return w.Code, resp
+	}
+
+	if code, _ := post(fmt.Sprintf(`{"san":"Nf3","uci":"g1f3","clientId":"c1","seatToken":%q}`, token)); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a move sent twice, got %d", code)
+	}
+	if _, resp := post(fmt.Sprintf(`{"san":"Nf6","clientId":"c1","seatToken":%q}`, token)); resp["ok"].(bool) || resp["error"] != "illegal move" {
+		t.Fatalf("expected an illegal move, got %v", resp)
+	}
+	if _, resp := post(fmt.Sprintf(`{"san":"Nf3+","clientId":"c1","seatToken":%q}`, token)); !resp["ok"].(bool) {
+		t.Fatalf("expected move to succeed, got %v", resp)
+	}
+	g.Mu.RLock()
+	moves := g.MovesUCI()
+	g.Mu.RUnlock()
+	if len(moves) != 1 || moves[0] != "g1f3" {
+		t.Fatalf("expected g1f3, got %v", moves)
+	}
+}
+
+func TestHandleMoveIdempotent(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	id := game.NewGameID()
+	g, _, err := hub.Get(context.Background(), id, "")
+	if err != nil {
+		t.Fatalf("get game: %v", err)
+	}
+	g.Clients["c1"] = chess.White
+	g.Clients["c2"] = chess.Black
+	token := hub.Seats.Token(id, "c1")
+
+	post := func(body, key string) map[string]any {
+		req := httptest.NewRequest("POST", "/move/"+id.String(), strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, req)
+		var resp map[string]any
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp
+	}
+
+	body := fmt.Sprintf(`{"uci":"e2e4","clientId":"c1","seatToken":%q,"moveId":"m1"}`, token)
+	if resp := post(body, ""); resp["ok"] != true || resp["duplicate"] != nil {
+		t.Fatalf("expected the move to be played, got %v", resp)
+	}
+	// The retry is answered as a success although it is now black's turn.
+	if resp := post(body, ""); resp["ok"] != true || resp["duplicate"] != true {
+		t.Fatalf("expected the retry to be recognized, got %v", resp)
+	}
+	g.Mu.RLock()
+	moves := g.MovesUCI()
+	g.Mu.RUnlock()
+	if len(moves) != 1 {
+		t.Fatalf("expected one move, got %v", moves)
+	}
+
+	if err := g.MakeMove("e7e5"); err != nil {
+		t.Fatalf("black move: %v", err)
+	}
+	body = fmt.Sprintf(`{"uci":"g1f3","clientId":"c1","seatToken":%q}`, token)
+	if resp := post(body, "m2"); resp["ok"] != true {
+		t.Fatalf("expected the keyed move to be played, got %v", resp)
+	}
+	if resp := post(body, "m2"); resp["duplicate"] != true {
+		t.Fatalf("expected the Idempotency-Key retry to be recognized, got %v", resp)
+	}
+	if resp := post(body, strings.Repeat("k", game.MaxMoveIDLength+1)); resp["error"] != "bad move id" {
+		t.Fatalf("expected a long move id to be refused, got %v", resp)
+	}
+}
+
+func TestHandleMoveHotSeat(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	owner := "00000000-0000-0000-0000-000000000001"
+	id, _, err := hub.CreateGame(context.Background(), owner, game.GameOptions{HotSeat: true})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	token := hub.Seats.Token(id, owner)
+
+	for _, uci := range []string{"e2e4", "e7e5", "g1f3"} {
+		body := fmt.Sprintf(`{"uci":%q,"clientId":%q,"seatToken":%q}`, uci, owner, token)
+		w := httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, httptest.NewRequest("POST", "/move/"+id.String(), strings.NewReader(body)))
+		var resp map[string]any
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if resp["ok"] != true {
+			t.Fatalf("%s: expected the owner to move either color, got %v", uci, resp)
+		}
+	}
+	// The side to move is still checked.
+	body := fmt.Sprintf(`{"uci":"d2d4","clientId":%q,"seatToken":%q}`, owner, token)
+	w := httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, httptest.NewRequest("POST", "/move/"+id.String(), strings.NewReader(body)))
+	var resp map[string]any
+	_ = json.NewDecoder(w.Body).Decode(&resp)
+	if resp["ok"] == true || resp["error"] != "wrong color" {
+		t.Fatalf("expected white's piece to be refused on black's turn, got %v", resp)
+	}
+}
+
+func TestHandleMoveMoveLimit(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	owner := "00000000-0000-0000-0000-000000000001"
+	rules := &game.HouseRules{MoveLimit: 1, Adjudicate: game.AdjudicateMaterial}
+	id, _, err := hub.CreateGame(context.Background(), owner, game.GameOptions{HotSeat: true, HouseRules: rules})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	token := hub.Seats.Token(id, owner)
+
+	var resp struct {
+		OK    bool           `json:"ok"`
+		State game.GameState `json:"state"`
+	}
+	for _, uci := range []string{"e2e4", "e7e5"} {
+		body := fmt.Sprintf(`{"uci":%q,"clientId":%q,"seatToken":%q}`, uci, owner, token)
+		w := httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, httptest.NewRequest("POST", "/move/"+id.String(), strings.NewReader(body)))
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || !resp.OK {
+			t.Fatalf("%s: %v %v", uci, resp, err)
+		}
+	}
+	if resp.State.Termination != game.TerminationMoveLimit || !strings.Contains(resp.State.PGN, `[Termination "adjudication"]`) {
+		t.Fatalf("expected the last move to end the game at the limit, got %q", resp.State.Termination)
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http/httptest"
 	"strings"
 	"testing"
@@ -15,15 +16,16 @@ import (
 func TestHandleRelease(t *testing.T) {
 	hub := game.NewHub(nil)
 	h := NewHandler(hub, nil)
-	g, _, err := hub.Get(context.Background(), "g1", "owner")
+	id := game.NewGameID()
+	g, _, err := hub.Get(context.Background(), id, "owner")
 	if err != nil {
 		t.Fatalf("get game: %v", err)
 	}
 	g.Clients["other"] = chess.Black
 
-	req := httptest.NewRequest("POST", "/release/g1", strings.NewReader(`{"clientId":"owner","targetId":"other"}`))
+	req := httptest.NewRequest("POST", "/release/"+id.String(), strings.NewReader(fmt.Sprintf(`{"clientId":"owner","targetId":"other","seatToken":%q}`, hub.Seats.Token(id, "owner"))))
 	w := httptest.NewRecorder()
-	h.HandleRelease(w, req)
+	h.Routes().ServeHTTP(w, req)
 
 	var resp map[string]any
 	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
@@ -40,15 +42,16 @@ func TestHandleRelease(t *testing.T) {
 func TestHandleReleaseNotOwner(t *testing.T) {
 	hub := game.NewHub(nil)
 	h := NewHandler(hub, nil)
-	g, _, err := hub.Get(context.Background(), "g2", "owner")
+	id := game.NewGameID()
+	g, _, err := hub.Get(context.Background(), id, "owner")
 	if err != nil {
 		t.Fatalf("get game: %v", err)
 	}
 	g.Clients["other"] = chess.Black
 
-	req := httptest.NewRequest("POST", "/release/g2", strings.NewReader(`{"clientId":"notowner","targetId":"other"}`))
+	req := httptest.NewRequest("POST", "/release/"+id.String(), strings.NewReader(fmt.Sprintf(`{"clientId":"notowner","targetId":"other","seatToken":%q}`, hub.Seats.Token(id, "notowner"))))
 	w := httptest.NewRecorder()
-	h.HandleRelease(w, req)
+	h.Routes().ServeHTTP(w, req)
 
 	var resp map[string]any
 	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
@@ -7,38 +7,126 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/corentings/chess/v2"
 	"github.com/google/uuid"
 
+	"tinychess/internal/federation"
+	"tinychess/internal/fediverse"
 	"tinychess/internal/game"
+	"tinychess/internal/i18n"
 	"tinychess/internal/logging"
+	"tinychess/internal/ratelimit"
 	"tinychess/internal/storage"
 	"tinychess/internal/templates"
+	"tinychess/internal/usage"
 )
 
 // Handler contains dependencies for HTTP handlers.
 type Handler struct {
 	Hub   *game.Hub
 	Store *storage.Store
+
+	// AdminToken guards the /admin endpoints. Admin access is disabled when empty.
+	AdminToken string
+	// RetentionAge is the default inactivity period used by retention reports.
+	RetentionAge time.Duration
+	// Remote relays games from other instances. Federation is disabled when nil.
+	Remote *federation.Relay
+	// Fediverse publishes finished games over ActivityPub when set.
+	Fediverse *fediverse.Publisher
+	// LadderReach is how many rungs up a ladder player may challenge;
+	// DefaultLadderReach is used when zero.
+	LadderReach int
+	// Usage counts API requests per caller and enforces the daily quota.
+	// Requests are neither counted nor limited when nil.
+	Usage *usage.Meter
+	// RateLimits throttle bursts per caller on the endpoint groups named
+	// by the Limit constants; groups without a limiter are not throttled.
+	RateLimits map[string]*ratelimit.Limiter
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For is
+	// believed when telling callers apart, see ClientIP and ParseProxies.
+	// The peer address is used as is when empty.
+	TrustedProxies []netip.Prefix
+	// CORSOrigins are the origins whose pages may call /api/*, /move/ and
+	// /sse/, or "*" for any; see ParseOrigins. Cross-origin requests are
+	// left to the browser's default refusal when empty.
+	CORSOrigins []string
+	// Heartbeat is how often idle event streams send a keep-alive frame;
+	// DefaultHeartbeat is used when zero.
+	Heartbeat time.Duration
+	// Disabled lists the features turned off on this instance, see
+	// ParseFeatures.
+	Disabled map[string]bool
+	// AlternateURL is the deployment clients move to when this instance
+	// drains its event streams, see Drain; they return to the same host
+	// when empty.
+	AlternateURL string
+	// Coaches are the users who review games on request, see
+	// HandleRequestReview. Reviews are unavailable when empty.
+	Coaches map[uuid.UUID]bool
+
+	drainMu  sync.Mutex
+	drainCh  chan struct{}
+	draining bool
+
+	// notations caches each user's preferred notation, see notationFor.
+	notationMu sync.Mutex
+	notations  map[uuid.UUID]cachedNotation
+}
+
+// cachedNotation is a user's preferred notation as loaded at some time.
+type cachedNotation struct {
+	notation game.Notation
+	at       time.Time
 }
 
+const (
+	// notationTTL is how long a user's preferred notation is reused before
+	// it is loaded again, which bounds how long another instance serves a
+	// changed preference.
+	notationTTL = time.Minute
+	// maxCachedNotations bounds the notation cache; it starts over when full.
+	maxCachedNotations = 10_000
+)
+
 // NewHandler creates a new handler instance.
 func NewHandler(hub *game.Hub, store *storage.Store) *Handler {
 	return &Handler{Hub: hub, Store: store}
 }
 
+// NewGameRequest is the body of POST /new.
+type NewGameRequest struct {
+	UserID      string `json:"userId" format:"uuid"`
+	TimeControl string `json:"timeControl"`
+	Language    string `json:"language"`
+	FEN         string `json:"fen"`
+	// JoinCode makes the game private; CodeForSpectators also requires it
+	// to watch.
+	JoinCode          string `json:"joinCode"`
+	CodeForSpectators bool   `json:"codeForSpectators"`
+	// InviteOnly seats the opponent only through the play invite.
+	InviteOnly bool `json:"inviteOnly"`
+	// HotSeat lets the creator move both colors on one device.
+	HotSeat bool `json:"hotSeat" doc:"Pass and play: the creator moves both colors on one device and the other seat stays closed. Hot-seat games are left out of the ladder."`
+	// MoveLimit ends the game after that many moves each, decided by
+	// material, or by the engine when Adjudicate is "eval".
+	MoveLimit  int    `json:"moveLimit" minimum:"0" maximum:"500" doc:"Ends the game once each side has made this many moves, adjudicated with termination move-limit. 0 plays without a limit."`
+	Adjudicate string `json:"adjudicate" enum:"material,eval" doc:"How a game at its move limit is decided: the side ahead in material wins (the default), or the engine's score must favour one side by a pawn. Level positions are drawn."`
+}
+
 // HandleNew creates a new game. POST requests respond with JSON, while GET
 // requests redirect to the new game URL.
 func (h *Handler) HandleNew(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	switch r.Method {
 	case http.MethodPost:
-		var body struct {
-			UserID string `json:"userId"`
-		}
+		var body NewGameRequest
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
 			return
@@ -48,46 +136,135 @@ func (h *Handler) HandleNew(w http.ResponseWriter, r *http.Request) {
 			WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing user id"})
 			return
 		}
+		opts, err := h.gameOptions(body.TimeControl, body.Language, body.FEN)
+		if err == nil {
+			opts.JoinCode, err = game.CleanJoinCode(body.JoinCode)
+		}
+		if err == nil {
+			opts.HouseRules, err = game.ParseHouseRules(body.MoveLimit, strings.TrimSpace(body.Adjudicate))
+		}
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+			return
+		}
+		opts.CodeForSpectators = body.CodeForSpectators && opts.JoinCode != ""
+		opts.InviteOnly = body.InviteOnly
+		opts.HotSeat = body.HotSeat
 
-		id, color, err := h.Hub.CreateGame(ctx, userID)
+		id, color, err := h.Hub.CreateGame(ctx, userID, opts)
 		if err != nil {
 			logging.Debugf("create game failed: %v", err)
 			WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not create game"})
 			return
 		}
-		WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "id": id, "color": color.String()})
+		WriteJSON(w, http.StatusOK, NewGameResponse{OK: true, ID: id, Color: color.String(), SeatToken: h.seatToken(id, userID), Invites: h.invites(id, color)})
 	default:
 		userID := strings.TrimSpace(r.URL.Query().Get("userId"))
 		if userID == "" {
 			http.Error(w, "missing user id", http.StatusBadRequest)
 			return
 		}
-		id, _, err := h.Hub.CreateGame(ctx, userID)
+		q := r.URL.Query()
+		opts, err := h.gameOptions(q.Get("timeControl"), q.Get("language"), q.Get("fen"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		id, _, err := h.Hub.CreateGame(ctx, userID, opts)
 		if err != nil {
 			logging.Debugf("create game failed: %v", err)
 			http.Error(w, "failed to create game", http.StatusInternalServerError)
 			return
 		}
-		http.Redirect(w, r, "/"+id, http.StatusFound)
+		http.Redirect(w, r, "/"+id.String(), http.StatusFound)
+	}
+}
+
+// gameOptions validates the settings submitted when creating a game.
+func (h *Handler) gameOptions(timeControl, language, fen string) (game.GameOptions, error) {
+	var opts game.GameOptions
+	if strings.TrimSpace(timeControl) != "" {
+		tc, err := h.Hub.ValidateTimeControl(timeControl)
+		if err != nil {
+			return opts, err
+		}
+		opts.TimeControl = tc.String()
 	}
+	if strings.TrimSpace(language) != "" {
+		lang, ok := i18n.Normalize(language)
+		if !ok {
+			return opts, fmt.Errorf("unsupported language %q", language)
+		}
+		// English is the default wording; only store other languages.
+		if lang != i18n.Default {
+			opts.Language = lang
+		}
+	}
+	start, err := game.ParseStartFEN(fen)
+	if err != nil {
+		return opts, err
+	}
+	opts.StartFEN = start
+	return opts, nil
+}
+
+// HandleLanguages lists the languages a game can be created with.
+func (h *Handler) HandleLanguages(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "languages": i18n.Supported()})
+}
+
+// HandleTimeControls lists the instance's time control presets.
+func (h *Handler) HandleTimeControls(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "timeControls": h.Hub.TimeControls()})
 }
 
 // HandlePage serves the home page or game page.
 func (h *Handler) HandlePage(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/")
-	if path == "" || path == "index.html" {
+	id := requestGameID(r)
+	if id.IsZero() {
 		templates.WriteHomeHTML(w)
 		return
 	}
-	if _, _, err := h.Hub.Get(r.Context(), path, ""); err != nil && !errors.Is(err, storage.ErrNotFound) {
-		logging.Debugf("ensure game %s failed: %v", path, err)
+	g, _, err := h.Hub.Get(r.Context(), id, "")
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logging.Debugf("ensure game %s failed: %v", id, err)
+	}
+	if pos, ok := h.sharedPly(r, g); ok {
+		if data, err := json.Marshal(pos); err == nil {
+			templates.WriteGameHTMLAt(w, id.String(), data)
+			return
+		}
 	}
-	templates.WriteGameHTML(w, path)
+	templates.WriteGameHTML(w, id.String())
+}
+
+// sharedPly returns the position a ?ply= link asks the game page to open at.
+// Private games only reveal it to callers who may view them; the page asks
+// the API itself otherwise.
+func (h *Handler) sharedPly(r *http.Request, g *game.Game) (game.PlyPosition, bool) {
+	v := r.URL.Query().Get("ply")
+	if v == "" || g == nil {
+		return game.PlyPosition{}, false
+	}
+	ply, err := strconv.Atoi(v)
+	if err != nil {
+		return game.PlyPosition{}, false
+	}
+	if _, granted := requestGrant(r); !granted && !g.CanView(requestUserID(r), r.URL.Query().Get("code")) {
+		return game.PlyPosition{}, false
+	}
+	return g.Snapshot().AtPly(ply)
+}
+
+// HandleOverlay serves a transparent live board for stream overlays. It
+// follows the game as a spectator; see overlay.html for its query options.
+func (h *Handler) HandleOverlay(w http.ResponseWriter, r *http.Request) {
+	templates.WriteOverlayHTML(w, requestGameID(r).String())
 }
 
 // HandleSSE handles Server-Sent Events for real-time game updates.
 func (h *Handler) HandleSSE(w http.ResponseWriter, r *http.Request) {
-	id := strings.TrimPrefix(r.URL.Path, "/sse/")
+	id := requestGameID(r)
 	clientID := strings.TrimSpace(r.URL.Query().Get("clientId"))
 	if clientID == "" {
 		clientID = strings.TrimSpace(r.Header.Get("X-User-ID"))
@@ -96,14 +273,45 @@ func (h *Handler) HandleSSE(w http.ResponseWriter, r *http.Request) {
 		clientID = uuid.NewString()
 	}
 
-	g, col, err := h.Hub.Get(r.Context(), id, clientID)
+	// Spectate-only connections (such as federation relays) never claim a seat.
+	seatID := clientID
+	if r.URL.Query().Get("spectate") == "1" {
+		seatID = ""
+	}
+
+	// Private games only seat clients that present the join code, and may
+	// keep spectators out without it as well.
+	g, _, err := h.Hub.Get(r.Context(), id, "")
 	if err != nil {
 		http.Error(w, "game unavailable", http.StatusInternalServerError)
 		return
 	}
+	// An invite link stands in for the join code: a play invite seats its
+	// holder in the color it names, and invite-only games seat nobody else.
+	code := r.URL.Query().Get("code")
+	var invite game.InviteRole
+	if t := r.URL.Query().Get("invite"); t != "" && h.Hub.Seats != nil {
+		invite, _ = h.Hub.Seats.ParseInvite(id, t)
+	}
+	grant, granted := requestGrant(r)
+	if !granted && invite == "" && !g.CanView(clientID, code) {
+		http.Error(w, "join code required", http.StatusForbidden)
+		return
+	}
+	if !g.InviteAdmits(clientID, invite) || (invite == "" && !g.CanJoin(clientID, code)) || (granted && grant.Scope == game.ScopeRead) {
+		seatID = ""
+	}
 
-	flusher, ok := w.(http.Flusher)
-	if !ok {
+	g, col, joined, err := h.Hub.Join(r.Context(), id, seatID)
+	if err != nil {
+		http.Error(w, "game unavailable", http.StatusInternalServerError)
+		return
+	}
+	// Rooms are kept in memory only, so games finished before a restart
+	// get theirs back when someone comes to look.
+	h.openPostMortem(g)
+
+	if _, ok := w.(http.Flusher); !ok {
 		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
 		return
 	}
@@ -113,51 +321,101 @@ func (h *Handler) HandleSSE(w http.ResponseWriter, r *http.Request) {
 
 	ch := make(chan []byte, 16)
 	g.AddWatcher(ch)
+	defer g.RemoveWatcher(ch)
+	g.Connect(clientID)
+	defer g.Disconnect(clientID)
 
-	g.Mu.Lock()
+	g.Mu.RLock()
 	state := g.StateLocked()
-	g.Mu.Unlock()
+	owner := g.OwnerID == clientID
+	g.Mu.RUnlock()
 
-	initial := game.ClientState{GameState: state, Role: "spectator", ClientID: clientID}
+	initial := game.ClientState{GameState: state, Role: "spectator", ClientID: clientID, ParticipantID: g.ParticipantID(clientID), Owner: owner}
+	// A seat token goes only to a caller who took the seat just now or
+	// already proved it holds it; naming a client ID is not enough.
+	proven := joined || h.seatAuthorized(r, id, clientID, r.URL.Query().Get("seatToken"))
 	if col != nil {
 		c := col.String()
 		initial.Color = &c
 		initial.Role = "player"
+		if proven {
+			initial.SeatToken = h.seatToken(id, clientID)
+		}
+	} else if seatID != "" && g.PostMortemOpen() {
+		// Spectators explore lines in open post-mortem rooms too.
+		initial.Role = "analyst"
+		if proven || g.ClaimAnalyst(clientID) {
+			initial.SeatToken = h.seatToken(id, clientID)
+		}
 	}
 	initialJSON, _ := json.Marshal(initial)
+	schema := game.NegotiateSchema(r.URL.Query().Get("schema"))
+	notation := h.notationFor(r, clientID).In(g.Language)
+	initialJSON = game.ConvertPayload(game.ApplyNotation(initialJSON, notation), schema)
 
-	_, _ = fmt.Fprintf(w, "data: %s\n\n", initialJSON)
-	flusher.Flush()
+	stream := newEventStream(w)
+	stream.Event(initialJSON)
+	if m := h.Hub.Maintenance(); m.Active {
+		data, _ := json.Marshal(m)
+		stream.Event(game.ConvertPayload(data, schema))
+	}
+	for _, msg := range g.ChatHistory() {
+		data, _ := json.Marshal(msg)
+		stream.Event(game.ConvertPayload(data, schema))
+	}
+	if err := stream.Flush(); err != nil {
+		return
+	}
 
 	lastSeen := g.Touch()
 	if err := h.persistLastSeen(r.Context(), id, lastSeen); err != nil {
 		logging.Debugf("update last seen failed: %v", err)
 	}
+	if col != nil {
+		if err := h.markViewed(r.Context(), id, clientID, lastSeen); err != nil {
+			logging.Debugf("mark viewed failed: %v", err)
+		}
+	}
 
-	ticker := time.NewTicker(15 * time.Second)
+	ticker := time.NewTicker(h.heartbeat())
 	defer ticker.Stop()
-	defer g.RemoveWatcher(ch)
 
 	ctx := r.Context()
+	drained := h.drained()
+	// A watcher too slow to keep up is evicted; ending the stream makes the
+	// client reconnect and start again from the current state.
+	evicted := g.Evicted(ch)
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-drained:
+			h.writeReconnect(stream, schema)
+			_ = stream.Flush()
+			return
+		case <-evicted:
+			logging.Debugf("sse %s: watcher evicted for falling behind", id)
+			return
 		case <-ticker.C:
-			_, _ = w.Write([]byte("data: {}\n\n"))
-			flusher.Flush()
+			stream.Heartbeat()
+			g.Resync(ch)
 		case msg := <-ch:
-			_, _ = w.Write([]byte("data: "))
-			_, _ = w.Write(msg)
-			_, _ = w.Write([]byte("\n\n"))
-			flusher.Flush()
+			stream.Event(game.ConvertPayload(game.ApplyNotation(msg, notation), schema))
+			for n := len(ch); n > 0; n-- {
+				stream.Event(game.ConvertPayload(game.ApplyNotation(<-ch, notation), schema))
+			}
+		}
+		if err := stream.Flush(); err != nil {
+			logging.Debugf("sse %s: dropping watcher: %v", id, err)
+			return
 		}
 	}
 }
 
-// HandleMove processes a chess move.
+// HandleMove processes a chess move, sent in UCI ("g1f3") or in standard
+// algebraic notation ("Nf3") decoded against the current position.
 func (h *Handler) HandleMove(w http.ResponseWriter, r *http.Request) {
-	id := strings.TrimPrefix(r.URL.Path, "/move/")
+	id := requestGameID(r)
 	g, _, err := h.Hub.Get(r.Context(), id, "")
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
@@ -175,28 +433,76 @@ func (h *Handler) HandleMove(w http.ResponseWriter, r *http.Request) {
 		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing client id"})
 		return
 	}
+	if !h.seatAuthorized(r, id, clientID, m.SeatToken) {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "bad seat token"})
+		return
+	}
+	moveID := strings.TrimSpace(m.MoveID)
+	if moveID == "" {
+		moveID = strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+	}
+	if len(moveID) > game.MaxMoveIDLength {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad move id"})
+		return
+	}
+	if moveID != "" && g.MovePlayed(clientID, moveID) {
+		h.writeDuplicateMove(w, r, g, clientID)
+		return
+	}
 
 	uci := strings.ToLower(strings.TrimSpace(m.UCI))
-	uci = appendPromotionIfPawn(g, uci)
+	if san := strings.TrimSpace(m.SAN); san != "" {
+		if uci != "" {
+			WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "conflicting move"})
+			return
+		}
+		if uci, err = g.DecodeSAN(san); err != nil {
+			WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "illegal move"})
+			return
+		}
+	}
+	promotion := strings.ToLower(strings.TrimSpace(m.Promotion))
+	if len(uci) == 5 {
+		if promotion != "" && promotion != uci[4:] {
+			WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "conflicting promotion"})
+			return
+		}
+		promotion = uci[4:]
+		uci = uci[:4]
+	}
+	if len(uci) != 4 {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad move"})
+		return
+	}
+	if promotion != "" {
+		if !game.ValidPromotion(promotion) {
+			WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad promotion piece"})
+			return
+		}
+		uci += promotion
+	} else {
+		// Older clients never send a piece; keep queening for them.
+		uci = appendPromotionIfPawn(g, uci)
+	}
 
 	from := uci[:2]
 
-	g.Mu.Lock()
+	g.Mu.RLock()
 	state := g.StateLocked()
-	playerColor, ok := g.Clients[clientID]
 	isOwner := g.OwnerID == clientID
-	g.Mu.Unlock()
+	g.Mu.RUnlock()
 
-	fenOpt, err := chess.FEN(state.FEN)
+	tmp, err := game.GameFromFEN(state.FEN)
 	if err != nil {
 		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "bad fen", "state": state})
 		return
 	}
-	tmp := chess.NewGame(fenOpt)
 	board := tmp.Position().Board()
 	fsq := parseSquare(from)
 	piece := board.Piece(fsq)
 	turn := tmp.Position().Turn()
+	// In hot-seat games the owner plays whichever side is to move.
+	playerColor, ok := g.MoverColor(clientID, turn)
 
 	if !ok {
 		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "unknown client", "state": state})
@@ -214,34 +520,245 @@ func (h *Handler) HandleMove(w http.ResponseWriter, r *http.Request) {
 	}
 
 	lastSeen := g.Touch()
+	before := state.FEN
 
-	if err := g.MakeMove(uci); err != nil {
+	if err := g.MakeMoveOnce(uci, clientID, moveID); err != nil {
+		if errors.Is(err, game.ErrDuplicateMove) {
+			h.writeDuplicateMove(w, r, g, clientID)
+			return
+		}
+		if errors.Is(err, game.ErrMaintenance) {
+			writeMaintenance(w, h.Hub.Maintenance())
+			return
+		}
 		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": err.Error(), "state": state})
 		return
 	}
+	if _, ok := g.PostMortemOf(); ok {
+		// Lines explored in a post-mortem room are kept out of every record.
+		go g.Broadcast()
+		g.Mu.RLock()
+		state = g.StateLocked()
+		g.Mu.RUnlock()
+		WriteJSON(w, http.StatusOK, StateResponse{OK: true, State: stateForClient(r, state, h.notationFor(r, clientID).In(g.Language))})
+		return
+	}
+	if h.Hub.EnforceHouseRules(g) {
+		if err := h.Hub.SaveTimeline(r.Context(), g); err != nil {
+			logging.Debugf("save timeline failed: %v", err)
+		}
+	}
+	h.openPostMortem(g)
 
 	go g.Broadcast()
 
-	g.Mu.Lock()
+	g.Mu.RLock()
 	state = g.StateLocked()
 	moveNumber := len(state.UCI)
-	g.Mu.Unlock()
+	g.Mu.RUnlock()
 
 	outcome := g.Outcome()
 
 	if err := h.persistGameState(r.Context(), id, state, outcome, lastSeen); err != nil {
 		logging.Debugf("persist game state failed: %v", err)
 	}
-	if err := h.recordMove(r.Context(), id, clientID, moveNumber, uci, playerColor, isOwner, lastSeen); err != nil {
+	h.announceFinished(id, state.Status, outcome)
+	h.resolveLadder(r.Context(), g, outcome)
+	h.Hub.QueueAnalysis(g)
+	go h.Hub.Evaluate(g)
+	captured := ""
+	if state.LastMove != nil {
+		captured = state.LastMove.CapturedPiece
+	}
+	var timing game.MoveTime
+	if n := len(state.MoveTimes); n > 0 {
+		timing = state.MoveTimes[n-1]
+	}
+	if err := h.recordMove(r.Context(), id, clientID, moveNumber, uci, captured, timing, playerColor, isOwner, lastSeen); err != nil {
 		logging.Debugf("record move failed: %v", err)
 	}
+	if state.Training != nil && isOwner {
+		go h.answerTraining(g, clientID, before, uci)
+	}
+
+	WriteJSON(w, http.StatusOK, StateResponse{OK: true, State: stateForClient(r, state, h.notationFor(r, clientID).In(g.Language))})
+}
+
+// writeDuplicateMove answers a move whose id was already played as the first
+// request was answered, with the current state, so a client retrying after a
+// lost response sees its move succeed.
+func (h *Handler) writeDuplicateMove(w http.ResponseWriter, r *http.Request, g *game.Game, clientID string) {
+	g.Mu.RLock()
+	state := g.StateLocked()
+	g.Mu.RUnlock()
+	WriteJSON(w, http.StatusOK, StateResponse{OK: true, Duplicate: true, State: stateForClient(r, state, h.notationFor(r, clientID).In(g.Language))})
+}
+
+// stateForClient writes the move list in the client's notation and converts
+// the state for clients that negotiated an older schema with the ?schema=
+// query parameter.
+func stateForClient(r *http.Request, state game.GameState, notation game.Notation) any {
+	schema := game.NegotiateSchema(r.URL.Query().Get("schema"))
+	if notation != game.NotationSAN {
+		state.Moves = game.FormatMoves(state.StartFEN, state.UCI, notation)
+	}
+	if schema == game.SchemaVersion {
+		return state
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return state
+	}
+	return json.RawMessage(game.ConvertPayload(data, schema))
+}
+
+// notationFor resolves the move notation for a request: the ?notation= query
+// parameter, then the user's saved preference, then SAN. Preferences are cached
+// for notationTTL, so move responses and new streams need not load them each
+// time.
+func (h *Handler) notationFor(r *http.Request, userID string) game.Notation {
+	if v := r.URL.Query().Get("notation"); v != "" {
+		if n, ok := game.ParseNotation(v); ok {
+			return n
+		}
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return game.NotationSAN
+	}
+	h.notationMu.Lock()
+	cached, ok := h.notations[uid]
+	h.notationMu.Unlock()
+	if ok && time.Since(cached.at) < notationTTL {
+		return cached.notation
+	}
+	pref, err := h.Store.Preferences(r.Context(), uid)
+	if err != nil {
+		logging.Debugf("load preferences failed: %v", err)
+		return game.NotationSAN
+	}
+	n, ok := game.ParseNotation(pref.Notation)
+	if !ok {
+		n = game.NotationSAN
+	}
+	h.rememberNotation(uid, n)
+	return n
+}
+
+// rememberNotation caches the notation userID prefers for notationFor.
+func (h *Handler) rememberNotation(userID uuid.UUID, n game.Notation) {
+	h.notationMu.Lock()
+	defer h.notationMu.Unlock()
+	if h.notations == nil || len(h.notations) >= maxCachedNotations {
+		h.notations = make(map[uuid.UUID]cachedNotation)
+	}
+	h.notations[userID] = cachedNotation{notation: n, at: time.Now()}
+}
+
+// invites returns the invite links for a new game whose owner plays
+// ownerColor: one for the opponent's seat and one for spectators. It is nil
+// when the hub does not sign seats.
+func (h *Handler) invites(id game.GameID, ownerColor chess.Color) map[game.InviteRole]string {
+	if h.Hub.Seats == nil {
+		return nil
+	}
+	links := make(map[game.InviteRole]string, 2)
+	for _, role := range []game.InviteRole{game.InviteRoleFor(ownerColor.Other()), game.InviteSpectate} {
+		links[role] = "/" + id.String() + "?invite=" + h.Hub.Seats.Invite(id, role)
+	}
+	return links
+}
+
+// seatToken returns the token proving clientID holds its seat in game id, or
+// "" when the hub does not sign seats.
+func (h *Handler) seatToken(id game.GameID, clientID string) string {
+	if h.Hub.Seats == nil {
+		return ""
+	}
+	return h.Hub.Seats.Token(id, clientID)
+}
+
+// seatAuthorized reports whether token was issued to clientID for game id, or
+// the request carries a play-scoped API token issued by clientID. Every
+// request is authorized when the hub does not sign seats.
+func (h *Handler) seatAuthorized(r *http.Request, id game.GameID, clientID, token string) bool {
+	if grant, ok := requestGrant(r); ok && grant.Scope == game.ScopePlay && grant.ClientID == clientID {
+		return true
+	}
+	return h.Hub.Seats == nil || h.Hub.Seats.Valid(id, clientID, token)
+}
 
-	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "state": state})
+// ResignRequest is the body of POST /resign/{id}.
+type ResignRequest struct {
+	ClientID  string `json:"clientId" required:"true"`
+	SeatToken string `json:"seatToken"`
+}
+
+// HandleResign ends the game as a loss for the requesting player.
+func (h *Handler) HandleResign(w http.ResponseWriter, r *http.Request) {
+	id := requestGameID(r)
+	g, _, err := h.Hub.Get(r.Context(), id, "")
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
+		return
+	}
+
+	var body ResignRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return
+	}
+	clientID := strings.TrimSpace(body.ClientID)
+	if clientID == "" {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing client id"})
+		return
+	}
+	if !h.seatAuthorized(r, id, clientID, body.SeatToken) {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "bad seat token"})
+		return
+	}
+
+	g.Mu.RLock()
+	playerColor, ok := g.Clients[clientID]
+	g.Mu.RUnlock()
+	if !ok {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "unknown client"})
+		return
+	}
+	if _, room := g.PostMortemOf(); room {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "post-mortem rooms cannot be resigned"})
+		return
+	}
+
+	lastSeen := g.Touch()
+	if err := g.Resign(playerColor); err != nil {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": err.Error()})
+		return
+	}
+	h.openPostMortem(g)
+
+	go g.Broadcast()
+
+	g.Mu.RLock()
+	state := g.StateLocked()
+	g.Mu.RUnlock()
+
+	if err := h.persistGameState(r.Context(), id, state, g.Outcome(), lastSeen); err != nil {
+		logging.Debugf("persist game state failed: %v", err)
+	}
+	if err := h.Hub.SaveTimeline(r.Context(), g); err != nil {
+		logging.Debugf("save timeline failed: %v", err)
+	}
+	h.announceFinished(id, state.Status, g.Outcome())
+	h.resolveLadder(r.Context(), g, g.Outcome())
+	h.Hub.QueueAnalysis(g)
+
+	WriteJSON(w, http.StatusOK, StateResponse{OK: true, State: stateForClient(r, state, h.notationFor(r, clientID).In(g.Language))})
 }
 
 // HandleReact processes a reaction/emoji.
 func (h *Handler) HandleReact(w http.ResponseWriter, r *http.Request) {
-	id := strings.TrimPrefix(r.URL.Path, "/react/")
+	id := requestGameID(r)
 	g, _, err := h.Hub.Get(r.Context(), id, "")
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
@@ -261,6 +778,7 @@ func (h *Handler) HandleReact(w http.ResponseWriter, r *http.Request) {
 	}
 
 	payload := game.ReactionPayload{
+		Schema: game.SchemaVersion,
 		Kind:   "emoji",
 		Emoji:  body.Emoji,
 		At:     time.Now().UnixMilli(),
@@ -273,7 +791,7 @@ func (h *Handler) HandleReact(w http.ResponseWriter, r *http.Request) {
 
 // HandleRelease removes a client from a game if requested by the owner.
 func (h *Handler) HandleRelease(w http.ResponseWriter, r *http.Request) {
-	id := strings.TrimPrefix(r.URL.Path, "/release/")
+	id := requestGameID(r)
 	g, _, err := h.Hub.Get(r.Context(), id, "")
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
@@ -281,8 +799,9 @@ func (h *Handler) HandleRelease(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var body struct {
-		ClientID string `json:"clientId"`
-		TargetID string `json:"targetId"`
+		ClientID  string `json:"clientId"`
+		TargetID  string `json:"targetId"`
+		SeatToken string `json:"seatToken"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
@@ -293,28 +812,40 @@ func (h *Handler) HandleRelease(w http.ResponseWriter, r *http.Request) {
 		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing client id"})
 		return
 	}
+	if !h.seatAuthorized(r, id, body.ClientID, body.SeatToken) {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "bad seat token"})
+		return
+	}
 
-	g.Mu.Lock()
+	g.Mu.RLock()
 	owner := g.OwnerID
-	g.Mu.Unlock()
+	g.Mu.RUnlock()
 	if body.ClientID != owner {
 		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "not owner"})
 		return
 	}
 
+	// Owners name other participants by participant ID.
+	if target, ok := g.ParticipantClient(body.TargetID); ok {
+		body.TargetID = target
+	}
 	g.RemoveClient(body.TargetID)
 	if err := h.deactivateSession(r.Context(), id, body.TargetID); err != nil {
 		logging.Debugf("deactivate session failed: %v", err)
 	}
+	if err := h.Hub.SaveTimeline(r.Context(), g); err != nil {
+		logging.Debugf("save timeline failed: %v", err)
+	}
 	go g.Broadcast()
 	WriteJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
 
 // HandleForget ends a game when the owner forgets it from the home page.
 func (h *Handler) HandleForget(w http.ResponseWriter, r *http.Request) {
-	id := strings.TrimPrefix(r.URL.Path, "/forget/")
+	id := requestGameID(r)
 	var body struct {
-		UserID string `json:"userId"`
+		UserID    string `json:"userId"`
+		SeatToken string `json:"seatToken"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
@@ -325,6 +856,10 @@ func (h *Handler) HandleForget(w http.ResponseWriter, r *http.Request) {
 		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing user id"})
 		return
 	}
+	if !h.seatAuthorized(r, id, userID, body.SeatToken) {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "bad seat token"})
+		return
+	}
 
 	g, _, err := h.Hub.Get(r.Context(), id, userID)
 	if err != nil {
@@ -332,9 +867,9 @@ func (h *Handler) HandleForget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	g.Mu.Lock()
+	g.Mu.RLock()
 	owner := g.OwnerID
-	g.Mu.Unlock()
+	g.Mu.RUnlock()
 	if owner != userID {
 		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "not owner"})
 		return
@@ -358,7 +893,7 @@ func (h *Handler) HandleForget(w http.ResponseWriter, r *http.Request) {
 // HandleStats returns aggregate statistics for display on the home page.
 func (h *Handler) HandleStats(w http.ResponseWriter, r *http.Request) {
 	if h.Store == nil {
-		WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "stats": storage.Stats{}})
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "stats": storage.Stats{Terminations: map[string]int64{}}})
 		return
 	}
 
@@ -370,55 +905,70 @@ func (h *Handler) HandleStats(w http.ResponseWriter, r *http.Request) {
 	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "stats": stats})
 }
 
-func (h *Handler) persistLastSeen(ctx context.Context, id string, ts time.Time) error {
+func (h *Handler) persistLastSeen(ctx context.Context, id game.GameID, ts time.Time) error {
 	if h.Store == nil {
 		return nil
 	}
-	gameID, err := uuid.Parse(id)
-	if err != nil {
-		return err
-	}
-	return h.Store.UpdateLastSeen(ctx, gameID, ts)
+	return h.Store.UpdateLastSeen(ctx, id.UUID(), ts)
 }
 
-func (h *Handler) persistGameState(ctx context.Context, id string, state game.GameState, outcome chess.Outcome, lastSeen time.Time) error {
+func (h *Handler) markViewed(ctx context.Context, gameID game.GameID, userID string, ts time.Time) error {
 	if h.Store == nil {
 		return nil
 	}
-	gameID, err := uuid.Parse(id)
+	uid, err := uuid.Parse(userID)
 	if err != nil {
 		return err
 	}
+	return h.Store.MarkViewed(ctx, gameID.UUID(), uid, ts)
+}
+
+func (h *Handler) persistGameState(ctx context.Context, id game.GameID, state game.GameState, outcome chess.Outcome, lastSeen time.Time) error {
+	if h.Store == nil {
+		return nil
+	}
 	fen := state.FEN
 	pgn := state.PGN
 	status := state.Status
+	plies := len(state.UCI)
 	active := outcome == chess.NoOutcome
+	byWhite := strings.Join(state.Captured.ByWhite, "")
+	byBlack := strings.Join(state.Captured.ByBlack, "")
 	upd := storage.GameStateUpdate{
-		FEN:      &fen,
-		PGN:      &pgn,
-		Status:   &status,
-		Active:   &active,
-		LastSeen: &lastSeen,
+		FEN:             &fen,
+		PGN:             &pgn,
+		Status:          &status,
+		Plies:           &plies,
+		Active:          &active,
+		LastSeen:        &lastSeen,
+		CapturedByWhite: &byWhite,
+		CapturedByBlack: &byBlack,
+	}
+	if state.Training != nil {
+		if data, err := json.Marshal(state.Training); err == nil {
+			training := string(data)
+			upd.Training = &training
+		}
 	}
 	if !active {
 		result := outcome.String()
 		if result != "" {
 			upd.Result = &result
 		}
+		if termination := state.Termination; termination != "" {
+			upd.Termination = &termination
+		}
 		completedAt := lastSeen
 		upd.CompletedAt = &completedAt
 	}
-	return h.Store.SaveGameState(ctx, gameID, upd)
+	return h.Store.SaveGameState(ctx, id.UUID(), upd)
 }
 
-func (h *Handler) recordMove(ctx context.Context, gameID, clientID string, number int, uci string, color chess.Color, isOwner bool, lastSeen time.Time) error {
+func (h *Handler) recordMove(ctx context.Context, gameID game.GameID, clientID string, number int, uci, captured string, timing game.MoveTime, color chess.Color, isOwner bool, lastSeen time.Time) error {
 	if h.Store == nil {
 		return nil
 	}
-	gid, err := uuid.Parse(gameID)
-	if err != nil {
-		return err
-	}
+	gid := gameID.UUID()
 	uid, err := uuid.Parse(clientID)
 	if err != nil {
 		return err
@@ -427,7 +977,11 @@ func (h *Handler) recordMove(ctx context.Context, gameID, clientID string, numbe
 	if color == chess.Black {
 		colorStr = "black"
 	}
-	if err := h.Store.RecordMove(ctx, gid, uid, number, uci, colorStr); err != nil {
+	playedAt := time.UnixMilli(timing.At)
+	if timing.At == 0 {
+		playedAt = lastSeen
+	}
+	if err := h.Store.RecordMove(ctx, gid, uid, number, uci, colorStr, captured, playedAt, timing.ThinkTime()); err != nil {
 		return err
 	}
 	role := "player"
@@ -437,29 +991,22 @@ func (h *Handler) recordMove(ctx context.Context, gameID, clientID string, numbe
 	return h.Store.EnsureUserSession(ctx, gid, uid, colorStr, role, lastSeen)
 }
 
-func (h *Handler) deactivateSession(ctx context.Context, gameID, userID string) error {
+func (h *Handler) deactivateSession(ctx context.Context, gameID game.GameID, userID string) error {
 	if h.Store == nil {
 		return nil
 	}
-	gid, err := uuid.Parse(gameID)
-	if err != nil {
-		return err
-	}
 	uid, err := uuid.Parse(userID)
 	if err != nil {
 		return err
 	}
-	return h.Store.DeactivateUserSession(ctx, gid, uid)
+	return h.Store.DeactivateUserSession(ctx, gameID.UUID(), uid)
 }
 
-func (h *Handler) markGameForgotten(ctx context.Context, id string) error {
+func (h *Handler) markGameForgotten(ctx context.Context, id game.GameID) error {
 	if h.Store == nil {
 		return nil
 	}
-	gameID, err := uuid.Parse(id)
-	if err != nil {
-		return err
-	}
+	gameID := id.UUID()
 	now := time.Now()
 	if err := h.Store.ForgetGame(ctx, gameID, now); err != nil {
 		return err
@@ -467,14 +1014,69 @@ func (h *Handler) markGameForgotten(ctx context.Context, id string) error {
 	return h.Store.DeactivateAllSessions(ctx, gameID)
 }
 
-// ClientIP extracts the client IP from the request.
-func ClientIP(r *http.Request) string {
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		parts := strings.Split(xff, ",")
-		return strings.TrimSpace(parts[0])
+// ParseProxies reads the addresses or CIDR ranges of the reverse proxies
+// whose X-Forwarded-For headers are trusted.
+func ParseProxies(specs []string) ([]netip.Prefix, error) {
+	proxies := make([]netip.Prefix, 0, len(specs))
+	for _, s := range specs {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if p, err := netip.ParsePrefix(s); err == nil {
+			proxies = append(proxies, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q", s)
+		}
+		proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return proxies, nil
+}
+
+// trustedProxy reports whether addr is one of the instance's reverse
+// proxies.
+func (h *Handler) trustedProxy(addr string) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, p := range h.TrustedProxies {
+		if p.Contains(ip) {
+			return true
+		}
 	}
-	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
-		return host
+	return false
+}
+
+// ClientIP returns the address of the client behind the request. It is
+// the peer address unless the peer is a trusted proxy, in which case
+// X-Forwarded-For is read from the right, skipping the trusted proxies
+// that appended to it, to the first address they vouch for.
+func (h *Handler) ClientIP(r *http.Request) string {
+	addr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	if !h.trustedProxy(addr) {
+		return addr
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break
+		}
+		addr = hop
+		if !h.trustedProxy(hop) {
+			break
+		}
 	}
-	return r.RemoteAddr
+	return addr
 }
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"tinychess/internal/logging"
+)
+
+// HandleHint suggests a move to the requesting player when it is their turn.
+// Each player may ask once per game.HintCooldown.
+func (h *Handler) HandleHint(w http.ResponseWriter, r *http.Request) {
+	if h.Hub.Engine == nil {
+		WriteJSON(w, http.StatusServiceUnavailable, map[string]any{"ok": false, "error": "hints unavailable"})
+		return
+	}
+	id := requestGameID(r)
+	g, _, err := h.Hub.Get(r.Context(), id, "")
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
+		return
+	}
+
+	var body struct {
+		ClientID  string `json:"clientId"`
+		SeatToken string `json:"seatToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return
+	}
+	clientID := strings.TrimSpace(body.ClientID)
+	if clientID == "" {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing client id"})
+		return
+	}
+	if !h.seatAuthorized(r, id, clientID, body.SeatToken) {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "bad seat token"})
+		return
+	}
+
+	g.Mu.RLock()
+	playerColor, ok := g.Clients[clientID]
+	state := g.StateLocked()
+	g.Mu.RUnlock()
+	if !ok {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "unknown client"})
+		return
+	}
+	if state.Termination != "" {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "game over"})
+		return
+	}
+	if state.Turn != playerColor.String() {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "not your turn"})
+		return
+	}
+	if canHint, wait := g.CanHint(clientID); !canHint {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": fmt.Sprintf("cooldown %ds", wait)})
+		return
+	}
+
+	hint, err := h.Hub.Hint(g)
+	if err != nil {
+		logging.Debugf("hint for %s failed: %v", g.ID, err)
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "no hint available"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "hint": hint})
+}
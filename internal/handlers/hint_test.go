@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tinychess/internal/game"
+)
+
+func TestHandleHintWithoutEngine(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+
+	req := httptest.NewRequest("POST", "/api/games/"+game.NewGameID().String()+"/hint", strings.NewReader(`{"clientId":"x"}`))
+	w := httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+}
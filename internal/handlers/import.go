@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+
+	"tinychess/internal/game"
+	"tinychess/internal/logging"
+)
+
+// HandleImport creates a game from the PGN in the request body, with its
+// mainline already played, so a game from elsewhere can be reviewed or
+// continued. The importer, given by ?userId= or X-User-ID, owns the game and
+// takes a seat as usual; the opponent's seat is left open.
+func (h *Handler) HandleImport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := requestUserID(r)
+	if userID == "" {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing user id"})
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, game.MaxImportSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			WriteJSON(w, http.StatusRequestEntityTooLarge, map[string]any{"ok": false, "error": "pgn too large"})
+			return
+		}
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad body"})
+		return
+	}
+	imported, err := game.ParsePGN(string(body))
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+		return
+	}
+
+	id, color, err := h.Hub.CreateGame(ctx, userID, game.GameOptions{StartFEN: imported.StartFEN})
+	if err != nil {
+		logging.Debugf("create game for import failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not create game"})
+		return
+	}
+	g, _, err := h.Hub.Get(ctx, id, "")
+	if err == nil {
+		err = g.LoadMoves(imported.UCI)
+	}
+	if err != nil {
+		logging.Debugf("load imported moves for %s failed: %v", id, err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not import game"})
+		return
+	}
+
+	g.Mu.RLock()
+	state := g.StateLocked()
+	g.Mu.RUnlock()
+	now := time.Now()
+	if err := h.persistGameState(ctx, id, state, g.Outcome(), now); err != nil {
+		logging.Debugf("persist imported game failed: %v", err)
+	}
+	h.recordImportedMoves(r, id, userID, state, now)
+	h.Hub.QueueAnalysis(g)
+
+	url := "/" + id.String()
+	if base := game.CurrentInstance().BaseURL; base != "" {
+		url = base + url
+	}
+	WriteJSON(w, http.StatusOK, ImportResponse{
+		OK:        true,
+		ID:        id,
+		URL:       url,
+		Color:     color.String(),
+		SeatToken: h.seatToken(id, userID),
+		Plies:     len(state.UCI),
+	})
+}
+
+// recordImportedMoves stores the imported mainline as the importer's moves,
+// so the game replays from the database like any other.
+func (h *Handler) recordImportedMoves(r *http.Request, id game.GameID, userID string, state game.GameState, at time.Time) {
+	if h.Store == nil {
+		return
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return
+	}
+	first := chess.White
+	if start := state.StartFEN; start != "" && strings.Fields(start)[1] == "b" {
+		first = chess.Black
+	}
+	for i, uci := range state.UCI {
+		color := "white"
+		if (first == chess.Black) == (i%2 == 0) {
+			color = "black"
+		}
+		if err := h.Store.RecordMove(r.Context(), id.UUID(), uid, i+1, uci, color, "", at, 0); err != nil {
+			logging.Debugf("record imported move for %s failed: %v", id, err)
+			return
+		}
+	}
+}
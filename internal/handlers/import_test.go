@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"tinychess/internal/game"
+	"tinychess/internal/storage"
+)
+
+func TestHandleImport(t *testing.T) {
+	db, err := storage.New("sqlite://"+filepath.Join(t.TempDir(), "test.db"), 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	store := storage.NewStore(db)
+	mux := NewHandler(game.NewHub(store), store).Routes()
+	userID := uuid.NewString()
+
+	post := func(body string) (int, map[string]any) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/import?userId="+userID, strings.NewReader(body)))
+		var resp map[string]any
+		_ = json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+
+	if code, _ := post("1. e5 *"); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an illegal move, got %d", code)
+	}
+	code, resp := post("[Event \"Casual\"]\n\n1. e4 e5 2. Nf3 {a comment} Nc6 (2... d6) 3. Bb5 *")
+	if code != http.StatusOK || resp["ok"] != true || resp["url"] != "/"+resp["id"].(string) {
+		t.Fatalf("unexpected response %d %v", code, resp)
+	}
+
+	// A fresh hub restores the imported mainline from the database.
+	id, err := game.ParseGameID(resp["id"].(string))
+	if err != nil {
+		t.Fatalf("id: %v", err)
+	}
+	g, _, err := game.NewHub(store).Get(context.Background(), id, "")
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	g.Mu.RLock()
+	uci := g.MovesUCI()
+	owner := g.OwnerID
+	g.Mu.RUnlock()
+	if want := []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1b5"}; !slices.Equal(uci, want) {
+		t.Fatalf("got moves %v, want %v", uci, want)
+	}
+	if owner != userID {
+		t.Fatalf("expected importer to own the game, got %q", owner)
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+
+	"tinychess/internal/game"
+	"tinychess/internal/logging"
+	"tinychess/internal/storage"
+	"tinychess/internal/templates"
+)
+
+// DefaultLadderReach is how many rungs up a player may challenge when
+// Handler.LadderReach is unset.
+const DefaultLadderReach = 3
+
+// MaxLadderName bounds the display name shown on the ladder.
+const MaxLadderName = 32
+
+func (h *Handler) ladderReach() int {
+	if h.LadderReach > 0 {
+		return h.LadderReach
+	}
+	return DefaultLadderReach
+}
+
+// HandleLadderPage serves the live ladder page.
+func (h *Handler) HandleLadderPage(w http.ResponseWriter, r *http.Request) {
+	templates.WriteLadderHTML(w)
+}
+
+// HandleLadder lists the ladder from the top rung down, along with the
+// challenges still being played.
+func (h *Handler) HandleLadder(w http.ResponseWriter, r *http.Request) {
+	rungs, err := h.Store.Ladder(r.Context())
+	if err != nil {
+		logging.Debugf("ladder failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not load ladder"})
+		return
+	}
+	challenges, err := h.Store.OpenChallenges(r.Context())
+	if err != nil {
+		logging.Debugf("ladder challenges failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not load ladder"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "reach": h.ladderReach(), "rungs": rungs, "challenges": challenges})
+}
+
+// HandleLadderJoin puts the caller on the bottom rung, or updates their name
+// if they are already on the ladder.
+func (h *Handler) HandleLadderJoin(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		UserID string `json:"userId"`
+		Name   string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return
+	}
+	userID, err := uuid.Parse(strings.TrimSpace(body.UserID))
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing user id"})
+		return
+	}
+	name := strings.TrimSpace(body.Name)
+	if name == "" || len(name) > MaxLadderName {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad name"})
+		return
+	}
+	if h.Store == nil {
+		WriteJSON(w, http.StatusServiceUnavailable, map[string]any{"ok": false, "error": "ladder unavailable"})
+		return
+	}
+	rung, err := h.Store.JoinLadder(r.Context(), userID, name, time.Now())
+	if err != nil {
+		logging.Debugf("join ladder failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not join ladder"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "rung": rung})
+}
+
+// HandleLadderChallenge starts a game deciding a challenge against a player
+// up to the ladder's reach above the challenger. The challenger owns the new
+// game and shares its link with the defender.
+func (h *Handler) HandleLadderChallenge(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		UserID     string `json:"userId"`
+		DefenderID string `json:"defenderId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return
+	}
+	challengerID, err := uuid.Parse(strings.TrimSpace(body.UserID))
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing user id"})
+		return
+	}
+	defenderID, err := uuid.Parse(strings.TrimSpace(body.DefenderID))
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad defender id"})
+		return
+	}
+	if h.Store == nil {
+		WriteJSON(w, http.StatusServiceUnavailable, map[string]any{"ok": false, "error": "ladder unavailable"})
+		return
+	}
+
+	ctx := r.Context()
+	reach := h.ladderReach()
+	if err := h.Store.CheckChallenge(ctx, challengerID, defenderID, reach); err != nil {
+		writeChallengeError(w, err)
+		return
+	}
+	id, color, err := h.Hub.CreateGame(ctx, challengerID.String(), game.GameOptions{})
+	if err != nil {
+		logging.Debugf("create ladder game failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not create game"})
+		return
+	}
+	if err := h.Store.CreateChallenge(ctx, id.UUID(), challengerID, defenderID, reach, time.Now()); err != nil {
+		writeChallengeError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "id": id, "color": color.String()})
+}
+
+func writeChallengeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotOnLadder), errors.Is(err, storage.ErrOutOfReach), errors.Is(err, storage.ErrChallengePending):
+		WriteJSON(w, http.StatusConflict, map[string]any{"ok": false, "error": err.Error()})
+	default:
+		logging.Debugf("ladder challenge failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not create challenge"})
+	}
+}
+
+// resolveLadder settles the ladder challenge decided by a finished game. The
+// winner is whichever client holds the winning color; draws and hot-seat
+// games leave the ladder unchanged.
+func (h *Handler) resolveLadder(ctx context.Context, g *game.Game, outcome chess.Outcome) {
+	if h.Store == nil || outcome == chess.NoOutcome || g.HotSeat() {
+		return
+	}
+	winnerColor := chess.NoColor
+	switch outcome {
+	case chess.WhiteWon:
+		winnerColor = chess.White
+	case chess.BlackWon:
+		winnerColor = chess.Black
+	}
+	winner := uuid.Nil
+	g.Mu.RLock()
+	for clientID, col := range g.Clients {
+		if col == winnerColor {
+			winner, _ = uuid.Parse(clientID)
+		}
+	}
+	g.Mu.RUnlock()
+	if _, err := h.Store.ResolveChallenge(ctx, g.ID.UUID(), winner, time.Now()); err != nil {
+		logging.Debugf("resolve ladder challenge %s failed: %v", g.ID, err)
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tinychess/internal/game"
+	"tinychess/internal/storage"
+)
+
+func TestLadderChallengeWinSwapsRungs(t *testing.T) {
+	db, err := storage.New("sqlite://"+filepath.Join(t.TempDir(), "test.db"), 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	store := storage.NewStore(db)
+	h := NewHandler(game.NewHub(store), store)
+	mux := h.Routes()
+
+	post := func(path, body string) map[string]any {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		var resp map[string]any
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+		return resp
+	}
+
+	top := "7d8e4f3c-0b7a-4a5e-9c1d-2f3e4a5b6c7d"
+	bottom := "0f1e2d3c-4b5a-4978-8695-a4b3c2d1e0f9"
+	for _, id := range []string{top, bottom} {
+		if resp := post("/api/ladder/join", `{"userId":"`+id+`","name":"p"}`); resp["ok"] != true {
+			t.Fatalf("join failed: %v", resp)
+		}
+	}
+	if resp := post("/api/ladder/challenges", `{"userId":"`+top+`","defenderId":"`+bottom+`"}`); resp["ok"] != false {
+		t.Fatalf("challenging down the ladder should fail, got %v", resp)
+	}
+	resp := post("/api/ladder/challenges", `{"userId":"`+bottom+`","defenderId":"`+top+`"}`)
+	if resp["ok"] != true {
+		t.Fatalf("challenge failed: %v", resp)
+	}
+	gameID := resp["id"].(string)
+	id, err := game.ParseGameID(gameID)
+	if err != nil {
+		t.Fatalf("challenge returned bad game id %q", gameID)
+	}
+
+	if _, _, err := h.Hub.Get(context.Background(), id, top); err != nil {
+		t.Fatalf("defender join: %v", err)
+	}
+	if resp := post("/resign/"+gameID, `{"clientId":"`+top+`","seatToken":"`+h.seatToken(id, top)+`"}`); resp["ok"] != true {
+		t.Fatalf("resign failed: %v", resp)
+	}
+
+	rungs, err := store.Ladder(context.Background())
+	if err != nil {
+		t.Fatalf("ladder: %v", err)
+	}
+	if rungs[0].UserID.String() != bottom || rungs[1].UserID.String() != top {
+		t.Fatalf("expected challenger on top after winning, got %+v", rungs)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/ladder", nil))
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), gameID) {
+		t.Fatalf("expected resolved challenge to leave the open list, got %d %s", w.Code, w.Body.String())
+	}
+}
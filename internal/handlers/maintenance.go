@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"tinychess/internal/game"
+)
+
+// MaxMaintenanceMessage bounds the banner shown during maintenance.
+const MaxMaintenanceMessage = 280
+
+// Writable refuses requests that create games or change their result with
+// 503 and the code "maintenance" while the instance is in maintenance mode.
+func (h *Handler) Writable(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m := h.Hub.Maintenance(); m.Active {
+			writeMaintenance(w, m)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeMaintenance(w http.ResponseWriter, m game.Maintenance) {
+	WriteJSON(w, http.StatusServiceUnavailable, map[string]any{"ok": false, "error": game.ErrMaintenance.Error(), "code": "maintenance", "maintenance": m})
+}
+
+// HandleAdminMaintenance reports (GET) or sets (POST) maintenance mode. The
+// POST body is {"active": true, "message": "…"}; watchers of loaded games
+// are told at once.
+func (h *Handler) HandleAdminMaintenance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "maintenance": h.Hub.Maintenance()})
+		return
+	}
+	var body struct {
+		Active  bool   `json:"active"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return
+	}
+	message := strings.TrimSpace(body.Message)
+	if len(message) > MaxMaintenanceMessage {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "message too long"})
+		return
+	}
+	m := h.Hub.SetMaintenance(body.Active, message)
+	// With another deployment to take over, watchers move there rather than
+	// sit out the maintenance.
+	switch {
+	case m.Active && h.AlternateURL != "":
+		h.Drain()
+	case !m.Active:
+		h.Undrain()
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "maintenance": m})
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"tinychess/internal/game"
+	"tinychess/internal/logging"
+	"tinychess/internal/storage"
+)
+
+// requestUserID returns the caller's identity: the user of an API key
+// accepted by Authenticate, or else the userId query parameter or the
+// X-User-ID header.
+func requestUserID(r *http.Request) string {
+	if id, ok := keyUser(r); ok {
+		return id
+	}
+	if id := strings.TrimSpace(r.URL.Query().Get("userId")); id != "" {
+		return id
+	}
+	return strings.TrimSpace(r.Header.Get("X-User-ID"))
+}
+
+// HandleRecent lists the caller's recent games from storage.
+func (h *Handler) HandleRecent(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(requestUserID(r))
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing user id"})
+		return
+	}
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 100 {
+			limit = n
+		}
+	}
+
+	games, err := h.Store.RecentGames(r.Context(), userID, limit)
+	if err != nil {
+		logging.Debugf("recent games failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not load games"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "games": games})
+}
+
+type bookmarkRequest struct {
+	UserID string `json:"userId"`
+	GameID string `json:"gameId"`
+}
+
+// HandleBookmark adds (POST) or removes (DELETE) a watch-later bookmark.
+func (h *Handler) HandleBookmark(w http.ResponseWriter, r *http.Request) {
+	var body bookmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return
+	}
+	userID, err := uuid.Parse(strings.TrimSpace(body.UserID))
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing user id"})
+		return
+	}
+	gameID, err := game.ParseGameID(body.GameID)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad game id"})
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		err = h.Store.RemoveBookmark(r.Context(), userID, gameID.UUID())
+	} else {
+		err = h.Store.AddBookmark(r.Context(), userID, gameID.UUID())
+	}
+	if err != nil {
+		logging.Debugf("bookmark failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not save bookmark"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// HandlePreferences returns (GET) or saves (PUT) the caller's display
+// preferences.
+func (h *Handler) HandlePreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		userID, err := uuid.Parse(requestUserID(r))
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing user id"})
+			return
+		}
+		pref, err := h.Store.Preferences(r.Context(), userID)
+		if err != nil {
+			logging.Debugf("load preferences failed: %v", err)
+			WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not load preferences"})
+			return
+		}
+		notation, ok := game.ParseNotation(pref.Notation)
+		if !ok {
+			notation = game.NotationSAN
+		}
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "preferences": preferencesJSON(pref, notation)})
+		return
+	}
+
+	// Fields left out of the body keep their saved values.
+	var body struct {
+		UserID   string  `json:"userId"`
+		Notation *string `json:"notation"`
+		HideSeen *bool   `json:"hideSeen"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return
+	}
+	userID, err := uuid.Parse(strings.TrimSpace(body.UserID))
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing user id"})
+		return
+	}
+	pref, err := h.Store.Preferences(r.Context(), userID)
+	if err != nil {
+		logging.Debugf("load preferences failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not load preferences"})
+		return
+	}
+	notation, ok := game.ParseNotation(pref.Notation)
+	if !ok {
+		notation = game.NotationSAN
+	}
+	if body.Notation != nil {
+		if notation, ok = game.ParseNotation(*body.Notation); !ok {
+			WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "unknown notation"})
+			return
+		}
+	}
+	pref.Notation = string(notation)
+	if body.HideSeen != nil {
+		pref.HideSeen = *body.HideSeen
+	}
+	if err := h.Store.SavePreferences(r.Context(), pref); err != nil {
+		logging.Debugf("save preferences failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not save preferences"})
+		return
+	}
+	h.rememberNotation(userID, notation)
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "preferences": preferencesJSON(pref, notation)})
+}
+
+func preferencesJSON(pref storage.UserPreference, notation game.Notation) map[string]any {
+	return map[string]any{"notation": notation, "hideSeen": pref.HideSeen}
+}
+
+// HandleBookmarks lists the caller's bookmarked games.
+func (h *Handler) HandleBookmarks(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(requestUserID(r))
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing user id"})
+		return
+	}
+	games, err := h.Store.Bookmarks(r.Context(), userID)
+	if err != nil {
+		logging.Debugf("bookmarks failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not load bookmarks"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "games": games})
+}
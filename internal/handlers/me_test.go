@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"tinychess/internal/game"
+	"tinychess/internal/storage"
+)
+
+func TestHandleRecentRequiresUser(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+
+	req := httptest.NewRequest("GET", "/api/me/recent", nil)
+	w := httptest.NewRecorder()
+	h.HandleRecent(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestHandleRecentWithoutStore(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+
+	req := httptest.NewRequest("GET", "/api/me/recent", nil)
+	req.Header.Set("X-User-ID", "7d8e4f3c-0b7a-4a5e-9c1d-2f3e4a5b6c7d")
+	w := httptest.NewRecorder()
+	h.HandleRecent(w, req)
+
+	var resp struct {
+		OK    bool  `json:"ok"`
+		Games []any `json:"games"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !resp.OK || resp.Games == nil || len(resp.Games) != 0 {
+		t.Fatalf("expected empty game list, got %+v", resp)
+	}
+}
+
+func TestHandleListGames(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+	req := httptest.NewRequest("GET", "/api/v1/games?userId=7d8e4f3c-0b7a-4a5e-9c1d-2f3e4a5b6c7d", nil)
+	w := httptest.NewRecorder()
+	h.HandleListGames(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without storage, got %d", w.Code)
+	}
+
+	db, err := storage.New("sqlite://"+filepath.Join(t.TempDir(), "test.db"), 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	store := storage.NewStore(db)
+	h = NewHandler(game.NewHub(store), store)
+	userID := uuid.New()
+	for i := 0; i < 3; i++ {
+		if _, _, err := h.Hub.CreateGame(context.Background(), userID.String(), game.GameOptions{}); err != nil {
+			t.Fatalf("create game: %v", err)
+		}
+	}
+
+	for _, q := range []string{"status=finished", "limit=0", "limit=101", "offset=-1"} {
+		w := httptest.NewRecorder()
+		h.HandleListGames(w, httptest.NewRequest("GET", "/api/v1/games?userId="+userID.String()+"&"+q, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+
+	page := func(q string) (games []storage.RecentGame, next *int) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.HandleListGames(w, httptest.NewRequest("GET", "/api/v1/games?userId="+userID.String()+"&"+q, nil))
+		var resp struct {
+			OK    bool                 `json:"ok"`
+			Games []storage.RecentGame `json:"games"`
+			Next  *int                 `json:"next"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || !resp.OK {
+			t.Fatalf("%s: bad response %d: %v", q, w.Code, err)
+		}
+		return resp.Games, resp.Next
+	}
+	games, next := page("limit=2")
+	if len(games) != 2 || next == nil || *next != 2 {
+		t.Fatalf("expected two games and a next page at 2, got %d and %v", len(games), next)
+	}
+	games, next = page("limit=2&offset=2")
+	if len(games) != 1 || next != nil {
+		t.Fatalf("expected one game on the last page, got %d and %v", len(games), next)
+	}
+	if games, _ = page("status=completed"); len(games) != 0 {
+		t.Fatalf("expected no completed games, got %d", len(games))
+	}
+}
+
+func TestHandleBookmarkValidatesIDs(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+
+	req := httptest.NewRequest("POST", "/api/bookmarks", strings.NewReader(`{"userId":"7d8e4f3c-0b7a-4a5e-9c1d-2f3e4a5b6c7d","gameId":"nope"}`))
+	w := httptest.NewRecorder()
+	h.HandleBookmark(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestHandlePreferencesRejectsUnknownNotation(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+
+	req := httptest.NewRequest("PUT", "/api/me/preferences", strings.NewReader(`{"userId":"7d8e4f3c-0b7a-4a5e-9c1d-2f3e4a5b6c7d","notation":"descriptive"}`))
+	w := httptest.NewRecorder()
+	h.HandlePreferences(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestNotationForCachesPreference(t *testing.T) {
+	db, err := storage.New("sqlite://"+filepath.Join(t.TempDir(), "test.db"), 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	store := storage.NewStore(db)
+	h := NewHandler(game.NewHub(store), store)
+	userID := uuid.New()
+	if err := store.SavePreferences(context.Background(), storage.UserPreference{UserID: userID, Notation: string(game.NotationLAN)}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	if n := h.notationFor(req, userID.String()); n != game.NotationLAN {
+		t.Fatalf("expected the saved notation, got %q", n)
+	}
+
+	// A change made behind the handler's back is not seen until the cache
+	// expires; one made through it is seen at once.
+	if err := store.SavePreferences(context.Background(), storage.UserPreference{UserID: userID}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if n := h.notationFor(req, userID.String()); n != game.NotationLAN {
+		t.Fatalf("expected the cached notation, got %q", n)
+	}
+	w := httptest.NewRecorder()
+	h.HandlePreferences(w, httptest.NewRequest("PUT", "/api/me/preferences", strings.NewReader(`{"userId":"`+userID.String()+`","notation":"san"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if n := h.notationFor(req, userID.String()); n != game.NotationSAN {
+		t.Fatalf("expected the new notation, got %q", n)
+	}
+}
@@ -1,13 +1,165 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/corentings/chess/v2"
 	"tinychess/internal/game"
+	"tinychess/internal/logging"
+	"tinychess/internal/storage"
 )
 
+// Middleware wraps an http.Handler with additional behavior.
+type Middleware func(http.Handler) http.Handler
+
+// Chain applies middlewares to h so that the first middleware is outermost.
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// statusRecorder captures the response status while still exposing the
+// underlying writer's streaming support.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+type gameIDKey struct{}
+
+type apiGrantKey struct{}
+
+// RequireGameID validates the {id} path parameter as a game ID, answering 404
+// for anything else. Handlers behind it read the parsed ID with
+// requestGameID instead of parsing the path again.
+func RequireGameID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := game.ParseGameID(r.PathValue("id"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), gameIDKey{}, id)))
+	})
+}
+
+// requestGameID returns the game ID validated by RequireGameID, or the zero
+// ID when the request did not pass through it.
+func requestGameID(r *http.Request) game.GameID {
+	id, _ := r.Context().Value(gameIDKey{}).(game.GameID)
+	return id
+}
+
+// RequireScope checks an API token against the game in the path and the
+// scope the route needs. The token is sent as a bearer credential, or in
+// ?token= by clients such as EventSource that cannot set headers. Requests
+// without a token, or with an API key (see Authenticate) instead, pass
+// through to the usual seat token and join code checks; a token that is
+// invalid, or read-only on a route that acts on a seat, is refused.
+// Handlers read the grant with requestGrant. It must run after
+// RequireGameID.
+func (h *Handler) RequireScope(want game.Scope) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.URL.Query().Get("token")
+			if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") && !strings.HasPrefix(auth, "Bearer "+storage.APIKeyPrefix) {
+				token = strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+			}
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if h.Hub.Seats == nil {
+				WriteJSON(w, http.StatusUnauthorized, map[string]any{"ok": false, "error": "bad api token"})
+				return
+			}
+			grant, ok := h.Hub.Seats.ParseAPIToken(requestGameID(r), token)
+			if !ok {
+				WriteJSON(w, http.StatusUnauthorized, map[string]any{"ok": false, "error": "bad api token"})
+				return
+			}
+			if !grant.Scope.Allows(want) {
+				WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "token is read-only"})
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiGrantKey{}, grant)))
+		})
+	}
+}
+
+// requestGrant returns the API token grant accepted by RequireScope.
+func requestGrant(r *http.Request) (game.APIGrant, bool) {
+	grant, ok := r.Context().Value(apiGrantKey{}).(game.APIGrant)
+	return grant, ok
+}
+
+// RequireViewer refuses game reads to callers shut out of a private game,
+// see game.Game.CanView. The join code comes from ?code=, and an API token
+// accepted by RequireScope stands in for it. It must run after RequireScope.
+func (h *Handler) RequireViewer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g, _, err := h.Hub.Get(r.Context(), requestGameID(r), "")
+		if err != nil {
+			http.Error(w, "game unavailable", http.StatusInternalServerError)
+			return
+		}
+		if _, ok := requestGrant(r); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !g.CanView(requestUserID(r), r.URL.Query().Get("code")) {
+			http.Error(w, "join code required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// LogRequests logs each request's method, path, status and duration at debug level.
+func LogRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		logging.Debugf("%s %s %d %s", r.Method, r.URL.Path, rec.status, time.Since(start))
+	})
+}
+
 // WriteJSON writes a JSON response with the given status code
 func WriteJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
@@ -32,18 +184,7 @@ func appendPromotionIfPawn(g *game.Game, uci string) string {
 		return uci
 	}
 
-	g.Mu.Lock()
-	state := g.StateLocked()
-	g.Mu.Unlock()
-
-	fenOpt, err := chess.FEN(state.FEN)
-	if err != nil {
-		return uci
-	}
-	tmp := chess.NewGame(fenOpt)
-	piece := tmp.Position().Board().Piece(sq)
-
-	if piece.Type() == chess.Pawn {
+	if g.PieceAt(sq).Type() == chess.Pawn {
 		return uci + "q"
 	}
 	return uci
@@ -51,13 +192,146 @@ func appendPromotionIfPawn(g *game.Game, uci string) string {
 
 // parseSquare converts a coordinate string like "e2" into a chess.Square.
 func parseSquare(s string) chess.Square {
-	if len(s) != 2 {
+	sq, err := game.ParseSquare(s)
+	if err != nil {
 		return chess.NoSquare
 	}
-	file := s[0] - 'a'
-	rank := s[1] - '1'
-	if file > 7 || rank > 7 {
-		return chess.NoSquare
+	return sq
+}
+
+// deadlineWriter buffers a response so it can be discarded if the handler
+// overruns its deadline.
+type deadlineWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (dw *deadlineWriter) Header() http.Header { return dw.header }
+
+func (dw *deadlineWriter) WriteHeader(code int) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	if dw.status == 0 && !dw.timedOut {
+		dw.status = code
+	}
+}
+
+func (dw *deadlineWriter) Write(b []byte) (int, error) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	if dw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if dw.status == 0 {
+		dw.status = http.StatusOK
+	}
+	return dw.buf.Write(b)
+}
+
+// Deadline bounds a handler to the given latency budget. The request context
+// carries the deadline so storage calls give up too; if the handler has not
+// finished in time the client gets a structured 504 instead of a hung request.
+// Not suitable for streaming endpoints.
+func Deadline(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			dw := &deadlineWriter{header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(dw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				dw.mu.Lock()
+				defer dw.mu.Unlock()
+				for k, v := range dw.header {
+					w.Header()[k] = v
+				}
+				if dw.status == 0 {
+					dw.status = http.StatusOK
+				}
+				w.WriteHeader(dw.status)
+				_, _ = w.Write(dw.buf.Bytes())
+			case <-ctx.Done():
+				dw.mu.Lock()
+				defer dw.mu.Unlock()
+				dw.timedOut = true
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					logging.Debugf("%s %s exceeded %s budget", r.Method, r.URL.Path, d)
+					WriteJSON(w, http.StatusGatewayTimeout, map[string]any{"ok": false, "error": "timeout"})
+				}
+			}
+		})
+	}
+}
+
+// headWriter discards a response body while counting its length.
+type headWriter struct {
+	http.ResponseWriter
+	status int
+	n      int
+}
+
+func (hw *headWriter) WriteHeader(code int) {
+	if hw.status == 0 {
+		hw.status = code
+	}
+}
+
+func (hw *headWriter) Write(b []byte) (int, error) {
+	if hw.status == 0 {
+		hw.status = http.StatusOK
 	}
-	return chess.Square(rank*8 + file)
+	hw.n += len(b)
+	return len(b), nil
+}
+
+// Head answers HEAD requests by running the GET handler and sending only its
+// status and headers, with the Content-Length the body would have had.
+func Head(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		hw := &headWriter{ResponseWriter: w}
+		next.ServeHTTP(hw, r)
+		if hw.status == 0 {
+			hw.status = http.StatusOK
+		}
+		if w.Header().Get("Content-Length") == "" {
+			w.Header().Set("Content-Length", strconv.Itoa(hw.n))
+		}
+		w.WriteHeader(hw.status)
+	})
+}
+
+// HeadStream answers HEAD requests for an event stream with its headers
+// instead of opening the stream.
+func HeadStream(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+	})
 }
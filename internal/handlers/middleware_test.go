@@ -2,14 +2,18 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"tinychess/internal/game"
 )
 
 func newGame(t *testing.T) *game.Game {
 	hub := game.NewHub(nil)
-	g, _, err := hub.Get(context.Background(), "test", "")
+	g, _, err := hub.Get(context.Background(), game.NewGameID(), "")
 	if err != nil {
 		t.Fatalf("failed to create game: %v", err)
 	}
@@ -43,3 +47,36 @@ func TestNoPromotionForRook(t *testing.T) {
 		t.Fatalf("rook move modified: %s", got)
 	}
 }
+
+func TestDeadlineTimesOut(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": true})
+	})
+	w := httptest.NewRecorder()
+	Deadline(10*time.Millisecond)(slow).ServeHTTP(w, httptest.NewRequest("POST", "/move/g1", nil))
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d", w.Code)
+	}
+	var resp map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp["ok"].(bool) || resp["error"] != "timeout" {
+		t.Fatalf("unexpected body %v", resp)
+	}
+}
+
+func TestDeadlinePassesThrough(t *testing.T) {
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "1")
+		WriteJSON(w, http.StatusCreated, map[string]any{"ok": true})
+	})
+	w := httptest.NewRecorder()
+	Deadline(time.Second)(fast).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusCreated || w.Header().Get("X-Test") != "1" {
+		t.Fatalf("expected handler response, got %d %v", w.Code, w.Header())
+	}
+}
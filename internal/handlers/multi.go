@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"tinychess/internal/game"
+)
+
+// MaxMultiGames caps how many games one multiplexed stream may follow.
+const MaxMultiGames = 32
+
+// multiEvent tags a game event with the game it belongs to.
+type multiEvent struct {
+	GameID game.GameID     `json:"gameId"`
+	Event  json.RawMessage `json:"event"`
+}
+
+// HandleMultiSSE streams events for several games over one connection, for
+// dashboards and TV grids. Games are listed in ?ids=a,b,c and followed as a
+// spectator; every event is wrapped as {"gameId": ..., "event": ...}, except
+// the "reconnect" event that ends a drained stream, see Drain.
+func (h *Handler) HandleMultiSSE(w http.ResponseWriter, r *http.Request) {
+	var ids []game.GameID
+	seen := make(map[game.GameID]bool)
+	for _, raw := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		id, err := game.ParseGameID(raw)
+		if err != nil {
+			http.Error(w, "bad game id", http.StatusBadRequest)
+			return
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		http.Error(w, "missing ids", http.StatusBadRequest)
+		return
+	}
+	if len(ids) > MaxMultiGames {
+		http.Error(w, fmt.Sprintf("at most %d games", MaxMultiGames), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	games := make([]*game.Game, 0, len(ids))
+	for _, id := range ids {
+		g, _, err := h.Hub.Get(ctx, id, "")
+		if err != nil {
+			http.Error(w, "game unavailable", http.StatusInternalServerError)
+			return
+		}
+		if !g.CanView("", "") {
+			http.Error(w, "join code required", http.StatusForbidden)
+			return
+		}
+		games = append(games, g)
+	}
+
+	if _, ok := w.(http.Flusher); !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	schema := game.NegotiateSchema(r.URL.Query().Get("schema"))
+	notation := h.notationFor(r, "")
+	stream := newEventStream(w)
+	write := func(id game.GameID, payload []byte) {
+		data, _ := json.Marshal(multiEvent{
+			GameID: id,
+			Event:  game.ConvertPayload(game.ApplyNotation(payload, notation), schema),
+		})
+		stream.Event(data)
+	}
+
+	events := make(chan multiEvent, 16*len(games))
+	for _, g := range games {
+		ch := make(chan []byte, 16)
+		g.AddWatcher(ch)
+		defer g.RemoveWatcher(ch)
+
+		g.Mu.RLock()
+		state := g.StateLocked()
+		g.Mu.RUnlock()
+		initial, _ := json.Marshal(state)
+		write(g.ID, initial)
+
+		go func(id game.GameID) {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case msg := <-ch:
+					select {
+					case events <- multiEvent{GameID: id, Event: msg}:
+					default:
+					}
+				}
+			}
+		}(g.ID)
+	}
+	if err := stream.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(h.heartbeat())
+	defer ticker.Stop()
+	drained := h.drained()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-drained:
+			h.writeReconnect(stream, schema)
+			_ = stream.Flush()
+			return
+		case <-ticker.C:
+			stream.Heartbeat()
+		case ev := <-events:
+			write(ev.GameID, ev.Event)
+			for n := len(events); n > 0; n-- {
+				ev = <-events
+				write(ev.GameID, ev.Event)
+			}
+		}
+		if err := stream.Flush(); err != nil {
+			return
+		}
+	}
+}
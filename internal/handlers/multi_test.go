@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tinychess/internal/game"
+)
+
+func TestHandleMultiSSETagsEvents(t *testing.T) {
+	hub := game.NewHub(nil)
+	srv := httptest.NewServer(NewHandler(hub, nil).Routes())
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m1, m2 := game.NewGameID(), game.NewGameID()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/sse/multi?ids="+m1.String()+","+m2.String()+","+m1.String(), nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer res.Body.Close()
+
+	scanner := bufio.NewScanner(res.Body)
+	next := func() multiEvent {
+		t.Helper()
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok || data == "{}" {
+				continue
+			}
+			var ev multiEvent
+			if err := json.Unmarshal([]byte(data), &ev); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			return ev
+		}
+		t.Fatalf("stream ended: %v", scanner.Err())
+		return multiEvent{}
+	}
+
+	if a, b := next(), next(); a.GameID != m1 || b.GameID != m2 {
+		t.Fatalf("expected initial states for m1 and m2, got %q and %q", a.GameID, b.GameID)
+	}
+
+	g, _, _ := hub.Get(ctx, m2, "")
+	if err := g.MakeMove("e2e4"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	g.Broadcast()
+
+	ev := next()
+	var st game.GameState
+	if err := json.Unmarshal(ev.Event, &st); err != nil {
+		t.Fatalf("decode state: %v", err)
+	}
+	if ev.GameID != m2 || len(st.UCI) != 1 {
+		t.Fatalf("expected move event for m2, got %q with %v", ev.GameID, st.UCI)
+	}
+}
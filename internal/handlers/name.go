@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"tinychess/internal/game"
+	"tinychess/internal/logging"
+)
+
+// HandleName sets the display name of a seated player. Names appear in the
+// game state, the participant list and the PGN player tags; an empty name
+// clears it.
+func (h *Handler) HandleName(w http.ResponseWriter, r *http.Request) {
+	id := requestGameID(r)
+	g, _, err := h.Hub.Get(r.Context(), id, "")
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
+		return
+	}
+
+	var body struct {
+		ClientID  string `json:"clientId"`
+		SeatToken string `json:"seatToken"`
+		Name      string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return
+	}
+	clientID := strings.TrimSpace(body.ClientID)
+	if clientID == "" {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing client id"})
+		return
+	}
+	name, err := game.CleanName(body.Name)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+		return
+	}
+	if !h.seatAuthorized(r, id, clientID, body.SeatToken) {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "bad seat token"})
+		return
+	}
+
+	if !g.SetName(clientID, name) {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "unknown client"})
+		return
+	}
+	if h.Store != nil {
+		if uid, err := uuid.Parse(clientID); err == nil {
+			if err := h.Store.SetSessionName(r.Context(), id.UUID(), uid, name); err != nil {
+				logging.Debugf("save name for %s failed: %v", id, err)
+			}
+		}
+	}
+	go g.Broadcast()
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "name": name})
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tinychess/internal/game"
+
+	"github.com/corentings/chess/v2"
+)
+
+func TestHandleName(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	id := game.NewGameID()
+	g, _, err := hub.Get(context.Background(), id, "")
+	if err != nil {
+		t.Fatalf("get game: %v", err)
+	}
+	g.Clients["c1"] = chess.Black
+
+	post := func(body string) (int, map[string]any) {
+		w := httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, httptest.NewRequest("POST", "/api/games/"+id.String()+"/name", strings.NewReader(body)))
+		var resp map[string]any
+		_ = json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+
+	token := hub.Seats.Token(id, "c1")
+	if code, _ := post(`{"clientId":"c1","seatToken":"nope","name":"Bob"}`); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a bad seat token, got %d", code)
+	}
+	if code, _ := post(fmt.Sprintf(`{"clientId":"c1","seatToken":%q,"name":%q}`, token, strings.Repeat("x", 40))); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a long name, got %d", code)
+	}
+	code, resp := post(fmt.Sprintf(`{"clientId":"c1","seatToken":%q,"name":" Bob "}`, token))
+	if code != http.StatusOK || resp["ok"] != true || resp["name"] != "Bob" {
+		t.Fatalf("unexpected response %d %v", code, resp)
+	}
+	g.Mu.RLock()
+	black := g.StateLocked().Black
+	g.Mu.RUnlock()
+	if black != "Bob" {
+		t.Fatalf("expected black to be named, got %q", black)
+	}
+}
@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"tinychess/internal/game"
+	"tinychess/internal/openapi"
+	"tinychess/internal/storage"
+	"tinychess/internal/tablebase"
+	"tinychess/internal/templates"
+	"tinychess/internal/usage"
+)
+
+// The responses below are the documented shapes of the API's answers. The
+// handlers write them, and the OpenAPI description is derived from them, so
+// the two cannot drift apart; the tests check real answers against it.
+
+// ErrorResponse is the body of a refused request.
+type ErrorResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+// OKResponse is the body of a request that returns nothing else.
+type OKResponse struct {
+	OK bool `json:"ok"`
+}
+
+// NewGameResponse answers POST /new.
+type NewGameResponse struct {
+	OK        bool        `json:"ok"`
+	ID        game.GameID `json:"id"`
+	Color     string      `json:"color" enum:"w,b"`
+	SeatToken string      `json:"seatToken"`
+	// Invites are links that seat the opponent or admit spectators, absent
+	// when the hub does not sign seats.
+	Invites map[game.InviteRole]string `json:"invites,omitempty" doc:"Invite links by role, for the opponent's seat and for spectators."`
+}
+
+// StateResponse answers moves and resignations with the game's state.
+type StateResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	// Duplicate is set when the move's id was already played.
+	Duplicate bool `json:"duplicate,omitempty"`
+	State     any  `json:"state,omitempty" ref:"GameState"`
+}
+
+// LegalResponse answers GET /api/games/{id}/legal.
+type LegalResponse struct {
+	OK      bool          `json:"ok"`
+	From    string        `json:"from"`
+	Targets []game.Target `json:"targets"`
+}
+
+// PlyResponse answers GET /api/games/{id}/ply/{ply}.
+type PlyResponse struct {
+	OK       bool             `json:"ok"`
+	Position game.PlyPosition `json:"position"`
+}
+
+// AnalysisResponse answers GET /api/games/{id}/analysis.
+type AnalysisResponse struct {
+	OK       bool                 `json:"ok"`
+	Status   string               `json:"status" enum:"done,pending"`
+	Analysis *game.AnalysisReport `json:"analysis,omitempty"`
+}
+
+// TokenResponse answers POST /api/games/{id}/tokens.
+type TokenResponse struct {
+	OK    bool       `json:"ok"`
+	Token string     `json:"token"`
+	Scope game.Scope `json:"scope"`
+}
+
+// ListGamesResponse answers GET /api/v1/games.
+type ListGamesResponse struct {
+	OK    bool                 `json:"ok"`
+	Games []storage.RecentGame `json:"games"`
+	// Next is the offset of the following page, absent on the last one.
+	Next *int `json:"next,omitempty"`
+}
+
+// ImportResponse answers POST /api/import.
+type ImportResponse struct {
+	OK        bool        `json:"ok"`
+	ID        game.GameID `json:"id"`
+	URL       string      `json:"url"`
+	Color     string      `json:"color" enum:"w,b"`
+	SeatToken string      `json:"seatToken"`
+	Plies     int         `json:"plies"`
+}
+
+// TablebaseResponse answers GET /api/tablebase.
+type TablebaseResponse struct {
+	OK  bool   `json:"ok"`
+	FEN string `json:"fen"`
+	tablebase.Result
+	WDL int `json:"wdl" doc:"The category as -2 to 2 for the side to move."`
+}
+
+// APIKeysResponse answers GET /api/keys.
+type APIKeysResponse struct {
+	OK   bool             `json:"ok"`
+	Keys []storage.APIKey `json:"keys"`
+}
+
+// NewAPIKeyResponse answers POST /api/keys.
+type NewAPIKeyResponse struct {
+	OK     bool           `json:"ok"`
+	Key    string         `json:"key"`
+	APIKey storage.APIKey `json:"apiKey"`
+}
+
+// PairResponse answers POST /api/pair.
+type PairResponse struct {
+	OK        bool      `json:"ok"`
+	Code      string    `json:"code" example:"482913"`
+	URL       string    `json:"url" example:"/pair/482913"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// UsageResponse answers GET /api/me/usage.
+type UsageResponse struct {
+	OK    bool         `json:"ok"`
+	Usage usage.Report `json:"usage"`
+}
+
+// apiSpec is the API's OpenAPI description, built on first use so the
+// version set at startup is included.
+var apiSpec = sync.OnceValue(func() *openapi.Spec {
+	spec := openapi.New(openapi.Info{
+		Title:       "Tiny Chess API",
+		Version:     templates.Version(),
+		Description: "Programmatic access to Tiny Chess games. Endpoints under /api/v1/ keep their shape across releases. Responses are JSON objects with an ok flag; refusals by the game's rules answer 200 with ok false and an error.",
+	})
+	spec.Components.SecuritySchemes = map[string]*openapi.SecurityScheme{
+		"apiKey":    {Type: "apiKey", In: "header", Name: "X-API-Key", Description: "A user's API key from POST /api/keys. It identifies the user in place of userId; seat actions still need seat tokens."},
+		"bearerKey": {Type: "http", Scheme: "bearer", Description: "An API key (tck_…) sent as a bearer credential."},
+		"gameToken": {Type: "http", Scheme: "bearer", Description: "A per-game read or play token from POST /api/games/{id}/tokens."},
+	}
+	spec.Security = []openapi.Requirement{{}, {"apiKey": {}}, {"bearerKey": {}}}
+	// Game-scoped operations also take the game's own tokens.
+	gameSecurity := []openapi.Requirement{{}, {"gameToken": {}}, {"apiKey": {}}, {"bearerKey": {}}}
+
+	spec.Define("Error", ErrorResponse{}, "")
+	spec.Define("GameState", game.GameState{}, "The payload of the game's event stream.")
+	spec.Define("Target", game.Target{}, "")
+	spec.Define("RecentGame", storage.RecentGame{}, "")
+	spec.Define("APIKey", storage.APIKey{}, "")
+	spec.Define("Usage", usage.Report{}, "")
+	gameID := spec.Parameter("GameID", openapi.Path("id", &openapi.Schema{Type: "string"}))
+	userID := spec.Parameter("UserID", openapi.Query("userId", &openapi.Schema{Type: "string", Format: "uuid"}, "The caller; not needed with an API key."))
+	notations := []any{"san", "lan", "figurine", "local", "san-de", "san-es", "san-fr", "san-it", "san-nl"}
+
+	body := func(v any) *openapi.RequestBody {
+		return openapi.Body("application/json", spec.Schema(v))
+	}
+	ok := func(description string, v any) *openapi.Response {
+		return openapi.Reply(description, "application/json", spec.Schema(v))
+	}
+	fail := func(description string) *openapi.Response {
+		return openapi.Reply(description, "application/json", spec.Ref("Error"))
+	}
+	throttled := fail("Too many requests in a short time; retry after the Retry-After header's seconds.")
+
+	spec.Add("POST", "/new", &openapi.Operation{
+		Summary:     "Create a game",
+		RequestBody: body(NewGameRequest{}),
+		Responses: map[string]*openapi.Response{
+			"200": ok("The new game, with the creator's color and seat token.", NewGameResponse{}),
+			"400": fail("Invalid options."),
+			"429": throttled,
+		},
+	})
+	spec.Add("POST", "/move/{id}", &openapi.Operation{
+		Summary:     "Play a move",
+		Parameters:  []*openapi.Parameter{gameID},
+		Security:    gameSecurity,
+		RequestBody: body(game.MoveRequest{}),
+		Responses: map[string]*openapi.Response{
+			"200": ok("Whether the move was played, and the state it was refused in.", StateResponse{}),
+			"400": fail("Malformed move."),
+			"403": fail("Bad seat token."),
+			"429": throttled,
+		},
+	})
+	spec.Add("POST", "/resign/{id}", &openapi.Operation{
+		Summary:     "Resign",
+		Parameters:  []*openapi.Parameter{gameID},
+		Security:    gameSecurity,
+		RequestBody: body(ResignRequest{}),
+		Responses: map[string]*openapi.Response{
+			"200": ok("Whether the game was resigned, and its state after.", StateResponse{}),
+		},
+	})
+	spec.Add("GET", "/sse/{id}", &openapi.Operation{
+		Summary:     "Follow a game",
+		Description: "A server-sent event stream of GameState payloads.",
+		Parameters:  []*openapi.Parameter{gameID},
+		Security:    gameSecurity,
+		Responses: map[string]*openapi.Response{
+			"200": openapi.Reply("Event stream.", "text/event-stream", &openapi.Schema{Type: "string"}),
+		},
+	})
+	spec.Add("GET", "/api/games/{id}", &openapi.Operation{
+		Summary: "Current game state",
+		Parameters: []*openapi.Parameter{
+			gameID,
+			openapi.Query("notation", &openapi.Schema{Type: "string", Enum: notations}, "Move notation. local is SAN with the piece letters of the game's language; san-de and the like pick a language."),
+			openapi.Query("schema", &openapi.Schema{Type: "integer"}, ""),
+		},
+		Security: gameSecurity,
+		Responses: map[string]*openapi.Response{
+			"200": openapi.Reply("The game's state.", "application/json", spec.Ref("GameState")),
+			"403": fail("Join code required."),
+		},
+	})
+	spec.Add("GET", "/api/games/{id}/pgn", &openapi.Operation{
+		Summary: "Game PGN",
+		Parameters: []*openapi.Parameter{
+			gameID,
+			openapi.Query("timeline", &openapi.Schema{Type: "string", Enum: []any{"1"}}, ""),
+			openapi.Query("notation", &openapi.Schema{Type: "string", Enum: notations}, "Movetext notation, as for the game state. Only san is standard PGN."),
+		},
+		Security: gameSecurity,
+		Responses: map[string]*openapi.Response{
+			"200": openapi.Reply("PGN text.", "application/x-chess-pgn", &openapi.Schema{Type: "string"}),
+		},
+	})
+	spec.Add("GET", "/api/games/{id}/legal", &openapi.Operation{
+		Summary: "Legal moves from a square",
+		Parameters: []*openapi.Parameter{
+			gameID,
+			{Name: "from", In: "query", Required: true, Schema: &openapi.Schema{Type: "string", Example: "e2"}},
+		},
+		Security: gameSecurity,
+		Responses: map[string]*openapi.Response{
+			"200": ok("Destination squares.", LegalResponse{}),
+			"400": fail("Invalid square."),
+		},
+	})
+	firstPly := 0.0
+	spec.Add("GET", "/api/games/{id}/ply/{ply}", &openapi.Operation{
+		Summary:     "Position after a number of moves",
+		Description: "The board after the first ply half-moves, as opened by /game/{id}?ply= links.",
+		Parameters: []*openapi.Parameter{
+			gameID,
+			openapi.Path("ply", &openapi.Schema{Type: "integer", Minimum: &firstPly, Example: 23}),
+		},
+		Security: gameSecurity,
+		Responses: map[string]*openapi.Response{
+			"200": ok("The position, with the move that led to it.", PlyResponse{}),
+			"400": fail("The ply is not a number."),
+			"404": fail("The game is shorter than that."),
+		},
+	})
+	spec.Add("GET", "/api/games/{id}/analysis", &openapi.Operation{
+		Summary:    "Post-game analysis",
+		Parameters: []*openapi.Parameter{gameID},
+		Security:   gameSecurity,
+		Responses: map[string]*openapi.Response{
+			"200": ok("Per-move evaluations and accuracy, or pending while the engine works.", AnalysisResponse{}),
+			"404": fail("No engine configured."),
+		},
+	})
+	spec.Add("POST", "/api/games/{id}/rewind", &openapi.Operation{
+		Summary:     "Rewind a post-mortem room",
+		Description: "Takes a post-mortem room, linked from a finished game's state as postMortem, back to an earlier ply for everyone in it. Its players, and its spectators when postMortemOpen is set, move for either side with /move/{id}.",
+		Parameters:  []*openapi.Parameter{gameID},
+		Security:    gameSecurity,
+		RequestBody: body(RewindRequest{}),
+		Responses: map[string]*openapi.Response{
+			"200": ok("Whether the room was rewound, and its state after.", StateResponse{}),
+			"400": fail("The ply is out of range."),
+			"403": fail("Bad seat token."),
+			"409": fail("Not a post-mortem room."),
+			"429": throttled,
+		},
+	})
+	spec.Add("POST", "/api/games/{id}/tokens", &openapi.Operation{
+		Summary:     "Issue a game token",
+		Parameters:  []*openapi.Parameter{gameID},
+		RequestBody: body(TokenRequest{}),
+		Responses: map[string]*openapi.Response{
+			"200": ok("The token.", TokenResponse{}),
+			"403": fail("Bad seat token."),
+		},
+	})
+	minLimit, maxLimit, minOffset := 1.0, float64(maxPageSize), 0.0
+	spec.Add("GET", "/api/v1/games", &openapi.Operation{
+		Summary: "List the caller's games",
+		Parameters: []*openapi.Parameter{
+			userID,
+			openapi.Query("status", &openapi.Schema{Type: "string", Enum: []any{storage.ListActive, storage.ListCompleted, "all"}}, ""),
+			openapi.Query("limit", &openapi.Schema{Type: "integer", Minimum: &minLimit, Maximum: &maxLimit, Default: defaultPageSize}, ""),
+			openapi.Query("offset", &openapi.Schema{Type: "integer", Minimum: &minOffset}, ""),
+		},
+		Responses: map[string]*openapi.Response{
+			"200": ok("A page of games, most recently seen first; next is the offset of the following page.", ListGamesResponse{}),
+			"400": fail("Invalid filter."),
+			"503": fail("No database."),
+		},
+	})
+	spec.Add("POST", "/api/import", &openapi.Operation{
+		Summary:     "Create a game from PGN",
+		Parameters:  []*openapi.Parameter{userID},
+		RequestBody: openapi.Body("application/x-chess-pgn", &openapi.Schema{Type: "string"}),
+		Responses: map[string]*openapi.Response{
+			"200": ok("The new game.", ImportResponse{}),
+			"400": fail("Invalid PGN."),
+		},
+	})
+	spec.Add("GET", "/api/tablebase", &openapi.Operation{
+		Summary: "Probe the endgame tablebase",
+		Parameters: []*openapi.Parameter{
+			{Name: "fen", In: "query", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]*openapi.Response{
+			"200": ok("The position's verdict and ranked moves.", TablebaseResponse{}),
+			"400": fail("Invalid position."),
+			"503": fail("No tablebase configured."),
+		},
+	})
+	keyGameID := openapi.Query("gameId", &openapi.Schema{Type: "string"}, "A game the caller is seated in; not needed with an API key.")
+	keySeatToken := openapi.Query("seatToken", &openapi.Schema{Type: "string"}, "The caller's seat token in gameId.")
+	spec.Add("GET", "/api/keys", &openapi.Operation{
+		Summary:    "List the caller's API keys",
+		Parameters: []*openapi.Parameter{userID, keyGameID, keySeatToken},
+		Responses: map[string]*openapi.Response{
+			"200": ok("Keys, without their secrets.", APIKeysResponse{}),
+			"403": fail("Neither an API key nor a seat token for the user."),
+			"503": fail("No database."),
+		},
+	})
+	spec.Add("POST", "/api/keys", &openapi.Operation{
+		Summary:     "Issue an API key",
+		RequestBody: body(APIKeyRequest{}),
+		Responses: map[string]*openapi.Response{
+			"200": ok("The key, shown only once.", NewAPIKeyResponse{}),
+			"403": fail("Neither an API key nor a seat token for the user."),
+			"503": fail("No database."),
+		},
+	})
+	spec.Add("DELETE", "/api/keys/{keyId}", &openapi.Operation{
+		Summary: "Revoke an API key",
+		Parameters: []*openapi.Parameter{
+			openapi.Path("keyId", &openapi.Schema{Type: "string", Format: "uuid"}),
+			userID, keyGameID, keySeatToken,
+		},
+		Responses: map[string]*openapi.Response{
+			"200": ok("Revoked.", OKResponse{}),
+			"403": fail("Neither an API key nor a seat token for the user."),
+			"404": openapi.Reply("No such key.", "", nil),
+		},
+	})
+	spec.Add("POST", "/api/pair", &openapi.Operation{
+		Summary:     "Issue a pairing code for the open seat",
+		Description: "A seated player gets a six-digit code, valid for five minutes and claimable once, that seats another device in the open seat when it opens /pair/{code}.",
+		RequestBody: body(PairRequest{}),
+		Responses: map[string]*openapi.Response{
+			"200": ok("The code and the path that claims it, or ok false when no seat is open.", PairResponse{}),
+			"403": fail("Not seated, or a bad seat token."),
+		},
+	})
+	spec.Add("GET", "/api/me/usage", &openapi.Operation{
+		Summary:    "The caller's API usage today",
+		Parameters: []*openapi.Parameter{userID},
+		Responses: map[string]*openapi.Response{
+			"200": ok("Usage and remaining quota.", UsageResponse{}),
+			"429": fail("Daily quota exceeded."),
+		},
+	})
+	return spec
+})
+
+// HandleOpenAPI serves the OpenAPI description of the API, for client
+// generators and API explorers.
+func (h *Handler) HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, apiSpec())
+}
+
+// HandleAPIDocs serves an API explorer for the OpenAPI description.
+func (h *Handler) HandleAPIDocs(w http.ResponseWriter, r *http.Request) {
+	templates.WriteAPIDocs(w)
+}
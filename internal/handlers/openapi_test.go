@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+
+	"tinychess/internal/game"
+	"tinychess/internal/openapi"
+	"tinychess/internal/storage"
+	"tinychess/internal/templates"
+)
+
+func TestOpenAPI(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+	w := httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&spec); err != nil || spec.OpenAPI == "" {
+		t.Fatalf("decode spec: %d %v", w.Code, err)
+	}
+	w = httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, httptest.NewRequest("GET", "/api/docs", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("expected the API explorer page, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	// The explorer's assets are served by the instance itself.
+	page := w.Body.String()
+	for _, asset := range []string{"swagger-ui.css", "swagger-ui-bundle.js", "docs.js"} {
+		path := templates.AssetPath(asset)
+		if path == "" || !strings.Contains(page, path) {
+			t.Fatalf("expected the explorer to load %s from %q", asset, path)
+		}
+		w := httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("serve %s: %d", path, w.Code)
+		}
+	}
+	if strings.Contains(page, "https://") {
+		t.Fatal("the explorer must not load third-party assets")
+	}
+
+	// Every documented operation is routed.
+	id := game.NewGameID().String()
+	for path, ops := range spec.Paths {
+		path = strings.NewReplacer("{id}", id, "{keyId}", uuid.NewString()).Replace(path)
+		for method := range ops {
+			r := httptest.NewRequest("OPTIONS", path, nil)
+			w := httptest.NewRecorder()
+			h.Routes().ServeHTTP(w, r)
+			if !strings.Contains(w.Header().Get("Allow"), strings.ToUpper(method)) {
+				t.Errorf("%s %s is documented but not routed (Allow: %q)", strings.ToUpper(method), path, w.Header().Get("Allow"))
+			}
+		}
+	}
+}
+
+// Every documented answer of the operations exercised here matches its
+// schema: no undocumented fields, and values of the documented types.
+func TestOpenAPIResponses(t *testing.T) {
+	db, err := storage.New("sqlite://"+filepath.Join(t.TempDir(), "test.db"), 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	store := storage.NewStore(db)
+	h := NewHandler(game.NewHub(store), store)
+	mux := h.Routes()
+	spec := apiSpec()
+
+	// call makes a request and checks the answer against the operation
+	// documented under pattern, returning the decoded body.
+	call := func(pattern, path, body string) map[string]any {
+		t.Helper()
+		method, documented, _ := strings.Cut(pattern, " ")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		op := spec.Paths[documented][strings.ToLower(method)]
+		if op == nil {
+			t.Fatalf("%s is not documented", pattern)
+		}
+		resp := op.Responses[fmt.Sprint(w.Code)]
+		if resp == nil {
+			t.Fatalf("%s answered %d, which is not documented: %s", pattern, w.Code, w.Body)
+		}
+		var v map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+			t.Fatalf("%s: decode: %v", pattern, err)
+		}
+		for _, err := range checkSchema(spec, resp.Content["application/json"].Schema, v, "") {
+			t.Errorf("%s %d: %v", pattern, w.Code, err)
+		}
+		return v
+	}
+
+	user := uuid.NewString()
+	created := call("POST /new", "/new", `{"userId":"`+user+`"}`)
+	id, token := created["id"].(string), created["seatToken"].(string)
+	call("POST /new", "/new", `{"userId":"`+user+`","moveLimit":-1}`)
+	call("GET /api/games/{id}", "/api/games/"+id, "")
+	call("GET /api/games/{id}/legal", "/api/games/"+id+"/legal?from=e2", "")
+	call("GET /api/games/{id}/legal", "/api/games/"+id+"/legal?from=z9", "")
+	call("GET /api/games/{id}/ply/{ply}", "/api/games/"+id+"/ply/0", "")
+	call("GET /api/games/{id}/ply/{ply}", "/api/games/"+id+"/ply/9", "")
+	call("POST /api/games/{id}/tokens", "/api/games/"+id+"/tokens", `{"clientId":"`+user+`","seatToken":"`+token+`","scope":"read"}`)
+	call("POST /api/pair", "/api/pair", `{"gameId":"`+id+`","clientId":"`+user+`","seatToken":"`+token+`"}`)
+	gid, _ := game.ParseGameID(id)
+	g, _ := h.Hub.Lookup(gid)
+	g.Mu.RLock()
+	color := g.Clients[user]
+	g.Mu.RUnlock()
+	// Black's first move is refused, which is documented as well.
+	move := "e2e4"
+	if color == chess.Black {
+		move = "e7e5"
+	}
+	call("POST /move/{id}", "/move/"+id, `{"clientId":"`+user+`","seatToken":"`+token+`","uci":"`+move+`","moveId":"m1"}`)
+	call("POST /move/{id}", "/move/"+id, `{"clientId":"`+user+`","seatToken":"`+token+`","uci":"`+move+`","moveId":"m1"}`)
+	call("POST /move/{id}", "/move/"+id, `{"clientId":"`+user+`","seatToken":"bad","uci":"`+move+`"}`)
+	call("POST /resign/{id}", "/resign/"+id, `{"clientId":"`+user+`","seatToken":"`+token+`"}`)
+	call("GET /api/games/{id}/analysis", "/api/games/"+id+"/analysis", "")
+	call("POST /api/keys", "/api/keys", `{"userId":"`+user+`","name":"bot"}`)
+	call("POST /api/keys", "/api/keys", `{"userId":"`+user+`","gameId":"`+id+`","seatToken":"`+token+`","name":"bot"}`)
+	call("GET /api/keys", "/api/keys?userId="+user+"&gameId="+id+"&seatToken="+token, "")
+	call("GET /api/v1/games", "/api/v1/games?limit=1&userId="+user, "")
+	call("GET /api/v1/games", "/api/v1/games?status=lost&userId="+user, "")
+	call("GET /api/me/usage", "/api/me/usage?userId="+user, "")
+}
+
+// checkSchema lists where v departs from schema. null is accepted
+// anywhere, for the nil slices and pointers Go encodes.
+func checkSchema(spec *openapi.Spec, schema *openapi.Schema, v any, at string) []error {
+	if name, ok := strings.CutPrefix(schema.Ref, "#/components/schemas/"); ok {
+		schema = spec.Components.Schemas[name]
+	}
+	if v == nil || schema.Type == "" {
+		return nil
+	}
+	var errs []error
+	mismatch := func() []error {
+		return []error{fmt.Errorf("%s: %T is not of type %s", at, v, schema.Type)}
+	}
+	switch schema.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return mismatch()
+		}
+		for key, val := range obj {
+			prop := schema.Properties[key]
+			if prop == nil {
+				prop = schema.AdditionalProperties
+			}
+			if prop == nil {
+				errs = append(errs, fmt.Errorf("%s: undocumented field %q", at, key))
+				continue
+			}
+			errs = append(errs, checkSchema(spec, prop, val, at+"/"+key)...)
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return mismatch()
+		}
+		for i, item := range arr {
+			errs = append(errs, checkSchema(spec, schema.Items, item, fmt.Sprintf("%s/%d", at, i))...)
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return mismatch()
+		}
+		if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, v) {
+			errs = append(errs, fmt.Errorf("%s: %q is not one of %v", at, v, schema.Enum))
+		}
+	case "integer", "number":
+		if n, ok := v.(float64); !ok || schema.Type == "integer" && n != float64(int64(n)) {
+			return mismatch()
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return mismatch()
+		}
+	}
+	return errs
+}
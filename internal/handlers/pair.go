@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+
+	"tinychess/internal/game"
+)
+
+// PairRequest is the body of POST /api/pair.
+type PairRequest struct {
+	GameID    string `json:"gameId" required:"true" format:"uuid"`
+	ClientID  string `json:"clientId" required:"true"`
+	SeatToken string `json:"seatToken"`
+}
+
+// HandlePair issues a pairing code for a game with an open seat, so a
+// second device can take that seat by scanning the code's QR or typing it
+// in ({"gameId", "clientId", "seatToken"}). Only a seated player may ask.
+func (h *Handler) HandlePair(w http.ResponseWriter, r *http.Request) {
+	if h.Hub.Seats == nil || h.Hub.Pairings == nil {
+		WriteJSON(w, http.StatusServiceUnavailable, map[string]any{"ok": false, "error": "pairing unavailable"})
+		return
+	}
+	var body PairRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return
+	}
+	id, err := game.ParseGameID(strings.TrimSpace(body.GameID))
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad game id"})
+		return
+	}
+	clientID := strings.TrimSpace(body.ClientID)
+	if clientID == "" {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing client id"})
+		return
+	}
+	if !h.seatAuthorized(r, id, clientID, body.SeatToken) {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "bad seat token"})
+		return
+	}
+	g, ok := h.Hub.Lookup(id)
+	if !ok {
+		WriteJSON(w, http.StatusNotFound, map[string]any{"ok": false, "error": "game not found"})
+		return
+	}
+	g.Mu.RLock()
+	_, seated := g.Clients[clientID]
+	g.Mu.RUnlock()
+	if !seated {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "not seated"})
+		return
+	}
+	if g.OpenColor() == chess.NoColor {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "no open seat"})
+		return
+	}
+	code, expires := h.Hub.Pairings.Issue(id)
+	WriteJSON(w, http.StatusOK, PairResponse{OK: true, Code: code, URL: "/pair/" + code, ExpiresAt: expires})
+}
+
+// HandlePairClaim redeems a pairing code, sending the device to the game
+// with a play invite for the open seat. Codes work once.
+func (h *Handler) HandlePairClaim(w http.ResponseWriter, r *http.Request) {
+	if h.Hub.Seats == nil || h.Hub.Pairings == nil {
+		http.NotFound(w, r)
+		return
+	}
+	id, ok := h.Hub.Pairings.Claim(strings.TrimSpace(r.PathValue("code")))
+	if !ok {
+		http.Error(w, "This pairing code has expired or was already used.", http.StatusNotFound)
+		return
+	}
+	g, ok := h.Hub.Lookup(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	open := g.OpenColor()
+	if open == chess.NoColor {
+		http.Error(w, "Both seats in this game are taken.", http.StatusConflict)
+		return
+	}
+	http.Redirect(w, r, "/"+id.String()+"?invite="+h.Hub.Seats.Invite(id, game.InviteRoleFor(open)), http.StatusSeeOther)
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+
+	"tinychess/internal/game"
+)
+
+// RewindRequest is the body of POST /api/games/{id}/rewind.
+type RewindRequest struct {
+	ClientID  string `json:"clientId" required:"true"`
+	SeatToken string `json:"seatToken"`
+	Ply       int    `json:"ply" minimum:"0" doc:"How many plies of the room's moves to keep." required:"true"`
+}
+
+// openPostMortem opens the post-mortem room of a finished game, unless the
+// feature is disabled. Call it before broadcasting the game's final state,
+// which links to the room.
+func (h *Handler) openPostMortem(g *game.Game) {
+	if h.Disabled[FeaturePostMortem] || !g.Over() {
+		return
+	}
+	h.Hub.OpenPostMortem(g)
+}
+
+// HandleRewind takes a post-mortem room back to an earlier ply for everyone
+// in it, so another line can be explored from there.
+func (h *Handler) HandleRewind(w http.ResponseWriter, r *http.Request) {
+	id := requestGameID(r)
+	g, _, err := h.Hub.Get(r.Context(), id, "")
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
+		return
+	}
+	var body RewindRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return
+	}
+	clientID := strings.TrimSpace(body.ClientID)
+	if clientID == "" {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing client id"})
+		return
+	}
+	if !h.seatAuthorized(r, id, clientID, body.SeatToken) {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "bad seat token"})
+		return
+	}
+	// Only those who may move in the room may rewind it.
+	if _, ok := g.MoverColor(clientID, chess.NoColor); !ok {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "unknown client"})
+		return
+	}
+	if err := g.Rewind(body.Ply); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, game.ErrNotPostMortem) {
+			status = http.StatusConflict
+		}
+		WriteJSON(w, status, map[string]any{"ok": false, "error": err.Error()})
+		return
+	}
+	g.Touch()
+	go g.Broadcast()
+
+	g.Mu.RLock()
+	state := g.StateLocked()
+	g.Mu.RUnlock()
+	WriteJSON(w, http.StatusOK, StateResponse{OK: true, State: stateForClient(r, state, h.notationFor(r, clientID).In(g.Language))})
+}
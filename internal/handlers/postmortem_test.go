@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tinychess/internal/game"
+)
+
+func TestPostMortemRoom(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+	mux := h.Routes()
+	post := func(path, body string) (int, map[string]any) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		var resp map[string]any
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+		return w.Code, resp
+	}
+
+	id := game.NewGameID()
+	for _, client := range []string{"a", "b"} {
+		if _, _, err := h.Hub.Get(context.Background(), id, client); err != nil {
+			t.Fatalf("seat %s: %v", client, err)
+		}
+	}
+	_, resp := post("/resign/"+id.String(), `{"clientId":"a","seatToken":"`+h.seatToken(id, "a")+`"}`)
+	state, _ := resp["state"].(map[string]any)
+	roomID, _ := state["postMortem"].(string)
+	if resp["ok"] != true || roomID == "" {
+		t.Fatalf("expected the final state to link a post-mortem room, got %v", resp)
+	}
+	room, err := game.ParseGameID(roomID)
+	if err != nil {
+		t.Fatalf("room id: %v", err)
+	}
+
+	// Whichever seat they held, players move for the side to move.
+	g, _ := h.Hub.Lookup(id)
+	g.Mu.RLock()
+	white := g.OwnerColor.String() == "w"
+	g.Mu.RUnlock()
+	black := "b"
+	if !white {
+		black = "a"
+	}
+	_, resp = post("/move/"+roomID, `{"clientId":"`+black+`","seatToken":"`+h.seatToken(room, black)+`","uci":"e2e4"}`)
+	if resp["ok"] != true {
+		t.Fatalf("expected the move played in the room, got %v", resp)
+	}
+	if code, resp := post("/api/games/"+roomID+"/rewind", `{"clientId":"stranger","seatToken":"`+h.seatToken(room, "stranger")+`","ply":0}`); code != http.StatusOK || resp["ok"] != false {
+		t.Fatalf("expected spectators refused, got %d %v", code, resp)
+	}
+	if code, _ := post("/api/games/"+roomID+"/rewind", `{"clientId":"a","seatToken":"`+h.seatToken(room, "a")+`","ply":5}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 rewinding past the moves, got %d", code)
+	}
+	_, resp = post("/api/games/"+roomID+"/rewind", `{"clientId":"a","seatToken":"`+h.seatToken(room, "a")+`","ply":0}`)
+	if state, _ := resp["state"].(map[string]any); resp["ok"] != true || len(state["uci"].([]any)) != 0 {
+		t.Fatalf("expected the room rewound to the start, got %v", resp)
+	}
+	if code, _ := post("/api/games/"+id.String()+"/rewind", `{"clientId":"a","seatToken":"`+h.seatToken(id, "a")+`","ply":0}`); code != http.StatusConflict {
+		t.Fatalf("expected 409 rewinding an ordinary game, got %d", code)
+	}
+	if _, resp := post("/resign/"+roomID, `{"clientId":"a","seatToken":"`+h.seatToken(room, "a")+`"}`); resp["ok"] != false {
+		t.Fatalf("expected rooms not to be resigned, got %v", resp)
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"tinychess/internal/templates"
+)
+
+// HandleManifest serves the web app manifest, so browsers offer to install
+// the site as an app.
+func (h *Handler) HandleManifest(w http.ResponseWriter, r *http.Request) {
+	templates.WriteManifest(w)
+}
+
+// HandleIcon serves the app icon named in the manifest.
+func (h *Handler) HandleIcon(w http.ResponseWriter, r *http.Request) {
+	templates.WriteIcon(w)
+}
+
+// HandleServiceWorker serves the service worker that keeps the pages
+// available offline and queues moves until the network returns. It is
+// served from the root so it controls every page.
+func (h *Handler) HandleServiceWorker(w http.ResponseWriter, r *http.Request) {
+	templates.WriteServiceWorker(w)
+}
+
+// HandleStatic serves the pages' scripts and styles under their
+// content-hashed names.
+func (h *Handler) HandleStatic(w http.ResponseWriter, r *http.Request) {
+	templates.WriteStatic(w, r.PathValue("file"))
+}
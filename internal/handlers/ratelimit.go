@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"tinychess/internal/ratelimit"
+)
+
+// Rate-limited endpoint groups, the keys of Handler.RateLimits. LimitAll is
+// shared by the other groups' endpoints.
+const (
+	LimitAll   = "all"
+	LimitNew   = "new"
+	LimitMove  = "move"
+	LimitReact = "react"
+	LimitChat  = "chat"
+)
+
+// LimitGroups lists the rate-limited endpoint groups.
+var LimitGroups = []string{LimitAll, LimitNew, LimitMove, LimitReact, LimitChat}
+
+// ParseRateLimits builds the limiters for Handler.RateLimits from limits by
+// group, written as ratelimit.Parse reads them.
+func ParseRateLimits(specs map[string]string) (map[string]*ratelimit.Limiter, error) {
+	limits := make(map[string]*ratelimit.Limiter, len(specs))
+	for group, spec := range specs {
+		if !slices.Contains(LimitGroups, group) {
+			return nil, fmt.Errorf("unknown rate limit group %q, want one of %s", group, strings.Join(LimitGroups, ", "))
+		}
+		l, err := ratelimit.Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", group, err)
+		}
+		limits[group] = l
+	}
+	return limits, nil
+}
+
+// Throttle refuses a request with 429 and Retry-After once any of the
+// caller's keys, see callerKeys, has used up the group's rate limit or the
+// one shared by all groups. Like Meter, it must run after RequireScope.
+func (h *Handler) Throttle(group string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keys, _ := h.callerKeys(r)
+			for _, l := range []*ratelimit.Limiter{h.RateLimits[LimitAll], h.RateLimits[group]} {
+				for _, key := range keys {
+					if ok, wait := l.Allow(key); !ok {
+						w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+						WriteJSON(w, http.StatusTooManyRequests, map[string]any{"ok": false, "error": "too many requests, slow down"})
+						return
+					}
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"tinychess/internal/federation"
+	"tinychess/internal/game"
+	"tinychess/internal/templates"
+)
+
+// HandleRemotePage serves the read-only game page for a game hosted on an
+// allowlisted remote instance.
+func (h *Handler) HandleRemotePage(w http.ResponseWriter, r *http.Request) {
+	host := r.PathValue("host")
+	if !h.Remote.Allowed(host) {
+		http.NotFound(w, r)
+		return
+	}
+	templates.WriteRemoteGameHTML(w, host, r.PathValue("id"))
+}
+
+// HandleRemoteSSE streams a remote game's events to a local spectator.
+func (h *Handler) HandleRemoteSSE(w http.ResponseWriter, r *http.Request) {
+	ch, unsubscribe, err := h.Remote.Subscribe(r.PathValue("host"), r.PathValue("id"))
+	switch {
+	case errors.Is(err, federation.ErrBadGameID):
+		http.Error(w, "bad game id", http.StatusBadRequest)
+		return
+	case err != nil:
+		http.NotFound(w, r)
+		return
+	}
+	defer unsubscribe()
+
+	if _, ok := w.(http.Flusher); !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	stream := newEventStream(w)
+	if err := stream.Flush(); err != nil {
+		return
+	}
+
+	schema := game.NegotiateSchema(r.URL.Query().Get("schema"))
+	notation := h.notationFor(r, "")
+	ticker := time.NewTicker(h.heartbeat())
+	defer ticker.Stop()
+
+	ctx := r.Context()
+	drained := h.drained()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-drained:
+			h.writeReconnect(stream, schema)
+			_ = stream.Flush()
+			return
+		case <-ticker.C:
+			stream.Heartbeat()
+		case msg := <-ch:
+			stream.Event(game.ConvertPayload(game.ApplyNotation(msg, notation), schema))
+			for n := len(ch); n > 0; n-- {
+				stream.Event(game.ConvertPayload(game.ApplyNotation(<-ch, notation), schema))
+			}
+		}
+		if err := stream.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tinychess/internal/game"
+)
+
+func TestResignRequiresSeatToken(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+	mux := h.Routes()
+	id := game.NewGameID()
+	for _, client := range []string{"a", "b"} {
+		if _, _, err := h.Hub.Get(context.Background(), id, client); err != nil {
+			t.Fatalf("seat %s: %v", client, err)
+		}
+	}
+	resign := func(token string) int {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", "/resign/"+id.String(), strings.NewReader(`{"clientId":"a","seatToken":"`+token+`"}`)))
+		return w.Code
+	}
+	for _, token := range []string{"", h.seatToken(id, "b")} {
+		if code := resign(token); code != http.StatusForbidden {
+			t.Fatalf("expected resigning with token %q refused, got %d", token, code)
+		}
+	}
+	g, _ := h.Hub.Lookup(id)
+	if g.Over() {
+		t.Fatalf("expected the game to go on")
+	}
+	if code := resign(h.seatToken(id, "a")); code != http.StatusOK || !g.Over() {
+		t.Fatalf("expected the player to resign, got %d", code)
+	}
+}
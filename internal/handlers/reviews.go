@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+
+	"tinychess/internal/game"
+	"tinychess/internal/logging"
+	"tinychess/internal/storage"
+)
+
+// Bounds on what players and coaches write in a review.
+const (
+	MaxReviewNote    = 500
+	MaxReviewSummary = 4000
+	MaxReviewComment = 1000
+)
+
+// Review statuses, as the API reports them.
+const (
+	ReviewQueued    = "queued"
+	ReviewClaimed   = "claimed"
+	ReviewPublished = "published"
+)
+
+// ReviewAnnotation is a coach's comment on the position after ply.
+type ReviewAnnotation struct {
+	Ply     int    `json:"ply"`
+	Comment string `json:"comment"`
+}
+
+// ParseCoaches reads the user ids allowed to review games.
+func ParseCoaches(ids []string) (map[uuid.UUID]bool, error) {
+	coaches := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		uid, err := uuid.Parse(strings.TrimSpace(id))
+		if err != nil {
+			return nil, fmt.Errorf("invalid coach id %q", id)
+		}
+		coaches[uid] = true
+	}
+	return coaches, nil
+}
+
+// coach returns the caller's user id if they are one of the instance's
+// coaches.
+func (h *Handler) coach(userID string) (uuid.UUID, bool) {
+	uid, err := uuid.Parse(strings.TrimSpace(userID))
+	return uid, err == nil && h.Coaches[uid]
+}
+
+// reviewsAvailable answers 503 and reports false when the instance cannot
+// take reviews: they need storage and at least one coach.
+func (h *Handler) reviewsAvailable(w http.ResponseWriter) bool {
+	if h.Store == nil || len(h.Coaches) == 0 {
+		WriteJSON(w, http.StatusServiceUnavailable, map[string]any{"ok": false, "error": "reviews unavailable"})
+		return false
+	}
+	return true
+}
+
+// reviewJSON is the API form of a review. The coach's draft is left out for
+// anyone but a coach until it is published.
+func reviewJSON(rv storage.Review, coach bool) map[string]any {
+	status := ReviewQueued
+	switch {
+	case rv.PublishedAt != nil:
+		status = ReviewPublished
+	case rv.CoachID != nil:
+		status = ReviewClaimed
+	}
+	out := map[string]any{
+		"id":        rv.ID,
+		"gameId":    game.GameID(rv.GameID),
+		"url":       "/" + game.GameID(rv.GameID).String(),
+		"status":    status,
+		"note":      rv.Note,
+		"createdAt": rv.CreatedAt,
+	}
+	if rv.ClaimedAt != nil {
+		out["claimedAt"] = rv.ClaimedAt
+	}
+	if rv.PublishedAt != nil {
+		out["publishedAt"] = rv.PublishedAt
+	}
+	if coach || rv.PublishedAt != nil {
+		annotations := []ReviewAnnotation{}
+		if rv.Annotations != "" {
+			if err := json.Unmarshal([]byte(rv.Annotations), &annotations); err != nil {
+				logging.Debugf("review %s annotations: %v", rv.ID, err)
+			}
+		}
+		out["summary"] = rv.Summary
+		out["annotations"] = annotations
+	}
+	return out
+}
+
+// HandleRequestReview queues a finished game for a coach to review, at the
+// request of one of its players. The body is {"userId", "seatToken",
+// "note"}; asking again for the same game returns the queued review.
+func (h *Handler) HandleRequestReview(w http.ResponseWriter, r *http.Request) {
+	if !h.reviewsAvailable(w) {
+		return
+	}
+	var body struct {
+		UserID    string `json:"userId"`
+		SeatToken string `json:"seatToken"`
+		Note      string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return
+	}
+	userID, err := uuid.Parse(strings.TrimSpace(body.UserID))
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing user id"})
+		return
+	}
+	note := strings.TrimSpace(body.Note)
+	if len(note) > MaxReviewNote {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "note too long"})
+		return
+	}
+	id := requestGameID(r)
+	if !h.seatAuthorized(r, id, userID.String(), body.SeatToken) {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "bad seat token"})
+		return
+	}
+	g, _, err := h.Hub.Get(r.Context(), id, "")
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
+		return
+	}
+	g.Mu.RLock()
+	color := g.Clients[userID.String()]
+	g.Mu.RUnlock()
+	if color == chess.NoColor {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "not a player"})
+		return
+	}
+	if !g.Over() {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "game in progress"})
+		return
+	}
+
+	rv, err := h.Store.RequestReview(r.Context(), id.UUID(), userID, note)
+	if err != nil {
+		logging.Debugf("request review of %s failed: %v", id, err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not request review"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "review": reviewJSON(rv, false)})
+}
+
+// HandleReviewQueue lists, for a coach, the reviews waiting for one and
+// those they are working on, oldest first.
+func (h *Handler) HandleReviewQueue(w http.ResponseWriter, r *http.Request) {
+	if !h.reviewsAvailable(w) {
+		return
+	}
+	coachID, ok := h.coach(requestUserID(r))
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "not a coach"})
+		return
+	}
+	reviews, err := h.Store.ReviewQueue(r.Context(), coachID)
+	if err != nil {
+		logging.Debugf("review queue failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not load reviews"})
+		return
+	}
+	out := make([]map[string]any, len(reviews))
+	for i, rv := range reviews {
+		out[i] = reviewJSON(rv, true)
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "reviews": out})
+}
+
+// HandleMyReviews lists the reviews the caller asked for, newest first; a
+// published review is their notice that it is ready.
+func (h *Handler) HandleMyReviews(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(requestUserID(r))
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing user id"})
+		return
+	}
+	reviews, err := h.Store.UserReviews(r.Context(), userID)
+	if err != nil {
+		logging.Debugf("user reviews failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not load reviews"})
+		return
+	}
+	out := make([]map[string]any, len(reviews))
+	for i, rv := range reviews {
+		out[i] = reviewJSON(rv, false)
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "reviews": out})
+}
+
+// HandleReview returns a review (GET) to the player who asked for it or to
+// a coach, or saves a coach's draft of it (PUT). The PUT body is {"userId",
+// "summary", "annotations": [{"ply", "comment"}], "publish"}; publishing
+// makes the review final and tells the game's watchers it is ready.
+func (h *Handler) HandleReview(w http.ResponseWriter, r *http.Request) {
+	if !h.reviewsAvailable(w) {
+		return
+	}
+	reviewID, err := uuid.Parse(r.PathValue("reviewId"))
+	if err != nil {
+		WriteJSON(w, http.StatusNotFound, map[string]any{"ok": false, "error": "unknown review"})
+		return
+	}
+	if r.Method == http.MethodGet {
+		rv, ok := h.loadReview(w, r, reviewID)
+		if !ok {
+			return
+		}
+		userID := requestUserID(r)
+		_, coach := h.coach(userID)
+		if !coach && userID != rv.UserID.String() {
+			WriteJSON(w, http.StatusNotFound, map[string]any{"ok": false, "error": "unknown review"})
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "review": reviewJSON(rv, coach)})
+		return
+	}
+
+	var body struct {
+		UserID      string             `json:"userId"`
+		Summary     string             `json:"summary"`
+		Annotations []ReviewAnnotation `json:"annotations"`
+		Publish     bool               `json:"publish"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return
+	}
+	coachID, ok := h.coach(body.UserID)
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "not a coach"})
+		return
+	}
+	rv, ok := h.loadReview(w, r, reviewID)
+	if !ok {
+		return
+	}
+	g, _, err := h.Hub.Get(r.Context(), game.GameID(rv.GameID), "")
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
+		return
+	}
+
+	summary := strings.TrimSpace(body.Summary)
+	if len(summary) > MaxReviewSummary {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "summary too long"})
+		return
+	}
+	g.Mu.RLock()
+	plies := len(g.MovesUCI())
+	g.Mu.RUnlock()
+	annotations := make([]ReviewAnnotation, 0, len(body.Annotations))
+	for _, a := range body.Annotations {
+		a.Comment = strings.TrimSpace(a.Comment)
+		if a.Ply < 0 || a.Ply > plies || a.Comment == "" || len(a.Comment) > MaxReviewComment {
+			WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": fmt.Sprintf("bad annotation for ply %d", a.Ply)})
+			return
+		}
+		annotations = append(annotations, a)
+	}
+	sort.SliceStable(annotations, func(i, j int) bool { return annotations[i].Ply < annotations[j].Ply })
+	encoded, _ := json.Marshal(annotations)
+	var publishedAt *time.Time
+	if body.Publish {
+		now := time.Now()
+		publishedAt = &now
+	}
+
+	rv, err = h.Store.SaveReview(r.Context(), reviewID, coachID, summary, string(encoded), publishedAt)
+	if !h.writeReviewError(w, reviewID, err) {
+		return
+	}
+	if rv.PublishedAt != nil {
+		g.Mu.RLock()
+		color := g.Clients[rv.UserID.String()]
+		g.Mu.RUnlock()
+		g.BroadcastReview(rv.ID.String(), color)
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "review": reviewJSON(rv, true)})
+}
+
+// HandleClaimReview assigns a queued review to the calling coach, so other
+// coaches leave it alone. The body is {"userId"}.
+func (h *Handler) HandleClaimReview(w http.ResponseWriter, r *http.Request) {
+	if !h.reviewsAvailable(w) {
+		return
+	}
+	var body struct {
+		UserID string `json:"userId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return
+	}
+	coachID, ok := h.coach(body.UserID)
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "not a coach"})
+		return
+	}
+	reviewID, err := uuid.Parse(r.PathValue("reviewId"))
+	if err != nil {
+		WriteJSON(w, http.StatusNotFound, map[string]any{"ok": false, "error": "unknown review"})
+		return
+	}
+	rv, err := h.Store.ClaimReview(r.Context(), reviewID, coachID, time.Now())
+	if !h.writeReviewError(w, reviewID, err) {
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "review": reviewJSON(rv, true)})
+}
+
+// loadReview looks up a review, answering 404 or 500 and reporting false
+// when it cannot.
+func (h *Handler) loadReview(w http.ResponseWriter, r *http.Request, id uuid.UUID) (storage.Review, bool) {
+	rv, err := h.Store.Review(r.Context(), id)
+	if err == nil {
+		return rv, true
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		WriteJSON(w, http.StatusNotFound, map[string]any{"ok": false, "error": "unknown review"})
+	} else {
+		logging.Debugf("load review %s failed: %v", id, err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not load review"})
+	}
+	return rv, false
+}
+
+// writeReviewError answers a failed change to a review and reports whether
+// err was nil.
+func (h *Handler) writeReviewError(w http.ResponseWriter, reviewID uuid.UUID, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, storage.ErrNotFound):
+		WriteJSON(w, http.StatusNotFound, map[string]any{"ok": false, "error": "unknown review"})
+	case errors.Is(err, storage.ErrReviewClaimed), errors.Is(err, storage.ErrReviewPublished):
+		WriteJSON(w, http.StatusConflict, map[string]any{"ok": false, "error": err.Error()})
+	default:
+		logging.Debugf("update review %s failed: %v", reviewID, err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not save review"})
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+
+	"tinychess/internal/game"
+	"tinychess/internal/storage"
+)
+
+func TestReviewWorkflow(t *testing.T) {
+	db, err := storage.New("sqlite://"+filepath.Join(t.TempDir(), "test.db"), 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	store := storage.NewStore(db)
+	h := NewHandler(game.NewHub(store), store)
+	coach, other := uuid.NewString(), uuid.NewString()
+	if h.Coaches, err = ParseCoaches([]string{coach, other}); err != nil {
+		t.Fatalf("coaches: %v", err)
+	}
+	mux := h.Routes()
+
+	call := func(method, path, body string, want int) map[string]any {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		var resp map[string]any
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode %s %s: %v", method, path, err)
+		}
+		if w.Code != want {
+			t.Fatalf("%s %s: got %d %v, want %d", method, path, w.Code, resp, want)
+		}
+		return resp
+	}
+
+	player := uuid.NewString()
+	id, _, err := h.Hub.CreateGame(context.Background(), player, game.GameOptions{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	g, _ := h.Hub.Lookup(id)
+	ask := `{"userId":"` + player + `","seatToken":"` + h.seatToken(id, player) + `","note":"Where did I go wrong?"}`
+	call("POST", "/api/games/"+id.String()+"/review", ask, http.StatusBadRequest)
+	g.Mu.RLock()
+	color := g.Clients[player]
+	g.Mu.RUnlock()
+	if err := g.Resign(chess.White); err != nil {
+		t.Fatalf("resign: %v", err)
+	}
+	stranger := uuid.NewString()
+	call("POST", "/api/games/"+id.String()+"/review", `{"userId":"`+stranger+`","seatToken":"`+h.seatToken(id, stranger)+`"}`, http.StatusForbidden)
+	review := call("POST", "/api/games/"+id.String()+"/review", ask, http.StatusOK)["review"].(map[string]any)
+	if review["status"] != ReviewQueued || review["note"] != "Where did I go wrong?" {
+		t.Fatalf("unexpected review %v", review)
+	}
+	path := "/api/reviews/" + review["id"].(string)
+
+	call("GET", "/api/reviews?userId="+player, "", http.StatusForbidden)
+	if queue := call("GET", "/api/reviews?userId="+coach, "", http.StatusOK)["reviews"].([]any); len(queue) != 1 {
+		t.Fatalf("expected one review queued, got %v", queue)
+	}
+	call("POST", path+"/claim", `{"userId":"`+coach+`"}`, http.StatusOK)
+	call("POST", path+"/claim", `{"userId":"`+other+`"}`, http.StatusConflict)
+	if queue := call("GET", "/api/reviews?userId="+other, "", http.StatusOK)["reviews"].([]any); len(queue) != 0 {
+		t.Fatalf("expected a claimed review to leave other coaches' queues, got %v", queue)
+	}
+
+	call("PUT", path, `{"userId":"`+coach+`","annotations":[{"ply":1,"comment":"?"}]}`, http.StatusBadRequest)
+	call("PUT", path, `{"userId":"`+coach+`","summary":"Draft","annotations":[{"ply":0,"comment":"Fine."}]}`, http.StatusOK)
+	if got := call("GET", path+"?userId="+player, "", http.StatusOK)["review"].(map[string]any); got["summary"] != nil {
+		t.Fatalf("expected the draft hidden from the player, got %v", got)
+	}
+
+	ch := make(chan []byte, 4)
+	g.AddWatcher(ch)
+	call("PUT", path, `{"userId":"`+coach+`","summary":"Resigning at once was premature.","annotations":[{"ply":0,"comment":"Play on."}],"publish":true}`, http.StatusOK)
+	var event game.ReviewPayload
+	if err := json.Unmarshal(<-ch, &event); err != nil || event.Kind != "review" || event.Color != color.String() {
+		t.Fatalf("expected a review event for the player's color, got %+v %v", event, err)
+	}
+	got := call("GET", path+"?userId="+player, "", http.StatusOK)["review"].(map[string]any)
+	if got["status"] != ReviewPublished || got["summary"] != "Resigning at once was premature." || len(got["annotations"].([]any)) != 1 {
+		t.Fatalf("unexpected published review %v", got)
+	}
+	call("GET", path+"?userId="+stranger, "", http.StatusNotFound)
+	call("PUT", path, `{"userId":"`+coach+`","summary":"Again"}`, http.StatusConflict)
+	if mine := call("GET", "/api/me/reviews?userId="+player, "", http.StatusOK)["reviews"].([]any); len(mine) != 1 {
+		t.Fatalf("expected the player's review listed, got %v", mine)
+	}
+}
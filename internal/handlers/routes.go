@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"tinychess/internal/game"
+)
+
+// Latency budgets per endpoint class, enforced by the Deadline middleware.
+var (
+	MoveBudget  = 2 * time.Second
+	APIBudget   = 5 * time.Second
+	AdminBudget = 30 * time.Second
+)
+
+// Routes returns a mux with every tinychess endpoint registered, so callers
+// (main, tests, simulations) can serve the handler without touching
+// http.DefaultServeMux. Patterns are method-aware, so unsupported methods get
+// a 405 from the mux, and each route carries its own middleware chain on top
+// of request logging. GET routes also answer HEAD, and every path answers
+// OPTIONS with its Allow header. Endpoints of disabled features answer 404.
+func (h *Handler) Routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	var paths []string
+	allowed := make(map[string][]string)
+	register := func(pattern string, fn http.HandlerFunc, mws []Middleware) {
+		method, path, _ := strings.Cut(pattern, " ")
+		if _, ok := allowed[path]; !ok {
+			paths = append(paths, path)
+		}
+		allowed[path] = append(allowed[path], method)
+		if method == http.MethodGet {
+			allowed[path] = append(allowed[path], http.MethodHead)
+		}
+		base := []Middleware{LogRequests, withRoute(pattern)}
+		if corsPath(path) {
+			base = append(base, h.CORS)
+		}
+		mux.Handle(pattern, Chain(fn, append(append(base, h.Authenticate), mws...)...))
+	}
+	route := func(pattern string, fn http.HandlerFunc, mws ...Middleware) {
+		if strings.HasPrefix(pattern, http.MethodGet+" ") {
+			mws = append([]Middleware{Head}, mws...)
+		}
+		register(pattern, fn, mws)
+	}
+	stream := func(pattern string, fn http.HandlerFunc, mws ...Middleware) {
+		register(pattern, fn, append(mws, HeadStream))
+	}
+
+	// Move and API requests count against the caller's daily quota, see
+	// Meter. Creating games, moves, reactions and chat are also throttled
+	// per caller, see Throttle, and routes that create games or change
+	// their result are refused during maintenance, see Writable.
+	moves := chain(Deadline(MoveBudget), h.Meter)
+	api := chain(Deadline(APIBudget), h.Meter)
+	admin := Deadline(AdminBudget)
+	// Scope checks for API tokens, see RequireScope.
+	read := h.RequireScope(game.ScopeRead)
+	play := h.RequireScope(game.ScopePlay)
+
+	route("GET /new", h.HandleNew, h.Writable, h.Throttle(LimitNew), api)
+	route("POST /new", h.HandleNew, h.Writable, h.Throttle(LimitNew), api)
+	stream("GET /sse/{id}", h.HandleSSE, RequireGameID, read)
+	stream("GET /sse/multi", h.HandleMultiSSE)
+	route("POST /move/{id}", h.HandleMove, RequireGameID, play, h.Writable, h.Throttle(LimitMove), moves)
+	route("POST /resign/{id}", h.HandleResign, RequireGameID, play, h.Writable, moves)
+	route("POST /react/{id}", h.feature(FeatureReactions, h.HandleReact), RequireGameID, play, h.Throttle(LimitReact), api)
+	route("POST /chat/{id}", h.feature(FeatureChat, h.HandleChat), RequireGameID, play, h.Throttle(LimitChat), api)
+	route("POST /chat/{id}/delete", h.feature(FeatureChat, h.HandleChatDelete), RequireGameID, play, h.Throttle(LimitChat), api)
+	route("POST /chat/{id}/mute", h.feature(FeatureChat, h.HandleChatMute), RequireGameID, play, h.Throttle(LimitChat), api)
+	route("POST /release/{id}", h.HandleRelease, RequireGameID, play, api)
+	route("POST /forget/{id}", h.HandleForget, RequireGameID, play, api)
+	route("POST /api/import", h.feature(FeatureImport, h.HandleImport), h.Writable, api)
+	route("GET /api/endings", h.HandleEndings, api)
+	route("POST /api/training", h.HandleTraining, h.Writable, api)
+	route("GET /api/tablebase", h.HandleTablebase, api)
+	route("GET /api/stats", h.HandleStats, api)
+	route("GET /api/timecontrols", h.HandleTimeControls, api)
+	route("GET /api/languages", h.HandleLanguages, api)
+	route("GET /api/games/{id}", h.HandleGame, RequireGameID, read, h.RequireViewer, api)
+	route("GET /api/games/{id}/pgn", h.HandlePGN, RequireGameID, read, h.RequireViewer, api)
+	route("GET /api/games/{id}/moves", h.HandleMoves, RequireGameID, read, h.RequireViewer, api)
+	route("GET /api/games/{id}/ply/{ply}", h.HandlePly, RequireGameID, read, h.RequireViewer, api)
+	route("GET /api/games/{id}/legal", h.HandleLegal, RequireGameID, read, h.RequireViewer, api)
+	route("GET /api/games/{id}/timeline", h.HandleTimeline, RequireGameID, read, h.RequireViewer, api)
+	route("GET /api/games/{id}/analysis", h.HandleAnalysis, RequireGameID, read, h.RequireViewer, api)
+	route("POST /api/games/{id}/hint", h.feature(FeatureHints, h.HandleHint), RequireGameID, play, api)
+	route("POST /api/games/{id}/name", h.HandleName, RequireGameID, play, api)
+	route("POST /api/games/{id}/tokens", h.HandleAPIToken, RequireGameID, play, api)
+	route("POST /api/games/{id}/review", h.HandleRequestReview, RequireGameID, play, api)
+	route("POST /api/games/{id}/rewind", h.feature(FeaturePostMortem, h.HandleRewind), RequireGameID, play, h.Throttle(LimitMove), api)
+	route("GET /api/games/{id}/board.svg", h.HandleBoardSVG, RequireGameID, read, h.RequireViewer, api)
+	route("GET /api/me/recent", h.HandleRecent, api)
+	route("GET /api/me/bookmarks", h.HandleBookmarks, api)
+	route("GET /api/me/usage", h.HandleUsage, api)
+	route("GET /api/me/reviews", h.HandleMyReviews, api)
+	route("GET /api/me/preferences", h.HandlePreferences, api)
+	route("PUT /api/me/preferences", h.HandlePreferences, api)
+	route("POST /api/bookmarks", h.HandleBookmark, api)
+	route("DELETE /api/bookmarks", h.HandleBookmark, api)
+	route("GET /api/reviews", h.HandleReviewQueue, api)
+	route("GET /api/reviews/{reviewId}", h.HandleReview, api)
+	route("PUT /api/reviews/{reviewId}", h.HandleReview, api)
+	route("POST /api/reviews/{reviewId}/claim", h.HandleClaimReview, api)
+	route("GET /api/keys", h.HandleAPIKeys, api)
+	route("POST /api/keys", h.HandleAPIKeys, api)
+	route("DELETE /api/keys/{keyId}", h.HandleRevokeAPIKey, api)
+	route("GET /api/openapi.json", h.HandleOpenAPI)
+	route("GET /api/docs", h.HandleAPIDocs)
+	route("POST /api/pair", h.feature(FeaturePairing, h.HandlePair), api)
+	route("GET /api/ladder", h.feature(FeatureLadder, h.HandleLadder), api)
+	route("GET /api/v1/games", h.HandleListGames, api)
+	route("POST /api/ladder/join", h.feature(FeatureLadder, h.HandleLadderJoin), api)
+	route("POST /api/ladder/challenges", h.feature(FeatureLadder, h.HandleLadderChallenge), h.Writable, api)
+	route("GET /admin/retention", h.HandleAdminRetention, h.RequireAdmin, admin)
+	route("POST /admin/retention", h.HandleAdminRetention, h.RequireAdmin, admin)
+	route("GET /admin/database", h.HandleAdminDatabase, h.RequireAdmin, admin)
+	route("POST /admin/database", h.HandleAdminDatabase, h.RequireAdmin, admin)
+	route("GET /admin/games/{id}/moves", h.HandleAdminMoveHistory, h.RequireAdmin, RequireGameID, admin)
+	route("POST /admin/adjudicate", h.HandleAdminAdjudicate, h.RequireAdmin, h.Writable, admin)
+	route("GET /admin/images", h.HandleAdminImages, h.RequireAdmin, admin)
+	route("GET /admin/evictions", h.HandleAdminEvictions, h.RequireAdmin, admin)
+	route("GET /admin/outbox", h.HandleAdminOutbox, h.RequireAdmin, admin)
+	route("GET /admin/usage", h.HandleAdminUsage, h.RequireAdmin, admin)
+	route("GET /admin/maintenance", h.HandleAdminMaintenance, h.RequireAdmin, admin)
+	route("POST /admin/maintenance", h.HandleAdminMaintenance, h.RequireAdmin, admin)
+	route("GET /.well-known/webfinger", h.HandleWebFinger, api)
+	route("GET /ap/actor", h.HandleActor, api)
+	route("POST /ap/inbox", h.HandleInbox, api)
+	route("GET /ap/outbox", h.HandleOutbox, api)
+	route("GET /ap/followers", h.HandleFollowers, api)
+	route("GET /ap/notes/{id}", h.HandleNote, api)
+	route("GET /remote/{host}/{id}", h.HandleRemotePage)
+	stream("GET /remote/{host}/sse/{id}", h.HandleRemoteSSE)
+	route("GET /pair/{code}", h.feature(FeaturePairing, h.HandlePairClaim), api)
+	route("GET /ladder", h.feature(FeatureLadder, h.HandleLadderPage))
+	route("GET /overlay/{id}", h.HandleOverlay, RequireGameID)
+	route("GET /manifest.webmanifest", h.HandleManifest)
+	route("GET /icon.svg", h.HandleIcon)
+	route("GET /sw.js", h.HandleServiceWorker)
+	route("GET /static/{file}", h.HandleStatic)
+	route("GET /{$}", h.HandlePage)
+	route("GET /index.html", h.HandlePage)
+	route("GET /{id}", h.HandlePage, RequireGameID)
+	route("GET /game/{id}", h.HandlePage, RequireGameID)
+	for _, path := range paths {
+		preflight := allowMethods(allowed[path])
+		if corsPath(path) {
+			preflight = h.CORS(preflight)
+		}
+		mux.Handle(http.MethodOptions+" "+path, LogRequests(preflight))
+	}
+	return mux
+}
+
+// chain combines middlewares into one, the first outermost.
+func chain(mws ...Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		return Chain(next, mws...)
+	}
+}
+
+// allowMethods answers OPTIONS requests, including CORS preflights, with the
+// methods a path supports. It does not grant any origin access; see CORS.
+func allowMethods(methods []string) http.Handler {
+	allow := strings.Join(append(methods, http.MethodOptions), ", ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		if r.Header.Get("Origin") != "" && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", allow)
+			if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,517 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"tinychess/internal/game"
+	"tinychess/internal/templates"
+)
+
+func TestRoutesRejectWrongMethod(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+	mux := h.Routes()
+
+	req := httptest.NewRequest("GET", "/move/g1", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow == "" {
+		t.Fatalf("expected Allow header on 405")
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := Chain(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		order = append(order, "handler")
+	}), mw("outer"), mw("inner"))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if len(order) != 3 || order[0] != "outer" || order[1] != "inner" || order[2] != "handler" {
+		t.Fatalf("unexpected order %v", order)
+	}
+}
+
+func TestRoutesHead(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+	mux := h.Routes()
+
+	get := httptest.NewRecorder()
+	mux.ServeHTTP(get, httptest.NewRequest("GET", "/api/timecontrols", nil))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("HEAD", "/api/timecontrols", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Fatalf("HEAD response should have no body")
+	}
+	if got, want := w.Header().Get("Content-Length"), strconv.Itoa(get.Body.Len()); got != want {
+		t.Fatalf("Content-Length %q, want %q", got, want)
+	}
+
+	// Event streams answer HEAD without opening the stream.
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("HEAD", "/sse/"+game.NewGameID().String(), nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected stream HEAD response %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
+
+func TestRoutesRejectBadGameID(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+	mux := h.Routes()
+
+	for _, path := range []string{"/g1", "/sse/g1", "/api/games/abc", "/api/games/abc/pgn", "/overlay/abc", "/00000000-0000-0000-0000-000000000000"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", path, w.Code)
+		}
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/move/g1", strings.NewReader(`{"uci":"e2e4","clientId":"c1"}`)))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for move in malformed game, got %d", w.Code)
+	}
+	if n := h.Hub.Len(); n != 0 {
+		t.Fatalf("malformed ids must not create games, have %d", n)
+	}
+}
+
+func TestRoutesOverlay(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+	id := game.NewGameID()
+	w := httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, httptest.NewRequest("GET", "/overlay/"+id.String()+"?theme=chroma", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("unexpected overlay response %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), `"`+id.String()+`"`) {
+		t.Fatalf("expected the overlay to follow %s", id)
+	}
+}
+
+func TestRoutesGameState(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	id := game.NewGameID()
+	g, _, err := hub.Get(context.Background(), id, "")
+	if err != nil {
+		t.Fatalf("get game: %v", err)
+	}
+	if err := g.MakeMove("e2e4"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, httptest.NewRequest("GET", "/api/games/"+id.String()+"?notation=lan", nil))
+	var state game.GameState
+	if err := json.NewDecoder(w.Body).Decode(&state); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("state: %d %v", w.Code, err)
+	}
+	if state.Kind != "state" || state.Turn != "b" || len(state.UCI) != 1 || state.Moves[0] != "e2e4" {
+		t.Fatalf("unexpected state %+v", state)
+	}
+
+	w = httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, httptest.NewRequest("GET", "/api/games/"+id.String()+"?schema=1", nil))
+	if strings.Contains(w.Body.String(), `"schema"`) {
+		t.Fatalf("expected a v1 payload, got %s", w.Body.String())
+	}
+}
+
+func TestRoutesSharedPly(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	id := game.NewGameID()
+	g, _, err := hub.Get(context.Background(), id, "")
+	if err != nil {
+		t.Fatalf("get game: %v", err)
+	}
+	for _, uci := range []string{"e2e4", "e7e5", "g1f3"} {
+		if err := g.MakeMove(uci); err != nil {
+			t.Fatalf("move %s: %v", uci, err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, httptest.NewRequest("GET", "/game/"+id.String()+"?ply=1", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"ply":1,"plies":3`) || !strings.Contains(w.Body.String(), `"san":"e4"`) {
+		t.Fatalf("expected the page to carry ply 1, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, httptest.NewRequest("GET", "/"+id.String(), nil))
+	if strings.Contains(w.Body.String(), "{{SHARED_PLY}}") || strings.Contains(w.Body.String(), `"plies"`) {
+		t.Fatal("expected no shared position without ?ply=")
+	}
+
+	path := "/api/games/" + id.String() + "/ply/"
+	w = httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, httptest.NewRequest("GET", path+"2", nil))
+	var resp struct {
+		OK       bool             `json:"ok"`
+		Position game.PlyPosition `json:"position"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || !resp.OK || resp.Position.UCI != "e7e5" {
+		t.Fatalf("ply 2: %d %+v %v", w.Code, resp, err)
+	}
+	for ply, want := range map[string]int{"4": http.StatusNotFound, "x": http.StatusBadRequest} {
+		w = httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, httptest.NewRequest("GET", path+ply, nil))
+		if w.Code != want {
+			t.Errorf("ply %s: got %d, want %d", ply, w.Code, want)
+		}
+	}
+}
+
+func TestRoutesLegal(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	id := game.NewGameID()
+	path := "/api/games/" + id.String() + "/legal"
+
+	w := httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, httptest.NewRequest("GET", path+"?from=z9", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad square, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, httptest.NewRequest("GET", path+"?from=G1", nil))
+	var resp struct {
+		OK      bool          `json:"ok"`
+		From    string        `json:"from"`
+		Targets []game.Target `json:"targets"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || !resp.OK {
+		t.Fatalf("legal: %d %v", w.Code, err)
+	}
+	if resp.From != "g1" || len(resp.Targets) != 2 || resp.Targets[0].To != "f3" || resp.Targets[1].To != "h3" {
+		t.Fatalf("unexpected knight targets %+v", resp)
+	}
+}
+
+func TestRoutesOptions(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+	mux := h.Routes()
+
+	req := httptest.NewRequest("OPTIONS", "/api/me/preferences", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, PUT, OPTIONS" {
+		t.Fatalf("unexpected Allow %q", allow)
+	}
+	if w.Header().Get("Access-Control-Allow-Methods") == "" {
+		t.Fatalf("expected preflight to list allowed methods")
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("preflight should not grant origin access")
+	}
+}
+
+func TestRoutesCORS(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+	origins, err := ParseOrigins(" https://Widget.example.com/ ,http://localhost:3000")
+	if err != nil {
+		t.Fatalf("parse origins: %v", err)
+	}
+	h.CORSOrigins = origins
+	mux := h.Routes()
+
+	do := func(method, path, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Origin", origin)
+		if method == "OPTIONS" {
+			req.Header.Set("Access-Control-Request-Method", "GET")
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("OPTIONS", "/api/timecontrols", "https://widget.example.com")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://widget.example.com" {
+		t.Fatalf("preflight: Access-Control-Allow-Origin = %q", got)
+	}
+	if w.Header().Get("Access-Control-Allow-Methods") == "" || w.Header().Get("Access-Control-Max-Age") == "" {
+		t.Fatalf("preflight missing headers: %v", w.Header())
+	}
+	w = do("GET", "/api/timecontrols", "http://localhost:3000")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:3000" {
+		t.Fatalf("Access-Control-Allow-Origin = %q", got)
+	}
+	if !strings.Contains(w.Header().Get("Access-Control-Expose-Headers"), "X-RateLimit-Remaining") {
+		t.Fatalf("expected the rate limit headers to be exposed, got %q", w.Header().Get("Access-Control-Expose-Headers"))
+	}
+	if got := do("GET", "/api/timecontrols", "https://evil.example.com").Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("unlisted origin granted %q", got)
+	}
+	if got := do("GET", "/ladder", "https://widget.example.com").Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("page outside the API granted %q", got)
+	}
+
+	h.CORSOrigins = []string{"*"}
+	if got := do("GET", "/api/timecontrols", "https://any.example.com").Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Fatalf("wildcard: Access-Control-Allow-Origin = %q", got)
+	}
+
+	for _, bad := range []string{"example.com", "ftp://example.com", "https://example.com/path", "https://"} {
+		if _, err := ParseOrigins(bad); err == nil {
+			t.Errorf("ParseOrigins(%q) should fail", bad)
+		}
+	}
+}
+
+func TestRoutesPWA(t *testing.T) {
+	mux := NewHandler(game.NewHub(nil), nil).Routes()
+	for path, typ := range map[string]string{
+		"/manifest.webmanifest": "application/manifest+json",
+		"/sw.js":                "text/javascript; charset=utf-8",
+		"/icon.svg":             "image/svg+xml",
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != typ {
+			t.Errorf("%s: %d %q", path, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/manifest.webmanifest", nil))
+	var manifest struct {
+		StartURL string `json:"start_url"`
+		Icons    []struct {
+			Src string `json:"src"`
+		} `json:"icons"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&manifest); err != nil || manifest.StartURL != "/" || len(manifest.Icons) == 0 {
+		t.Fatalf("bad manifest: %+v %v", manifest, err)
+	}
+}
+
+func TestRoutesStatic(t *testing.T) {
+	mux := NewHandler(game.NewHub(nil), nil).Routes()
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	page := w.Body.String()
+	js := templates.AssetPath("home.js")
+	if js == "" || !strings.Contains(page, `src="`+js+`"`) || strings.Contains(page, "{{") {
+		t.Fatalf("expected the home page to load %q with every placeholder filled", js)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", js, nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/javascript; charset=utf-8" ||
+		!strings.Contains(w.Header().Get("Cache-Control"), "immutable") {
+		t.Fatalf("%s: %d %v", js, w.Code, w.Header())
+	}
+	for _, path := range []string{"/static/home.js", "/static/home.0000000000.js"} {
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestRoutesBranding(t *testing.T) {
+	if err := templates.SetBranding(templates.Branding{Accents: []string{"red"}}); err == nil {
+		t.Fatal("expected a named color to be rejected")
+	}
+	defer templates.SetBranding(templates.Branding{})
+	err := templates.SetBranding(templates.Branding{
+		Name:    `Club "Rook" & Co`,
+		LogoURL: "/static/logo.png",
+		Footer:  "Run by the chess club",
+		Accents: []string{"#C0FFEE", "#123"},
+		BaseURL: "https://chess.example/",
+	})
+	if err != nil {
+		t.Fatalf("branding: %v", err)
+	}
+	mux := NewHandler(game.NewHub(nil), nil).Routes()
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	page := w.Body.String()
+	for _, want := range []string{
+		"<title>Club &#34;Rook&#34; &amp; Co</title>",
+		`<meta property="og:site_name" content="Club &#34;Rook&#34; &amp; Co" />`,
+		`<meta property="og:image" content="https://chess.example/static/logo.png" />`,
+		`<img class="brand-logo" src="/static/logo.png"`,
+		"Run by the chess club · Version",
+		"--accent: #c0ffee;",
+		`data-accent="#123"`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("home page lacks %s", want)
+		}
+	}
+	if strings.Contains(page, "{{") || strings.Contains(page, "#6ee7ff") {
+		t.Error("home page has unbranded leftovers")
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/manifest.webmanifest", nil))
+	var manifest struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&manifest); err != nil || manifest.Name != `Club "Rook" & Co` {
+		t.Fatalf("bad manifest: %+v %v", manifest, err)
+	}
+}
+
+func TestRoutesRateLimit(t *testing.T) {
+	if _, err := ParseRateLimits(map[string]string{"spam": "1/m"}); err == nil {
+		t.Fatal("expected an unknown group to be rejected")
+	}
+	limits, err := ParseRateLimits(map[string]string{LimitNew: "2/m"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	h := NewHandler(game.NewHub(nil), nil)
+	h.RateLimits = limits
+	if h.TrustedProxies, err = ParseProxies([]string{"10.0.0.1", "10.1.0.0/16"}); err != nil {
+		t.Fatalf("parse proxies: %v", err)
+	}
+	mux := h.Routes()
+
+	newGame := func(addr, user, forwarded string) *httptest.ResponseRecorder {
+		userID := "00000000-0000-0000-0000-00000000000" + user
+		req := httptest.NewRequest("POST", "/new", strings.NewReader(`{"userId":"`+userID+`"}`))
+		req.Header.Set("X-User-ID", userID)
+		req.RemoteAddr = addr
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	for i := 0; i < 2; i++ {
+		if w := newGame("192.0.2.1:1234", "a", ""); w.Code != http.StatusOK {
+			t.Fatalf("game %d: expected 200, got %d %s", i+1, w.Code, w.Body.String())
+		}
+	}
+	w := newGame("192.0.2.1:1234", "a", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" {
+		t.Fatalf("expected 429 with Retry-After 30, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	// Neither a fresh user nor a forged X-Forwarded-For escapes the
+	// address's limit.
+	if w := newGame("192.0.2.1:1234", "b", "198.51.100.7"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the address to stay limited, got %d", w.Code)
+	}
+	// The user's limit follows them to another address.
+	if w := newGame("192.0.2.2:1234", "a", ""); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the user to stay limited, got %d", w.Code)
+	}
+	if w := newGame("192.0.2.2:1234", "c", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected another address and user to be served, got %d", w.Code)
+	}
+	// Behind a trusted proxy, callers are told apart by the address the
+	// proxies vouch for, however much the client prepends.
+	for i, user := range []string{"d", "e"} {
+		if w := newGame("10.0.0.1:1234", user, "192.0.2.1, 203.0.113.9, 10.1.2.3"); w.Code != http.StatusOK {
+			t.Fatalf("proxied game %d: expected 200, got %d", i+1, w.Code)
+		}
+	}
+	if w := newGame("10.0.0.1:1234", "f", "192.0.2.2, 203.0.113.9, 10.1.2.3"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the proxied client to be limited, got %d", w.Code)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	h := NewHandler(game.NewHub(nil), nil)
+	var err error
+	if h.TrustedProxies, err = ParseProxies([]string{"10.0.0.1", " 10.1.0.0/16 ", "::ffff:10.2.0.1"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if _, err := ParseProxies([]string{"proxy.example"}); err == nil {
+		t.Fatal("expected a host name to be rejected")
+	}
+	cases := []struct{ remote, forwarded, want string }{
+		{"192.0.2.1:1234", "", "192.0.2.1"},
+		{"192.0.2.1:1234", "198.51.100.7", "192.0.2.1"},
+		{"10.0.0.1:1234", "", "10.0.0.1"},
+		{"10.0.0.1:1234", "198.51.100.7", "198.51.100.7"},
+		{"10.0.0.1:1234", "192.0.2.9, 198.51.100.7, 10.1.2.3", "198.51.100.7"},
+		{"10.0.0.1:1234", "10.1.0.5, 10.1.2.3", "10.1.0.5"},
+		{"10.0.0.1:1234", "198.51.100.7, junk", "10.0.0.1"},
+		{"10.2.0.1:1234", "198.51.100.7", "198.51.100.7"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = c.remote
+		if c.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", c.forwarded)
+		}
+		if got := h.ClientIP(r); got != c.want {
+			t.Errorf("ClientIP(%s, %q) = %s, want %s", c.remote, c.forwarded, got, c.want)
+		}
+	}
+}
+
+func TestRoutesDisabledFeature(t *testing.T) {
+	if _, err := ParseFeatures([]string{"chat", "telepathy"}); err == nil {
+		t.Fatal("expected an unknown feature to be rejected")
+	}
+	disabled, err := ParseFeatures([]string{" Ladder ", ""})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	h := NewHandler(game.NewHub(nil), nil)
+	h.Disabled = disabled
+	mux := h.Routes()
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/ladder", nil))
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "ladder is disabled") {
+		t.Fatalf("expected a disabled ladder to answer 404, got %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/timecontrols", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected other routes to keep working, got %d", w.Code)
+	}
+}
+
+func TestRoutesNewRejectsBadOptions(t *testing.T) {
+	mux := NewHandler(game.NewHub(nil), nil).Routes()
+	for _, body := range []string{
+		`{"userId":"00000000-0000-0000-0000-00000000000a","joinCode":"` + strings.Repeat("x", 65) + `"}`,
+		`{"userId":"00000000-0000-0000-0000-00000000000a","joinCode":"` + strings.Repeat("x", 65) + `","moveLimit":40}`,
+		`{"userId":"00000000-0000-0000-0000-00000000000a","moveLimit":-1}`,
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", "/new", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected %s refused, got %d %s", body, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxPooledFrame keeps unusually large event buffers out of the pool.
+const maxPooledFrame = 64 << 10
+
+// SSEWriteTimeout bounds how long a single flush to a watcher may block. A
+// client that stops reading is dropped once its socket buffer fills, rather
+// than pinning a goroutine and a watcher channel indefinitely.
+const SSEWriteTimeout = 10 * time.Second
+
+// DefaultHeartbeat is how often an idle stream sends a keep-alive frame
+// when Handler.Heartbeat is unset.
+const DefaultHeartbeat = 15 * time.Second
+
+func (h *Handler) heartbeat() time.Duration {
+	if h.Heartbeat > 0 {
+		return h.Heartbeat
+	}
+	return DefaultHeartbeat
+}
+
+var framePool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// writeEvent writes payload to an SSE stream as a single data frame. Every
+// watcher of a game writes every broadcast, so the frame is assembled in a
+// pooled buffer rather than allocating per write.
+func writeEvent(w io.Writer, payload []byte) {
+	buf := framePool.Get().(*bytes.Buffer)
+	buf.Reset()
+	buf.WriteString("data: ")
+	buf.Write(payload)
+	buf.WriteString("\n\n")
+	_, _ = w.Write(buf.Bytes())
+	if buf.Cap() <= maxPooledFrame {
+		framePool.Put(buf)
+	}
+}
+
+// eventStream buffers SSE frames so a burst of events goes out in one write,
+// and flushes them under a write deadline.
+type eventStream struct {
+	buf *bufio.Writer
+	rc  *http.ResponseController
+}
+
+func newEventStream(w http.ResponseWriter) *eventStream {
+	return &eventStream{buf: bufio.NewWriter(w), rc: http.NewResponseController(w)}
+}
+
+// Event queues payload as a data frame.
+func (s *eventStream) Event(payload []byte) {
+	writeEvent(s.buf, payload)
+}
+
+// Heartbeat queues an empty keep-alive frame.
+func (s *eventStream) Heartbeat() {
+	_, _ = s.buf.WriteString("data: {}\n\n")
+}
+
+// Retry queues a retry directive, setting how long the browser waits before
+// reconnecting once the stream ends.
+func (s *eventStream) Retry(d time.Duration) {
+	_, _ = fmt.Fprintf(s.buf, "retry: %d\n\n", d.Milliseconds())
+}
+
+// Flush sends everything queued to the client. An error means the client is
+// gone or too slow, and the stream should be closed.
+func (s *eventStream) Flush() error {
+	err := s.rc.SetWriteDeadline(time.Now().Add(SSEWriteTimeout))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if err := s.buf.Flush(); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
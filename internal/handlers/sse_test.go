@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"tinychess/internal/game"
+)
+
+func TestWriteEvent(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeEvent(w, []byte(`{"kind":"state"}`))
+	writeEvent(w, []byte(`{}`))
+	if got := w.Body.String(); got != "data: {\"kind\":\"state\"}\n\ndata: {}\n\n" {
+		t.Fatalf("unexpected stream %q", got)
+	}
+}
+
+func TestEventStreamBatchesUntilFlush(t *testing.T) {
+	w := httptest.NewRecorder()
+	s := newEventStream(w)
+	s.Event([]byte(`{"kind":"state"}`))
+	s.Heartbeat()
+	if w.Body.Len() != 0 || w.Flushed {
+		t.Fatalf("expected nothing written before flush, got %q", w.Body.String())
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if got := w.Body.String(); got != "data: {\"kind\":\"state\"}\n\ndata: {}\n\n" || !w.Flushed {
+		t.Fatalf("unexpected stream %q (flushed %v)", got, w.Flushed)
+	}
+}
+
+var errGone = errors.New("client gone")
+
+type brokenWriter struct{ *httptest.ResponseRecorder }
+
+func (brokenWriter) Write([]byte) (int, error) { return 0, errGone }
+
+func TestHandleSSEDropsDeadWatcher(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	id := game.NewGameID()
+	r := httptest.NewRequest("GET", "/sse/"+id.String(), nil)
+
+	done := make(chan struct{})
+	go func() {
+		h.Routes().ServeHTTP(brokenWriter{httptest.NewRecorder()}, r)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler kept streaming to a broken writer")
+	}
+
+	g, _, _ := hub.Get(context.Background(), id, "")
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	if n := len(g.Watchers); n != 0 {
+		t.Fatalf("expected dead watcher removed, have %d", n)
+	}
+}
+
+type discardWriter struct{ httptest.ResponseRecorder }
+
+func (discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+
+func BenchmarkWriteEvent(b *testing.B) {
+	g, _, err := game.NewHub(nil).Get(context.Background(), game.NewGameID(), "")
+	if err != nil {
+		b.Fatalf("get: %v", err)
+	}
+	ch := make(chan []byte, 1)
+	g.AddWatcher(ch)
+	g.Broadcast()
+	payload := <-ch
+	w := &discardWriter{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		writeEvent(w, payload)
+	}
+}
+
+func TestHandleSSEDrain(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	h.AlternateURL = "https://green.example"
+	id := game.NewGameID()
+	g, _, _ := hub.Get(context.Background(), id, "")
+
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		h.Routes().ServeHTTP(w, httptest.NewRequest("GET", "/sse/"+id.String(), nil))
+		close(done)
+	}()
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		g.Mu.RLock()
+		watching := len(g.Watchers) > 0
+		g.Mu.RUnlock()
+		if watching {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stream never started")
+		}
+	}
+
+	h.Drain()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("drained stream stayed open")
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "\nretry: ") || !strings.Contains(body, `"kind":"reconnect","url":"https://green.example"`) {
+		t.Fatalf("expected a retry directive and reconnect event, got %q", body)
+	}
+
+	// Streams opened while drained end at once; Undrain keeps them open.
+	w = httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, httptest.NewRequest("GET", "/sse/"+id.String(), nil))
+	if !strings.Contains(w.Body.String(), `"kind":"reconnect"`) {
+		t.Fatalf("expected a new stream to be told to reconnect, got %q", w.Body.String())
+	}
+	h.Undrain()
+	select {
+	case <-h.drained():
+		t.Fatal("expected streams to stay open after Undrain")
+	default:
+	}
+}
+
+func TestPrivateGameRequiresCodeToWatch(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	owner := "00000000-0000-0000-0000-000000000001"
+	id, _, err := hub.CreateGame(context.Background(), owner, game.GameOptions{JoinCode: "sekrit", CodeForSpectators: true})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	for _, path := range []string{"/sse/" + id.String(), "/api/games/" + id.String(), "/api/games/" + id.String() + "/pgn"} {
+		w := httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("%s: expected 403 without code, got %d", path, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, httptest.NewRequest("GET", "/api/games/"+id.String()+"/pgn?code=sekrit", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected pgn with code, got %d", w.Code)
+	}
+}
+
+// openState opens an SSE stream for target and returns its initial state.
+func openState(t *testing.T, h *Handler, g *game.Game, target string) game.ClientState {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		h.Routes().ServeHTTP(w, httptest.NewRequest("GET", target, nil).WithContext(ctx))
+		close(done)
+	}()
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		g.Mu.RLock()
+		watching := len(g.Watchers) > 0
+		g.Mu.RUnlock()
+		if watching {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stream never started")
+		}
+	}
+	cancel()
+	<-done
+	line, _, _ := strings.Cut(strings.TrimPrefix(w.Body.String(), "data: "), "\n")
+	var st game.ClientState
+	if err := json.Unmarshal([]byte(line), &st); err != nil {
+		t.Fatalf("decode initial state %q: %v", line, err)
+	}
+	return st
+}
+
+// Test that a stream naming a seated client's ID gets no seat token unless
+// it took the seat just now or presents the token it was given.
+func TestHandleSSESeatTokenOnlyForSeatHolder(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	owner := "00000000-0000-0000-0000-000000000001"
+	victim := "00000000-0000-0000-0000-000000000002"
+	id, _, err := hub.CreateGame(context.Background(), owner, game.GameOptions{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	g, _, _ := hub.Get(context.Background(), id, "")
+
+	first := openState(t, h, g, "/sse/"+id.String()+"?clientId="+victim)
+	if first.Role != "player" || first.SeatToken == "" {
+		t.Fatalf("expected the joining player to get a seat token, got role %q token %q", first.Role, first.SeatToken)
+	}
+
+	second := openState(t, h, g, "/sse/"+id.String()+"?clientId="+victim)
+	if second.SeatToken != "" {
+		t.Fatalf("expected no seat token for a second stream naming the player, got %q", second.SeatToken)
+	}
+
+	again := openState(t, h, g, "/sse/"+id.String()+"?clientId="+victim+"&seatToken="+first.SeatToken)
+	if again.SeatToken != first.SeatToken {
+		t.Fatalf("expected the seat holder to get its token back, got %q", again.SeatToken)
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"tinychess/internal/game"
+	"tinychess/internal/logging"
+	"tinychess/internal/tablebase"
+)
+
+// HandleTablebase probes the position given by ?fen= in the configured
+// tablebase and returns its exact outcome for the side to move, with every
+// legal move ranked best first.
+func (h *Handler) HandleTablebase(w http.ResponseWriter, r *http.Request) {
+	if h.Hub.Tablebase == nil {
+		WriteJSON(w, http.StatusServiceUnavailable, map[string]any{"ok": false, "error": "tablebase unavailable"})
+		return
+	}
+	fen := strings.TrimSpace(r.URL.Query().Get("fen"))
+	parsed, err := game.GameFromFEN(fen)
+	if err != nil || fen == "" {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "invalid fen"})
+		return
+	}
+	fen = parsed.Position().String()
+	if tablebase.Pieces(fen) > tablebase.MaxPieces {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "too many pieces"})
+		return
+	}
+
+	res, err := h.Hub.Tablebase.Probe(r.Context(), fen)
+	if err != nil {
+		logging.Debugf("tablebase probe for %q failed: %v", fen, err)
+		WriteJSON(w, http.StatusBadGateway, map[string]any{"ok": false, "error": "tablebase unavailable"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, TablebaseResponse{OK: true, FEN: fen, Result: res, WDL: res.Category.WDL()})
+}
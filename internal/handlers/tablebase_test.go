@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"tinychess/internal/game"
+)
+
+func TestHandleTablebase(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	probe := func(fen string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/tablebase?fen="+url.QueryEscape(fen), nil)
+		w := httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, req)
+		return w
+	}
+
+	const fen = "4k3/8/4K3/8/8/8/8/R7 w - - 0 1"
+	if w := probe(fen); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without a tablebase, got %d", w.Code)
+	}
+	hub.Tablebase = firstMoveTablebase{}
+
+	if w := probe(""); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a missing fen, got %d", w.Code)
+	}
+	if w := probe("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "too many pieces") {
+		t.Fatalf("expected 400 for the start position, got %d %s", w.Code, w.Body.String())
+	}
+	w := probe(fen)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"wdl":2`) || !strings.Contains(w.Body.String(), `"uci":"a1a8"`) {
+		t.Fatalf("probe: %d %s", w.Code, w.Body.String())
+	}
+}
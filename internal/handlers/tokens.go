@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"tinychess/internal/game"
+)
+
+// TokenRequest is the body of POST /api/games/{id}/tokens.
+type TokenRequest struct {
+	ClientID  string `json:"clientId" required:"true"`
+	SeatToken string `json:"seatToken"`
+	Scope     string `json:"scope" required:"true" enum:"read,play"`
+}
+
+// HandleAPIToken issues an API token for the game to a seated player, for
+// integrations such as overlays and dashboards. A "read" token follows the
+// game, private or not, without being able to act; a "play" token also acts
+// for the issuer's seat. Tokens are presented as a bearer credential and
+// checked by RequireScope.
+func (h *Handler) HandleAPIToken(w http.ResponseWriter, r *http.Request) {
+	if h.Hub.Seats == nil {
+		WriteJSON(w, http.StatusServiceUnavailable, map[string]any{"ok": false, "error": "api tokens unavailable"})
+		return
+	}
+	id := requestGameID(r)
+	g, _, err := h.Hub.Get(r.Context(), id, "")
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "game unavailable"})
+		return
+	}
+
+	var body TokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return
+	}
+	clientID := strings.TrimSpace(body.ClientID)
+	if clientID == "" {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing client id"})
+		return
+	}
+	scope, ok := game.ParseScope(strings.TrimSpace(body.Scope))
+	if !ok {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad scope"})
+		return
+	}
+	if !h.seatAuthorized(r, id, clientID, body.SeatToken) {
+		WriteJSON(w, http.StatusForbidden, map[string]any{"ok": false, "error": "bad seat token"})
+		return
+	}
+
+	g.Mu.RLock()
+	_, seated := g.Clients[clientID]
+	g.Mu.RUnlock()
+	if !seated {
+		WriteJSON(w, http.StatusOK, map[string]any{"ok": false, "error": "unknown client"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, TokenResponse{OK: true, Token: h.Hub.Seats.APIToken(id, clientID, scope), Scope: scope})
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"tinychess/internal/game"
+	"tinychess/internal/usage"
+)
+
+func TestAPITokenScopes(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	owner := "00000000-0000-0000-0000-000000000001"
+	id, color, err := hub.CreateGame(context.Background(), owner, game.GameOptions{JoinCode: "sekrit", CodeForSpectators: true})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	issue := func(scope string) string {
+		body := fmt.Sprintf(`{"clientId":%q,"seatToken":%q,"scope":%q}`, owner, hub.Seats.Token(id, owner), scope)
+		w := httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, httptest.NewRequest("POST", "/api/games/"+id.String()+"/tokens", strings.NewReader(body)))
+		var resp struct {
+			OK    bool   `json:"ok"`
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || !resp.OK {
+			t.Fatalf("issue %s token: %d %v", scope, w.Code, err)
+		}
+		return resp.Token
+	}
+	read, play := issue("read"), issue("play")
+
+	do := func(method, path, token, body string) int {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, r)
+		return w.Code
+	}
+
+	pgn := "/api/games/" + id.String() + "/pgn"
+	if code := do("GET", pgn, read, ""); code != http.StatusOK {
+		t.Fatalf("read token should view a private game, got %d", code)
+	}
+	if code := do("GET", pgn+"?token="+read, "", ""); code != http.StatusOK {
+		t.Fatalf("read token in the query should view a private game, got %d", code)
+	}
+	if code := do("GET", pgn, "read.x.y", ""); code != http.StatusUnauthorized {
+		t.Fatalf("expected forged token to be refused, got %d", code)
+	}
+	other := hub.Seats.APIToken(game.NewGameID(), owner, game.ScopeRead)
+	if code := do("GET", pgn, other, ""); code != http.StatusUnauthorized {
+		t.Fatalf("expected token for another game to be refused, got %d", code)
+	}
+
+	uci := "e2e4"
+	if color.String() == "b" {
+		uci = "e7e5"
+	}
+	move := fmt.Sprintf(`{"uci":%q,"clientId":%q}`, uci, owner)
+	if code := do("POST", "/move/"+id.String(), read, move); code != http.StatusForbidden {
+		t.Fatalf("read token must not move, got %d", code)
+	}
+	if color.String() == "w" {
+		if code := do("POST", "/move/"+id.String(), play, move); code != http.StatusOK {
+			t.Fatalf("play token should move for its issuer, got %d", code)
+		}
+	}
+}
+
+func TestHandleNewReturnsInvites(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	body := `{"userId":"00000000-0000-0000-0000-000000000001","inviteOnly":true}`
+	w := httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, httptest.NewRequest("POST", "/new", strings.NewReader(body)))
+
+	var resp struct {
+		ID      game.GameID                `json:"id"`
+		Color   string                     `json:"color"`
+		Invites map[game.InviteRole]string `json:"invites"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	open := game.InviteWhite
+	if resp.Color == "w" {
+		open = game.InviteBlack
+	}
+	if len(resp.Invites) != 2 || resp.Invites[open] == "" || resp.Invites[game.InviteSpectate] == "" {
+		t.Fatalf("unexpected invites %v", resp.Invites)
+	}
+	g, ok := hub.Lookup(resp.ID)
+	if !ok || !g.InviteOnly() {
+		t.Fatalf("expected an invite-only game")
+	}
+}
+
+func TestPair(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	owner := "00000000-0000-0000-0000-000000000001"
+	id, color, err := hub.CreateGame(context.Background(), owner, game.GameOptions{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	pair := func(clientID, token string) (int, map[string]any) {
+		body := fmt.Sprintf(`{"gameId":%q,"clientId":%q,"seatToken":%q}`, id.String(), clientID, token)
+		w := httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, httptest.NewRequest("POST", "/api/pair", strings.NewReader(body)))
+		var resp map[string]any
+		_ = json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+	if code, _ := pair(owner, "forged"); code != http.StatusForbidden {
+		t.Fatalf("expected a forged seat token to be refused, got %d", code)
+	}
+	code, resp := pair(owner, hub.Seats.Token(id, owner))
+	if code != http.StatusOK || resp["ok"] != true {
+		t.Fatalf("pair: %d %v", code, resp)
+	}
+
+	claim := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, httptest.NewRequest("GET", resp["url"].(string), nil))
+		return w
+	}
+	w := claim()
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("claim: %d", w.Code)
+	}
+	u, err := url.Parse(w.Header().Get("Location"))
+	if err != nil || u.Path != "/"+id.String() {
+		t.Fatalf("unexpected redirect %q", w.Header().Get("Location"))
+	}
+	role, ok := hub.Seats.ParseInvite(id, u.Query().Get("invite"))
+	if !ok || role != game.InviteRoleFor(color.Other()) {
+		t.Fatalf("expected a play invite for the open seat, got %q %v", role, ok)
+	}
+	if w := claim(); w.Code != http.StatusNotFound {
+		t.Fatalf("expected a second claim to fail, got %d", w.Code)
+	}
+}
+
+func TestMeterQuota(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	h.Usage = usage.New(2)
+	owner := "00000000-0000-0000-0000-000000000001"
+	id, _, err := hub.CreateGame(context.Background(), owner, game.GameOptions{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	token := hub.Seats.APIToken(id, owner, game.ScopeRead)
+
+	addr := "192.0.2.1:1234"
+	get := func(path, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		r.RemoteAddr = addr
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, r)
+		return w
+	}
+
+	// A token request counts against its issuer, as does one naming them.
+	if w := get("/api/games/"+id.String(), token); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Fatalf("expected the first request to pass with one left, got %d %q", w.Code, w.Header().Get("X-RateLimit-Remaining"))
+	}
+	if w := get("/api/stats?userId="+owner, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected the second request to pass, got %d", w.Code)
+	}
+	w := get("/api/me/usage?userId="+owner, "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d", w.Code)
+	}
+	// The address's quota is spent too, whichever user the caller names.
+	if w := get("/api/stats?userId="+uuid.NewString(), ""); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected a fresh user from the same address to be refused, got %d", w.Code)
+	}
+	addr = "192.0.2.2:1234"
+	if w := get("/api/stats", ""); w.Code != http.StatusOK {
+		t.Fatalf("an anonymous caller elsewhere has its own quota, got %d", w.Code)
+	}
+	if w := get("/"+id.String(), ""); w.Code != http.StatusOK {
+		t.Fatalf("pages are not metered, got %d", w.Code)
+	}
+
+	rep := h.Usage.Usage("user:" + owner)
+	if rep.Requests != 2 || rep.Refused != 1 || rep.Tokens != 1 || rep.Routes["GET /api/games/{id}"] != 1 {
+		t.Fatalf("unexpected usage %+v", rep)
+	}
+}
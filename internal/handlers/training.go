@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+
+	"tinychess/internal/game"
+	"tinychess/internal/logging"
+)
+
+// HandleEndings lists the endings offered for practice, and whether practice
+// is available on this instance.
+func (h *Handler) HandleEndings(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "endings": game.Endings, "available": h.Hub.Tablebase != nil})
+}
+
+// HandleTraining starts an endgame practice game: the user plays the winning
+// side of the requested ending against the tablebase, which grades every
+// move.
+func (h *Handler) HandleTraining(w http.ResponseWriter, r *http.Request) {
+	if h.Hub.Tablebase == nil {
+		WriteJSON(w, http.StatusServiceUnavailable, map[string]any{"ok": false, "error": "tablebase unavailable"})
+		return
+	}
+	var body struct {
+		UserID string `json:"userId"`
+		Ending string `json:"ending"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "bad json"})
+		return
+	}
+	userID := strings.TrimSpace(body.UserID)
+	if userID == "" {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing user id"})
+		return
+	}
+
+	id, color, err := h.Hub.StartTraining(r.Context(), userID, body.Ending)
+	if errors.Is(err, game.ErrUnknownEnding) {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		logging.Debugf("start training failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not create game"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "id": id, "color": color.String(), "seatToken": h.seatToken(id, userID), "ending": body.Ending})
+}
+
+// answerTraining grades the trainee's move in a practice game and plays the
+// tablebase's reply. It runs after the move request has been answered, so
+// the reply reaches the trainee through the event stream.
+func (h *Handler) answerTraining(g *game.Game, traineeID, before, uci string) {
+	ctx, cancel := context.WithTimeout(context.Background(), APIBudget)
+	defer cancel()
+
+	reply, err := h.Hub.TrainingReply(ctx, g, before, uci)
+	if err != nil {
+		logging.Debugf("training reply for %s failed: %v", g.ID, err)
+		g.BroadcastNotice("The tablebase did not answer.")
+		return
+	}
+	h.openPostMortem(g)
+	g.Broadcast()
+
+	lastSeen := g.Touch()
+	g.Mu.RLock()
+	state := g.StateLocked()
+	g.Mu.RUnlock()
+	outcome := g.Outcome()
+	if err := h.persistGameState(ctx, g.ID, state, outcome, lastSeen); err != nil {
+		logging.Debugf("persist game state failed: %v", err)
+	}
+	h.Hub.QueueAnalysis(g)
+	if reply == "" || h.Store == nil {
+		return
+	}
+	// The defender has no session of its own; its moves are kept under the
+	// trainee so the game replays from the database.
+	uid, err := uuid.Parse(traineeID)
+	if err != nil {
+		return
+	}
+	color := "white"
+	if state.Turn == chess.White.String() {
+		color = "black"
+	}
+	playedAt := lastSeen
+	if n := len(state.MoveTimes); n > 0 {
+		playedAt = time.UnixMilli(state.MoveTimes[n-1].At)
+	}
+	taken := ""
+	if state.LastMove != nil {
+		taken = state.LastMove.CapturedPiece
+	}
+	if err := h.Store.RecordMove(ctx, g.ID.UUID(), uid, len(state.UCI), reply, color, taken, playedAt, 0); err != nil {
+		logging.Debugf("record training reply failed: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+
+	"tinychess/internal/game"
+	"tinychess/internal/tablebase"
+)
+
+// firstMoveTablebase calls every position won and ranks the legal moves in
+// the order the chess package generates them.
+type firstMoveTablebase struct{}
+
+func (firstMoveTablebase) Probe(ctx context.Context, fen string) (tablebase.Result, error) {
+	g, err := game.GameFromFEN(fen)
+	if err != nil {
+		return tablebase.Result{}, err
+	}
+	r := tablebase.Result{Category: tablebase.Win}
+	for _, m := range g.ValidMoves() {
+		r.Moves = append(r.Moves, tablebase.Move{UCI: chess.UCINotation{}.Encode(g.Position(), &m), Category: tablebase.Loss})
+	}
+	return r, nil
+}
+
+func TestHandleTraining(t *testing.T) {
+	hub := game.NewHub(nil)
+	h := NewHandler(hub, nil)
+	user := uuid.NewString()
+	start := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/training", strings.NewReader(fmt.Sprintf(`{"userId":%q,"ending":"KQvK"}`, user)))
+		w := httptest.NewRecorder()
+		h.Routes().ServeHTTP(w, req)
+		return w
+	}
+
+	if w := start(); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without a tablebase, got %d", w.Code)
+	}
+	hub.Tablebase = firstMoveTablebase{}
+
+	w := start()
+	var created struct {
+		OK        bool        `json:"ok"`
+		ID        game.GameID `json:"id"`
+		Color     string      `json:"color"`
+		SeatToken string      `json:"seatToken"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil || !created.OK {
+		t.Fatalf("start: %d %v %+v", w.Code, err, created)
+	}
+
+	g, _ := hub.Lookup(created.ID)
+	g.Mu.RLock()
+	state := g.StateLocked()
+	g.Mu.RUnlock()
+	if state.Turn != created.Color {
+		t.Fatalf("trainee %s, but %s to move", created.Color, state.Turn)
+	}
+	tmp, _ := game.GameFromFEN(state.FEN)
+	moves := tmp.ValidMoves()
+	uci := chess.UCINotation{}.Encode(tmp.Position(), &moves[0])
+
+	req := httptest.NewRequest("POST", "/move/"+created.ID.String(), strings.NewReader(fmt.Sprintf(`{"uci":%q,"clientId":%q,"seatToken":%q}`, uci, user, created.SeatToken)))
+	w = httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"ok":true`) {
+		t.Fatalf("move: %d %s", w.Code, w.Body.String())
+	}
+
+	plies := func() int {
+		g.Mu.RLock()
+		defer g.Mu.RUnlock()
+		return len(g.MovesUCI())
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for g.Training().Moves < 1 || (plies() < 2 && g.Outcome() == chess.NoOutcome) {
+		if time.Now().After(deadline) {
+			t.Fatalf("tablebase did not reply")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if tr := g.Training(); tr == nil || tr.Moves != 1 || tr.Optimal != 1 {
+		t.Fatalf("got training %+v", tr)
+	}
+
+	req = httptest.NewRequest("GET", "/api/endings", nil)
+	w = httptest.NewRecorder()
+	h.Routes().ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), `"KBNvK"`) || !strings.Contains(w.Body.String(), `"available":true`) {
+		t.Fatalf("endings: %s", w.Body.String())
+	}
+}
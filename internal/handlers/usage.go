@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type routeKey struct{}
+
+// withRoute records the pattern a request matched, for Meter.
+func withRoute(pattern string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeKey{}, pattern)))
+		})
+	}
+}
+
+// callerKeys lists who a request counts against: always the client's
+// address, see ClientIP, and then the issuer of an API token accepted by
+// RequireScope, the owner of an API key, or the user in ?userId= or
+// X-User-ID. The user is the caller's to choose, so it narrows the address's
+// allowance but never replaces it.
+func (h *Handler) callerKeys(r *http.Request) (keys []string, token bool) {
+	keys = []string{"ip:" + h.ClientIP(r)}
+	if grant, ok := requestGrant(r); ok {
+		return append(keys, "user:"+grant.ClientID), true
+	}
+	if id, ok := keyUser(r); ok {
+		return append(keys, "user:"+id), true
+	}
+	if id := requestUserID(r); id != "" {
+		return append(keys, "user:"+id), false
+	}
+	return keys, false
+}
+
+// Meter counts the request against the daily quotas of the caller's keys,
+// see callerKeys and usage.Meter, and refuses it with 429 once any of them
+// is spent. When a quota
+// is set, responses carry X-RateLimit-Limit, -Remaining and -Reset (Unix
+// seconds). It must run after RequireScope so token requests are counted
+// against the token's issuer.
+func (h *Handler) Meter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.Usage == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		keys, token := h.callerKeys(r)
+		route, _ := r.Context().Value(routeKey{}).(string)
+		rep, ok := h.Usage.Allow(keys, route, token)
+		if rep.Limit > 0 {
+			w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(rep.Limit, 10))
+			w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(rep.Remaining, 10))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(rep.ResetAt.Unix(), 10))
+		}
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(rep.ResetAt).Seconds())+1))
+			WriteJSON(w, http.StatusTooManyRequests, map[string]any{"ok": false, "error": "daily quota exceeded"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HandleUsage reports the caller's API usage for the day and what is left of
+// the quota: the user's when the request names one, else the address's.
+func (h *Handler) HandleUsage(w http.ResponseWriter, r *http.Request) {
+	keys, _ := h.callerKeys(r)
+	key := keys[len(keys)-1]
+	WriteJSON(w, http.StatusOK, UsageResponse{OK: true, Usage: h.Usage.Usage(key)})
+}
+
+// HandleAdminUsage reports the day's API usage across callers and the
+// busiest of them, ?top= at a time (20 by default).
+func (h *Handler) HandleAdminUsage(w http.ResponseWriter, r *http.Request) {
+	top := 20
+	if v := r.URL.Query().Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "invalid top"})
+			return
+		}
+		top = n
+	}
+	totals, callers := h.Usage.Top(top)
+	WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "totals": totals, "callers": callers})
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"tinychess/internal/logging"
+	"tinychess/internal/storage"
+)
+
+// Page sizes for GET /api/v1/games.
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// HandleListGames lists a page of the caller's games from storage, most
+// recently seen first. ?status=active or completed narrows the list, ?limit
+// sets the page size and ?offset skips earlier pages; next is the offset of
+// the following page, absent on the last one.
+func (h *Handler) HandleListGames(w http.ResponseWriter, r *http.Request) {
+	if h.Store == nil {
+		WriteJSON(w, http.StatusServiceUnavailable, map[string]any{"ok": false, "error": "storage unavailable"})
+		return
+	}
+	userID, err := uuid.Parse(requestUserID(r))
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "missing user id"})
+		return
+	}
+	q := r.URL.Query()
+	filter := storage.GameFilter{Limit: defaultPageSize}
+	switch status := q.Get("status"); status {
+	case "", "all":
+	case storage.ListActive, storage.ListCompleted:
+		filter.Status = status
+	default:
+		WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "invalid status"})
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "invalid limit"})
+			return
+		}
+		filter.Limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			WriteJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "invalid offset"})
+			return
+		}
+		filter.Offset = n
+	}
+
+	// One extra row tells whether another page follows.
+	page := filter.Limit
+	filter.Limit++
+	games, err := h.Store.ListGames(r.Context(), userID, filter)
+	if err != nil {
+		logging.Debugf("list games failed: %v", err)
+		WriteJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "could not load games"})
+		return
+	}
+	resp := ListGamesResponse{OK: true}
+	if len(games) > page {
+		games = games[:page]
+		next := filter.Offset + page
+		resp.Next = &next
+	}
+	resp.Games = games
+	w.Header().Set("Cache-Control", "no-store")
+	WriteJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,133 @@
+// Package i18n holds the translations for text the server generates itself,
+// such as game status lines and admin notices.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Default is the language used when a game has none or an unknown one.
+const Default = "en"
+
+// Message keys.
+const (
+	StatusEnded          = "status.ended" // result, reason
+	StatusAbandoned      = "status.abandoned"
+	NoticeAdjudicateDraw = "notice.adjudicated_draw"
+	NoticeAbandoned      = "notice.abandoned"
+)
+
+// Termination reasons are looked up under "reason." + the stored termination.
+const reasonPrefix = "reason."
+
+var catalogs = map[string]map[string]string{
+	"en": {
+		StatusEnded:                    "%s by %s",
+		StatusAbandoned:                "Abandoned",
+		NoticeAdjudicateDraw:           "This game was adjudicated as a draw by the instance admin.",
+		NoticeAbandoned:                "This game was closed as abandoned by the instance admin.",
+		"reason.checkmate":             "checkmate",
+		"reason.resignation":           "resignation",
+		"reason.agreement":             "agreement",
+		"reason.stalemate":             "stalemate",
+		"reason.repetition":            "repetition",
+		"reason.fifty-move":            "fifty-move rule",
+		"reason.insufficient-material": "insufficient material",
+		"reason.timeout":               "timeout",
+		"reason.abandonment":           "abandonment",
+		"reason.adjudication":          "adjudication",
+		"reason.move-limit":            "move limit",
+	},
+	"de": {
+		StatusEnded:                    "%s durch %s",
+		StatusAbandoned:                "Abgebrochen",
+		NoticeAdjudicateDraw:           "Diese Partie wurde von der Instanzverwaltung remis gewertet.",
+		NoticeAbandoned:                "Diese Partie wurde von der Instanzverwaltung als abgebrochen geschlossen.",
+		"reason.checkmate":             "Schachmatt",
+		"reason.resignation":           "Aufgabe",
+		"reason.agreement":             "Einigung",
+		"reason.stalemate":             "Patt",
+		"reason.repetition":            "Stellungswiederholung",
+		"reason.fifty-move":            "50-Züge-Regel",
+		"reason.insufficient-material": "ungenügendes Material",
+		"reason.timeout":               "Zeitüberschreitung",
+		"reason.abandonment":           "Abbruch",
+		"reason.adjudication":          "Entscheid",
+		"reason.move-limit":            "Zuglimit",
+	},
+	"fr": {
+		StatusEnded:                    "%s par %s",
+		StatusAbandoned:                "Abandonnée",
+		NoticeAdjudicateDraw:           "Cette partie a été déclarée nulle par l'administration de l'instance.",
+		NoticeAbandoned:                "Cette partie a été close comme abandonnée par l'administration de l'instance.",
+		"reason.checkmate":             "échec et mat",
+		"reason.resignation":           "abandon",
+		"reason.agreement":             "accord mutuel",
+		"reason.stalemate":             "pat",
+		"reason.repetition":            "répétition",
+		"reason.fifty-move":            "règle des cinquante coups",
+		"reason.insufficient-material": "matériel insuffisant",
+		"reason.timeout":               "temps écoulé",
+		"reason.abandonment":           "abandon de partie",
+		"reason.adjudication":          "arbitrage",
+		"reason.move-limit":            "limite de coups",
+	},
+	"es": {
+		StatusEnded:                    "%s por %s",
+		StatusAbandoned:                "Abandonada",
+		NoticeAdjudicateDraw:           "La administración de la instancia declaró esta partida tablas.",
+		NoticeAbandoned:                "La administración de la instancia cerró esta partida como abandonada.",
+		"reason.checkmate":             "jaque mate",
+		"reason.resignation":           "rendición",
+		"reason.agreement":             "acuerdo",
+		"reason.stalemate":             "ahogado",
+		"reason.repetition":            "repetición",
+		"reason.fifty-move":            "regla de los cincuenta movimientos",
+		"reason.insufficient-material": "material insuficiente",
+		"reason.timeout":               "tiempo agotado",
+		"reason.abandonment":           "abandono",
+		"reason.adjudication":          "adjudicación",
+		"reason.move-limit":            "límite de movimientos",
+	},
+}
+
+// Supported lists the available languages.
+func Supported() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Normalize reduces a language tag such as "de-AT" to a supported language,
+// reporting false when there is no catalog for it.
+func Normalize(tag string) (string, bool) {
+	base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	base, _, _ = strings.Cut(base, "_")
+	_, ok := catalogs[base]
+	return base, ok
+}
+
+// T formats the message for key in lang, falling back to English and then to
+// the key itself.
+func T(lang, key string, args ...any) string {
+	msg, ok := catalogs[lang][key]
+	if !ok {
+		if msg, ok = catalogs[Default][key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Reason translates a termination reason.
+func Reason(lang, termination string) string {
+	return T(lang, reasonPrefix+termination)
+}
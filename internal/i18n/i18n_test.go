@@ -0,0 +1,30 @@
+package i18n
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]struct {
+		lang string
+		ok   bool
+	}{
+		"de":    {"de", true},
+		"de-AT": {"de", true},
+		"FR_ca": {"fr", true},
+		"xx":    {"xx", false},
+	}
+	for in, want := range cases {
+		lang, ok := Normalize(in)
+		if lang != want.lang || ok != want.ok {
+			t.Errorf("Normalize(%q) = %q, %v; want %q, %v", in, lang, ok, want.lang, want.ok)
+		}
+	}
+}
+
+func TestTFallsBackToEnglish(t *testing.T) {
+	if got := T("xx", StatusAbandoned); got != "Abandoned" {
+		t.Fatalf("expected English fallback, got %q", got)
+	}
+	if got := T("es", StatusEnded, "1-0", Reason("es", "checkmate")); got != "1-0 por jaque mate" {
+		t.Fatalf("unexpected Spanish status %q", got)
+	}
+}
@@ -0,0 +1,358 @@
+// Package openapi builds OpenAPI 3 descriptions from Go types. Schemas are
+// derived by reflection from the structs handlers decode and encode, using
+// their json tags, so the description follows the code. A few more struct
+// tags refine a field's schema:
+//
+//	doc:"…"          description
+//	format:"uuid"    string format
+//	enum:"a,b"       allowed values
+//	example:"…"      example value
+//	default:"…"      default value
+//	minimum:"0"      smallest number
+//	maximum:"100"    largest number
+//	maxLength:"64"   longest string
+//	required:"true"  the field must be sent
+//	ref:"Name"       the field is the named component, for fields typed any
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Version is the OpenAPI version Spec documents conform to.
+const Version = "3.0.3"
+
+// Spec is an OpenAPI document.
+type Spec struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Security   []Requirement       `json:"security,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+	types      map[reflect.Type]string
+}
+
+// Info describes the API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Requirement names security schemes that together authorize a request; an
+// empty Requirement makes authentication optional.
+type Requirement map[string][]string
+
+// PathItem holds a path's operations by lower-case method.
+type PathItem map[string]*Operation
+
+// Components holds the definitions operations refer to.
+type Components struct {
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+	Parameters      map[string]*Parameter      `json:"parameters,omitempty"`
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+}
+
+// SecurityScheme is a way of authenticating.
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	In          string `json:"in,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Operation is one method on a path.
+type Operation struct {
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	Security    []Requirement        `json:"security,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is a path or query parameter, or a reference to a component
+// parameter.
+type Parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody is an operation's body by content type.
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response is one status's answer.
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body in one content type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema describes a JSON value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Example              any                `json:"example,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// New returns an empty document.
+func New(info Info) *Spec {
+	return &Spec{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			SecuritySchemes: make(map[string]*SecurityScheme),
+			Parameters:      make(map[string]*Parameter),
+			Schemas:         make(map[string]*Schema),
+		},
+		types: make(map[reflect.Type]string),
+	}
+}
+
+// Define adds the schema of v's type as a component, which later schemas
+// containing the type refer to by name.
+func (s *Spec) Define(name string, v any, description string) {
+	t := deref(reflect.TypeOf(v))
+	schema := s.build(t, map[reflect.Type]bool{})
+	schema.Description = description
+	s.Components.Schemas[name] = schema
+	s.types[t] = name
+}
+
+// Ref refers to the component schema name.
+func (s *Spec) Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// Schema returns the schema of v's type.
+func (s *Spec) Schema(v any) *Schema {
+	return s.schema(reflect.TypeOf(v), map[reflect.Type]bool{})
+}
+
+// Parameter adds a component parameter and returns a reference to it.
+func (s *Spec) Parameter(name string, p *Parameter) *Parameter {
+	s.Components.Parameters[name] = p
+	return &Parameter{Ref: "#/components/parameters/" + name}
+}
+
+// Add documents an operation.
+func (s *Spec) Add(method, path string, op *Operation) {
+	if s.Paths[path] == nil {
+		s.Paths[path] = make(PathItem)
+	}
+	s.Paths[path][strings.ToLower(method)] = op
+}
+
+// Body is a required request body of one content type.
+func Body(contentType string, schema *Schema) *RequestBody {
+	return &RequestBody{Required: true, Content: map[string]*MediaType{contentType: {Schema: schema}}}
+}
+
+// Reply is a response with a body of one content type, or none when schema
+// is nil.
+func Reply(description, contentType string, schema *Schema) *Response {
+	r := &Response{Description: description}
+	if schema != nil {
+		r.Content = map[string]*MediaType{contentType: {Schema: schema}}
+	}
+	return r
+}
+
+// Query is a query parameter.
+func Query(name string, schema *Schema, description string) *Parameter {
+	return &Parameter{Name: name, In: "query", Schema: schema, Description: description}
+}
+
+// Path is a path parameter.
+func Path(name string, schema *Schema) *Parameter {
+	return &Parameter{Name: name, In: "path", Required: true, Schema: schema}
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	uuidType      = reflect.TypeOf(uuid.UUID{})
+	rawType       = reflect.TypeOf(json.RawMessage{})
+	textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func deref(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// schema refers to t's component if it has one and builds its schema
+// otherwise. visiting holds the structs being built, so recursive types end
+// in a plain object instead of looping.
+func (s *Spec) schema(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	t = deref(t)
+	if t == nil {
+		return &Schema{}
+	}
+	if name, ok := s.types[t]; ok {
+		return s.Ref(name)
+	}
+	return s.build(t, visiting)
+}
+
+func (s *Spec) build(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == uuidType || t.ConvertibleTo(uuidType) && t.Implements(textMarshaler):
+		return &Schema{Type: "string", Format: "uuid"}
+	case t == rawType:
+		return &Schema{}
+	case t.Kind() != reflect.String && t.Implements(textMarshaler):
+		return &Schema{Type: "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schema(t.Elem(), visiting)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			return &Schema{Type: "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		s.fields(schema, t, visiting)
+		return schema
+	}
+	return &Schema{}
+}
+
+// fields adds t's JSON fields to schema, flattening embedded structs as
+// encoding/json does.
+func (s *Spec) fields(schema *Schema, t reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && deref(f.Type).Kind() == reflect.Struct {
+			s.fields(schema, deref(f.Type), visiting)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		var prop *Schema
+		if ref := f.Tag.Get("ref"); ref != "" {
+			prop = s.Ref(ref)
+		} else {
+			prop = s.schema(f.Type, visiting)
+			if prop.Ref == "" {
+				// Copy, so tags never change a shared schema.
+				c := *prop
+				prop = &c
+				refine(prop, f.Tag)
+			}
+		}
+		schema.Properties[name] = prop
+		if f.Tag.Get("required") == "true" {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// refine applies a field's schema tags.
+func refine(schema *Schema, tag reflect.StructTag) {
+	if doc := tag.Get("doc"); doc != "" {
+		schema.Description = doc
+	}
+	if format := tag.Get("format"); format != "" {
+		schema.Format = format
+	}
+	if enum := tag.Get("enum"); enum != "" {
+		schema.Enum = nil
+		for _, v := range strings.Split(enum, ",") {
+			schema.Enum = append(schema.Enum, value(schema.Type, v))
+		}
+	}
+	if v, ok := tag.Lookup("example"); ok {
+		schema.Example = value(schema.Type, v)
+	}
+	if v, ok := tag.Lookup("default"); ok {
+		schema.Default = value(schema.Type, v)
+	}
+	if v, err := strconv.ParseFloat(tag.Get("minimum"), 64); err == nil {
+		schema.Minimum = &v
+	}
+	if v, err := strconv.ParseFloat(tag.Get("maximum"), 64); err == nil {
+		schema.Maximum = &v
+	}
+	if v, err := strconv.Atoi(tag.Get("maxLength")); err == nil {
+		schema.MaxLength = &v
+	}
+}
+
+// value converts a tag's text to the schema's type.
+func value(typ, v string) any {
+	switch typ {
+	case "integer":
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
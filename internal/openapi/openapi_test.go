@@ -0,0 +1,62 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type embedded struct {
+	Count int `json:"count" minimum:"0" maximum:"9"`
+}
+
+type sample struct {
+	embedded
+	ID      uuid.UUID       `json:"id"`
+	Name    string          `json:"name" required:"true" maxLength:"8" doc:"Shown to others."`
+	Kind    string          `json:"kind,omitempty" enum:"a,b" default:"a"`
+	At      *time.Time      `json:"at,omitempty"`
+	Tags    []string        `json:"tags"`
+	Scores  map[string]int  `json:"scores"`
+	Child   *child          `json:"child"`
+	Payload any             `json:"payload" ref:"Child"`
+	Raw     json.RawMessage `json:"raw"`
+	Skipped string          `json:"-"`
+	hidden  string
+}
+
+type child struct {
+	Next *child `json:"next"`
+}
+
+func TestSchema(t *testing.T) {
+	spec := New(Info{Title: "Test", Version: "1"})
+	spec.Define("Child", child{}, "A node.")
+	got, err := json.Marshal(spec.Schema(sample{}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `{"type":"object","properties":{` +
+		`"at":{"type":"string","format":"date-time"},` +
+		`"child":{"$ref":"#/components/schemas/Child"},` +
+		`"count":{"type":"integer","minimum":0,"maximum":9},` +
+		`"id":{"type":"string","format":"uuid"},` +
+		`"kind":{"type":"string","enum":["a","b"],"default":"a"},` +
+		`"name":{"type":"string","description":"Shown to others.","maxLength":8},` +
+		`"payload":{"$ref":"#/components/schemas/Child"},` +
+		`"raw":{},` +
+		`"scores":{"type":"object","additionalProperties":{"type":"integer"}},` +
+		`"tags":{"type":"array","items":{"type":"string"}}},` +
+		`"required":["name"]}`
+	if string(got) != want {
+		t.Fatalf("unexpected schema\n got %s\nwant %s", got, want)
+	}
+
+	// A recursive type without a component ends in a plain object.
+	node, _ := json.Marshal(spec.Components.Schemas["Child"])
+	if string(node) != `{"type":"object","description":"A node.","properties":{"next":{"type":"object"}}}` {
+		t.Fatalf("unexpected recursive schema %s", node)
+	}
+}
@@ -0,0 +1,176 @@
+// Package outbox delivers outgoing events, such as fediverse posts, at least
+// once. Events are written to the store before any delivery is attempted and
+// retried with backoff until they succeed, so neither a crash nor a failed
+// request loses them; receivers must tolerate the occasional duplicate.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"tinychess/internal/logging"
+	"tinychess/internal/storage"
+)
+
+// DefaultInterval is how often Run looks for due events.
+const DefaultInterval = 5 * time.Second
+
+// MaxAttempts is how many times an event is tried before it is marked dead.
+const MaxAttempts = 10
+
+// batchSize bounds the stored events delivered per pass.
+const batchSize = 50
+
+// Handler delivers one event of a kind to its target.
+type Handler func(ctx context.Context, target string, payload []byte) error
+
+// Backoff returns the delay before retrying an event that has failed
+// attempts times: 30 seconds, doubling up to an hour.
+func Backoff(attempts int) time.Duration {
+	d := 30 * time.Second
+	for i := 1; i < attempts && d < time.Hour; i++ {
+		d *= 2
+	}
+	return min(d, time.Hour)
+}
+
+// Dispatcher queues events and runs their handlers. Without a store, or
+// while the store refuses writes, events are queued in memory instead and
+// only survive as long as the process.
+type Dispatcher struct {
+	Store *storage.Store
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	pending  []storage.OutboxEvent
+	wake     chan struct{}
+	now      func() time.Time
+}
+
+// New returns a dispatcher backed by store, which may be nil.
+func New(store *storage.Store) *Dispatcher {
+	return &Dispatcher{
+		Store:    store,
+		handlers: make(map[string]Handler),
+		wake:     make(chan struct{}, 1),
+		now:      time.Now,
+	}
+}
+
+// Register sets the handler for events of kind.
+func (d *Dispatcher) Register(kind string, fn Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[kind] = fn
+}
+
+// Publish queues an event for delivery to target. It only fails if the event
+// could not be queued at all.
+func (d *Dispatcher) Publish(ctx context.Context, kind, target string, payload []byte) error {
+	ev := storage.OutboxEvent{Kind: kind, Target: target, Payload: string(payload), NextAttemptAt: d.now()}
+	if d.Store == nil {
+		d.queue(ev)
+		return nil
+	}
+	if err := d.Store.EnqueueEvent(ctx, ev); err != nil {
+		logging.Debugf("outbox: store %s event, keeping it in memory: %v", kind, err)
+		d.queue(ev)
+		return nil
+	}
+	d.poke()
+	return nil
+}
+
+func (d *Dispatcher) queue(ev storage.OutboxEvent) {
+	d.mu.Lock()
+	d.pending = append(d.pending, ev)
+	d.mu.Unlock()
+	d.poke()
+}
+
+func (d *Dispatcher) poke() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers due events every interval, and as soon as new ones are
+// published, until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		d.Flush(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// Flush makes one delivery attempt for every due event.
+func (d *Dispatcher) Flush(ctx context.Context) {
+	now := d.now()
+
+	d.mu.Lock()
+	var due, later []storage.OutboxEvent
+	for _, ev := range d.pending {
+		if ev.NextAttemptAt.After(now) {
+			later = append(later, ev)
+		} else {
+			due = append(due, ev)
+		}
+	}
+	d.pending = later
+	d.mu.Unlock()
+	for _, ev := range due {
+		if err := d.deliver(ctx, ev); err != nil {
+			ev.Attempts++
+			if ev.Attempts >= MaxAttempts {
+				logging.Debugf("outbox: dropping %s event for %s after %d attempts: %v", ev.Kind, ev.Target, ev.Attempts, err)
+				continue
+			}
+			ev.NextAttemptAt = now.Add(Backoff(ev.Attempts))
+			d.mu.Lock()
+			d.pending = append(d.pending, ev)
+			d.mu.Unlock()
+		}
+	}
+
+	stored, err := d.Store.DueEvents(ctx, now, batchSize)
+	if err != nil {
+		logging.Debugf("outbox: load due events: %v", err)
+		return
+	}
+	for _, ev := range stored {
+		if err := d.deliver(ctx, ev); err != nil {
+			attempts := ev.Attempts + 1
+			dead := attempts >= MaxAttempts
+			if dead {
+				logging.Debugf("outbox: %s event %s for %s is dead after %d attempts: %v", ev.Kind, ev.ID, ev.Target, attempts, err)
+			}
+			if err := d.Store.FailEvent(ctx, ev.ID, attempts, now.Add(Backoff(attempts)), err.Error(), dead); err != nil {
+				logging.Debugf("outbox: record failure of %s: %v", ev.ID, err)
+			}
+			continue
+		}
+		if err := d.Store.CompleteEvent(ctx, ev.ID); err != nil {
+			logging.Debugf("outbox: complete %s: %v", ev.ID, err)
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, ev storage.OutboxEvent) error {
+	d.mu.Lock()
+	fn := d.handlers[ev.Kind]
+	d.mu.Unlock()
+	if fn == nil {
+		return fmt.Errorf("outbox: no handler for %s", ev.Kind)
+	}
+	return fn(ctx, ev.Target, []byte(ev.Payload))
+}
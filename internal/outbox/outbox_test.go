@@ -0,0 +1,135 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tinychess/internal/storage"
+)
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		8:  time.Hour,
+		20: time.Hour,
+	}
+	for attempts, want := range cases {
+		if got := Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+// flaky fails its first failures calls and records the rest.
+type flaky struct {
+	failures  int
+	delivered []string
+}
+
+func (f *flaky) handle(ctx context.Context, target string, payload []byte) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("unreachable")
+	}
+	f.delivered = append(f.delivered, target+" "+string(payload))
+	return nil
+}
+
+func TestMemoryRetry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	d := New(nil)
+	d.now = func() time.Time { return now }
+	f := &flaky{failures: 1}
+	d.Register("note", f.handle)
+
+	if err := d.Publish(ctx, "note", "inbox", []byte("hello")); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	d.Flush(ctx)
+	if len(f.delivered) != 0 {
+		t.Fatalf("expected the first attempt to fail, got %v", f.delivered)
+	}
+	d.Flush(ctx)
+	if len(f.delivered) != 0 {
+		t.Fatal("expected no retry before the backoff elapsed")
+	}
+	now = now.Add(Backoff(1))
+	d.Flush(ctx)
+	if len(f.delivered) != 1 || f.delivered[0] != "inbox hello" {
+		t.Fatalf("expected one delivery after the backoff, got %v", f.delivered)
+	}
+	d.Flush(ctx)
+	if len(f.delivered) != 1 {
+		t.Fatalf("expected a delivered event to leave the queue, got %v", f.delivered)
+	}
+}
+
+func TestStoredEventsSurviveRestart(t *testing.T) {
+	ctx := context.Background()
+	db, err := storage.New("sqlite://"+filepath.Join(t.TempDir(), "test.db"), 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	store := storage.NewStore(db)
+
+	// The first process queues an event and stops before delivering it.
+	first := New(store)
+	if err := first.Publish(ctx, "note", "inbox", []byte("hello")); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if pending, _, _ := store.OutboxCounts(ctx); pending != 1 {
+		t.Fatalf("expected one pending event, got %d", pending)
+	}
+
+	now := time.Now()
+	second := New(store)
+	second.now = func() time.Time { return now }
+	f := &flaky{failures: 1}
+	second.Register("note", f.handle)
+	second.Flush(ctx)
+	if len(f.delivered) != 0 {
+		t.Fatalf("expected the first attempt to fail, got %v", f.delivered)
+	}
+	now = now.Add(Backoff(1))
+	second.Flush(ctx)
+	if len(f.delivered) != 1 || f.delivered[0] != "inbox hello" {
+		t.Fatalf("expected the event to be delivered, got %v", f.delivered)
+	}
+	if pending, dead, _ := store.OutboxCounts(ctx); pending != 0 || dead != 0 {
+		t.Fatalf("expected an empty outbox, got %d pending and %d dead", pending, dead)
+	}
+}
+
+func TestStoredEventDies(t *testing.T) {
+	ctx := context.Background()
+	db, err := storage.New("sqlite://"+filepath.Join(t.TempDir(), "test.db"), 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	store := storage.NewStore(db)
+	now := time.Now()
+	d := New(store)
+	d.now = func() time.Time { return now }
+	f := &flaky{failures: MaxAttempts + 1}
+	d.Register("note", f.handle)
+
+	if err := d.Publish(ctx, "note", "inbox", []byte("hello")); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	for i := 0; i < MaxAttempts+1; i++ {
+		d.Flush(ctx)
+		now = now.Add(time.Hour)
+	}
+	if f.failures != 1 {
+		t.Fatalf("expected %d attempts, %d were made", MaxAttempts, MaxAttempts+1-f.failures)
+	}
+	if pending, dead, _ := store.OutboxCounts(ctx); pending != 0 || dead != 1 {
+		t.Fatalf("expected one dead event, got %d pending and %d dead", pending, dead)
+	}
+}
@@ -0,0 +1,94 @@
+// Package ratelimit throttles bursts of requests per caller with token
+// buckets. Unlike the daily quota in package usage, it smooths traffic over
+// seconds and minutes: each caller may make Burst requests at once and then
+// one more every Interval.
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// idleBuckets is how many buckets a limiter keeps before it drops those that
+// have refilled, which are no different from new ones.
+const idleBuckets = 10000
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// Limiter is a token bucket per caller key. A nil Limiter allows everything.
+type Limiter struct {
+	// Burst is the bucket size; Interval the time to earn back one request.
+	Burst    int
+	Interval time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// New returns a limiter allowing n requests per period, all of which may be
+// made at once.
+func New(n int, per time.Duration) *Limiter {
+	return &Limiter{Burst: n, Interval: per / time.Duration(n), buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Parse reads a limit written as "<n>/<unit>", such as "10/s", "30/m" or
+// "500/h". An empty string means no limit and returns nil.
+func Parse(spec string) (*Limiter, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	count, unit, ok := strings.Cut(spec, "/")
+	n, err := strconv.Atoi(count)
+	if !ok || err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid rate limit %q, want e.g. 30/m", spec)
+	}
+	per, ok := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}[unit]
+	if !ok {
+		return nil, fmt.Errorf("invalid rate limit %q: unit must be s, m or h", spec)
+	}
+	return New(n, per), nil
+}
+
+// Allow takes a token from key's bucket. When the bucket is empty it reports
+// false and how long until the next token.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	b := l.buckets[key]
+	if b == nil {
+		if len(l.buckets) >= idleBuckets {
+			l.pruneLocked(now)
+		}
+		b = &bucket{tokens: float64(l.Burst), at: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(float64(l.Burst), b.tokens+float64(now.Sub(b.at))/float64(l.Interval))
+	b.at = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(l.Interval))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// pruneLocked drops the buckets that have refilled since their last use.
+func (l *Limiter) pruneLocked(now time.Time) {
+	full := l.Interval * time.Duration(l.Burst)
+	for key, b := range l.buckets {
+		if now.Sub(b.at) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
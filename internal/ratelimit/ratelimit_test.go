@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l, err := Parse("3/m")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("ip:1"); !ok {
+			t.Fatalf("request %d of the burst should be allowed", i+1)
+		}
+	}
+	ok, wait := l.Allow("ip:1")
+	if ok || wait != 20*time.Second {
+		t.Fatalf("expected a 20s wait once the burst is spent, got %v %v", ok, wait)
+	}
+	if ok, _ := l.Allow("ip:2"); !ok {
+		t.Fatal("another caller has a bucket of its own")
+	}
+
+	now = now.Add(15 * time.Second)
+	if ok, wait := l.Allow("ip:1"); ok || wait != 5*time.Second {
+		t.Fatalf("expected 5s more to wait, got %v %v", ok, wait)
+	}
+	now = now.Add(5 * time.Second)
+	if ok, _ := l.Allow("ip:1"); !ok {
+		t.Fatal("expected a token back after 20s")
+	}
+	if ok, _ := l.Allow("ip:1"); ok {
+		t.Fatal("expected only one token back")
+	}
+}
+
+func TestParse(t *testing.T) {
+	if l, err := Parse(""); l != nil || err != nil {
+		t.Fatalf("expected no limit for an empty spec, got %v %v", l, err)
+	}
+	if l, err := Parse("10/s"); err != nil || l.Burst != 10 || l.Interval != 100*time.Millisecond {
+		t.Fatalf("unexpected limiter %+v %v", l, err)
+	}
+	for _, spec := range []string{"10", "0/s", "x/m", "10/d"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) accepted", spec)
+		}
+	}
+	var l *Limiter
+	if ok, _ := l.Allow("ip:1"); !ok {
+		t.Fatal("a nil limiter allows everything")
+	}
+}
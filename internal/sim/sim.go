@@ -0,0 +1,206 @@
+// Package sim scripts complete games against a running tinychess server
+// through its public HTTP endpoints. It backs the integration tests and the
+// load generator.
+package sim
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Client talks to a tinychess server.
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// NewClient returns a client for the server at baseURL.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), HTTP: httpClient}
+}
+
+// Player is a seated participant in a simulated game. Color uses the
+// server's FEN notation ("w" or "b"); Token is the seat token issued with it.
+type Player struct {
+	ID    string
+	Color string
+	Token string
+}
+
+// State mirrors the subset of the server's game state the simulator needs.
+type State struct {
+	FEN    string   `json:"fen"`
+	Turn   string   `json:"turn"`
+	Status string   `json:"status"`
+	UCI    []string `json:"uci"`
+}
+
+// Result describes how a scripted game ended.
+type Result struct {
+	GameID string
+	White  Player
+	Black  Player
+	State  State
+}
+
+type response struct {
+	OK        bool   `json:"ok"`
+	Error     string `json:"error"`
+	ID        string `json:"id"`
+	Color     string `json:"color"`
+	SeatToken string `json:"seatToken"`
+	State     State  `json:"state"`
+}
+
+func (c *Client) post(ctx context.Context, path string, body any) (response, error) {
+	var resp response
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return resp, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+path, bytes.NewReader(buf))
+	if err != nil {
+		return resp, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.HTTP.Do(req)
+	if err != nil {
+		return resp, err
+	}
+	defer res.Body.Close()
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return resp, fmt.Errorf("%s: decode: %w", path, err)
+	}
+	if !resp.OK {
+		return resp, fmt.Errorf("%s: %s", path, resp.Error)
+	}
+	return resp, nil
+}
+
+// CreateGame creates a game owned by ownerID and returns its id and the
+// seated owner.
+func (c *Client) CreateGame(ctx context.Context, ownerID string) (string, Player, error) {
+	resp, err := c.post(ctx, "/new", map[string]string{"userId": ownerID})
+	if err != nil {
+		return "", Player{}, err
+	}
+	return resp.ID, Player{ID: ownerID, Color: resp.Color, Token: resp.SeatToken}, nil
+}
+
+// Join connects to the game's event stream as clientID, reads the initial
+// state and disconnects. The player's color is empty for spectators.
+func (c *Client) Join(ctx context.Context, gameID, clientID string) (Player, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/sse/"+gameID+"?clientId="+clientID, nil)
+	if err != nil {
+		return Player{}, err
+	}
+	res, err := c.HTTP.Do(req)
+	if err != nil {
+		return Player{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return Player{}, fmt.Errorf("join: status %d", res.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var initial struct {
+			Color     *string `json:"color"`
+			SeatToken string  `json:"seatToken"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &initial); err != nil {
+			return Player{}, fmt.Errorf("join: decode: %w", err)
+		}
+		p := Player{ID: clientID, Token: initial.SeatToken}
+		if initial.Color != nil {
+			p.Color = *initial.Color
+		}
+		return p, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return Player{}, err
+	}
+	return Player{}, errors.New("join: stream closed before initial state")
+}
+
+// Move plays a UCI move as p.
+func (c *Client) Move(ctx context.Context, gameID string, p Player, uci string) (State, error) {
+	resp, err := c.post(ctx, "/move/"+gameID, map[string]string{"uci": uci, "clientId": p.ID, "seatToken": p.Token})
+	return resp.State, err
+}
+
+// Resign resigns the game on behalf of p.
+func (c *Client) Resign(ctx context.Context, gameID string, p Player) (State, error) {
+	resp, err := c.post(ctx, "/resign/"+gameID, map[string]string{"clientId": p.ID, "seatToken": p.Token})
+	return resp.State, err
+}
+
+// Script describes a game to play: the moves in UCI order, alternating from
+// white, and whether the side to move resigns afterwards.
+type Script struct {
+	Moves  []string
+	Resign bool
+}
+
+// Play creates a game, seats two fresh players and runs the script.
+func (c *Client) Play(ctx context.Context, script Script) (*Result, error) {
+	gameID, owner, err := c.CreateGame(ctx, uuid.NewString())
+	if err != nil {
+		return nil, err
+	}
+
+	guest, err := c.Join(ctx, gameID, uuid.NewString())
+	if err != nil {
+		return nil, err
+	}
+	if guest.Color == "" {
+		return nil, errors.New("guest was not seated")
+	}
+
+	res := &Result{GameID: gameID}
+	if owner.Color == "w" {
+		res.White, res.Black = owner, guest
+	} else {
+		res.White, res.Black = guest, owner
+	}
+
+	for i, uci := range script.Moves {
+		mover := res.White
+		if i%2 == 1 {
+			mover = res.Black
+		}
+		if res.State, err = c.Move(ctx, gameID, mover, uci); err != nil {
+			return res, fmt.Errorf("move %d (%s): %w", i+1, uci, err)
+		}
+	}
+
+	if script.Resign {
+		mover := res.White
+		if len(script.Moves)%2 == 1 {
+			mover = res.Black
+		}
+		if res.State, err = c.Resign(ctx, gameID, mover); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,48 @@
+package sim
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tinychess/internal/game"
+	"tinychess/internal/handlers"
+)
+
+func newServer(t *testing.T) *Client {
+	h := handlers.NewHandler(game.NewHub(nil), nil)
+	srv := httptest.NewServer(h.Routes())
+	t.Cleanup(srv.Close)
+	return NewClient(srv.URL, srv.Client())
+}
+
+func TestPlayScholarsMate(t *testing.T) {
+	c := newServer(t)
+	res, err := c.Play(context.Background(), Script{
+		Moves: []string{"e2e4", "e7e5", "f1c4", "b8c6", "d1h5", "g8f6", "h5f7"},
+	})
+	if err != nil {
+		t.Fatalf("play: %v", err)
+	}
+	if !strings.Contains(res.State.Status, "Checkmate") {
+		t.Fatalf("expected checkmate, got %q", res.State.Status)
+	}
+}
+
+func TestPlayResign(t *testing.T) {
+	c := newServer(t)
+	res, err := c.Play(context.Background(), Script{
+		Moves:  []string{"e2e4", "e7e5"},
+		Resign: true,
+	})
+	if err != nil {
+		t.Fatalf("play: %v", err)
+	}
+	if !strings.HasPrefix(res.State.Status, "0-1") {
+		t.Fatalf("expected white resignation, got %q", res.State.Status)
+	}
+	if _, err := c.Move(context.Background(), res.GameID, res.White, "d2d4"); err == nil {
+		t.Fatalf("expected move after resignation to fail")
+	}
+}
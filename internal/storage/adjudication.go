@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AdjudicationFilter selects unfinished games for bulk adjudication.
+type AdjudicationFilter struct {
+	IdleSince   time.Time
+	OwnerID     uuid.UUID
+	MaxPlies    int
+	TimeControl string
+	Limit       int
+}
+
+// StaleGame is an unfinished game matched by an adjudication filter along
+// with the players seated in it.
+type StaleGame struct {
+	ID       uuid.UUID   `json:"id"`
+	LastSeen time.Time   `json:"lastSeen"`
+	Plies    int         `json:"plies"`
+	Players  []uuid.UUID `json:"players"`
+}
+
+// StaleGames lists active games idle since before the filter's cutoff.
+func (s *Store) StaleGames(ctx context.Context, f AdjudicationFilter) ([]StaleGame, error) {
+	out := []StaleGame{}
+	if s == nil {
+		return out, nil
+	}
+	q := s.db.WithContext(ctx).Model(&Game{}).
+		Where("active = ? AND completed_at IS NULL AND last_seen < ?", true, f.IdleSince)
+	if f.OwnerID != uuid.Nil {
+		q = q.Where("owner_id = ?", f.OwnerID)
+	}
+	if f.MaxPlies > 0 {
+		q = q.Where("plies <= ?", f.MaxPlies)
+	}
+	if f.TimeControl != "" {
+		q = q.Where("time_control = ?", f.TimeControl)
+	}
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit)
+	}
+	var games []Game
+	if err := q.Order("last_seen").Find(&games).Error; err != nil {
+		return out, err
+	}
+	if len(games) == 0 {
+		return out, nil
+	}
+
+	ids := make([]uuid.UUID, len(games))
+	for i, g := range games {
+		ids[i] = g.ID
+	}
+	var sessions []UserSession
+	if err := s.db.WithContext(ctx).
+		Where("game_id IN ? AND active = ?", ids, true).
+		Find(&sessions).Error; err != nil {
+		return out, err
+	}
+	players := make(map[uuid.UUID][]uuid.UUID)
+	for _, us := range sessions {
+		players[us.GameID] = append(players[us.GameID], us.UserID)
+	}
+	for _, g := range games {
+		out = append(out, StaleGame{ID: g.ID, LastSeen: g.LastSeen, Plies: g.Plies, Players: players[g.ID]})
+	}
+	return out, nil
+}
+
+// AdjudicateGame records an instance decision ending a game.
+func (s *Store) AdjudicateGame(ctx context.Context, id uuid.UUID, status, result, termination string, at time.Time) error {
+	if s == nil {
+		return nil
+	}
+	active := false
+	upd := GameStateUpdate{
+		Status:      &status,
+		Termination: &termination,
+		Active:      &active,
+		CompletedAt: &at,
+	}
+	if result != "" {
+		upd.Result = &result
+	}
+	return s.SaveGameState(ctx, id, upd)
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// SaveAnalysis stores a game's post-game report, replacing any earlier one.
+func (s *Store) SaveAnalysis(ctx context.Context, gameID uuid.UUID, report string) error {
+	if s == nil {
+		return nil
+	}
+	a := GameAnalysis{GameID: gameID, Report: report}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&a).Error
+}
+
+// Analysis returns a game's stored post-game report, or ErrNotFound.
+func (s *Store) Analysis(ctx context.Context, gameID uuid.UUID) (string, error) {
+	if s == nil {
+		return "", ErrNotFound
+	}
+	var found []GameAnalysis
+	if err := s.db.WithContext(ctx).Where("game_id = ?", gameID).Limit(1).Find(&found).Error; err != nil {
+		return "", err
+	}
+	if len(found) == 0 {
+		return "", ErrNotFound
+	}
+	return found[0].Report, nil
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix starts every API key, so keys are recognizable in logs and
+// secret scanners and are never mistaken for other bearer tokens.
+const APIKeyPrefix = "tck_"
+
+// ErrUnknownKey is returned for API keys that were never issued or have
+// been revoked.
+var ErrUnknownKey = errors.New("unknown api key")
+
+// HashAPIKey returns the stored form of an API key. Keys are long random
+// strings, so a fast hash is enough; unlike join codes they cannot be
+// guessed.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey issues a new API key for the user and returns it. The key
+// itself is not stored and cannot be recovered later.
+func (s *Store) CreateAPIKey(ctx context.Context, userID uuid.UUID, name string) (string, APIKey, error) {
+	if s == nil {
+		return "", APIKey{}, errors.New("no store")
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", APIKey{}, err
+	}
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	k := APIKey{
+		ID:     uuid.New(),
+		UserID: userID,
+		Name:   name,
+		Prefix: key[:len(APIKeyPrefix)+6],
+		Hash:   HashAPIKey(key),
+	}
+	if err := s.db.WithContext(ctx).Create(&k).Error; err != nil {
+		return "", APIKey{}, err
+	}
+	return key, k, nil
+}
+
+// APIKeyUser returns the user an API key was issued to and records its use.
+func (s *Store) APIKeyUser(ctx context.Context, key string, at time.Time) (uuid.UUID, error) {
+	if s == nil || !strings.HasPrefix(key, APIKeyPrefix) {
+		return uuid.Nil, ErrUnknownKey
+	}
+	var k APIKey
+	err := s.db.WithContext(ctx).Where("hash = ?", HashAPIKey(key)).First(&k).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, ErrUnknownKey
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err := s.db.WithContext(ctx).Model(&APIKey{}).Where("id = ?", k.ID).Update("last_used_at", at).Error; err != nil {
+		return uuid.Nil, err
+	}
+	return k.UserID, nil
+}
+
+// APIKeys lists the user's keys, newest first.
+func (s *Store) APIKeys(ctx context.Context, userID uuid.UUID) ([]APIKey, error) {
+	keys := []APIKey{}
+	if s == nil {
+		return keys, nil
+	}
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// RevokeAPIKey deletes one of the user's keys. It reports whether the key
+// existed.
+func (s *Store) RevokeAPIKey(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+	res := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&APIKey{})
+	return res.RowsAffected > 0, res.Error
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// BookmarkedGame is a bookmarked game along with its current status.
+type BookmarkedGame struct {
+	ID           uuid.UUID `json:"id"`
+	Status       string    `json:"status"`
+	Result       string    `json:"result"`
+	Active       bool      `json:"active"`
+	LastSeen     time.Time `json:"lastSeen"`
+	BookmarkedAt time.Time `json:"bookmarkedAt"`
+}
+
+// AddBookmark bookmarks a game for a user. Bookmarking twice is a no-op.
+func (s *Store) AddBookmark(ctx context.Context, userID, gameID uuid.UUID) error {
+	if s == nil {
+		return nil
+	}
+	b := Bookmark{UserID: userID, GameID: gameID}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&b).Error
+}
+
+// RemoveBookmark deletes a user's bookmark for a game.
+func (s *Store) RemoveBookmark(ctx context.Context, userID, gameID uuid.UUID) error {
+	if s == nil {
+		return nil
+	}
+	return s.db.WithContext(ctx).Where("user_id = ? AND game_id = ?", userID, gameID).Delete(&Bookmark{}).Error
+}
+
+// Bookmarks lists a user's bookmarked games, newest bookmark first.
+func (s *Store) Bookmarks(ctx context.Context, userID uuid.UUID) ([]BookmarkedGame, error) {
+	games := []BookmarkedGame{}
+	if s == nil {
+		return games, nil
+	}
+	err := s.db.WithContext(ctx).
+		Table("bookmarks").
+		Select("games.id, games.status, games.result, games.active, games.last_seen, bookmarks.created_at AS bookmarked_at").
+		Joins("JOIN games ON games.id = bookmarks.game_id").
+		Where("bookmarks.user_id = ?", userID).
+		Order("bookmarks.created_at DESC").
+		Scan(&games).Error
+	return games, err
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// SaveChat records a chat message. A zero CreatedAt means now.
+func (s *Store) SaveChat(ctx context.Context, msg ChatMessage) error {
+	if s == nil {
+		return nil
+	}
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
+	return s.db.WithContext(ctx).Create(&msg).Error
+}
+
+// DeleteChat hides a chat message from replays.
+func (s *Store) DeleteChat(ctx context.Context, gameID, messageID uuid.UUID, at time.Time) error {
+	if s == nil {
+		return nil
+	}
+	return s.db.WithContext(ctx).Model(&ChatMessage{}).
+		Where("id = ? AND game_id = ?", messageID, gameID).
+		Update("removed_at", at).Error
+}
+
+// RecentChat returns up to limit of a game's latest undeleted chat messages,
+// oldest first.
+func (s *Store) RecentChat(ctx context.Context, gameID uuid.UUID, limit int) ([]ChatMessage, error) {
+	if s == nil {
+		return nil, nil
+	}
+	var msgs []ChatMessage
+	err := s.db.WithContext(ctx).
+		Where("game_id = ? AND removed_at IS NULL", gameID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&msgs).Error
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(msgs)
+	return msgs, nil
+}
+
+// MuteChat silences a user in a game's chat.
+func (s *Store) MuteChat(ctx context.Context, gameID, userID uuid.UUID, at time.Time) error {
+	if s == nil {
+		return nil
+	}
+	mute := ChatMute{GameID: gameID, UserID: userID, CreatedAt: at}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&mute).Error
+}
+
+// ChatMutes returns the users muted in a game's chat.
+func (s *Store) ChatMutes(ctx context.Context, gameID uuid.UUID) ([]uuid.UUID, error) {
+	if s == nil {
+		return nil, nil
+	}
+	var ids []uuid.UUID
+	err := s.db.WithContext(ctx).Model(&ChatMute{}).
+		Where("game_id = ?", gameID).
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
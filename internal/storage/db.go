@@ -1,27 +1,138 @@
 package storage
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
-// New initializes the database connection and performs migrations.
-func New(dsn string) (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.New(postgres.Config{
-		DSN:                  dsn,
-		PreferSimpleProtocol: true,
-	}), &gorm.Config{})
+// Database dialects, chosen by the DSN's scheme.
+const (
+	dialectPostgres = "postgres"
+	dialectSQLite   = "sqlite"
+	dialectMySQL    = "mysql"
+)
+
+// models are the tables New migrates.
+var models = []any{&Game{}, &GameSession{}, &UserSession{}, &Move{}, &Bookmark{}, &Follower{}, &UserPreference{}, &LadderRung{}, &LadderChallenge{}, &GameAnalysis{}, &ChatMessage{}, &ChatMute{}, &OutboxEvent{}, &APIKey{}, &Review{}, &Setting{}}
+
+// New initializes the database connection and performs migrations. DSNs of the
+// form sqlite://path open an embedded SQLite file, and mysql://dsn (or
+// mariadb://dsn) a MySQL or MariaDB database, where dsn is in the Go MySQL
+// driver's form, such as user:pass@tcp(db:3306)/tinychess. Anything else is
+// treated as a Postgres DSN.
+//
+// moveShards splits the moves into that many tables, moves_0 and up, each
+// holding the moves of the games whose ids hash to it, so replaying or
+// exporting a game searches one table however many moves the instance keeps.
+// 0 keeps them all in the moves table. The existing rows are moved whenever
+// it changes.
+func New(dsn string, moveShards int) (*gorm.DB, error) {
+	dialector, dialect, err := openDialector(dsn)
 	if err != nil {
 		return nil, err
 	}
-	if err := db.Exec("DROP INDEX IF EXISTS idx_game_user").Error; err != nil {
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
 		return nil, err
 	}
-	if err := db.AutoMigrate(&Game{}, &GameSession{}, &UserSession{}, &Move{}); err != nil {
-		return nil, err
+	if dialect == dialectSQLite {
+		// SQLite allows a single writer; serialize access instead of failing
+		// with "database is locked".
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+	s := &Store{db: db, moveShards: moveShards}
+	if dialect == dialectMySQL {
+		if err := s.mysqlTypes(); err != nil {
+			return nil, err
+		}
+	} else {
+		// MySQL never had the old index and creates the unique one from the
+		// model tags; it has no IF [NOT] EXISTS for indexes either.
+		if err := db.Exec("DROP INDEX IF EXISTS idx_game_user").Error; err != nil {
+			return nil, err
+		}
 	}
-	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_user_sessions_game_user ON user_sessions (game_id, user_id)").Error; err != nil {
+	if err := db.AutoMigrate(models...); err != nil {
 		return nil, err
 	}
+	if err := s.migrateMoves(); err != nil {
+		return nil, fmt.Errorf("split moves: %w", err)
+	}
+	if dialect != dialectMySQL {
+		if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_user_sessions_game_user ON user_sessions (game_id, user_id)").Error; err != nil {
+			return nil, err
+		}
+	}
 	return db, nil
 }
+
+func openDialector(dsn string) (gorm.Dialector, string, error) {
+	if path, ok := strings.CutPrefix(dsn, "sqlite://"); ok {
+		if !strings.Contains(path, "?") {
+			path += "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+		}
+		return sqlite.Open(path), dialectSQLite, nil
+	}
+	for _, scheme := range []string{"mysql://", "mariadb://"} {
+		if rest, ok := strings.CutPrefix(dsn, scheme); ok {
+			cfg, err := mysqldriver.ParseDSN(rest)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid MySQL DSN: %w", err)
+			}
+			// Timestamps scan into time.Time only with parseTime.
+			cfg.ParseTime = true
+			return mysql.New(mysql.Config{DSNConfig: cfg}), dialectMySQL, nil
+		}
+	}
+	return postgres.New(postgres.Config{
+		DSN:                  dsn,
+		PreferSimpleProtocol: true,
+	}), dialectPostgres, nil
+}
+
+// mysqlTypes adjusts the models' column types for MySQL before migrating:
+// MySQL has no uuid type, so ids are stored as char(36), and it cannot index
+// a text column, so indexed strings without a size get a varchar that fits
+// an index key in utf8mb4. The parsed schemas are cached per connection, so
+// the adjustment holds for every later query.
+func (s *Store) mysqlTypes() error {
+	for _, model := range models {
+		if err := mysqlTable(s.db, model, ""); err != nil {
+			return err
+		}
+	}
+	// Schemas parsed for another table name are cached apart.
+	for _, table := range moveTables(s.moveShards) {
+		if err := mysqlTable(s.db, &Move{}, table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mysqlTable(db *gorm.DB, model any, table string) error {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.ParseWithSpecialTableName(model, table); err != nil {
+		return err
+	}
+	for _, field := range stmt.Schema.Fields {
+		switch {
+		case field.DataType == "uuid":
+			field.DataType = "char(36)"
+		case field.DataType == schema.String && field.Size == 0 && field.TagSettings["UNIQUEINDEX"] != "":
+			field.Size = 191
+		}
+	}
+	return nil
+}
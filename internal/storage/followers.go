@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+
+	"gorm.io/gorm/clause"
+)
+
+// AddFollower records a fediverse follower, updating the inbox if the actor
+// already follows.
+func (s *Store) AddFollower(ctx context.Context, actor, inbox string) error {
+	if s == nil {
+		return nil
+	}
+	f := Follower{Actor: actor, Inbox: inbox}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "actor"}},
+		DoUpdates: clause.AssignmentColumns([]string{"inbox"}),
+	}).Create(&f).Error
+}
+
+// RemoveFollower deletes a fediverse follower.
+func (s *Store) RemoveFollower(ctx context.Context, actor string) error {
+	if s == nil {
+		return nil
+	}
+	return s.db.WithContext(ctx).Where("actor = ?", actor).Delete(&Follower{}).Error
+}
+
+// FollowerInboxes lists the distinct inboxes of all followers.
+func (s *Store) FollowerInboxes(ctx context.Context) ([]string, error) {
+	inboxes := []string{}
+	if s == nil {
+		return inboxes, nil
+	}
+	err := s.db.WithContext(ctx).Model(&Follower{}).Distinct().Pluck("inbox", &inboxes).Error
+	return inboxes, err
+}
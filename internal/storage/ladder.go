@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Errors returned when a ladder challenge cannot be made.
+var (
+	ErrNotOnLadder      = errors.New("not on the ladder")
+	ErrOutOfReach       = errors.New("defender is out of reach")
+	ErrChallengePending = errors.New("challenge already pending")
+)
+
+// Ladder returns every rung from the top down.
+func (s *Store) Ladder(ctx context.Context) ([]LadderRung, error) {
+	rungs := []LadderRung{}
+	if s == nil {
+		return rungs, nil
+	}
+	err := s.db.WithContext(ctx).Order("rung").Find(&rungs).Error
+	return rungs, err
+}
+
+// OpenChallenges returns the unresolved ladder challenges, oldest first.
+func (s *Store) OpenChallenges(ctx context.Context) ([]LadderChallenge, error) {
+	challenges := []LadderChallenge{}
+	if s == nil {
+		return challenges, nil
+	}
+	err := s.db.WithContext(ctx).
+		Where("resolved_at IS NULL").
+		Order("created_at").
+		Find(&challenges).Error
+	return challenges, err
+}
+
+// JoinLadder places a user on the bottom rung, or renames them if they are
+// already on the ladder.
+func (s *Store) JoinLadder(ctx context.Context, userID uuid.UUID, name string, at time.Time) (LadderRung, error) {
+	var rung LadderRung
+	if s == nil {
+		return rung, nil
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.First(&rung, "user_id = ?", userID).Error
+		if err == nil {
+			rung.Name = name
+			return tx.Model(&rung).Update("name", name).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		var bottom int
+		if err := tx.Model(&LadderRung{}).Select("COALESCE(MAX(rung), 0)").Scan(&bottom).Error; err != nil {
+			return err
+		}
+		rung = LadderRung{UserID: userID, Name: name, Rung: bottom + 1, JoinedAt: at}
+		return tx.Create(&rung).Error
+	})
+	return rung, err
+}
+
+// CheckChallenge reports whether challengerID may challenge defenderID, who
+// must sit between one and reach rungs above them. Players already in an
+// open challenge cannot start another.
+func (s *Store) CheckChallenge(ctx context.Context, challengerID, defenderID uuid.UUID, reach int) error {
+	if s == nil {
+		return ErrNotOnLadder
+	}
+	return checkChallenge(s.db.WithContext(ctx), challengerID, defenderID, reach)
+}
+
+func checkChallenge(tx *gorm.DB, challengerID, defenderID uuid.UUID, reach int) error {
+	var challenger, defender LadderRung
+	if err := tx.First(&challenger, "user_id = ?", challengerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotOnLadder
+		}
+		return err
+	}
+	if err := tx.First(&defender, "user_id = ?", defenderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotOnLadder
+		}
+		return err
+	}
+	if gap := challenger.Rung - defender.Rung; gap < 1 || gap > reach {
+		return ErrOutOfReach
+	}
+	var pending int64
+	err := tx.Model(&LadderChallenge{}).
+		Where("resolved_at IS NULL").
+		Where("challenger_id IN ? OR defender_id IN ?", []uuid.UUID{challengerID, defenderID}, []uuid.UUID{challengerID, defenderID}).
+		Count(&pending).Error
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		return ErrChallengePending
+	}
+	return nil
+}
+
+// CreateChallenge records that gameID decides a challenge, re-checking the
+// challenge under the same transaction.
+func (s *Store) CreateChallenge(ctx context.Context, gameID, challengerID, defenderID uuid.UUID, reach int, at time.Time) error {
+	if s == nil {
+		return ErrNotOnLadder
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkChallenge(tx, challengerID, defenderID, reach); err != nil {
+			return err
+		}
+		return tx.Create(&LadderChallenge{
+			GameID:       gameID,
+			ChallengerID: challengerID,
+			DefenderID:   defenderID,
+			CreatedAt:    at,
+		}).Error
+	})
+}
+
+// ResolveChallenge settles the open challenge decided by gameID, if any. When
+// winnerID is the challenger and they still sit below the defender, the two
+// swap rungs; any other result leaves the ladder unchanged. It reports
+// whether a challenge was resolved.
+func (s *Store) ResolveChallenge(ctx context.Context, gameID, winnerID uuid.UUID, at time.Time) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+	resolved := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var c LadderChallenge
+		err := tx.First(&c, "game_id = ? AND resolved_at IS NULL", gameID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		won := winnerID != uuid.Nil && winnerID == c.ChallengerID
+		if err := tx.Model(&c).Updates(map[string]any{"resolved_at": at, "challenger_won": won}).Error; err != nil {
+			return err
+		}
+		resolved = true
+		if !won {
+			return nil
+		}
+		var challenger, defender LadderRung
+		if err := tx.First(&challenger, "user_id = ?", c.ChallengerID).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&defender, "user_id = ?", c.DefenderID).Error; err != nil {
+			return err
+		}
+		if challenger.Rung <= defender.Rung {
+			return nil
+		}
+		top, bottom := defender.Rung, challenger.Rung
+		if err := tx.Model(&challenger).Update("rung", top).Error; err != nil {
+			return err
+		}
+		return tx.Model(&defender).Update("rung", bottom).Error
+	})
+	return resolved, err
+}
@@ -4,29 +4,57 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // Game represents a chess game.
 type Game struct {
-	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
 	FEN         string
 	PGN         string
 	OwnerID     uuid.UUID `gorm:"type:uuid;index"`
 	OwnerColor  string
 	Status      string
 	Result      string
-	Active      bool `gorm:"index"`
-	CompletedAt *time.Time
-	LastSeen    time.Time
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Sessions    []GameSession
-	Moves       []Move
+	Termination string
+	Plies       int
+	TimeControl string
+	Language    string
+	// StartFEN is the custom starting position, empty for the standard one.
+	StartFEN string
+	// Timeline is the JSON list of non-move events, such as joins.
+	Timeline string
+	// JoinCodeHash is the bcrypt hash of a private game's join code, empty
+	// for open games. CodeForSpectators extends the code to spectators.
+	JoinCodeHash      string
+	CodeForSpectators bool
+	// InviteOnly hands the open seat only to holders of a play invite.
+	InviteOnly bool
+	// HotSeat marks pass-and-play games, where one user moves both colors;
+	// they are left out of the ladder.
+	HotSeat bool
+	// HouseRules is the JSON of a game's house rules, such as a move limit,
+	// empty for plain chess.
+	HouseRules string
+	// Training is the JSON progress of an endgame practice game, empty for
+	// other games.
+	Training string
+	// CapturedByWhite and CapturedByBlack hold captured piece types, one
+	// letter per piece in capture order.
+	CapturedByWhite string
+	CapturedByBlack string
+	Active          bool `gorm:"index"`
+	CompletedAt     *time.Time
+	LastSeen        time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Sessions        []GameSession
+	Moves           []Move
 }
 
 // GameSession represents an instance of a game session.
 type GameSession struct {
-	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
 	GameID    uuid.UUID `gorm:"type:uuid;index"`
 	Game      Game      `gorm:"constraint:OnDelete:CASCADE;"`
 	CreatedAt time.Time
@@ -36,25 +64,174 @@ type GameSession struct {
 
 // UserSession links a user to a game session.
 type UserSession struct {
-	ID            uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey"`
 	GameID        uuid.UUID `gorm:"type:uuid;index;uniqueIndex:idx_user_sessions_game_user"`
 	GameSessionID uuid.UUID `gorm:"type:uuid;index"`
 	UserID        uuid.UUID `gorm:"type:uuid;index;uniqueIndex:idx_user_sessions_game_user"`
 	Color         string
 	Role          string
-	Active        bool
-	LastSeen      time.Time
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	// Name is the display name the user chose for this game.
+	Name     string
+	Active   bool
+	LastSeen time.Time
+	// ViewedAt is when the user last opened the game's event stream.
+	ViewedAt  *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 // Move stores a single move in a game.
 type Move struct {
-	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	ID     uuid.UUID `gorm:"type:uuid;primaryKey"`
+	GameID uuid.UUID `gorm:"type:uuid;index"`
+	UserID uuid.UUID `gorm:"type:uuid;index"`
+	Number int
+	UCI    string
+	Color  string
+	// Captured is the piece type taken by this move, if any.
+	Captured string
+	// ThinkMs is how long the mover took over this ply, in milliseconds.
+	ThinkMs int64
+	// CreatedAt is when the ply was played.
+	CreatedAt time.Time
+	// RevokedAt marks a ply taken back or superseded; revoked moves stay for
+	// auditing but are not part of the mainline.
+	RevokedAt *time.Time `gorm:"index"`
+}
+
+// Bookmark marks a game a user wants to watch later.
+type Bookmark struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_bookmarks_user_game"`
+	GameID    uuid.UUID `gorm:"type:uuid;index;uniqueIndex:idx_bookmarks_user_game"`
+	CreatedAt time.Time
+}
+
+// UserPreference holds per-user display settings.
+type UserPreference struct {
+	UserID   uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Notation string
+	// HideSeen keeps the user's ViewedAt from being shown to opponents.
+	HideSeen  bool
+	UpdatedAt time.Time
+}
+
+// Follower is a fediverse actor following the instance's games.
+type Follower struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Actor     string    `gorm:"uniqueIndex"`
+	Inbox     string
+	CreatedAt time.Time
+}
+
+// OutboxEvent is an outgoing delivery, such as a fediverse post, kept until
+// it succeeds so it survives crashes and failed attempts. Events that keep
+// failing are marked dead rather than deleted.
+type OutboxEvent struct {
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Kind          string
+	Target        string
+	Payload       string
+	Attempts      int
+	NextAttemptAt time.Time `gorm:"index"`
+	LastError     string
+	DeadAt        *time.Time
+	CreatedAt     time.Time
+}
+
+// APIKey lets a user's programs act for them. Only a hash of the key is
+// kept; Prefix is its first characters, shown so users can tell keys apart.
+type APIKey struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;index" json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Hash       string     `gorm:"uniqueIndex" json:"-"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// GameAnalysis is the stored post-game engine report of a game, kept as JSON.
+type GameAnalysis struct {
+	GameID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Report    string
+	CreatedAt time.Time
+}
+
+// LadderRung is a player's place on the challenge ladder; rung 1 is the top.
+type LadderRung struct {
+	UserID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"userId"`
+	Name     string    `json:"name"`
+	Rung     int       `gorm:"index" json:"rung"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+// LadderChallenge links a ladder game to the players whose rungs are at stake.
+type LadderChallenge struct {
+	GameID       uuid.UUID  `gorm:"type:uuid;primaryKey" json:"gameId"`
+	ChallengerID uuid.UUID  `gorm:"type:uuid;index" json:"challengerId"`
+	DefenderID   uuid.UUID  `gorm:"type:uuid;index" json:"defenderId"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	ResolvedAt   *time.Time `gorm:"index" json:"resolvedAt,omitempty"`
+	// ChallengerWon records whether the rungs were swapped.
+	ChallengerWon bool `json:"challengerWon"`
+}
+
+// ChatMessage is a chat line posted in a game. Deleted messages are kept
+// but no longer replayed.
+type ChatMessage struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
 	GameID    uuid.UUID `gorm:"type:uuid;index"`
 	UserID    uuid.UUID `gorm:"type:uuid;index"`
-	Number    int
-	UCI       string
 	Color     string
+	Text      string
 	CreatedAt time.Time
+	RemovedAt *time.Time
 }
+
+// ChatMute silences a participant in one game's chat.
+type ChatMute struct {
+	GameID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	CreatedAt time.Time
+}
+
+// Review is a player's request for a coach to annotate one of their
+// finished games. A coach claims it from the queue, drafts the review and
+// publishes it; Annotations is the JSON list of comments by ply.
+type Review struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	GameID      uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_reviews_game_user"`
+	UserID      uuid.UUID `gorm:"type:uuid;index;uniqueIndex:idx_reviews_game_user"`
+	Note        string
+	CoachID     *uuid.UUID `gorm:"type:uuid;index"`
+	Summary     string
+	Annotations string
+	CreatedAt   time.Time
+	ClaimedAt   *time.Time
+	PublishedAt *time.Time `gorm:"index"`
+}
+
+// Setting is a value the storage layer keeps about its own layout, such as
+// how many tables the moves are split into.
+type Setting struct {
+	Name  string `gorm:"primaryKey;size:64"`
+	Value string
+}
+
+// newID fills in a primary key before insert; IDs are generated in Go so the
+// schema works on databases without gen_random_uuid().
+func newID(id *uuid.UUID) {
+	if *id == uuid.Nil {
+		*id = uuid.New()
+	}
+}
+
+func (g *Game) BeforeCreate(*gorm.DB) error         { newID(&g.ID); return nil }
+func (gs *GameSession) BeforeCreate(*gorm.DB) error { newID(&gs.ID); return nil }
+func (us *UserSession) BeforeCreate(*gorm.DB) error { newID(&us.ID); return nil }
+func (m *Move) BeforeCreate(*gorm.DB) error         { newID(&m.ID); return nil }
+func (b *Bookmark) BeforeCreate(*gorm.DB) error     { newID(&b.ID); return nil }
+func (f *Follower) BeforeCreate(*gorm.DB) error     { newID(&f.ID); return nil }
+func (c *ChatMessage) BeforeCreate(*gorm.DB) error  { newID(&c.ID); return nil }
+func (r *Review) BeforeCreate(*gorm.DB) error       { newID(&r.ID); return nil }
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EnqueueEvent stores an outgoing delivery, due immediately.
+func (s *Store) EnqueueEvent(ctx context.Context, ev OutboxEvent) error {
+	if s == nil {
+		return nil
+	}
+	if ev.ID == uuid.Nil {
+		ev.ID = uuid.New()
+	}
+	if ev.NextAttemptAt.IsZero() {
+		ev.NextAttemptAt = time.Now()
+	}
+	return s.db.WithContext(ctx).Create(&ev).Error
+}
+
+// DueEvents returns up to limit live events due by now, oldest first.
+func (s *Store) DueEvents(ctx context.Context, now time.Time, limit int) ([]OutboxEvent, error) {
+	var events []OutboxEvent
+	if s == nil {
+		return events, nil
+	}
+	err := s.db.WithContext(ctx).
+		Where("dead_at IS NULL AND next_attempt_at <= ?", now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// CompleteEvent removes a delivered event.
+func (s *Store) CompleteEvent(ctx context.Context, id uuid.UUID) error {
+	if s == nil {
+		return nil
+	}
+	return s.db.WithContext(ctx).Delete(&OutboxEvent{}, "id = ?", id).Error
+}
+
+// FailEvent records a failed attempt. The event is retried at next, or
+// marked dead when dead is set.
+func (s *Store) FailEvent(ctx context.Context, id uuid.UUID, attempts int, next time.Time, lastErr string, dead bool) error {
+	if s == nil {
+		return nil
+	}
+	updates := map[string]any{
+		"attempts":        attempts,
+		"next_attempt_at": next,
+		"last_error":      lastErr,
+	}
+	if dead {
+		updates["dead_at"] = time.Now()
+	}
+	return s.db.WithContext(ctx).Model(&OutboxEvent{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// OutboxCounts reports the live and dead events in the outbox.
+func (s *Store) OutboxCounts(ctx context.Context) (pending, dead int64, err error) {
+	if s == nil {
+		return 0, 0, nil
+	}
+	db := s.db.WithContext(ctx).Model(&OutboxEvent{})
+	if err := db.Where("dead_at IS NULL").Count(&pending).Error; err != nil {
+		return 0, 0, err
+	}
+	err = s.db.WithContext(ctx).Model(&OutboxEvent{}).Where("dead_at IS NOT NULL").Count(&dead).Error
+	return pending, dead, err
+}
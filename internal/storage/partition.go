@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"fmt"
+	"hash/crc32"
+	"strconv"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// moveShardsSetting names the Setting recording the moves' layout.
+const moveShardsSetting = "move_shards"
+
+// moveBatch is how many moves are moved to another table at a time.
+const moveBatch = 1000
+
+// moveTables lists the tables holding the moves when they are split into
+// shards tables.
+func moveTables(shards int) []string {
+	if shards <= 0 {
+		return []string{"moves"}
+	}
+	tables := make([]string, shards)
+	for i := range tables {
+		tables[i] = fmt.Sprintf("moves_%d", i)
+	}
+	return tables
+}
+
+// moveTable names the table holding the game's moves when they are split
+// into shards tables.
+func moveTable(gameID uuid.UUID, shards int) string {
+	if shards <= 0 {
+		return "moves"
+	}
+	return fmt.Sprintf("moves_%d", crc32.ChecksumIEEE(gameID[:])%uint32(shards))
+}
+
+// movesIn scopes db to the table holding the game's moves.
+func (s *Store) movesIn(db *gorm.DB, gameID uuid.UUID) *gorm.DB {
+	return db.Table(moveTable(gameID, s.moveShards))
+}
+
+// recordedMoveShards returns the moves' layout as the database records it,
+// 0 when nothing is recorded.
+func recordedMoveShards(db *gorm.DB) int {
+	var setting Setting
+	if err := db.Where("name = ?", moveShardsSetting).Limit(1).Find(&setting).Error; err != nil {
+		return 0
+	}
+	shards, _ := strconv.Atoi(setting.Value)
+	return shards
+}
+
+// migrateMoves splits the moves into s.moveShards tables, or joins them back
+// into one when it is 0. Rows are moved in batches, each in a transaction
+// that inserts them in their new table and deletes them from the old one,
+// so an interrupted migration loses nothing and picks up where it stopped
+// on the next start. The layout is recorded once every row has moved.
+func (s *Store) migrateMoves() error {
+	db, shards := s.db, s.moveShards
+	if shards < 0 {
+		return fmt.Errorf("invalid number of move tables: %d", shards)
+	}
+	for _, table := range moveTables(shards) {
+		if err := db.Table(table).AutoMigrate(&Move{}); err != nil {
+			return err
+		}
+	}
+	from := recordedMoveShards(db)
+	if from == shards {
+		return nil
+	}
+	keep := make(map[string]bool, shards)
+	for _, table := range moveTables(shards) {
+		keep[table] = true
+	}
+	for _, src := range moveTables(from) {
+		if !db.Migrator().HasTable(src) {
+			continue
+		}
+		if err := s.rehomeMoves(src); err != nil {
+			return err
+		}
+		if !keep[src] && src != "moves" {
+			if err := db.Migrator().DropTable(src); err != nil {
+				return err
+			}
+		}
+	}
+	return db.Save(&Setting{Name: moveShardsSetting, Value: strconv.Itoa(shards)}).Error
+}
+
+// rehomeMoves moves the rows of table src that belong elsewhere when the
+// moves are split into s.moveShards tables.
+func (s *Store) rehomeMoves(src string) error {
+	db, shards := s.db, s.moveShards
+	var after uuid.UUID
+	for {
+		var batch []Move
+		if err := db.Table(src).Where("id > ?", after).Order("id").Limit(moveBatch).Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		after = batch[len(batch)-1].ID
+		byTable := make(map[string][]Move)
+		for _, m := range batch {
+			if dst := moveTable(m.GameID, shards); dst != src {
+				byTable[dst] = append(byTable[dst], m)
+			}
+		}
+		if len(byTable) == 0 {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for dst, moves := range byTable {
+				ids := make([]uuid.UUID, len(moves))
+				for i, m := range moves {
+					ids[i] = m.ID
+				}
+				if err := tx.Table(dst).Create(&moves).Error; err != nil {
+					return err
+				}
+				if err := tx.Table(src).Where("id IN ?", ids).Delete(&Move{}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
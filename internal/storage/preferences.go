@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// Preferences returns a user's saved preferences, or zero values if the user
+// has none.
+func (s *Store) Preferences(ctx context.Context, userID uuid.UUID) (UserPreference, error) {
+	pref := UserPreference{UserID: userID}
+	if s == nil {
+		return pref, nil
+	}
+	// Find rather than First: most users have no preferences, and a missing
+	// row is not worth gorm's "record not found" log line.
+	res := s.db.WithContext(ctx).Where("user_id = ?", userID).Limit(1).Find(&pref)
+	if res.Error != nil {
+		return pref, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return UserPreference{UserID: userID}, nil
+	}
+	return pref, nil
+}
+
+// SavePreferences stores a user's preferences, replacing any previous values.
+func (s *Store) SavePreferences(ctx context.Context, pref UserPreference) error {
+	if s == nil {
+		return nil
+	}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&pref).Error
+}
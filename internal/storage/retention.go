@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RetentionReport summarizes what a retention pass touched (or would touch
+// when run as a dry run).
+type RetentionReport struct {
+	Cutoff   time.Time `json:"cutoff"`
+	DryRun   bool      `json:"dryRun"`
+	Users    int64     `json:"users"`
+	Sessions int64     `json:"sessions"`
+	Moves    int64     `json:"moves"`
+	Games    int64     `json:"games"`
+	// Chat counts the chat messages anonymized; ChatMutes, Bookmarks,
+	// Preferences and APIKeys the rows deleted.
+	Chat        int64 `json:"chat"`
+	ChatMutes   int64 `json:"chatMutes"`
+	Bookmarks   int64 `json:"bookmarks"`
+	Preferences int64 `json:"preferences"`
+	APIKeys     int64 `json:"apiKeys"`
+}
+
+// userTable is a table of rows belonging to a user that a retention pass
+// covers, with the report count it fills in.
+type userTable struct {
+	model any
+	count *int64
+	// anonymize keeps the rows without their user instead of deleting them.
+	anonymize bool
+	// activity is the column expression telling when a row was last used,
+	// which counts as activity of its user.
+	activity string
+}
+
+// userTables lists the tables covered besides sessions, moves and games.
+// Chat messages are anonymized like moves, so conversations keep their
+// shape; the rest only concern their user and are deleted.
+func (r *RetentionReport) userTables() []userTable {
+	return []userTable{
+		{&ChatMessage{}, &r.Chat, true, "created_at"},
+		{&ChatMute{}, &r.ChatMutes, false, "created_at"},
+		{&Bookmark{}, &r.Bookmarks, false, "created_at"},
+		{&UserPreference{}, &r.Preferences, false, "updated_at"},
+		{&APIKey{}, &r.APIKeys, false, "COALESCE(last_used_at, created_at)"},
+	}
+}
+
+// inactiveUsers returns a subquery selecting users whose most recent
+// activity is older than the cutoff. Sessions and every user table count,
+// so a user with only, say, a bookmark or an API key is covered too, and
+// one still using an API key is not.
+func (s *Store) inactiveUsers(tx *gorm.DB, cutoff time.Time) *gorm.DB {
+	latest := func(model any, column string) *gorm.DB {
+		return tx.Model(model).
+			Select("user_id, MAX("+column+") AS last_active").
+			Where("user_id <> ?", uuid.Nil).
+			Group("user_id")
+	}
+	sql := "?"
+	parts := []any{latest(&UserSession{}, "last_seen")}
+	for _, t := range (&RetentionReport{}).userTables() {
+		sql += " UNION ALL ?"
+		parts = append(parts, latest(t.model, t.activity))
+	}
+	return tx.Table("(?) AS activity", tx.Raw(sql, parts...)).
+		Select("user_id").
+		Group("user_id").
+		Having("MAX(last_active) < ?", cutoff)
+}
+
+// ApplyRetention removes sessions for users inactive since before the cutoff,
+// see inactiveUsers, and anonymizes their moves, owned games and chat messages; their chat
+// mutes, bookmarks, preferences and API keys are deleted. With dryRun set, nothing is
+// modified and the report only contains the affected counts.
+func (s *Store) ApplyRetention(ctx context.Context, cutoff time.Time, dryRun bool) (RetentionReport, error) {
+	report := RetentionReport{Cutoff: cutoff, DryRun: dryRun}
+	if s == nil {
+		return report, nil
+	}
+	db := s.db.WithContext(ctx)
+
+	if dryRun {
+		if err := db.Table("(?) AS inactive", s.inactiveUsers(db, cutoff)).Count(&report.Users).Error; err != nil {
+			return report, err
+		}
+		if err := db.Model(&UserSession{}).Where("user_id IN (?)", s.inactiveUsers(db, cutoff)).Count(&report.Sessions).Error; err != nil {
+			return report, err
+		}
+		for _, table := range moveTables(s.moveShards) {
+			var n int64
+			if err := db.Table(table).Where("user_id IN (?)", s.inactiveUsers(db, cutoff)).Count(&n).Error; err != nil {
+				return report, err
+			}
+			report.Moves += n
+		}
+		if err := db.Model(&Game{}).Where("owner_id IN (?)", s.inactiveUsers(db, cutoff)).Count(&report.Games).Error; err != nil {
+			return report, err
+		}
+		for _, t := range report.userTables() {
+			if err := db.Model(t.model).Where("user_id IN (?)", s.inactiveUsers(db, cutoff)).Count(t.count).Error; err != nil {
+				return report, err
+			}
+		}
+		return report, nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var users []uuid.UUID
+		if err := s.inactiveUsers(tx, cutoff).Pluck("user_id", &users).Error; err != nil {
+			return err
+		}
+		report.Users = int64(len(users))
+		if len(users) == 0 {
+			return nil
+		}
+		for _, table := range moveTables(s.moveShards) {
+			res := tx.Table(table).Where("user_id IN ?", users).Update("user_id", uuid.Nil)
+			if res.Error != nil {
+				return res.Error
+			}
+			report.Moves += res.RowsAffected
+		}
+		res := tx.Model(&Game{}).Where("owner_id IN ?", users).Update("owner_id", uuid.Nil)
+		if res.Error != nil {
+			return res.Error
+		}
+		report.Games = res.RowsAffected
+		res = tx.Where("user_id IN ?", users).Delete(&UserSession{})
+		if res.Error != nil {
+			return res.Error
+		}
+		report.Sessions = res.RowsAffected
+		for _, t := range report.userTables() {
+			if t.anonymize {
+				res = tx.Model(t.model).Where("user_id IN ?", users).Update("user_id", uuid.Nil)
+			} else {
+				res = tx.Where("user_id IN ?", users).Delete(t.model)
+			}
+			if res.Error != nil {
+				return res.Error
+			}
+			*t.count = res.RowsAffected
+		}
+		return nil
+	})
+	return report, err
+}
+
+// RunRetention applies the retention policy every interval until the context
+// is cancelled. Users inactive for longer than maxAge are purged.
+func (s *Store) RunRetention(ctx context.Context, maxAge, interval time.Duration) {
+	if s == nil || maxAge <= 0 || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := s.ApplyRetention(ctx, time.Now().Add(-maxAge), false)
+			if err != nil {
+				log.Printf("retention failed: %v", err)
+				continue
+			}
+			if report.Users > 0 {
+				log.Printf("retention purged %d users (%d sessions, %d moves, %d games, %d chat messages anonymized)",
+					report.Users, report.Sessions, report.Moves, report.Games, report.Chat)
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Errors returned when a coach cannot work on a review.
+var (
+	ErrReviewClaimed   = errors.New("review claimed by another coach")
+	ErrReviewPublished = errors.New("review already published")
+)
+
+// RequestReview queues a review of a game for the user who played it. A
+// user asking twice for the same game gets the review already queued, with
+// their note updated while no coach has claimed it.
+func (s *Store) RequestReview(ctx context.Context, gameID, userID uuid.UUID, note string) (Review, error) {
+	var rv Review
+	if s == nil {
+		return rv, ErrNotFound
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		rv = Review{GameID: gameID, UserID: userID, Note: note}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rv).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&rv, "game_id = ? AND user_id = ?", gameID, userID).Error; err != nil {
+			return err
+		}
+		if rv.CoachID != nil || rv.Note == note {
+			return nil
+		}
+		rv.Note = note
+		return tx.Model(&rv).Update("note", note).Error
+	})
+	return rv, err
+}
+
+// Review returns a review by id, or ErrNotFound.
+func (s *Store) Review(ctx context.Context, id uuid.UUID) (Review, error) {
+	var rv Review
+	if s == nil {
+		return rv, ErrNotFound
+	}
+	err := s.db.WithContext(ctx).First(&rv, "id = ?", id).Error
+	return rv, err
+}
+
+// ReviewQueue lists the unpublished reviews a coach may work on: those no
+// coach has claimed and those they claimed themselves, oldest first.
+func (s *Store) ReviewQueue(ctx context.Context, coachID uuid.UUID) ([]Review, error) {
+	reviews := []Review{}
+	if s == nil {
+		return reviews, nil
+	}
+	err := s.db.WithContext(ctx).
+		Where("published_at IS NULL AND (coach_id IS NULL OR coach_id = ?)", coachID).
+		Order("created_at").
+		Find(&reviews).Error
+	return reviews, err
+}
+
+// UserReviews lists the reviews a user asked for, newest first.
+func (s *Store) UserReviews(ctx context.Context, userID uuid.UUID) ([]Review, error) {
+	reviews := []Review{}
+	if s == nil {
+		return reviews, nil
+	}
+	err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&reviews).Error
+	return reviews, err
+}
+
+// ClaimReview assigns an unclaimed review to a coach. Claiming a review the
+// coach already holds is a no-op.
+func (s *Store) ClaimReview(ctx context.Context, id, coachID uuid.UUID, at time.Time) (Review, error) {
+	return s.updateReview(ctx, id, coachID, func(tx *gorm.DB, rv *Review) error {
+		if rv.CoachID != nil {
+			return nil
+		}
+		rv.CoachID, rv.ClaimedAt = &coachID, &at
+		return tx.Model(rv).Updates(map[string]any{"coach_id": coachID, "claimed_at": at}).Error
+	})
+}
+
+// SaveReview stores a coach's draft of a review, claiming it if need be, and
+// publishes it when publishedAt is set. Published reviews are final.
+func (s *Store) SaveReview(ctx context.Context, id, coachID uuid.UUID, summary, annotations string, publishedAt *time.Time) (Review, error) {
+	return s.updateReview(ctx, id, coachID, func(tx *gorm.DB, rv *Review) error {
+		updates := map[string]any{"summary": summary, "annotations": annotations, "published_at": publishedAt}
+		if rv.CoachID == nil {
+			now := time.Now()
+			rv.CoachID, rv.ClaimedAt = &coachID, &now
+			updates["coach_id"], updates["claimed_at"] = coachID, now
+		}
+		rv.Summary, rv.Annotations, rv.PublishedAt = summary, annotations, publishedAt
+		return tx.Model(rv).Updates(updates).Error
+	})
+}
+
+// updateReview applies update to a review the coach may still work on.
+func (s *Store) updateReview(ctx context.Context, id, coachID uuid.UUID, update func(*gorm.DB, *Review) error) (Review, error) {
+	var rv Review
+	if s == nil {
+		return rv, ErrNotFound
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&rv, "id = ?", id).Error; err != nil {
+			return err
+		}
+		switch {
+		case rv.PublishedAt != nil:
+			return ErrReviewPublished
+		case rv.CoachID != nil && *rv.CoachID != coachID:
+			return ErrReviewClaimed
+		}
+		return update(tx, &rv)
+	})
+	return rv, err
+}
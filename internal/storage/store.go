@@ -13,6 +13,8 @@ import (
 // Store wraps a gorm DB instance and provides helper methods for persisting games.
 type Store struct {
 	db *gorm.DB
+	// moveShards is how many tables the moves are split into, see New.
+	moveShards int
 }
 
 // NewStore creates a new store helper from a gorm DB.
@@ -20,7 +22,7 @@ func NewStore(db *gorm.DB) *Store {
 	if db == nil {
 		return nil
 	}
-	return &Store{db: db}
+	return &Store{db: db, moveShards: recordedMoveShards(db)}
 }
 
 // DB exposes the underlying gorm DB instance.
@@ -36,13 +38,27 @@ var ErrNotFound = gorm.ErrRecordNotFound
 
 // GameStateUpdate represents a partial update to a game row.
 type GameStateUpdate struct {
-	FEN         *string
-	PGN         *string
-	Status      *string
-	Result      *string
-	Active      *bool
-	LastSeen    *time.Time
-	CompletedAt *time.Time
+	FEN               *string
+	PGN               *string
+	Status            *string
+	Result            *string
+	Termination       *string
+	Plies             *int
+	TimeControl       *string
+	Language          *string
+	StartFEN          *string
+	Timeline          *string
+	JoinCodeHash      *string
+	CodeForSpectators *bool
+	InviteOnly        *bool
+	HotSeat           *bool
+	HouseRules        *string
+	Training          *string
+	CapturedByWhite   *string
+	CapturedByBlack   *string
+	Active            *bool
+	LastSeen          *time.Time
+	CompletedAt       *time.Time
 }
 
 // CreateGame inserts a new game with the provided identifiers.
@@ -78,6 +94,48 @@ func (s *Store) SaveGameState(ctx context.Context, id uuid.UUID, upd GameStateUp
 	if upd.Result != nil {
 		updates["result"] = *upd.Result
 	}
+	if upd.Termination != nil {
+		updates["termination"] = *upd.Termination
+	}
+	if upd.Plies != nil {
+		updates["plies"] = *upd.Plies
+	}
+	if upd.TimeControl != nil {
+		updates["time_control"] = *upd.TimeControl
+	}
+	if upd.Language != nil {
+		updates["language"] = *upd.Language
+	}
+	if upd.StartFEN != nil {
+		updates["start_fen"] = *upd.StartFEN
+	}
+	if upd.Timeline != nil {
+		updates["timeline"] = *upd.Timeline
+	}
+	if upd.JoinCodeHash != nil {
+		updates["join_code_hash"] = *upd.JoinCodeHash
+	}
+	if upd.CodeForSpectators != nil {
+		updates["code_for_spectators"] = *upd.CodeForSpectators
+	}
+	if upd.InviteOnly != nil {
+		updates["invite_only"] = *upd.InviteOnly
+	}
+	if upd.HotSeat != nil {
+		updates["hot_seat"] = *upd.HotSeat
+	}
+	if upd.HouseRules != nil {
+		updates["house_rules"] = *upd.HouseRules
+	}
+	if upd.Training != nil {
+		updates["training"] = *upd.Training
+	}
+	if upd.CapturedByWhite != nil {
+		updates["captured_by_white"] = *upd.CapturedByWhite
+	}
+	if upd.CapturedByBlack != nil {
+		updates["captured_by_black"] = *upd.CapturedByBlack
+	}
 	if upd.Active != nil {
 		updates["active"] = *upd.Active
 	}
@@ -128,19 +186,77 @@ func (s *Store) DeactivateUserSession(ctx context.Context, gameID, userID uuid.U
 		Updates(map[string]any{"active": false}).Error
 }
 
-// RecordMove inserts a move row for the given game.
-func (s *Store) RecordMove(ctx context.Context, gameID, userID uuid.UUID, number int, uci, color string) error {
+// SetSessionName stores the display name a user chose in a game. It is a
+// no-op for users without a session in the game.
+func (s *Store) SetSessionName(ctx context.Context, gameID, userID uuid.UUID, name string) error {
+	if s == nil {
+		return nil
+	}
+	return s.db.WithContext(ctx).
+		Model(&UserSession{}).
+		Where("game_id = ? AND user_id = ?", gameID, userID).
+		Updates(map[string]any{"name": name}).Error
+}
+
+// RecordMove inserts a move row for the given game, played at playedAt after
+// thinking for think. Any live moves at or after the same ply are revoked
+// first, so replacing a ply never rewrites history.
+func (s *Store) RecordMove(ctx context.Context, gameID, userID uuid.UUID, number int, uci, color, captured string, playedAt time.Time, think time.Duration) error {
 	if s == nil {
 		return nil
 	}
+	if playedAt.IsZero() {
+		playedAt = time.Now()
+	}
 	move := Move{
-		GameID: gameID,
-		UserID: userID,
-		Number: number,
-		UCI:    uci,
-		Color:  color,
+		GameID:    gameID,
+		UserID:    userID,
+		Number:    number,
+		UCI:       uci,
+		Color:     color,
+		Captured:  captured,
+		ThinkMs:   think.Milliseconds(),
+		CreatedAt: playedAt,
 	}
-	return s.db.WithContext(ctx).Create(&move).Error
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.revokeFrom(tx, gameID, number, playedAt); err != nil {
+			return err
+		}
+		return s.movesIn(tx, gameID).Create(&move).Error
+	})
+}
+
+func (s *Store) revokeFrom(tx *gorm.DB, gameID uuid.UUID, number int, at time.Time) error {
+	return s.movesIn(tx, gameID).
+		Where("game_id = ? AND number >= ? AND revoked_at IS NULL", gameID, number).
+		Update("revoked_at", at).Error
+}
+
+// Moves returns the game's current mainline in ply order.
+func (s *Store) Moves(ctx context.Context, gameID uuid.UUID) ([]Move, error) {
+	var moves []Move
+	if s == nil {
+		return moves, nil
+	}
+	err := s.movesIn(s.db.WithContext(ctx), gameID).
+		Where("game_id = ? AND revoked_at IS NULL", gameID).
+		Order("number").
+		Find(&moves).Error
+	return moves, err
+}
+
+// MoveHistory returns every move recorded for the game, including revoked
+// plies, in ply and insertion order for auditing.
+func (s *Store) MoveHistory(ctx context.Context, gameID uuid.UUID) ([]Move, error) {
+	var moves []Move
+	if s == nil {
+		return moves, nil
+	}
+	err := s.movesIn(s.db.WithContext(ctx), gameID).
+		Where("game_id = ?", gameID).
+		Order("number, created_at").
+		Find(&moves).Error
+	return moves, err
 }
 
 // LoadGame fetches a persisted game and its active sessions.
@@ -171,11 +287,20 @@ type Stats struct {
 	Started   int64 `json:"started"`
 	Completed int64 `json:"completed"`
 	Active    int64 `json:"active"`
+
+	WhiteWins    int64            `json:"whiteWins"`
+	BlackWins    int64            `json:"blackWins"`
+	Draws        int64            `json:"draws"`
+	WhitePct     float64          `json:"whitePct"`
+	BlackPct     float64          `json:"blackPct"`
+	DrawPct      float64          `json:"drawPct"`
+	AveragePlies float64          `json:"averagePlies"`
+	Terminations map[string]int64 `json:"terminations"`
 }
 
 // FetchStats aggregates counts for display on the home page.
 func (s *Store) FetchStats(ctx context.Context) (Stats, error) {
-	var stats Stats
+	stats := Stats{Terminations: map[string]int64{}}
 	if s == nil {
 		return stats, nil
 	}
@@ -188,6 +313,59 @@ func (s *Store) FetchStats(ctx context.Context) (Stats, error) {
 	if err := s.db.WithContext(ctx).Model(&Game{}).Where("completed_at IS NOT NULL").Count(&stats.Completed).Error; err != nil {
 		return stats, err
 	}
+
+	var results []struct {
+		Result string
+		Count  int64
+	}
+	if err := s.db.WithContext(ctx).Model(&Game{}).
+		Select("result, COUNT(*) AS count").
+		Where("completed_at IS NOT NULL").
+		Group("result").
+		Scan(&results).Error; err != nil {
+		return stats, err
+	}
+	for _, r := range results {
+		switch r.Result {
+		case "1-0":
+			stats.WhiteWins = r.Count
+		case "0-1":
+			stats.BlackWins = r.Count
+		case "1/2-1/2":
+			stats.Draws = r.Count
+		}
+	}
+	if decided := stats.WhiteWins + stats.BlackWins + stats.Draws; decided > 0 {
+		stats.WhitePct = 100 * float64(stats.WhiteWins) / float64(decided)
+		stats.BlackPct = 100 * float64(stats.BlackWins) / float64(decided)
+		stats.DrawPct = 100 * float64(stats.Draws) / float64(decided)
+	}
+
+	var avg struct{ Plies *float64 }
+	if err := s.db.WithContext(ctx).Model(&Game{}).
+		Select("AVG(plies) AS plies").
+		Where("completed_at IS NOT NULL AND plies > 0").
+		Scan(&avg).Error; err != nil {
+		return stats, err
+	}
+	if avg.Plies != nil {
+		stats.AveragePlies = *avg.Plies
+	}
+
+	var terminations []struct {
+		Termination string
+		Count       int64
+	}
+	if err := s.db.WithContext(ctx).Model(&Game{}).
+		Select("termination, COUNT(*) AS count").
+		Where("completed_at IS NOT NULL AND termination <> ''").
+		Group("termination").
+		Scan(&terminations).Error; err != nil {
+		return stats, err
+	}
+	for _, t := range terminations {
+		stats.Terminations[t.Termination] = t.Count
+	}
 	return stats, nil
 }
 
@@ -227,9 +405,11 @@ func (s *Store) ForgetGame(ctx context.Context, id uuid.UUID, when time.Time) er
 		return nil
 	}
 	status := "Abandoned"
+	termination := "abandonment"
 	active := false
 	return s.SaveGameState(ctx, id, GameStateUpdate{
 		Status:      &status,
+		Termination: &termination,
 		Active:      &active,
 		CompletedAt: &when,
 	})
@@ -243,5 +423,124 @@ func (s *Store) DeactivateAllSessions(ctx context.Context, gameID uuid.UUID) err
 	return s.db.WithContext(ctx).Model(&UserSession{}).Where("game_id = ?", gameID).Updates(map[string]any{"active": false}).Error
 }
 
+// ActiveGamesSince lists unfinished games seen at or after since, most
+// recently seen first. At most limit IDs are returned when limit is positive.
+func (s *Store) ActiveGamesSince(ctx context.Context, since time.Time, limit int) ([]uuid.UUID, error) {
+	ids := []uuid.UUID{}
+	if s == nil {
+		return ids, nil
+	}
+	q := s.db.WithContext(ctx).Model(&Game{}).
+		Where("active = ? AND completed_at IS NULL AND last_seen >= ?", true, since).
+		Order("last_seen DESC")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	return ids, q.Pluck("id", &ids).Error
+}
+
 // ErrMissingGame is returned when attempting to operate on a non-existing game.
 var ErrMissingGame = errors.New("game not found")
+
+// RecentGame is a game a user has taken part in, as listed on the home page.
+type RecentGame struct {
+	ID          uuid.UUID  `json:"id"`
+	Color       string     `json:"color"`
+	Role        string     `json:"role"`
+	Status      string     `json:"status"`
+	Result      string     `json:"result"`
+	Active      bool       `json:"active"`
+	LastSeen    time.Time  `json:"lastSeen"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	// OpponentSeenAt is when an opponent last viewed the game, unless they
+	// hide it.
+	OpponentSeenAt *time.Time `json:"opponentSeenAt,omitempty"`
+}
+
+// RecentGames lists the games the user is seated in, most recently seen first.
+func (s *Store) RecentGames(ctx context.Context, userID uuid.UUID, limit int) ([]RecentGame, error) {
+	return s.ListGames(ctx, userID, GameFilter{Limit: limit})
+}
+
+// Game listing statuses for GameFilter.
+const (
+	ListActive    = "active"
+	ListCompleted = "completed"
+)
+
+// GameFilter selects a page of a user's games.
+type GameFilter struct {
+	// Status is ListActive for unfinished games, ListCompleted for finished
+	// ones, or empty for both.
+	Status string
+	Limit  int
+	Offset int
+}
+
+// ListGames lists a page of the games the user is seated in, most recently
+// seen first.
+func (s *Store) ListGames(ctx context.Context, userID uuid.UUID, f GameFilter) ([]RecentGame, error) {
+	games := []RecentGame{}
+	if s == nil {
+		return games, nil
+	}
+	q := s.db.WithContext(ctx).
+		Table("games").
+		Select("games.id, user_sessions.color, user_sessions.role, games.status, games.result, games.active, games.last_seen, games.completed_at").
+		Joins("JOIN user_sessions ON user_sessions.game_id = games.id").
+		Where("user_sessions.user_id = ? AND user_sessions.active = ?", userID, true)
+	switch f.Status {
+	case ListActive:
+		q = q.Where("games.completed_at IS NULL")
+	case ListCompleted:
+		q = q.Where("games.completed_at IS NOT NULL")
+	}
+	err := q.Order("games.last_seen DESC, games.id").
+		Limit(f.Limit).
+		Offset(f.Offset).
+		Scan(&games).Error
+	if err != nil || len(games) == 0 {
+		return games, err
+	}
+	return games, s.fillOpponentSeen(ctx, userID, games)
+}
+
+// fillOpponentSeen sets OpponentSeenAt from the other seated players'
+// sessions, skipping players who hide when they were last seen.
+func (s *Store) fillOpponentSeen(ctx context.Context, userID uuid.UUID, games []RecentGame) error {
+	ids := make([]uuid.UUID, len(games))
+	for i, g := range games {
+		ids[i] = g.ID
+	}
+	var sessions []UserSession
+	err := s.db.WithContext(ctx).
+		Where("game_id IN ? AND user_id <> ? AND active = ? AND viewed_at IS NOT NULL", ids, userID, true).
+		Where("user_id NOT IN (?)", s.db.Model(&UserPreference{}).Select("user_id").Where("hide_seen = ?", true)).
+		Find(&sessions).Error
+	if err != nil {
+		return err
+	}
+	seen := make(map[uuid.UUID]time.Time, len(sessions))
+	for _, us := range sessions {
+		if at := *us.ViewedAt; at.After(seen[us.GameID]) {
+			seen[us.GameID] = at
+		}
+	}
+	for i := range games {
+		if at, ok := seen[games[i].ID]; ok {
+			games[i].OpponentSeenAt = &at
+		}
+	}
+	return nil
+}
+
+// MarkViewed records that a seated user opened the game.
+func (s *Store) MarkViewed(ctx context.Context, gameID, userID uuid.UUID, at time.Time) error {
+	if s == nil {
+		return nil
+	}
+	return s.db.WithContext(ctx).
+		Model(&UserSession{}).
+		Where("game_id = ? AND user_id = ?", gameID, userID).
+		Update("viewed_at", at).Error
+}
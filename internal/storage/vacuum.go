@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PartitionWarnRows is how many moves a moves table holds before database
+// reports advise splitting the moves further, see New.
+const PartitionWarnRows = 10_000_000
+
+// DatabaseReport describes the database's tables and indexes after an
+// optional vacuum, with warnings for whatever needs an operator.
+type DatabaseReport struct {
+	Dialect  string `json:"dialect"`
+	Vacuumed bool   `json:"vacuumed"`
+	// Took is how long the vacuum and the report took, in milliseconds.
+	Took int64 `json:"tookMs"`
+	// Bytes is the size of the whole database, and FreeBytes the part of it
+	// a vacuum would give back.
+	Bytes     int64        `json:"bytes"`
+	FreeBytes int64        `json:"freeBytes,omitempty"`
+	Tables    []TableStats `json:"tables"`
+	Indexes   []IndexStats `json:"indexes,omitempty"`
+	Warnings  []string     `json:"warnings,omitempty"`
+}
+
+// TableStats is a table's row count and size. Postgres and MySQL count rows
+// from their statistics, so the counts are estimates there.
+type TableStats struct {
+	Name       string `json:"name"`
+	Rows       int64  `json:"rows" gorm:"column:row_count"`
+	TableBytes int64  `json:"tableBytes,omitempty"`
+	IndexBytes int64  `json:"indexBytes,omitempty"`
+}
+
+// IndexStats is an index's size and, on Postgres, how often it was used
+// since statistics were last reset.
+type IndexStats struct {
+	Name   string `json:"name"`
+	Table  string `json:"table"`
+	Bytes  int64  `json:"bytes,omitempty"`
+	Scans  *int64 `json:"scans,omitempty"`
+	Unique bool   `json:"unique,omitempty"`
+}
+
+// MaintainDatabase vacuums the database when vacuum is set, then reports on
+// its tables and indexes. Postgres runs VACUUM (ANALYZE); SQLite checkpoints
+// its write-ahead log, compacts the file with VACUUM and runs ANALYZE; MySQL
+// runs OPTIMIZE TABLE on every table. A vacuum can take long on a large
+// database, and SQLite's blocks writes while it runs.
+func (s *Store) MaintainDatabase(ctx context.Context, vacuum bool) (DatabaseReport, error) {
+	start := time.Now()
+	db := s.db.WithContext(ctx)
+	report := DatabaseReport{Dialect: db.Dialector.Name()}
+	if vacuum {
+		if err := s.vacuum(db, report.Dialect); err != nil {
+			return report, fmt.Errorf("vacuum: %w", err)
+		}
+		report.Vacuumed = true
+	}
+	var err error
+	switch report.Dialect {
+	case dialectPostgres:
+		err = postgresStats(db, &report)
+	case dialectMySQL:
+		err = mysqlStats(db, &report)
+	default:
+		err = sqliteStats(db, &report)
+	}
+	if err != nil {
+		return report, err
+	}
+	for _, t := range report.Tables {
+		if slices.Contains(moveTables(s.moveShards), t.Name) && t.Rows > PartitionWarnRows {
+			advice := fmt.Sprintf("consider splitting the moves into more tables than %d with MOVE_SHARDS", max(s.moveShards, 1))
+			report.Warnings = append(report.Warnings, fmt.Sprintf("the %s table holds %d rows, more than %d: %s", t.Name, t.Rows, PartitionWarnRows, advice))
+		}
+	}
+	report.Took = time.Since(start).Milliseconds()
+	return report, nil
+}
+
+func (s *Store) vacuum(db *gorm.DB, dialect string) error {
+	switch dialect {
+	case dialectPostgres:
+		return db.Exec("VACUUM (ANALYZE)").Error
+	case dialectMySQL:
+		tables, err := db.Migrator().GetTables()
+		if err != nil {
+			return err
+		}
+		for _, t := range tables {
+			// OPTIMIZE TABLE answers with a result set, reporting failures
+			// there rather than as an error.
+			var results []struct {
+				MsgType string `gorm:"column:Msg_type"`
+				MsgText string `gorm:"column:Msg_text"`
+			}
+			if err := db.Raw("OPTIMIZE TABLE " + quoteMySQL(t)).Scan(&results).Error; err != nil {
+				return err
+			}
+			for _, r := range results {
+				if r.MsgType == "error" {
+					return fmt.Errorf("optimize %s: %s", t, r.MsgText)
+				}
+			}
+		}
+		return nil
+	default:
+		for _, stmt := range []string{"PRAGMA wal_checkpoint(TRUNCATE)", "VACUUM", "ANALYZE"} {
+			if err := db.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func quoteMySQL(name string) string {
+	return "`" + name + "`"
+}
+
+func postgresStats(db *gorm.DB, report *DatabaseReport) error {
+	if err := db.Raw("SELECT pg_database_size(current_database())").Row().Scan(&report.Bytes); err != nil {
+		return err
+	}
+	err := db.Raw(`SELECT relname AS name, n_live_tup AS row_count,
+		pg_table_size(relid) AS table_bytes, pg_indexes_size(relid) AS index_bytes
+		FROM pg_stat_user_tables ORDER BY pg_total_relation_size(relid) DESC`).Scan(&report.Tables).Error
+	if err != nil {
+		return err
+	}
+	var indexes []struct {
+		IndexStats
+		Valid bool
+	}
+	err = db.Raw(`SELECT s.indexrelname AS name, s.relname AS "table",
+		pg_relation_size(s.indexrelid) AS bytes, s.idx_scan AS scans,
+		i.indisunique AS "unique", i.indisvalid AS valid
+		FROM pg_stat_user_indexes s JOIN pg_index i ON i.indexrelid = s.indexrelid
+		ORDER BY bytes DESC`).Scan(&indexes).Error
+	if err != nil {
+		return err
+	}
+	for _, idx := range indexes {
+		report.Indexes = append(report.Indexes, idx.IndexStats)
+		switch {
+		case !idx.Valid:
+			report.Warnings = append(report.Warnings, fmt.Sprintf("index %s on %s is invalid, most likely from a failed build: rebuild it with REINDEX", idx.Name, idx.Table))
+		case !idx.Unique && idx.Scans != nil && *idx.Scans == 0:
+			report.Warnings = append(report.Warnings, fmt.Sprintf("index %s on %s has not been used since statistics were reset", idx.Name, idx.Table))
+		}
+	}
+	return nil
+}
+
+func mysqlStats(db *gorm.DB, report *DatabaseReport) error {
+	var tables []struct {
+		TableStats
+		FreeBytes int64
+	}
+	err := db.Raw(`SELECT table_name AS name, table_rows AS row_count,
+		data_length AS table_bytes, index_length AS index_bytes, data_free AS free_bytes
+		FROM information_schema.tables WHERE table_schema = DATABASE()
+		ORDER BY data_length + index_length DESC`).Scan(&tables).Error
+	if err != nil {
+		return err
+	}
+	for _, t := range tables {
+		report.Tables = append(report.Tables, t.TableStats)
+		report.Bytes += t.TableBytes + t.IndexBytes
+		report.FreeBytes += t.FreeBytes
+	}
+	return nil
+}
+
+func sqliteStats(db *gorm.DB, report *DatabaseReport) error {
+	var pages, pageSize, free int64
+	for stmt, dst := range map[string]*int64{"PRAGMA page_count": &pages, "PRAGMA page_size": &pageSize, "PRAGMA freelist_count": &free} {
+		if err := db.Raw(stmt).Row().Scan(dst); err != nil {
+			return err
+		}
+	}
+	report.Bytes, report.FreeBytes = pages*pageSize, free*pageSize
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		return err
+	}
+	for _, t := range tables {
+		stats := TableStats{Name: t}
+		if err := db.Table(t).Count(&stats.Rows).Error; err != nil {
+			return err
+		}
+		report.Tables = append(report.Tables, stats)
+	}
+	err = db.Raw(`SELECT name, tbl_name AS "table" FROM sqlite_master
+		WHERE type = 'index' ORDER BY tbl_name, name`).Scan(&report.Indexes).Error
+	if err != nil {
+		return err
+	}
+	// quick_check finds damaged pages and indexes out of order without the
+	// full cost of integrity_check.
+	var check []string
+	if err := db.Raw("PRAGMA quick_check").Scan(&check).Error; err != nil {
+		return err
+	}
+	if len(check) != 1 || check[0] != "ok" {
+		for _, problem := range check {
+			report.Warnings = append(report.Warnings, "integrity check: "+problem)
+		}
+	}
+	return nil
+}
+
+// RunDatabaseMaintenance vacuums the database every interval until the
+// context is cancelled, logging the report's warnings.
+func (s *Store) RunDatabaseMaintenance(ctx context.Context, interval time.Duration) {
+	if s == nil || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := s.MaintainDatabase(ctx, true)
+			if err != nil {
+				log.Printf("database maintenance failed: %v", err)
+				continue
+			}
+			log.Printf("database maintenance took %dms, database is %d bytes", report.Took, report.Bytes)
+			for _, w := range report.Warnings {
+				log.Printf("database maintenance: %s", w)
+			}
+		}
+	}
+}
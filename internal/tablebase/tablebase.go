@@ -0,0 +1,162 @@
+// Package tablebase probes Syzygy endgame tablebases through the Lichess
+// tablebase API, or any server speaking the same protocol. Probes give the
+// exact outcome of positions with few enough pieces, and how every legal move
+// changes it.
+package tablebase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultURL is the public Lichess tablebase for standard chess.
+const DefaultURL = "https://tablebase.lichess.ovh/standard"
+
+// MaxPieces is the most pieces, kings included, the tablebases cover.
+const MaxPieces = 7
+
+// ErrTooManyPieces is returned for positions the tablebases do not cover.
+var ErrTooManyPieces = errors.New("tablebase: too many pieces")
+
+// Category is the outcome of a position for the side to move. Cursed wins
+// and blessed losses are wins and losses that the fifty-move rule turns into
+// draws.
+type Category string
+
+const (
+	Win         Category = "win"
+	CursedWin   Category = "cursed-win"
+	Draw        Category = "draw"
+	BlessedLoss Category = "blessed-loss"
+	Loss        Category = "loss"
+	Unknown     Category = "unknown"
+)
+
+// WDL scores a category from 2 (win) down to -2 (loss), with cursed wins and
+// blessed losses at ±1. Unknown outcomes score as draws.
+func (c Category) WDL() int {
+	switch c {
+	case Win:
+		return 2
+	case CursedWin:
+		return 1
+	case BlessedLoss:
+		return -1
+	case Loss:
+		return -2
+	}
+	return 0
+}
+
+// Opposite returns the category for the other side.
+func (c Category) Opposite() Category {
+	switch c {
+	case Win:
+		return Loss
+	case CursedWin:
+		return BlessedLoss
+	case BlessedLoss:
+		return CursedWin
+	case Loss:
+		return Win
+	}
+	return c
+}
+
+// Move is a legal move in a probed position. Its category and DTZ describe
+// the position after the move, for the side then to move, so a winning move
+// has category Loss.
+type Move struct {
+	UCI      string   `json:"uci"`
+	SAN      string   `json:"san"`
+	Category Category `json:"category"`
+	// DTZ counts plies to the next capture or pawn move under optimal play,
+	// negative when the side to move loses. Nil when unknown.
+	DTZ       *int `json:"dtz"`
+	Zeroing   bool `json:"zeroing"`
+	Checkmate bool `json:"checkmate"`
+	Stalemate bool `json:"stalemate"`
+}
+
+// Result is the verdict on a position. Moves are ordered best first for the
+// side to move.
+type Result struct {
+	Category  Category `json:"category"`
+	DTZ       *int     `json:"dtz"`
+	Checkmate bool     `json:"checkmate"`
+	Stalemate bool     `json:"stalemate"`
+	Moves     []Move   `json:"moves"`
+}
+
+// Move returns the probed move with the given UCI, if it is legal.
+func (r Result) Move(uci string) (Move, bool) {
+	for _, m := range r.Moves {
+		if m.UCI == uci {
+			return m, true
+		}
+	}
+	return Move{}, false
+}
+
+// Pieces counts the pieces, kings included, in the board field of fen.
+func Pieces(fen string) int {
+	board, _, _ := strings.Cut(strings.TrimSpace(fen), " ")
+	n := 0
+	for _, r := range board {
+		if strings.ContainsRune("pnbrqkPNBRQK", r) {
+			n++
+		}
+	}
+	return n
+}
+
+// Client probes a remote tablebase server.
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// NewClient returns a client for the server at baseURL, DefaultURL when
+// empty.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), HTTP: httpClient}
+}
+
+// Probe looks up the position fen.
+func (c *Client) Probe(ctx context.Context, fen string) (Result, error) {
+	if Pieces(fen) > MaxPieces {
+		return Result{}, ErrTooManyPieces
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"?fen="+url.QueryEscape(fen), nil)
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := c.HTTP.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return Result{}, fmt.Errorf("tablebase: status %d", res.StatusCode)
+	}
+	var r Result
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return Result{}, fmt.Errorf("tablebase: decode: %w", err)
+	}
+	if r.Category == "" {
+		r.Category = Unknown
+	}
+	return r, nil
+}
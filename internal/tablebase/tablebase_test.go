@@ -0,0 +1,42 @@
+package tablebase
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientProbe(t *testing.T) {
+	const fen = "4k3/8/4K3/8/8/8/8/R7 w - - 0 1"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fen") != fen {
+			http.Error(w, "bad fen", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"category":"win","dtz":1,"checkmate":false,"moves":[{"uci":"a1a8","san":"Ra8#","category":"loss","dtz":0,"checkmate":true},{"uci":"a1a2","san":"Ra2","category":"loss","dtz":-4}]}`)
+	}))
+	defer srv.Close()
+
+	r, err := NewClient(srv.URL, srv.Client()).Probe(context.Background(), fen)
+	if err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if r.Category != Win || r.DTZ == nil || *r.DTZ != 1 || len(r.Moves) != 2 {
+		t.Fatalf("got %+v", r)
+	}
+	if m, ok := r.Move("a1a8"); !ok || !m.Checkmate || m.Category.WDL() != -2 {
+		t.Fatalf("got %+v", m)
+	}
+	if _, ok := r.Move("e6e7"); ok {
+		t.Fatalf("expected an unlisted move to be missing")
+	}
+
+	if _, err := NewClient(srv.URL, srv.Client()).Probe(context.Background(), "8/8/8/8/8/8/8/8 w - - 0 1"); err == nil {
+		t.Fatalf("expected an error status to fail")
+	}
+	if _, err := NewClient(srv.URL, nil).Probe(context.Background(), "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"); err != ErrTooManyPieces {
+		t.Fatalf("expected ErrTooManyPieces, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Branding is how an instance presents itself on its pages and in link
+// previews.
+type Branding struct {
+	Name string
+	// LogoURL replaces the pawn beside the name; it is also the link preview
+	// image. It may be absolute or a path on this instance.
+	LogoURL string
+	// Footer is plain text shown above the version line.
+	Footer string
+	// Accents are the theme picker's colors as #rgb or #rrggbb; the first is
+	// the default.
+	Accents []string
+	// BaseURL makes preview URLs absolute.
+	BaseURL string
+}
+
+// DefaultAccents is the palette of an unbranded instance.
+var DefaultAccents = []string{"#6ee7ff", "#a78bfa", "#f472b6", "#f59e0b", "#10b981"}
+
+var accentNames = map[string]string{
+	"#6ee7ff": "cyan",
+	"#a78bfa": "purple",
+	"#f472b6": "pink",
+	"#f59e0b": "amber",
+	"#10b981": "green",
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-f]{3}|[0-9a-f]{6})$`)
+
+var branding = Branding{Name: "Tiny Chess", Accents: DefaultAccents}
+
+// SetBranding configures the instance's branding. Empty fields keep the
+// defaults.
+func SetBranding(b Branding) error {
+	if b.Name == "" {
+		b.Name = "Tiny Chess"
+	}
+	if len(b.Accents) == 0 {
+		b.Accents = DefaultAccents
+	}
+	accents := make([]string, 0, len(b.Accents))
+	for _, a := range b.Accents {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if !hexColor.MatchString(a) {
+			return fmt.Errorf("invalid accent color %q, want #rgb or #rrggbb", a)
+		}
+		accents = append(accents, a)
+	}
+	b.Accents = accents
+	if b.LogoURL != "" {
+		u, err := url.Parse(b.LogoURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https" && (u.Scheme != "" || !strings.HasPrefix(u.Path, "/"))) {
+			return fmt.Errorf("invalid logo URL %q, want an http(s) URL or an absolute path", b.LogoURL)
+		}
+	}
+	b.BaseURL = strings.TrimRight(b.BaseURL, "/")
+	branding = b
+	return nil
+}
+
+// CurrentBranding returns the instance's branding.
+func CurrentBranding() Branding {
+	return branding
+}
+
+// brand fills a page's branding placeholders. title is the link preview's
+// title and path its URL path on this instance.
+func brand(html, title, path string) string {
+	b := branding
+	logo := `<span class="chess-icon">♙</span>`
+	if b.LogoURL != "" {
+		logo = `<img class="brand-logo" src="` + template.HTMLEscapeString(b.LogoURL) + `" alt="" />`
+	}
+	footer := ""
+	if b.Footer != "" {
+		footer = template.HTMLEscapeString(b.Footer) + " · "
+	}
+	return strings.NewReplacer(
+		"{{INSTANCE_NAME}}", template.HTMLEscapeString(b.Name),
+		"{{ACCENT}}", b.Accents[0],
+		"{{SWATCHES}}", swatches(b.Accents),
+		"{{LOGO}}", logo,
+		"{{FOOTER}}", footer,
+		"{{OPEN_GRAPH}}", openGraph(b, title, path),
+	).Replace(html)
+}
+
+func swatches(accents []string) string {
+	var sb strings.Builder
+	for i, a := range accents {
+		name := accentNames[a]
+		if name == "" {
+			name = a
+		}
+		if i > 0 {
+			sb.WriteString("\n        ")
+		}
+		fmt.Fprintf(&sb, `<button class="swatch" data-accent="%s" style="background: %s" aria-label="Accent %s"></button>`, a, a, name)
+	}
+	return sb.String()
+}
+
+// openGraph returns the meta tags that link previews are built from.
+func openGraph(b Branding, title, path string) string {
+	tags := [][2]string{{"og:site_name", b.Name}, {"og:type", "website"}, {"og:title", title}}
+	if b.BaseURL != "" {
+		tags = append(tags, [2]string{"og:url", b.BaseURL + path})
+	}
+	if image := absoluteURL(b, b.LogoURL); image != "" {
+		tags = append(tags, [2]string{"og:image", image})
+	}
+	var sb strings.Builder
+	for i, t := range tags {
+		if i > 0 {
+			sb.WriteString("\n    ")
+		}
+		fmt.Fprintf(&sb, `<meta property="%s" content="%s" />`, t[0], template.HTMLEscapeString(t[1]))
+	}
+	return sb.String()
+}
+
+// absoluteURL resolves a path on this instance against its base URL, which
+// previews need; it is empty when that is not possible.
+func absoluteURL(b Branding, ref string) string {
+	if ref == "" || strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") {
+		return ref
+	}
+	if b.BaseURL == "" {
+		return ""
+	}
+	return b.BaseURL + ref
+}
+
+// manifestName is the instance name as a JSON string for the web app
+// manifest.
+func manifestName() string {
+	data, _ := json.Marshal(branding.Name)
+	return string(data)
+}
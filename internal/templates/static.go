@@ -0,0 +1,81 @@
+package templates
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// The pages' scripts and styles live under static/ and are served at
+// /static/ under names carrying a hash of their content, so browsers may keep
+// them for good: a new release changes the name, not the file behind it.
+var (
+	assetsOnce sync.Once
+	hashed     map[string]string // "game.js" -> "/static/game.1a2b3c4d5e.js"
+	unhashed   map[string]string // "game.1a2b3c4d5e.js" -> "static/game.js"
+)
+
+var assetRef = regexp.MustCompile(`\{\{ASSET ([\w.-]+)\}\}`)
+
+func loadAssets() {
+	hashed = make(map[string]string)
+	unhashed = make(map[string]string)
+	entries, _ := fs.ReadDir(files, "static")
+	for _, e := range entries {
+		content, err := files.ReadFile("static/" + e.Name())
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(content)
+		ext := path.Ext(e.Name())
+		name := strings.TrimSuffix(e.Name(), ext) + "." + hex.EncodeToString(sum[:5]) + ext
+		hashed[e.Name()] = "/static/" + name
+		unhashed[name] = "static/" + e.Name()
+	}
+}
+
+// AssetPath returns the path a static asset is served at, or "" if there is
+// no such asset.
+func AssetPath(name string) string {
+	assetsOnce.Do(loadAssets)
+	return hashed[name]
+}
+
+// withAssets replaces each {{ASSET name}} with the asset's hashed path.
+func withAssets(s string) string {
+	return assetRef.ReplaceAllStringFunc(s, func(ref string) string {
+		return AssetPath(assetRef.FindStringSubmatch(ref)[1])
+	})
+}
+
+// WriteStatic serves a static asset by its hashed name, cached for a year.
+// Names from an older or newer build are not found.
+func WriteStatic(w http.ResponseWriter, name string) {
+	assetsOnce.Do(loadAssets)
+	file, ok := unhashed[name]
+	if !ok {
+		http.Error(w, "404 page not found", http.StatusNotFound)
+		return
+	}
+	content, err := files.ReadFile(file)
+	if err != nil {
+		http.Error(w, "Template not found", http.StatusInternalServerError)
+		return
+	}
+	contentType := "text/plain; charset=utf-8"
+	switch path.Ext(name) {
+	case ".css":
+		contentType = "text/css; charset=utf-8"
+	case ".js":
+		contentType = "text/javascript; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(content)
+}
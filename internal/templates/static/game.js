@@ -0,0 +1,1453 @@
+(function () {
+  const START_FEN =
+    "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1";
+
+  // ---- IDs / elements ----
+  const USER_ID_KEY = "tinychess:userId";
+  const CLIENT_ID_KEY = "tinychess:clientId";
+  function generateId() {
+    if (window.crypto && typeof window.crypto.randomUUID === "function") {
+      return window.crypto.randomUUID();
+    }
+    const tpl = "xxxxxxxx-xxxx-4xxx-yxxx-xxxxxxxxxxxx";
+    return tpl.replace(/[xy]/g, function (c) {
+      const r = (Math.random() * 16) | 0;
+      const v = c === "x" ? r : (r & 0x3) | 0x8;
+      return v.toString(16);
+    });
+  }
+  function ensureClientId() {
+    let id = "";
+    try {
+      id = localStorage.getItem(USER_ID_KEY) || "";
+    } catch {}
+    if (!id) {
+      try {
+        id = sessionStorage.getItem(CLIENT_ID_KEY) || "";
+      } catch {}
+    }
+    if (!id) {
+      id = generateId();
+    }
+    try {
+      localStorage.setItem(USER_ID_KEY, id);
+    } catch {}
+    try {
+      sessionStorage.setItem(CLIENT_ID_KEY, id);
+    } catch {}
+    return id;
+  }
+  let clientId = ensureClientId();
+  // Other watchers know this client by its participant ID, which the
+  // server sends with the first state.
+  let participantId = "";
+  // The page names its game; fall back to the path if it does not.
+  const gameId =
+    document.body.dataset.gameId || location.pathname.replace(/^\/+/, "");
+  // Games relayed from another instance are read-only and stay out of
+  // the local recent list.
+  const remoteHost = document.body.dataset.remoteHost || "";
+  // The server hands seated players a token that must accompany moves
+  // and seat changes; it is kept so the home page can forget the game.
+  function seatKey(id) {
+    return "tinychess:" + String(id || "") + ":seat:v1";
+  }
+  let seatToken = "";
+  try {
+    seatToken = localStorage.getItem(seatKey(gameId)) || "";
+  } catch {}
+  const boardEl = document.getElementById("board");
+  const statusEl = document.getElementById("status");
+  const turnEl = document.getElementById("turn");
+  const presenceEl = document.getElementById("presence");
+  const trainingEl = document.getElementById("training");
+
+  // Sends the display name chosen on the home page once seated, unless
+  // the game already shows it.
+  let nameSent = false;
+  function sendName(st) {
+    if (nameSent || isSpectator || remoteHost || !playerColor) return;
+    let name = "";
+    try {
+      name = localStorage.getItem("tinychess:name:v1") || "";
+    } catch (e) {}
+    const shown = playerColor === "white" ? st.white : st.black;
+    if (!name || name === shown) return;
+    nameSent = true;
+    fetch("/api/games/" + gameId + "/name", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        clientId: clientId,
+        seatToken: seatToken,
+        name: name,
+      }),
+    }).catch(function () {});
+  }
+
+  // Shows who is seated and whether they are connected, plus the
+  // number of spectators, from the participant list.
+  function renderPresence(list) {
+    if (!presenceEl || !Array.isArray(list)) return;
+    const players = list.filter(function (p) {
+      return p.role === "player";
+    });
+    const spectators = list.length - players.length;
+    const parts = players.map(function (p) {
+      const who =
+        p.id === participantId
+          ? "You"
+          : p.name || (p.color === "w" ? "White" : "Black");
+      return who + (p.connected ? " ●" : " ○ away");
+    });
+    if (spectators > 0)
+      parts.push(spectators + (spectators === 1 ? " spectator" : " spectators"));
+    presenceEl.textContent = parts.join(" · ");
+    const pairBtn = document.getElementById("pair");
+    if (pairBtn)
+      pairBtn.style.display =
+        !isSpectator && !gameOver && !remoteHost && players.length < 2 ? "" : "none";
+    if (players.length >= 2) {
+      const pairDialog = document.getElementById("pairDialog");
+      if (pairDialog && pairDialog.open) pairDialog.close();
+    }
+  }
+  // Shows endgame practice progress: how many moves were the
+  // tablebase's fastest win, and whether the win is still on the board.
+  function renderTraining(tr) {
+    if (!trainingEl) return;
+    trainingEl.hidden = !tr;
+    if (!tr) return;
+    let text = tr.ending + " practice";
+    if (tr.moves > 0)
+      text +=
+        " · " + tr.optimal + "/" + tr.moves + " optimal (" +
+        Math.round(tr.accuracy) + "%)";
+    if (!tr.held) text += " · the win slipped away";
+    trainingEl.textContent = text;
+  }
+  const pgnEl = document.getElementById("pgn");
+  const movesEl = document.querySelector(".moves");
+  const lanEl = document.getElementById("lan");
+  const capWhiteEl = document.getElementById("cap_by_white");
+  const capBlackEl = document.getElementById("cap_by_black");
+  const rxEl = document.getElementById("rx");
+  const reactBtn = document.getElementById("reactbtn");
+  const emojiDialog = document.getElementById("emojiDialog");
+  const emojiPicker = document.getElementById("emojiPicker");
+  const recentEl = document.getElementById("recent-emojis");
+  const RECENT_EMOJI_KEY = "tinychess:recentEmojis:v1";
+
+  // Orientation (default white; updated from server message)
+  let playerColor = "white";
+  let playerColorSet = false;
+  let isSpectator = !!remoteHost;
+  let gameOver = false;
+  let prevCaptured = { byWhite: [], byBlack: [] };
+
+  function loadRecent() {
+    try {
+      return JSON.parse(localStorage.getItem(RECENT_EMOJI_KEY) || "[]");
+    } catch {
+      return [];
+    }
+  }
+  function saveRecent(list) {
+    try {
+      localStorage.setItem(RECENT_EMOJI_KEY, JSON.stringify(list));
+    } catch {}
+  }
+  function renderRecent(list) {
+    if (!recentEl) return;
+    recentEl.innerHTML = "";
+    for (const em of list) {
+      const b = document.createElement("button");
+      b.className = "react";
+      b.textContent = em;
+      b.addEventListener("click", () => sendReaction(em, b));
+      recentEl.appendChild(b);
+    }
+  }
+  function rememberEmoji(em) {
+    let arr = loadRecent();
+    arr = arr.filter((x) => x !== em);
+    arr.unshift(em);
+    if (arr.length > 15) arr = arr.slice(0, 15);
+    saveRecent(arr);
+    renderRecent(arr);
+  }
+  renderRecent(loadRecent());
+
+  function normalizeColor(c) {
+    if (!c) return "white";
+    const v = String(c).toLowerCase();
+    if (v === "w" || v === "white") return "white";
+    if (v === "b" || v === "black") return "black";
+    return "white";
+  }
+
+  // Theme picker
+  const root = document.documentElement;
+  let theme = localStorage.getItem("theme") || "dark";
+  let accent =
+    localStorage.getItem("accent") ||
+    getComputedStyle(root).getPropertyValue("--accent").trim() ||
+    "#6ee7ff";
+  root.setAttribute("data-theme", theme);
+  root.style.setProperty("--accent", accent);
+  function markActive() {
+    var sw = document.querySelectorAll(".swatch");
+    for (var i = 0; i < sw.length; i++) {
+      if (sw[i].getAttribute("data-accent") === accent)
+        sw[i].classList.add("active");
+      else sw[i].classList.remove("active");
+    }
+    var md = document.querySelectorAll(".mode");
+    for (var j = 0; j < md.length; j++) {
+      if (md[j].getAttribute("data-theme") === theme)
+        md[j].classList.add("active");
+      else md[j].classList.remove("active");
+    }
+  }
+  markActive();
+  document.addEventListener("click", (e) => {
+    const t = e.target;
+    if (t.matches(".swatch")) {
+      accent = t.getAttribute("data-accent");
+      root.style.setProperty("--accent", accent);
+      localStorage.setItem("accent", accent);
+      markActive();
+    } else if (t.matches(".mode")) {
+      theme = t.getAttribute("data-theme");
+      root.setAttribute("data-theme", theme);
+      localStorage.setItem("theme", theme);
+      markActive();
+    }
+  });
+
+  // ----- Pieces -----
+  const glyph = {
+    P: "\u2659",
+    N: "\u2658",
+    B: "\u2657",
+    R: "\u2656",
+    Q: "\u2655",
+    K: "\u2654",
+    p: "\u265F",
+    n: "\u265E",
+    b: "\u265D",
+    r: "\u265C",
+    q: "\u265B",
+    k: "\u265A",
+  };
+  let selected = null;
+  let targets = []; // legal destinations of the selected piece
+  let lastMoveSquares = []; // [from, to]
+
+  // Reactions
+  const COOLDOWN_MS = 5000;
+  let lastReact = 0;
+  if (reactBtn && emojiDialog && emojiPicker) {
+    reactBtn.addEventListener("click", function () {
+      emojiDialog.showModal();
+    });
+    emojiPicker.addEventListener("emoji-click", function (ev) {
+      emojiDialog.close();
+      sendReaction(ev.detail.unicode, reactBtn);
+    });
+  }
+
+  function showReaction(e) {
+    // Big flash (center screen)
+    const big = document.createElement("div");
+    big.textContent = e;
+    big.className = "big-emoji";
+    document.body.appendChild(big);
+    setTimeout(() => big.remove(), 1200);
+
+    // Small burst under reactions area
+    if (rxEl) {
+      const small = document.createElement("span");
+      small.textContent = e;
+      small.className = "burst";
+      rxEl.appendChild(small);
+      setTimeout(() => small.remove(), 1600);
+    }
+  }
+
+  async function sendReaction(emoji, btn) {
+    rememberEmoji(emoji);
+    if (!gameId) return;
+    const now = Date.now();
+    if (now - lastReact < COOLDOWN_MS) {
+      if (btn) {
+        btn.style.background = "var(--err)";
+        setTimeout(() => (btn.style.background = ""), 600);
+      }
+      status("Hold up… cooldown", true);
+      return;
+    }
+    lastReact = now;
+    if (btn) {
+      btn.disabled = true;
+      setTimeout(() => (btn.disabled = false), COOLDOWN_MS);
+    }
+
+    try {
+      const res = await fetch("/react/" + gameId, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ emoji: emoji, sender: clientId }),
+      });
+      const j = await res.json();
+      if (!j.ok) {
+        if (btn) {
+          btn.style.background = "var(--err)";
+          setTimeout(() => (btn.style.background = ""), 600);
+        }
+        status(j.error || "reaction failed", true);
+      } else {
+        if (btn) {
+          btn.style.background = "var(--ok)";
+          setTimeout(() => (btn.style.background = ""), 600);
+        }
+        // Show locally immediately so the sender also sees it
+        showReaction(emoji);
+      }
+    } catch (_) {
+      if (btn) {
+        btn.style.background = "var(--err)";
+        setTimeout(() => (btn.style.background = ""), 600);
+      }
+    }
+  }
+
+
+  // --- board helpers ---
+  function cellSquare(row, col) {
+    if (playerColor === "black") {
+      const file = String.fromCharCode("a".charCodeAt(0) + (7 - col));
+      const rank = String(row + 1);
+      return file + rank;
+    }
+    const file = String.fromCharCode("a".charCodeAt(0) + col);
+    const rank = String(8 - row);
+    return file + rank;
+  }
+
+  function renderFEN(fen) {
+    const board = fen.split(" ")[0].split("/");
+    boardEl.innerHTML = "";
+
+    for (let r = 0; r < 8; r++) {
+      const row = document.createElement("div");
+      row.className = "rank";
+      const fenRank = board[playerColor === "black" ? 7 - r : r];
+      const cells = [];
+
+      for (let i = 0; i < fenRank.length; i++) {
+        const ch = fenRank[i];
+        if (/\d/.test(ch)) {
+          const n = parseInt(ch, 10);
+          for (let k = 0; k < n; k++) cells.push("");
+        } else {
+          cells.push(ch);
+        }
+      }
+
+      for (let c = 0; c < 8; c++) {
+        const piece = cells[playerColor === "black" ? 7 - c : c] || "";
+        const cell = document.createElement("div");
+        cell.className = "cell " + ((r + c) % 2 === 1 ? "light" : "dark"); // a8 dark
+        const sq = cellSquare(r, c);
+        cell.dataset.square = sq;
+
+        if (piece) {
+          cell.dataset.piece = piece;
+          const isWhite = piece === piece.toUpperCase();
+          cell.textContent = glyph[piece] || "";
+          cell.classList.add(isWhite ? "white-piece" : "black-piece");
+        } else {
+          cell.textContent = "";
+        }
+
+        // coordinates
+        if (
+          (playerColor === "white" && r === 7) ||
+          (playerColor === "black" && r === 0)
+        ) {
+          const f = document.createElement("span");
+          f.className = "coord coord-file";
+          f.textContent = sq[0];
+          cell.appendChild(f);
+        }
+        if (
+          (playerColor === "white" && c === 0) ||
+          (playerColor === "black" && c === 7)
+        ) {
+          const rr = document.createElement("span");
+          rr.className = "coord coord-rank";
+          rr.textContent = sq[1];
+          cell.appendChild(rr);
+        }
+
+        if (selected && sq === selected) cell.classList.add("sel");
+        markTarget(cell, sq);
+        if (
+          lastMoveSquares.length &&
+          (sq === lastMoveSquares[0] || sq === lastMoveSquares[1])
+        ) {
+          cell.classList.add("last-move");
+        }
+        row.appendChild(cell);
+      }
+      boardEl.appendChild(row);
+    }
+  }
+
+  function markTarget(cell, sq) {
+    const t = targets.find(function (t) {
+      return t.to === sq;
+    });
+    cell.classList.toggle("target", !!t);
+    cell.classList.toggle("capture", !!(t && t.capture));
+  }
+
+  function renderSelected() {
+    if (!selected) targets = [];
+    document.querySelectorAll(".cell").forEach(function (el) {
+      el.classList.toggle("sel", el.dataset.square === selected);
+      markTarget(el, el.dataset.square);
+    });
+  }
+
+  // The server knows the rules: ask it where the selected piece may
+  // go and highlight those squares.
+  async function loadTargets(from) {
+    const params = new URLSearchParams({ from: from, userId: clientId });
+    const code = new URLSearchParams(location.search).get("code");
+    if (code) params.set("code", code);
+    try {
+      const res = await fetch(
+        "/api/games/" + gameId + "/legal?" + params.toString()
+      );
+      const data = await res.json().catch(() => null);
+      if (!data || !data.ok || selected !== from) return;
+      targets = data.targets || [];
+      renderSelected();
+    } catch (e) {}
+  }
+
+  // Ask which piece a pawn promotes to; resolves to "" if cancelled.
+  const promoDialog = document.getElementById("promoDialog");
+  function choosePromotion(white) {
+    return new Promise((resolve) => {
+      promoDialog.querySelectorAll("button").forEach((btn) => {
+        const p = white ? btn.value.toUpperCase() : btn.value;
+        btn.textContent = glyph[p];
+      });
+      promoDialog.returnValue = "";
+      promoDialog.addEventListener(
+        "close",
+        () => resolve(promoDialog.returnValue),
+        { once: true }
+      );
+      promoDialog.showModal();
+    });
+  }
+
+  function makeMove(uci, promotion) {
+    console.log("Attempting move:", uci, promotion || "");
+    return sendMove({ uci: uci, promotion: promotion || undefined });
+  }
+
+  // During maintenance the game is read-only; the banner says why.
+  let frozen = false;
+  const maintenanceEl = document.getElementById("maintenance");
+  function showMaintenance(m) {
+    frozen = !!(m && m.active);
+    maintenanceEl.hidden = !frozen;
+    maintenanceEl.textContent = frozen
+      ? "Maintenance: moves are paused and clocks are stopped." +
+        (m.message ? " " + m.message : "")
+      : "";
+  }
+
+  function newMoveId() {
+    if (crypto.randomUUID) return crypto.randomUUID();
+    return Date.now().toString(36) + Math.random().toString(36).slice(2);
+  }
+
+  // Moves typed in SAN are decoded by the server. Each move gets an id
+  // so the service worker can replay it after a dropped connection
+  // without the server playing it twice.
+  async function sendMove(fields) {
+    if (!gameId) {
+      status("No game id");
+      return false;
+    }
+    try {
+      const res = await fetch("/move/" + gameId, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(
+          Object.assign(
+            { clientId: clientId, seatToken: seatToken, moveId: newMoveId() },
+            fields
+          )
+        ),
+      });
+      const j = await res.json();
+      if (j.queued) {
+        status("Offline: your move will be sent when you reconnect");
+        return true;
+      }
+      if (j.code === "maintenance") {
+        showMaintenance(j.maintenance);
+        return false;
+      }
+      if (!j.ok) {
+        console.log("Move failed:", j.error);
+        status("Illegal move: " + (j.error || "unknown"), true);
+        return false;
+      }
+      console.log("Move successful");
+      return true;
+    } catch (err) {
+      console.log("Network error:", err);
+      status("Network error", true);
+      return false;
+    }
+  }
+
+  if ("serviceWorker" in navigator && !remoteHost) {
+    navigator.serviceWorker.register("/sw.js").catch(function (err) {
+      console.log("Service worker registration failed:", err);
+    });
+    // Queued moves go out as soon as the browser is back online, also
+    // where Background Sync is unavailable.
+    window.addEventListener("online", function () {
+      if (navigator.serviceWorker.controller)
+        navigator.serviceWorker.controller.postMessage("flush");
+    });
+    navigator.serviceWorker.addEventListener("message", function (ev) {
+      const m = ev.data || {};
+      if (m.kind !== "moveSynced" || !m.url.endsWith("/move/" + gameId)) return;
+      status(m.ok ? "" : "Queued move failed: " + (m.error || "unknown"), !m.ok);
+    });
+  }
+
+  const moveForm = document.getElementById("moveform");
+  const moveInput = document.getElementById("moveinput");
+  if (moveForm && moveInput) {
+    moveForm.addEventListener("submit", async function (e) {
+      e.preventDefault();
+      const san = moveInput.value.trim();
+      if (!san || isSpectator || gameOver || viewing || frozen) return;
+      if (await sendMove({ san: san })) moveInput.value = "";
+    });
+  }
+
+  // Board-level click handler
+  boardEl.addEventListener("click", async (e) => {
+    if (isSpectator || gameOver || viewing || frozen) return;
+    const rect = boardEl.getBoundingClientRect();
+    const x = Math.min(
+      Math.max(0, e.clientX - rect.left),
+      rect.width - 0.01
+    );
+    const y = Math.min(
+      Math.max(0, e.clientY - rect.top),
+      rect.height - 0.01
+    );
+    let col = Math.floor((x / rect.width) * 8);
+    let row = Math.floor((y / rect.height) * 8);
+    const sq = cellSquare(row, col); // uses playerColor for orientation
+
+    console.log("Click at row:", row, "col:", col, "square:", sq);
+
+    if (!selected) {
+      selected = sq;
+      console.log("Selected:", sq);
+      renderSelected();
+      loadTargets(sq);
+      return;
+    }
+    if (selected === sq) {
+      selected = null;
+      console.log("Deselected");
+      renderSelected();
+      return;
+    }
+    const uci = (selected + sq).toLowerCase();
+    console.log("Making move from", selected, "to", sq, "UCI:", uci);
+    const fromCell = boardEl.querySelector(
+      '[data-square="' + selected + '"]'
+    );
+    const moving = (fromCell && fromCell.dataset.piece) || "";
+    selected = null;
+    renderSelected();
+    let promotion = "";
+    if (moving.toLowerCase() === "p" && /[18]$/.test(sq)) {
+      promotion = await choosePromotion(moving === "P");
+      if (!promotion) return;
+    }
+    makeMove(uci, promotion);
+  });
+
+  // moveLimitNote tells how far a game with a move limit is from
+  // being adjudicated.
+  function moveLimitNote(st) {
+    const limit = st.houseRules && st.houseRules.moveLimit;
+    if (!limit) return "";
+    const played = Math.floor(((st.uci || []).length + 1) / 2);
+    const by = st.houseRules.adjudicate === "eval" ? "engine" : "material";
+    return "Move " + played + " of " + limit + ", then decided by " + by;
+  }
+  function status(msg, isErr) {
+    statusEl.textContent = msg || "";
+    statusEl.style.color = isErr ? "var(--err)" : "inherit";
+  }
+
+  function updateTurn(st) {
+    if (!turnEl) return;
+    if (st.status) {
+      turnEl.textContent = "Game over";
+      return;
+    }
+    const t = normalizeColor(st.turn);
+    if (isSpectator || st.postMortemOf) {
+      turnEl.textContent = t || "";
+    } else if (t === playerColor) {
+      turnEl.textContent = "Your turn";
+    } else {
+      turnEl.textContent = "Their turn";
+    }
+  }
+
+  // ---- Captured pieces (derived from FEN) + persisted per game ----
+  var startCounts = {
+    P: 8,
+    N: 2,
+    B: 2,
+    R: 2,
+    Q: 1,
+    K: 1,
+    p: 8,
+    n: 2,
+    b: 2,
+    r: 2,
+    q: 1,
+    k: 1,
+  };
+
+  function countsFromFEN(fen) {
+    var boardOnly = fen.split(" ")[0];
+    var c = {
+      P: 0,
+      N: 0,
+      B: 0,
+      R: 0,
+      Q: 0,
+      K: 0,
+      p: 0,
+      n: 0,
+      b: 0,
+      r: 0,
+      q: 0,
+      k: 0,
+    };
+    for (var i = 0; i < boardOnly.length; i++) {
+      var ch = boardOnly[i];
+      if (/[prnbqkPRNBQK]/.test(ch)) c[ch] = (c[ch] || 0) + 1;
+    }
+    return c;
+  }
+
+  function capturedFromFEN(fen) {
+    var cur = countsFromFEN(fen);
+
+    var lostWhite = { P: 0, N: 0, B: 0, R: 0, Q: 0, K: 0 };
+    for (var k in lostWhite) {
+      lostWhite[k] = Math.max(0, (startCounts[k] || 0) - (cur[k] || 0));
+    }
+
+    var lostBlack = { p: 0, n: 0, b: 0, r: 0, q: 0, k: 0 };
+    for (var k2 in lostBlack) {
+      lostBlack[k2] = Math.max(
+        0,
+        (startCounts[k2] || 0) - (cur[k2] || 0)
+      );
+    }
+
+    var byWhite = [];
+    var byBlack = [];
+
+    for (var k3 in lostBlack) {
+      for (var i = 0; i < lostBlack[k3]; i++) byWhite.push(glyph[k3]);
+    }
+    for (var k4 in lostWhite) {
+      for (var j = 0; j < lostWhite[k4]; j++) byBlack.push(glyph[k4]);
+    }
+    return { byWhite: byWhite, byBlack: byBlack };
+  }
+
+  // The server tracks captures per move, which stays correct after
+  // promotions; FEN counting is only a fallback for older servers.
+  function capturedFromState(captured) {
+    return {
+      byWhite: (captured.byWhite || []).map(function (p) {
+        return glyph[p];
+      }),
+      byBlack: (captured.byBlack || []).map(function (p) {
+        return glyph[p.toUpperCase()];
+      }),
+    };
+  }
+
+  // --- formatting helpers ---
+  // The server sends full PGN including tag pairs; only the movetext is shown.
+  function pgnMovetext(pgn) {
+    return (pgn || "").replace(/^\[.*\]\s*$/gm, "").trim();
+  }
+
+  function formatPGNLines(pgn) {
+    pgn = pgnMovetext(pgn);
+    if (!pgn) return "";
+    const tokens = pgn.split(/\s+/);
+    const lines = [];
+    let line = [];
+    for (let i = 0; i < tokens.length; i++) {
+      const t = tokens[i];
+      if (/^\d+\.$/.test(t)) {
+        if (line.length) lines.push(line.join(" "));
+        line = [t];
+      } else if (/^(1-0|0-1|1\/2-1\/2|\*)$/.test(t)) {
+        if (line.length) {
+          lines.push(line.join(" "));
+          line = [];
+        }
+      } else {
+        line.push(t);
+      }
+    }
+    if (line.length) lines.push(line.join(" "));
+    return lines.join("\n");
+  }
+
+  function formatUCIMoves(uciList) {
+    if (!uciList || !uciList.length) return "";
+    let out = [];
+    for (let i = 0, n = 1; i < uciList.length; i += 2, n++) {
+      const w = uciList[i] || "";
+      const b = uciList[i + 1] || "";
+      out.push(b ? n + ". " + w + " " + b : n + ". " + w);
+    }
+    return out.join("\n");
+  }
+
+  function renderCaptured(byWhite, byBlack) {
+    capWhiteEl.textContent = "";
+    capBlackEl.textContent = "";
+    for (var i = 0; i < byWhite.length; i++) {
+      var s1 = document.createElement("span");
+      s1.textContent = byWhite[i];
+      s1.classList.add("black-piece");
+      if (i >= prevCaptured.byWhite.length) s1.classList.add("recent");
+      capWhiteEl.appendChild(s1);
+    }
+    for (var j = 0; j < byBlack.length; j++) {
+      var s2 = document.createElement("span");
+      s2.textContent = byBlack[j];
+      s2.classList.add("white-piece");
+      if (j >= prevCaptured.byBlack.length) s2.classList.add("recent");
+      capBlackEl.appendChild(s2);
+    }
+    prevCaptured.byWhite = byWhite.slice();
+    prevCaptured.byBlack = byBlack.slice();
+  }
+
+  function capKey(id) {
+    return "tinychess:" + String(id || "") + ":captured:v1";
+  }
+
+  // Prefill from storage to avoid blank on reload
+  try {
+    var saved = JSON.parse(
+      localStorage.getItem(capKey(gameId)) || "null"
+    );
+    if (saved && saved.byWhite && saved.byBlack)
+      renderCaptured(saved.byWhite, saved.byBlack);
+  } catch (e) {}
+
+  const releaseBtn = document.getElementById("release");
+  if (releaseBtn)
+    releaseBtn.addEventListener("click", async () => {
+      if (!gameId || !clientId) return;
+      try {
+        const resp = await fetch("/release/" + gameId, {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({
+            clientId: clientId,
+            targetId: clientId,
+            seatToken: seatToken,
+          }),
+        });
+        const data = await resp.json().catch(() => null);
+        if (data && data.ok) {
+          isSpectator = true;
+          playerColorSet = false;
+          releaseBtn.style.display = "none";
+          status("Seat released");
+        } else {
+          status("Release failed", true);
+        }
+      } catch (e) {
+        status("Release failed", true);
+      }
+    });
+  // ----- Post-game analysis -----
+  const analysisEl = document.getElementById("analysis");
+  let analysisPending = false;
+  let analysisDone = false;
+  function renderAnalysis(report) {
+    const side = function (label, s) {
+      return (
+        "<div class=\"row\"><strong>" +
+        label +
+        ":</strong> " +
+        s.accuracy +
+        "% accuracy · " +
+        s.inaccuracies +
+        " inaccuracies · " +
+        s.mistakes +
+        " mistakes · " +
+        s.blunders +
+        " blunders</div>"
+      );
+    };
+    // Tablebase verdicts are exact, so in endgames they replace the
+    // engine's flag whenever a move changed the result.
+    const tbChanged = function (m) {
+      return m.tablebase && m.tablebase.before !== m.tablebase.after;
+    };
+    const flagged = (report.moves || [])
+      .filter(function (m) {
+        return m.classification || tbChanged(m);
+      })
+      .map(function (m) {
+        const num =
+          Math.ceil(m.ply / 2) + (m.color === "w" ? ". " : "... ");
+        if (tbChanged(m))
+          return (
+            '<div class="blunder">' +
+            num +
+            m.san +
+            " – tablebase: " +
+            m.tablebase.before +
+            " → " +
+            m.tablebase.after +
+            (m.tablebase.bestMove
+              ? " (best " + m.tablebase.bestMove + ")"
+              : "") +
+            "</div>"
+          );
+        return (
+          '<div class="' +
+          m.classification +
+          '">' +
+          num +
+          m.san +
+          " – " +
+          m.classification +
+          " (best " +
+          m.bestMove +
+          ")</div>"
+        );
+      })
+      .join("");
+    analysisEl.innerHTML =
+      "<strong>Analysis</strong>" +
+      side("White", report.white) +
+      side("Black", report.black) +
+      flagged;
+    analysisEl.hidden = false;
+  }
+  async function loadAnalysis() {
+    if (!analysisEl || analysisDone || analysisPending) return;
+    analysisPending = true;
+    try {
+      const resp = await fetch("/api/games/" + gameId + "/analysis");
+      const data = await resp.json().catch(() => null);
+      if (data && data.ok && data.status === "done") {
+        analysisDone = true;
+        renderAnalysis(data.analysis);
+      } else if (!data || !data.ok) {
+        analysisDone = true;
+      } else {
+        analysisEl.innerHTML = "<strong>Analysis</strong> in progress…";
+        analysisEl.hidden = false;
+      }
+    } catch (e) {}
+    setTimeout(function () {
+      analysisPending = false;
+      loadAnalysis();
+    }, 5000);
+  }
+
+  const hintBtn = document.getElementById("hint");
+  if (hintBtn)
+    hintBtn.addEventListener("click", async () => {
+      if (!gameId || !clientId) return;
+      try {
+        const resp = await fetch("/api/games/" + gameId + "/hint", {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ clientId: clientId, seatToken: seatToken }),
+        });
+        const data = await resp.json().catch(() => null);
+        if (data && data.ok) {
+          status("Hint: " + data.hint.san);
+        } else if (resp.status === 503) {
+          hintBtn.style.display = "none";
+        } else {
+          status((data && data.error) || "Hint failed", true);
+        }
+      } catch (e) {
+        status("Hint failed", true);
+      }
+    });
+  const chatLog = document.getElementById("chatlog");
+  const chatForm = document.getElementById("chatform");
+  const chatInput = document.getElementById("chatinput");
+  let isOwner = false;
+  function showChat(msg) {
+    if (!chatLog) return;
+    if (msg.id && chatLog.querySelector('[data-id="' + msg.id + '"]')) return;
+    const line = document.createElement("div");
+    if (msg.id) line.dataset.id = msg.id;
+    const who = document.createElement("span");
+    who.className = "who";
+    if (msg.sender === participantId) who.textContent = "You:";
+    else if (msg.color === "w") who.textContent = "White:";
+    else if (msg.color === "b") who.textContent = "Black:";
+    else who.textContent = "Spectator:";
+    line.appendChild(who);
+    line.appendChild(document.createTextNode(msg.text || ""));
+    if (isOwner && msg.sender !== participantId) {
+      line.appendChild(modButton("✕", "Delete message", "delete", { messageId: msg.id }));
+      line.appendChild(modButton("🔇", "Mute for this game", "mute", { targetId: msg.sender }));
+    }
+    chatLog.appendChild(line);
+    chatLog.scrollTop = chatLog.scrollHeight;
+  }
+  function modButton(label, title, action, fields) {
+    const b = document.createElement("button");
+    b.className = "mod";
+    b.textContent = label;
+    b.title = title;
+    b.addEventListener("click", async () => {
+      try {
+        const resp = await fetch("/chat/" + gameId + "/" + action, {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify(
+            Object.assign({ clientId: clientId, seatToken: seatToken }, fields),
+          ),
+        });
+        const data = await resp.json().catch(() => null);
+        if (!data || !data.ok) {
+          status((data && data.error) || "Moderation failed", true);
+        } else if (action === "mute") {
+          status("Participant muted");
+        }
+      } catch (e) {
+        status("Moderation failed", true);
+      }
+    });
+    return b;
+  }
+  if (chatForm)
+    chatForm.addEventListener("submit", async (ev) => {
+      ev.preventDefault();
+      const text = chatInput.value.trim();
+      if (!gameId || !clientId || !text) return;
+      try {
+        const resp = await fetch("/chat/" + gameId, {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ text: text, sender: clientId }),
+        });
+        const data = await resp.json().catch(() => null);
+        if (data && data.ok) {
+          chatInput.value = "";
+        } else {
+          status((data && data.error) || "Chat failed", true);
+        }
+      } catch (e) {
+        status("Chat failed", true);
+      }
+    });
+  // After a decisive result the server locks reactions and chat for a
+  // while; the state says until when and is sent again once they reopen.
+  function renderCoolOff(until) {
+    const locked = !!until && until > Date.now();
+    if (reactBtn) reactBtn.disabled = locked;
+    if (recentEl)
+      for (const b of recentEl.querySelectorAll("button")) b.disabled = locked;
+    if (chatInput) {
+      chatInput.disabled = locked;
+      chatInput.placeholder = locked
+        ? "Chat reopens in a moment…"
+        : "Say something…";
+    }
+  }
+  // Pairing shows a short-lived code, and its link as a QR, that seats
+  // a phone in the same room without sending it the game URL.
+  const pairBtn = document.getElementById("pair");
+  const pairDialog = document.getElementById("pairDialog");
+  let pairTimer = null;
+  if (pairBtn && pairDialog)
+    pairBtn.addEventListener("click", async () => {
+      if (!gameId || !clientId) return;
+      try {
+        const resp = await fetch("/api/pair", {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({
+            gameId: gameId,
+            clientId: clientId,
+            seatToken: seatToken,
+          }),
+        });
+        const data = await resp.json().catch(() => null);
+        if (!data || !data.ok) {
+          status("Pairing failed: " + ((data && data.error) || "unknown"), true);
+          return;
+        }
+        const link = location.origin + data.url;
+        const qrEl = document.getElementById("pairqr");
+        if (window.qrcode) {
+          const qr = qrcode(0, "M");
+          qr.addData(link);
+          qr.make();
+          qrEl.innerHTML = qr.createSvgTag({ cellSize: 5, margin: 2 });
+        } else {
+          qrEl.textContent = link;
+        }
+        document.getElementById("paircode").textContent = data.code;
+        const expiryEl = document.getElementById("pairexpiry");
+        const expires = new Date(data.expiresAt).getTime();
+        clearInterval(pairTimer);
+        const tick = () => {
+          const left = Math.max(0, Math.round((expires - Date.now()) / 1000));
+          expiryEl.textContent = left
+            ? "Expires in " + Math.floor(left / 60) + ":" + String(left % 60).padStart(2, "0")
+            : "Expired";
+          if (!left) clearInterval(pairTimer);
+        };
+        tick();
+        pairTimer = setInterval(tick, 1000);
+        pairDialog.showModal();
+      } catch (e) {
+        status("Pairing failed", true);
+      }
+    });
+
+  const bookmarkBtn = document.getElementById("bookmark");
+  if (bookmarkBtn)
+    bookmarkBtn.addEventListener("click", async () => {
+      if (!gameId || !clientId) return;
+      try {
+        const resp = await fetch("/api/bookmarks", {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ userId: clientId, gameId: gameId }),
+        });
+        const data = await resp.json().catch(() => null);
+        if (data && data.ok) {
+          status("Saved to watch later");
+          setTimeout(() => status(""), 1200);
+        } else {
+          status("Bookmark failed", true);
+        }
+      } catch (e) {
+        status("Bookmark failed", true);
+      }
+    });
+  // Invite links handed out when this browser created the game: "Copy
+  // link" shares the opponent's seat, the second button spectating only.
+  let invites = {};
+  try {
+    invites =
+      JSON.parse(
+        localStorage.getItem("tinychess:" + gameId + ":invites:v1") || "{}"
+      ) || {};
+  } catch (e) {}
+  const spectateLink = invites.spectate;
+  const playLink = invites.white || invites.black;
+  function copyLink(path) {
+    return async () => {
+      try {
+        await navigator.clipboard.writeText(
+          path ? location.origin + path : location.href
+        );
+        status("Link copied!");
+        setTimeout(() => status(""), 1200);
+      } catch {
+        status("Copy failed", true);
+      }
+    };
+  }
+  document.getElementById("copy").addEventListener("click", copyLink(playLink));
+  if (spectateLink) {
+    const btn = document.getElementById("copyspectate");
+    btn.style.display = "";
+    btn.addEventListener("click", copyLink(spectateLink));
+  }
+
+  // ---- local game index (per-browser) ----
+  const INDEX_KEY = "tinychess:games:v1";
+  function loadIndex() {
+    try {
+      return JSON.parse(localStorage.getItem(INDEX_KEY) || "{}");
+    } catch {
+      return {};
+    }
+  }
+  function saveIndex(m) {
+    try {
+      localStorage.setItem(INDEX_KEY, JSON.stringify(m));
+    } catch {}
+  }
+  function rememberGame(id) {
+    if (!id || remoteHost) return;
+    var m = loadIndex();
+    if (!m[id])
+      m[id] = {
+        id: id,
+        createdAt: Date.now(),
+        lastSeen: Date.now(),
+        moves: 0,
+        result: null,
+        status: "",
+      };
+    else m[id].lastSeen = Date.now();
+    saveIndex(m);
+  }
+  function setGameState(id, fields) {
+    if (!id || remoteHost) return;
+    var m = loadIndex();
+    m[id] = Object.assign(
+      m[id] || { id: id, createdAt: Date.now() },
+      fields
+    );
+    saveIndex(m);
+  }
+  rememberGame(gameId);
+
+  // live updates
+  function deriveLastMoveSquares(uciList) {
+    if (!uciList || !uciList.length) return [];
+    const mv = uciList[uciList.length - 1] || "";
+    if (!mv) return [];
+    const base = mv.length >= 4 ? mv.slice(0, 4) : "";
+    if (base.length !== 4) return [];
+    return [base.slice(0, 2), base.slice(2, 4)];
+  }
+
+  // ---- shared moments ----
+  // A /game/{id}?ply=N link opens the game at that ply. The server puts
+  // the position in the page when the visitor may see it; otherwise it
+  // is asked for with the visitor's id. The board stays on it, and
+  // takes no moves, until "Back to live".
+  let viewing = null;
+  let liveState = null;
+  const replayEl = document.getElementById("replay");
+  const shareMoveBtn = document.getElementById("sharemove");
+  function renderBoard() {
+    if (viewing) {
+      lastMoveSquares = viewing.uci
+        ? [viewing.uci.slice(0, 2), viewing.uci.slice(2, 4)]
+        : [];
+      renderFEN(viewing.fen);
+    } else if (liveState) {
+      lastMoveSquares = liveState.lastMove
+        ? [liveState.lastMove.from, liveState.lastMove.to]
+        : deriveLastMoveSquares(liveState.uci || []);
+      renderFEN(liveState.fen);
+    }
+    const plies = liveState ? (liveState.uci || []).length : 0;
+    shareMoveBtn.style.display = viewing || plies > 0 ? "" : "none";
+  }
+  // plyLabel names the move that led to a position, numbered from its
+  // FEN so games from a custom position count right.
+  function plyLabel(pos) {
+    if (!pos.san) return "the starting position";
+    const fields = pos.fen.split(" ");
+    const full = parseInt(fields[5], 10) || 1;
+    return fields[1] === "b"
+      ? "move " + full + ". " + pos.san
+      : "move " + (full - 1) + "… " + pos.san;
+  }
+  function showPly(pos) {
+    viewing = pos;
+    replayEl.hidden = false;
+    document.getElementById("replaylabel").textContent =
+      "Viewing " + plyLabel(pos) + " (ply " + pos.ply + " of " + pos.plies + ")";
+    renderBoard();
+  }
+  document.getElementById("replaylive").addEventListener("click", function () {
+    viewing = null;
+    replayEl.hidden = true;
+    const url = new URL(location.href);
+    url.searchParams.delete("ply");
+    history.replaceState(null, "", url.pathname + url.search);
+    renderBoard();
+  });
+  shareMoveBtn.addEventListener("click", function () {
+    const ply = viewing ? viewing.ply : (liveState.uci || []).length;
+    copyLink("/game/" + gameId + "?ply=" + ply)();
+  });
+
+  // ---- post-mortem ----
+  // A finished game links to its post-mortem room, where the players
+  // (and spectators, when the instance allows it) move for either side
+  // and take the board back to explore other lines together.
+  const postMortemEl = document.getElementById("postmortem");
+  const takeBackBtn = document.getElementById("takeback");
+  const exploreBtn = document.getElementById("replayexplore");
+  function renderPostMortem(st) {
+    const target = st.postMortemOf || st.postMortem;
+    postMortemEl.hidden = !target || !!remoteHost;
+    postMortemEl.innerHTML = "";
+    if (target) {
+      if (st.postMortemOf)
+        postMortemEl.appendChild(
+          document.createTextNode("Post-mortem: either side may move. ")
+        );
+      const a = document.createElement("a");
+      a.href = "/" + target;
+      a.textContent = st.postMortemOf
+        ? "Back to the game"
+        : "Talk it over in the post-mortem room";
+      postMortemEl.appendChild(a);
+    }
+    const explorer = !!st.postMortemOf && !isSpectator;
+    takeBackBtn.style.display =
+      explorer && (st.uci || []).length > 0 ? "" : "none";
+    exploreBtn.hidden = !explorer;
+  }
+  async function rewind(ply) {
+    try {
+      const resp = await fetch("/api/games/" + gameId + "/rewind", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ clientId: clientId, seatToken: seatToken, ply: ply }),
+      });
+      const data = await resp.json().catch(() => null);
+      if (data && data.ok) return true;
+      status((data && data.error) || "Rewind failed", true);
+    } catch (e) {
+      status("Rewind failed", true);
+    }
+    return false;
+  }
+  takeBackBtn.addEventListener("click", function () {
+    if (liveState) rewind((liveState.uci || []).length - 1);
+  });
+  exploreBtn.addEventListener("click", async function () {
+    if (viewing && (await rewind(viewing.ply)))
+      document.getElementById("replaylive").click();
+  });
+
+  // Render start position immediately (prevents blank board)
+  renderFEN(START_FEN);
+  turnEl.textContent = "";
+  status("");
+  (function () {
+    let shared = null;
+    try {
+      shared = JSON.parse(document.getElementById("sharedply").textContent);
+    } catch {}
+    if (shared) {
+      showPly(shared);
+      return;
+    }
+    const q = new URLSearchParams(location.search);
+    const ply = q.get("ply");
+    if (!ply || !gameId || remoteHost) return;
+    const params = new URLSearchParams({ userId: clientId });
+    if (q.get("code")) params.set("code", q.get("code"));
+    fetch("/api/games/" + gameId + "/ply/" + encodeURIComponent(ply) + "?" + params)
+      .then(function (res) {
+        return res.json();
+      })
+      .then(function (j) {
+        if (j.ok) showPly(j.position);
+        else status("That move is not part of this game", true);
+      })
+      .catch(function () {});
+  })();
+
+  if (gameId) {
+    let sseURL = "/sse/" + gameId;
+    if (remoteHost) {
+      sseURL = "/remote/" + remoteHost + "/sse/" + gameId;
+      ["bookmark", "release", "hint", "reactbtn", "recent-emojis", "chat", "moveform"].forEach(
+        function (id) {
+          const el = document.getElementById(id);
+          if (el) el.style.display = "none";
+        }
+      );
+    } else {
+      const params = new URLSearchParams();
+      if (clientId) params.set("clientId", clientId);
+      if (seatToken) params.set("seatToken", seatToken);
+      const search = new URLSearchParams(location.search);
+      ["code", "invite"].forEach(function (k) {
+        const v = search.get(k);
+        if (v) params.set(k, v);
+      });
+      const qs = params.toString();
+      if (qs) sseURL += "?" + qs;
+    }
+    const es = new EventSource(sseURL);
+    es.onmessage = (ev) => {
+      const st = JSON.parse(ev.data || "{}");
+      if (st.kind === "emoji") {
+        if (st.sender !== participantId) showReaction(st.emoji);
+        return;
+      }
+      if (st.kind === "chat") {
+        showChat(st);
+        return;
+      }
+      if (st.kind === "chatDelete") {
+        const line =
+          chatLog && chatLog.querySelector('[data-id="' + st.id + '"]');
+        if (line) line.remove();
+        return;
+      }
+      // The instance is going away. The browser reconnects by itself
+      // after the retry delay; when another deployment takes over,
+      // the page moves there instead.
+      if (st.kind === "reconnect") {
+        status("Server restarting. Reconnecting…");
+        if (st.url && new URL(st.url).origin !== location.origin) {
+          es.close();
+          setTimeout(function () {
+            location.replace(
+              new URL(location.pathname + location.search + location.hash, st.url)
+            );
+          }, st.retryMs || 0);
+        }
+        return;
+      }
+      if (st.kind === "maintenance") {
+        showMaintenance(st);
+        return;
+      }
+      // A coach published the review this player asked for.
+      if (st.kind === "review") {
+        if (!isSpectator && normalizeColor(st.color) === playerColor)
+          status("Your coach's review of this game is ready.");
+        return;
+      }
+      if (st.kind === "notice") {
+        status(st.message || "");
+        return;
+      }
+      if (st.kind === "presence") {
+        renderPresence(st.participants);
+        return;
+      }
+      if (st.kind === "state") {
+        if (st.clientId) {
+          clientId = st.clientId;
+          try {
+            sessionStorage.setItem(CLIENT_ID_KEY, clientId);
+          } catch {}
+          try {
+            localStorage.setItem(USER_ID_KEY, clientId);
+          } catch {}
+        }
+        if (st.participantId) participantId = st.participantId;
+        if (st.role === "spectator") {
+          isSpectator = true;
+        }
+        if (st.owner) isOwner = true;
+        if (st.seatToken) {
+          seatToken = st.seatToken;
+          try {
+            localStorage.setItem(seatKey(gameId), seatToken);
+          } catch {}
+        }
+        if (!playerColorSet) {
+          playerColor = normalizeColor(st.color);
+          playerColorSet = true;
+        }
+        // Pass and play: the board turns to face whoever is to move.
+        if (st.hotSeat && !isSpectator)
+          playerColor = normalizeColor(st.turn);
+        if (releaseBtn)
+          releaseBtn.style.display = isSpectator ? "none" : "";
+        if (hintBtn && isSpectator) hintBtn.style.display = "none";
+        liveState = st;
+        renderBoard();
+        updateTurn(st);
+        renderPresence(st.participants);
+        renderTraining(st.training);
+        renderCoolOff(st.coolOffUntil);
+        renderPostMortem(st);
+        sendName(st);
+        pgnEl.textContent = formatPGNLines(st.pgn || "");
+        movesEl.style.display = pgnMovetext(st.pgn).replace(/\*$/, "")
+          ? "block"
+          : "none";
+        lanEl.textContent = formatUCIMoves(st.uci || []);
+        status(st.status || moveLimitNote(st));
+        gameOver = !!st.status;
+        if (gameOver && !remoteHost && !st.postMortemOf) loadAnalysis();
+        const caps = st.captured
+          ? capturedFromState(st.captured)
+          : capturedFromFEN(st.fen);
+        renderCaptured(caps.byWhite, caps.byBlack);
+        try {
+          localStorage.setItem(capKey(gameId), JSON.stringify(caps));
+        } catch {}
+
+        // Persist summary to recent list
+        var resultFromPGN = (function () {
+          var txt = pgnMovetext(st.pgn);
+          var m = txt.match(/\b(1-0|0-1|1\/2-1\/2|\*)\b\s*$/);
+          return m ? (m[1] === "*" ? null : m[1]) : null;
+        })();
+        var finishedNow =
+          !!resultFromPGN ||
+          (!!st.status && /(1-0|0-1|1\/2-1\/2)/.test(st.status || ""));
+        setGameState(gameId, {
+          moves: Array.isArray(st.uci) ? st.uci.length : 0,
+          status: st.status || "",
+          result:
+            resultFromPGN ||
+            (function () {
+              var m = (st.status || "").match(/(1-0|0-1|1\/2-1\/2)/);
+              return m ? m[1] : null;
+            })(),
+          finishedAt: finishedNow ? Date.now() : undefined,
+          lastSeen: st.lastSeen,
+          color: st.color ? normalizeColor(st.color) : null,
+          role: st.role || "",
+        });
+      }
+    };
+    es.onopen = () => {
+      status("");
+    };
+    es.onerror = () => {
+      status("Disconnected. Reconnecting…", true);
+    };
+  }
+})();
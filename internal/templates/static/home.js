@@ -0,0 +1,611 @@
+(function () {
+  if ("serviceWorker" in navigator) {
+    navigator.serviceWorker.register("/sw.js").catch(function (err) {
+      console.log("Service worker registration failed:", err);
+    });
+  }
+
+  const root = document.documentElement;
+  let theme = localStorage.getItem("theme") || "dark";
+  let accent =
+    localStorage.getItem("accent") ||
+    getComputedStyle(root).getPropertyValue("--accent").trim() ||
+    "#6ee7ff";
+  root.setAttribute("data-theme", theme);
+  root.style.setProperty("--accent", accent);
+
+  function markActive() {
+    document.querySelectorAll(".swatch").forEach((el) => {
+      el.classList.toggle(
+        "active",
+        el.getAttribute("data-accent") === accent
+      );
+    });
+    document.querySelectorAll(".mode").forEach((el) => {
+      el.classList.toggle(
+        "active",
+        el.getAttribute("data-theme") === theme
+      );
+    });
+  }
+  markActive();
+
+  document.addEventListener("click", (e) => {
+    const t = e.target;
+    if (t.matches(".swatch")) {
+      accent = t.getAttribute("data-accent");
+      root.style.setProperty("--accent", accent);
+      localStorage.setItem("accent", accent);
+      markActive();
+    } else if (t.matches(".mode")) {
+      theme = t.getAttribute("data-theme");
+      root.setAttribute("data-theme", theme);
+      localStorage.setItem("theme", theme);
+      markActive();
+    }
+  });
+
+  // ----- User identity -----
+  const USER_ID_KEY = "tinychess:userId";
+  function generateId() {
+    if (window.crypto && typeof window.crypto.randomUUID === "function") {
+      return window.crypto.randomUUID();
+    }
+    const tpl = "xxxxxxxx-xxxx-4xxx-yxxx-xxxxxxxxxxxx";
+    return tpl.replace(/[xy]/g, function (c) {
+      const r = (Math.random() * 16) | 0;
+      const v = c === "x" ? r : (r & 0x3) | 0x8;
+      return v.toString(16);
+    });
+  }
+  function ensureUserId() {
+    let id = "";
+    try {
+      id = localStorage.getItem(USER_ID_KEY) || "";
+    } catch {}
+    if (!id) {
+      id = generateId();
+      try {
+        localStorage.setItem(USER_ID_KEY, id);
+      } catch {}
+    }
+    return id;
+  }
+  const userId = ensureUserId();
+
+  // ----- Stats -----
+  function renderStats(stats) {
+    const box = document.getElementById("stats");
+    if (!box) return;
+    const pct = function (v) {
+      return Math.round(Number(v || 0)) + "%";
+    };
+    const list = [
+      { label: "Being played", value: Number(stats.active || 0).toLocaleString() },
+      { label: "Started", value: Number(stats.started || 0).toLocaleString() },
+      { label: "Completed", value: Number(stats.completed || 0).toLocaleString() },
+    ];
+    if (stats.whiteWins || stats.blackWins || stats.draws) {
+      list.push(
+        { label: "White wins", value: pct(stats.whitePct) },
+        { label: "Black wins", value: pct(stats.blackPct) },
+        { label: "Draws", value: pct(stats.drawPct) }
+      );
+    }
+    if (stats.averagePlies) {
+      list.push({
+        label: "Avg. moves",
+        value: Math.round(Number(stats.averagePlies) / 2).toLocaleString(),
+      });
+    }
+    const terms = Object.entries(stats.terminations || {}).sort(
+      function (a, b) {
+        return b[1] - a[1];
+      }
+    );
+    box.innerHTML = list
+      .map(function (item) {
+        return (
+          '<div class="stat-pill"><strong>' +
+          item.value +
+          "</strong><span>" +
+          item.label +
+          "</span></div>"
+        );
+      })
+      .join("");
+    if (terms.length) {
+      box.innerHTML +=
+        '<div class="stat-pill"><strong>' +
+        terms
+          .map(function (t) {
+            return t[0] + ": " + Number(t[1]).toLocaleString();
+          })
+          .join(" · ") +
+        "</strong><span>How games end</span></div>";
+    }
+  }
+
+  async function loadStats() {
+    try {
+      const res = await fetch("/api/stats");
+      const data = await res.json().catch(() => null);
+      if (!data || !data.ok || !data.stats) return;
+      renderStats(data.stats);
+    } catch (e) {}
+  }
+
+  renderStats({ started: 0, completed: 0, active: 0 });
+  loadStats();
+
+  // ----- Recent/active games -----
+  const KEY = "tinychess:games:v1";
+  function loadGames() {
+    try {
+      return JSON.parse(localStorage.getItem(KEY) || "{}");
+    } catch {
+      return {};
+    }
+  }
+  function saveGames(map) {
+    try {
+      localStorage.setItem(KEY, JSON.stringify(map));
+    } catch {}
+  }
+  function byLastSeenDesc(a, b) {
+    return (b.lastSeen || 0) - (a.lastSeen || 0);
+  }
+  function hasResult(g) {
+    return !!(g && g.result);
+  }
+  function activeGames() {
+    const m = loadGames();
+    return Object.values(m).filter(function (g) {
+      return !hasResult(g);
+    });
+  }
+
+  let creatingGame = false;
+  function startFEN() {
+    const el = document.getElementById("startfen");
+    return el ? el.value.trim() : "";
+  }
+  function joinCode() {
+    const el = document.getElementById("joincode");
+    return el ? el.value.trim() : "";
+  }
+  function codeForSpectators() {
+    const el = document.getElementById("codespectators");
+    return !!(el && el.checked);
+  }
+  function inviteOnly() {
+    const el = document.getElementById("inviteonly");
+    return !!(el && el.checked);
+  }
+  function hotSeat() {
+    const el = document.getElementById("hotseat");
+    return !!(el && el.checked);
+  }
+  // houseRules returns the move limit fields for /new, none when the
+  // limit is left empty.
+  function houseRules() {
+    const el = document.getElementById("movelimit");
+    const limit = el ? parseInt(el.value, 10) : 0;
+    if (!(limit > 0)) return {};
+    return {
+      moveLimit: limit,
+      adjudicate: document.getElementById("adjudicate").value,
+    };
+  }
+
+  async function createGame() {
+    if (creatingGame) return;
+    creatingGame = true;
+    try {
+      const res = await fetch("/new", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({
+          userId: userId,
+          fen: startFEN(),
+          joinCode: joinCode(),
+          codeForSpectators: codeForSpectators(),
+          inviteOnly: inviteOnly(),
+          hotSeat: hotSeat(),
+          ...houseRules(),
+        }),
+      });
+      const data = await res.json().catch(() => null);
+      if (data && data.ok && data.id) {
+        try {
+          if (data.seatToken)
+            localStorage.setItem(seatKey(data.id), data.seatToken);
+          if (data.invites)
+            localStorage.setItem(
+              "tinychess:" + data.id + ":invites:v1",
+              JSON.stringify(data.invites)
+            );
+        } catch (e) {}
+        const code = joinCode();
+        location.href =
+          "/" + data.id + (code ? "?code=" + encodeURIComponent(code) : "");
+        return;
+      }
+      if (data && data.error && res.status === 400) {
+        alert(data.error);
+        return;
+      }
+      alert("Unable to create a game right now. Please try again.");
+    } catch (e) {
+      alert("Unable to create a game right now. Please try again.");
+    } finally {
+      creatingGame = false;
+    }
+  }
+
+  const importBtn = document.getElementById("importbtn");
+  if (importBtn) {
+    importBtn.addEventListener("click", async function () {
+      const pgn = document.getElementById("importpgn").value;
+      if (!pgn.trim()) return;
+      try {
+        const res = await fetch(
+          "/api/import?userId=" + encodeURIComponent(userId),
+          { method: "POST", headers: { "Content-Type": "application/x-chess-pgn" }, body: pgn }
+        );
+        const data = await res.json().catch(() => null);
+        if (data && data.ok && data.id) {
+          try {
+            if (data.seatToken)
+              localStorage.setItem(seatKey(data.id), data.seatToken);
+          } catch (e) {}
+          location.href = "/" + data.id;
+          return;
+        }
+        alert((data && data.error) || "Unable to import the game.");
+      } catch (e) {
+        alert("Unable to import the game.");
+      }
+    });
+  }
+
+  // A pairing code shown on another device seats this one in its game.
+  const pairForm = document.getElementById("pairform");
+  if (pairForm) {
+    pairForm.addEventListener("submit", function (e) {
+      e.preventDefault();
+      const code = document.getElementById("paircode").value.trim();
+      if (/^\d{6}$/.test(code)) location.href = "/pair/" + code;
+    });
+  }
+
+  fetch("/api/endings")
+    .then((res) => res.json())
+    .then(function (data) {
+      if (!data || !data.ok || !data.available) return;
+      const select = document.getElementById("ending");
+      data.endings.forEach(function (e) {
+        const opt = document.createElement("option");
+        opt.value = e;
+        opt.textContent = e;
+        select.appendChild(opt);
+      });
+      document.getElementById("practice").hidden = false;
+    })
+    .catch(function () {});
+
+  document.getElementById("practicebtn").addEventListener("click", async function () {
+    try {
+      const res = await fetch("/api/training", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({
+          userId: userId,
+          ending: document.getElementById("ending").value,
+        }),
+      });
+      const data = await res.json().catch(() => null);
+      if (data && data.ok && data.id) {
+        try {
+          if (data.seatToken)
+            localStorage.setItem(seatKey(data.id), data.seatToken);
+        } catch (e) {}
+        location.href = "/" + data.id;
+        return;
+      }
+      alert((data && data.error) || "Unable to start practice.");
+    } catch (e) {
+      alert("Unable to start practice.");
+    }
+  });
+
+  function seatKey(id) {
+    return "tinychess:" + String(id || "") + ":seat:v1";
+  }
+
+  async function forgetRemote(id) {
+    if (!id) return;
+    let seatToken = "";
+    try {
+      seatToken = localStorage.getItem(seatKey(id)) || "";
+    } catch (e) {}
+    try {
+      await fetch("/forget/" + id, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ userId: userId, seatToken: seatToken }),
+      });
+    } catch (e) {}
+    loadStats();
+  }
+
+  function timeAgo(ts) {
+    const secs = Math.max(0, Math.round((Date.now() - ts) / 1000));
+    if (secs < 60) return "just now";
+    const units = [
+      [86400, "d"],
+      [3600, "h"],
+      [60, "m"],
+    ];
+    for (const [size, label] of units) {
+      if (secs >= size) return Math.floor(secs / size) + label + " ago";
+    }
+  }
+
+  // Pages of games from /api/v1/games once the server answers. Until
+  // then, and on instances without a database, the local index is
+  // shown instead.
+  let serverGames = null;
+  let nextOffset = null;
+
+  function renderRecent() {
+    const box = document.getElementById("recent");
+    if (!box) return;
+
+    const games =
+      serverGames || Object.values(loadGames()).sort(byLastSeenDesc);
+    if (!games.length) {
+      box.innerHTML =
+        '<p style="opacity:.8">No games yet — start one above.</p>';
+      return;
+    }
+
+    box.innerHTML = "";
+    for (var i = 0; i < games.length; i++) {
+      var g = games[i];
+      var a = document.createElement("div");
+      a.className = "card";
+
+      // prefer server lastSeen, fallback to local, then createdAt
+      var seen =
+        g.lastSeen || g.lastSeenLocal || g.createdAt || Date.now();
+      var when = new Date(seen).toLocaleString();
+
+      var res = g.result
+        ? '<span class="pill">Result: ' + g.result + "</span>"
+        : '<span class="pill">In progress</span>';
+      var stat = g.status
+        ? '<span class="pill">' + g.status + "</span>"
+        : "";
+      if (g.opponentSeenAt && !g.result)
+        stat +=
+          ' <span class="pill">Opponent seen ' +
+          timeAgo(g.opponentSeenAt) +
+          "</span>";
+
+      a.innerHTML =
+        '<div class="row">' +
+        '  <strong>ID:</strong> <span class="mono">' +
+        g.id +
+        "</span> " +
+        "  " +
+        res +
+        " " +
+        stat +
+        "</div>" +
+        '<div class="row" style="margin-top:6px;">' +
+        '  <button class="btn" data-goto="' +
+        g.id +
+        '">Open</button>' +
+        '  <button class="btn" data-copy="' +
+        g.id +
+        '">Copy link</button>' +
+        '  <button class="btn" data-remove="' +
+        g.id +
+        '">Forget</button>' +
+        '  <span style="opacity:.7; margin-left:auto;">Last seen: ' +
+        when +
+        "</span>" +
+        "</div>";
+      box.appendChild(a);
+    }
+    if (serverGames && nextOffset !== null) {
+      var more = document.createElement("button");
+      more.className = "btn";
+      more.setAttribute("data-more", "");
+      more.textContent = "Show more";
+      box.appendChild(more);
+    }
+  }
+
+  renderRecent();
+
+  // The server's list is authoritative, so recent games follow the
+  // user across browsers and survive clearing site data. The first
+  // page replaces the local index, which is kept for the new game
+  // button and for instances without a database.
+  async function syncRecent(offset) {
+    try {
+      const params = new URLSearchParams({ userId: userId, limit: "20" });
+      if (offset) params.set("offset", String(offset));
+      const res = await fetch("/api/v1/games?" + params.toString());
+      const data = await res.json().catch(() => null);
+      if (!data || !data.ok || !Array.isArray(data.games)) return;
+      const m = loadGames();
+      const page = data.games.map(function (g) {
+        return Object.assign({}, m[g.id], {
+          id: g.id,
+          result: g.result || "",
+          status: g.status || "",
+          lastSeen: Date.parse(g.lastSeen) || undefined,
+          opponentSeenAt: Date.parse(g.opponentSeenAt) || undefined,
+        });
+      });
+      if (offset) {
+        serverGames = (serverGames || []).concat(page);
+      } else {
+        serverGames = page;
+        const index = {};
+        page.forEach(function (g) {
+          index[g.id] = g;
+        });
+        saveGames(index);
+      }
+      nextOffset = typeof data.next === "number" ? data.next : null;
+      renderRecent();
+    } catch (e) {}
+  }
+  syncRecent(0);
+
+  // ----- Display name, sent by the game page once seated -----
+  const NAME_KEY = "tinychess:name:v1";
+  const nameEl = document.getElementById("displayname");
+  if (nameEl) {
+    try {
+      nameEl.value = localStorage.getItem(NAME_KEY) || "";
+    } catch (e) {}
+    nameEl.addEventListener("change", function () {
+      try {
+        localStorage.setItem(NAME_KEY, nameEl.value.trim());
+      } catch (e) {}
+    });
+  }
+
+  // ----- Seen-state privacy -----
+  const hideSeenEl = document.getElementById("hideseen");
+  async function loadPreferences() {
+    try {
+      const res = await fetch(
+        "/api/me/preferences?userId=" + encodeURIComponent(userId)
+      );
+      const data = await res.json().catch(() => null);
+      if (data && data.ok && hideSeenEl)
+        hideSeenEl.checked = !!data.preferences.hideSeen;
+    } catch (e) {}
+  }
+  if (hideSeenEl) {
+    hideSeenEl.addEventListener("change", function () {
+      fetch("/api/me/preferences", {
+        method: "PUT",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({
+          userId: userId,
+          hideSeen: hideSeenEl.checked,
+        }),
+      }).catch(function () {});
+    });
+    loadPreferences();
+  }
+
+  // ----- Watch-later bookmarks -----
+  async function loadBookmarks() {
+    const box = document.getElementById("bookmarks");
+    if (!box) return;
+    let games = [];
+    try {
+      const res = await fetch(
+        "/api/me/bookmarks?userId=" + encodeURIComponent(userId)
+      );
+      const data = await res.json().catch(() => null);
+      if (data && data.ok && Array.isArray(data.games)) games = data.games;
+    } catch (e) {}
+    if (!games.length) {
+      box.innerHTML =
+        '<p style="opacity:.8">Bookmark a game to watch it later.</p>';
+      return;
+    }
+    box.innerHTML = "";
+    games.forEach(function (g) {
+      var a = document.createElement("div");
+      a.className = "card";
+      var res = g.result
+        ? '<span class="pill">Result: ' + g.result + "</span>"
+        : '<span class="pill">In progress</span>';
+      a.innerHTML =
+        '<div class="row">' +
+        '  <strong>ID:</strong> <span class="mono">' +
+        g.id +
+        "</span> " +
+        res +
+        "</div>" +
+        '<div class="row" style="margin-top:6px;">' +
+        '  <button class="btn" data-goto="' +
+        g.id +
+        '">Open</button>' +
+        '  <button class="btn" data-unbookmark="' +
+        g.id +
+        '">Remove</button>' +
+        "</div>";
+      box.appendChild(a);
+    });
+  }
+  loadBookmarks();
+
+  document.addEventListener("click", function (e) {
+    const t = e.target;
+    if (t.matches("[data-goto]")) {
+      location.href = "/" + t.getAttribute("data-goto");
+    }
+    if (t.matches("[data-copy]")) {
+      try {
+        navigator.clipboard.writeText(
+          location.origin + "/" + t.getAttribute("data-copy")
+        );
+      } catch (e) {}
+    }
+    if (t.matches("[data-unbookmark]")) {
+      fetch("/api/bookmarks", {
+        method: "DELETE",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({
+          userId: userId,
+          gameId: t.getAttribute("data-unbookmark"),
+        }),
+      })
+        .catch(function () {})
+        .then(loadBookmarks);
+    }
+    if (t.matches("[data-remove]")) {
+      const id = t.getAttribute("data-remove");
+      const m = loadGames();
+      delete m[id];
+      saveGames(m);
+      if (serverGames)
+        serverGames = serverGames.filter(function (g) {
+          return g.id !== id;
+        });
+      forgetRemote(id);
+      renderRecent();
+    }
+    if (t.matches("[data-more]") && nextOffset !== null) {
+      t.disabled = true;
+      syncRecent(nextOffset);
+    }
+  });
+
+  function handleNewClick(ev) {
+    const act = activeGames();
+    if (act.length && !startFEN()) {
+      act.sort(byLastSeenDesc);
+      location.href = "/" + act[0].id;
+      ev.preventDefault();
+      return;
+    }
+    ev.preventDefault();
+    createGame();
+  }
+  ["newgame", "newgame2"].forEach(function (id) {
+    const el = document.getElementById(id);
+    if (el) el.addEventListener("click", handleNewClick);
+  });
+})();
@@ -0,0 +1,138 @@
+(function () {
+  const root = document.documentElement;
+  root.setAttribute("data-theme", localStorage.getItem("theme") || "dark");
+  const accent = localStorage.getItem("accent");
+  if (accent) root.style.setProperty("--accent", accent);
+
+  const USER_ID_KEY = "tinychess:userId";
+  function ensureUserId() {
+    let id = "";
+    try {
+      id = localStorage.getItem(USER_ID_KEY) || "";
+    } catch {}
+    if (!id) {
+      id = window.crypto.randomUUID();
+      try {
+        localStorage.setItem(USER_ID_KEY, id);
+      } catch {}
+    }
+    return id;
+  }
+  const userId = ensureUserId();
+
+  function esc(s) {
+    const d = document.createElement("div");
+    d.textContent = String(s);
+    return d.innerHTML;
+  }
+
+  function showError(msg) {
+    document.getElementById("error").textContent = msg || "";
+  }
+
+  function render(data) {
+    const rungs = data.rungs || [];
+    const challenges = data.challenges || [];
+    document.getElementById("reach").textContent = data.reach;
+    const names = {};
+    rungs.forEach(function (r) {
+      names[r.userId] = r.name;
+    });
+    const mine = rungs.find(function (r) {
+      return r.userId === userId;
+    });
+    const busy = {};
+    challenges.forEach(function (c) {
+      busy[c.challengerId] = busy[c.defenderId] = c.gameId;
+    });
+    if (mine) document.getElementById("name").value = mine.name;
+
+    document.getElementById("rungs").innerHTML =
+      rungs
+        .map(function (r) {
+          const gap = mine ? mine.rung - r.rung : 0;
+          let action = "";
+          if (busy[r.userId]) {
+            action =
+              '<a class="btn" href="/' + busy[r.userId] + '">Watch</a>';
+          } else if (mine && !busy[userId] && gap >= 1 && gap <= data.reach) {
+            action =
+              '<button class="btn" data-challenge="' +
+              r.userId +
+              '">Challenge</button>';
+          }
+          return (
+            '<div class="card row' +
+            (r.userId === userId ? " me" : "") +
+            '"><span class="rung">#' +
+            r.rung +
+            "</span><span>" +
+            esc(r.name) +
+            '</span><div style="flex: 1"></div>' +
+            action +
+            "</div>"
+          );
+        })
+        .join("") || "<p>No one is on the ladder yet.</p>";
+
+    document.getElementById("challenges").innerHTML =
+      challenges
+        .map(function (c) {
+          return (
+            '<div class="card row"><span>' +
+            esc(names[c.challengerId] || "?") +
+            " challenges " +
+            esc(names[c.defenderId] || "?") +
+            '</span><div style="flex: 1"></div><a class="btn" href="/' +
+            c.gameId +
+            '">Watch</a></div>'
+          );
+        })
+        .join("") || "<p>No challenges in play.</p>";
+  }
+
+  async function load() {
+    try {
+      const res = await fetch("/api/ladder");
+      const data = await res.json();
+      if (data.ok) render(data);
+    } catch {}
+  }
+
+  async function post(url, body) {
+    const res = await fetch(url, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(body),
+    });
+    return res.json();
+  }
+
+  document.getElementById("join").addEventListener("submit", async function (e) {
+    e.preventDefault();
+    const data = await post("/api/ladder/join", {
+      userId: userId,
+      name: document.getElementById("name").value,
+    });
+    showError(data.ok ? "" : data.error);
+    load();
+  });
+
+  document.addEventListener("click", async function (e) {
+    const t = e.target;
+    if (!t.matches("[data-challenge]")) return;
+    const data = await post("/api/ladder/challenges", {
+      userId: userId,
+      defenderId: t.getAttribute("data-challenge"),
+    });
+    if (data.ok) {
+      location.href = "/" + data.id;
+      return;
+    }
+    showError(data.error);
+    load();
+  });
+
+  load();
+  setInterval(load, 5000);
+})();
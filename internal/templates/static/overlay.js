@@ -0,0 +1,210 @@
+(function () {
+  const gameId = document.body.dataset.gameId;
+  const q = new URLSearchParams(location.search);
+  const root = document.documentElement;
+
+  // ?size=<px> board width, ?theme=transparent|chroma|dark|light,
+  // ?show=names,clocks,eval,pace picks the elements (pace shows how
+  // long the side to move has been thinking and each side's average
+  // move time; it is off by default), ?flip=1 puts black at
+  // the bottom, and ?white= / ?black= override the players' names.
+  const size = Math.min(1600, Math.max(160, parseInt(q.get("size"), 10) || 480));
+  root.style.setProperty("--size", size + "px");
+  root.setAttribute("data-theme", q.get("theme") || "transparent");
+  const show = new Set(
+    (q.get("show") || "names,clocks,eval").split(",").map(function (s) {
+      return s.trim();
+    })
+  );
+  const flipped = q.get("flip") === "1";
+  const names = {
+    w: q.get("white") || "White",
+    b: q.get("black") || "Black",
+  };
+
+  const boardEl = document.getElementById("board");
+  const plates = {
+    top: document.getElementById("top"),
+    bottom: document.getElementById("bottom"),
+  };
+  const sides = flipped ? { top: "w", bottom: "b" } : { top: "b", bottom: "w" };
+  Object.keys(plates).forEach(function (k) {
+    const plate = plates[k];
+    plate.querySelector(".name").textContent = names[sides[k]];
+    plate.querySelector(".name").hidden = !show.has("names");
+    plate.querySelector(".clock").hidden = !show.has("clocks");
+    plate.querySelector(".pace").hidden = !show.has("pace");
+    plate.hidden =
+      !show.has("names") && !show.has("clocks") && !show.has("pace");
+  });
+  document.getElementById("evalbar").hidden = !show.has("eval");
+
+  const glyph = {
+    P: "♙",
+    N: "♘",
+    B: "♗",
+    R: "♖",
+    Q: "♕",
+    K: "♔",
+    p: "♟",
+    n: "♞",
+    b: "♝",
+    r: "♜",
+    q: "♛",
+    k: "♚",
+  };
+
+  function renderBoard(fen, last) {
+    const ranks = fen.split(" ")[0].split("/");
+    const lit = new Set(last ? [last.slice(0, 2), last.slice(2, 4)] : []);
+    boardEl.innerHTML = "";
+    for (let r = 0; r < 8; r++) {
+      const cells = [];
+      for (const ch of ranks[flipped ? 7 - r : r]) {
+        if (/\d/.test(ch)) {
+          for (let k = 0; k < parseInt(ch, 10); k++) cells.push("");
+        } else {
+          cells.push(ch);
+        }
+      }
+      for (let c = 0; c < 8; c++) {
+        const col = flipped ? 7 - c : c;
+        const piece = cells[col] || "";
+        const sq =
+          String.fromCharCode(97 + col) + String(flipped ? r + 1 : 8 - r);
+        const cell = document.createElement("div");
+        cell.className = "cell " + ((r + c) % 2 === 1 ? "dark" : "light");
+        if (lit.has(sq)) cell.classList.add("last");
+        if (piece) {
+          cell.textContent = glyph[piece] || "";
+          cell.classList.add(
+            piece === piece.toUpperCase() ? "white-piece" : "black-piece"
+          );
+        }
+        boardEl.appendChild(cell);
+      }
+    }
+  }
+
+  function renderEval(ev) {
+    const fill = document.getElementById("evalfill");
+    const score = document.getElementById("evalscore");
+    if (!ev) return;
+    let pct;
+    if (ev.mate) {
+      pct = ev.mate > 0 ? 100 : 0;
+      score.textContent = "M" + Math.abs(ev.mate);
+    } else {
+      // Map centipawns onto the bar with a soft clamp around +/-10.
+      pct = 50 + 50 * (2 / (1 + Math.exp(-ev.cp / 400)) - 1);
+      score.textContent = (ev.cp >= 0 ? "+" : "") + (ev.cp / 100).toFixed(1);
+    }
+    fill.style.height = pct + "%";
+  }
+
+  // Clocks are reconstructed from the time control and the recorded
+  // think time of every ply; the side to move ticks from its last move.
+  let clock = null;
+  function parseTimeControl(tc) {
+    const m = /^(\d+)\+(\d+)$/.exec(tc || "");
+    return m ? { initial: m[1] * 60000, increment: m[2] * 1000 } : null;
+  }
+  function formatClock(ms) {
+    ms = Math.max(0, ms);
+    const s = Math.floor(ms / 1000);
+    const mm = Math.floor(s / 60);
+    const ss = String(s % 60).padStart(2, "0");
+    return mm + ":" + ss;
+  }
+  function renderClocks() {
+    Object.keys(plates).forEach(function (k) {
+      const side = sides[k];
+      const el = plates[k].querySelector(".clock");
+      plates[k].classList.toggle("to-move", !!clock && clock.turn === side);
+      if (!clock || !clock.tc) {
+        el.textContent = "";
+        return;
+      }
+      let left = clock.left[side];
+      if (clock.running && clock.turn === side) left -= Date.now() - clock.since;
+      el.textContent = formatClock(left);
+      el.classList.toggle("low", left < 20000);
+    });
+  }
+  function updateClocks(st) {
+    const tc = parseTimeControl(st.timeControl);
+    const left = tc ? { w: tc.initial, b: tc.initial } : { w: 0, b: 0 };
+    const times = st.moveTimes || [];
+    times.forEach(function (mt, i) {
+      const side = i % 2 === 0 ? "w" : "b";
+      // The first move of each side is not charged, as on most servers.
+      if (i >= 2) left[side] -= mt.thinkMs || 0;
+      left[side] += tc ? tc.increment : 0;
+    });
+    const paused = !!(st.pace && st.pace.paused);
+    if (paused && times.length >= 2) left[st.turn] -= st.pace.thinkingMs || 0;
+    const last = times.length ? times[times.length - 1].at : 0;
+    clock = {
+      tc: tc,
+      left: left,
+      turn: st.turn,
+      since: (st.pace && st.pace.thinkingSince) || last || Date.now(),
+      // Maintenance stops the clock; the pause is left out of the
+      // side to move's time.
+      running: times.length >= 2 && !st.termination && !paused,
+    };
+    renderClocks();
+  }
+  let pace = null;
+  function renderPace() {
+    Object.keys(plates).forEach(function (k) {
+      const side = sides[k];
+      const el = plates[k].querySelector(".pace");
+      if (!pace) {
+        el.textContent = "";
+        return;
+      }
+      if (pace.turn === side && pace.thinkingSince) {
+        el.textContent =
+          "thinking " + formatClock(Date.now() - pace.thinkingSince);
+        return;
+      }
+      const avg = side === "w" ? pace.whiteAvgMs : pace.blackAvgMs;
+      el.textContent = avg ? "avg " + formatClock(avg) : "";
+    });
+  }
+  setInterval(function () {
+    renderClocks();
+    renderPace();
+  }, 250);
+
+  renderBoard("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1");
+
+  const params = new URLSearchParams({ spectate: "1" });
+  ["code", "invite", "token"].forEach(function (k) {
+    if (q.get(k)) params.set(k, q.get(k));
+  });
+  const es = new EventSource("/sse/" + gameId + "?" + params.toString());
+  es.onmessage = function (ev) {
+    const st = JSON.parse(ev.data || "{}");
+    if (st.kind === "reconnect" && st.url && new URL(st.url).origin !== location.origin) {
+      es.close();
+      setTimeout(function () {
+        location.replace(new URL(location.pathname + location.search, st.url));
+      }, st.retryMs || 0);
+      return;
+    }
+    if (!st.fen) return;
+    renderBoard(st.fen, st.lastMove && st.lastMove.uci);
+    renderEval(st.eval);
+    updateClocks(st);
+    pace = st.pace ? Object.assign({ turn: st.turn }, st.pace) : null;
+    renderPace();
+    Object.keys(plates).forEach(function (k) {
+      const side = sides[k];
+      const fromState = side === "w" ? st.white : st.black;
+      if (!q.get(side === "w" ? "white" : "black") && fromState)
+        plates[k].querySelector(".name").textContent = fromState;
+    });
+  };
+})();
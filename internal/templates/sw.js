@@ -0,0 +1,175 @@
+// Tiny Chess service worker, build {{COMMIT}}.
+//
+// Pages are fetched from the network and fall back to the last copy seen, so
+// the app opens while offline. Moves posted while the network is down are
+// kept in an outbox and sent once it returns, through Background Sync where
+// the browser has it and otherwise when a page reports being back online.
+// Every queued move carries a moveId, so a move the server did receive
+// before the connection dropped is not played twice.
+
+const CACHE = "tinychess-{{COMMIT}}";
+const SHELL = [
+  "/",
+  "/manifest.webmanifest",
+  "/icon.svg",
+  "{{ASSET home.css}}",
+  "{{ASSET home.js}}",
+  "{{ASSET game.css}}",
+  "{{ASSET game.js}}",
+];
+
+self.addEventListener("install", (event) => {
+  event.waitUntil(
+    caches
+      .open(CACHE)
+      .then((cache) => cache.addAll(SHELL))
+      .then(() => self.skipWaiting())
+  );
+});
+
+self.addEventListener("activate", (event) => {
+  event.waitUntil(
+    caches
+      .keys()
+      .then((keys) =>
+        Promise.all(keys.filter((k) => k !== CACHE).map((k) => caches.delete(k)))
+      )
+      .then(() => self.clients.claim())
+  );
+});
+
+self.addEventListener("fetch", (event) => {
+  const req = event.request;
+  const url = new URL(req.url);
+  if (url.origin !== location.origin) return;
+  if (req.method === "POST" && url.pathname.startsWith("/move/")) {
+    event.respondWith(postMove(req));
+    return;
+  }
+  // Static assets are named by their content, so a cached copy is never
+  // stale.
+  if (req.method === "GET" && url.pathname.startsWith("/static/")) {
+    event.respondWith(cacheFirst(req));
+    return;
+  }
+  if (req.method === "GET" && (req.mode === "navigate" || SHELL.includes(url.pathname))) {
+    event.respondWith(networkFirst(req));
+  }
+});
+
+async function networkFirst(req) {
+  const cache = await caches.open(CACHE);
+  try {
+    const res = await fetch(req);
+    if (res.ok) cache.put(req, res.clone());
+    return res;
+  } catch (err) {
+    const hit = (await cache.match(req)) || (req.mode === "navigate" && (await cache.match("/")));
+    if (hit) return hit;
+    throw err;
+  }
+}
+
+async function cacheFirst(req) {
+  const cache = await caches.open(CACHE);
+  const hit = await cache.match(req);
+  if (hit) return hit;
+  const res = await fetch(req);
+  if (res.ok) cache.put(req, res.clone());
+  return res;
+}
+
+// postMove sends a move, queueing it when the network is unreachable. The
+// page learns it was queued from the 202 response.
+async function postMove(req) {
+  const body = await req.clone().text();
+  try {
+    return await fetch(req);
+  } catch (err) {
+    await enqueue({
+      url: req.url,
+      headers: [...req.headers],
+      body: body,
+      queuedAt: Date.now(),
+    });
+    if (self.registration.sync) {
+      try {
+        await self.registration.sync.register("moves");
+      } catch {}
+    }
+    return new Response(JSON.stringify({ ok: false, queued: true, error: "offline" }), {
+      status: 202,
+      headers: { "Content-Type": "application/json" },
+    });
+  }
+}
+
+self.addEventListener("sync", (event) => {
+  if (event.tag === "moves") event.waitUntil(flush());
+});
+
+self.addEventListener("message", (event) => {
+  if (event.data === "flush") event.waitUntil(flush());
+});
+
+// flush sends queued moves in order until one cannot reach the server. A move
+// the server refuses is dropped, since replaying it cannot succeed; pages are
+// told how each one went.
+async function flush() {
+  for (const entry of await queued()) {
+    let result;
+    try {
+      const res = await fetch(entry.url, {
+        method: "POST",
+        headers: entry.headers,
+        body: entry.body,
+      });
+      result = await res.json().catch(() => ({ ok: res.ok }));
+    } catch {
+      throw new Error("still offline");
+    }
+    await dequeue(entry.id);
+    const clients = await self.clients.matchAll();
+    clients.forEach((c) =>
+      c.postMessage({
+        kind: "moveSynced",
+        url: entry.url,
+        ok: !!result.ok,
+        error: result.error || "",
+      })
+    );
+  }
+}
+
+// The outbox lives in IndexedDB so it survives the worker being stopped.
+function db() {
+  return new Promise((resolve, reject) => {
+    const open = indexedDB.open("tinychess", 1);
+    open.onupgradeneeded = () =>
+      open.result.createObjectStore("outbox", { keyPath: "id", autoIncrement: true });
+    open.onsuccess = () => resolve(open.result);
+    open.onerror = () => reject(open.error);
+  });
+}
+
+async function tx(mode, fn) {
+  const conn = await db();
+  return new Promise((resolve, reject) => {
+    const t = conn.transaction("outbox", mode);
+    const req = fn(t.objectStore("outbox"));
+    t.oncomplete = () => resolve(req && req.result);
+    t.onerror = () => reject(t.error);
+  });
+}
+
+function enqueue(entry) {
+  return tx("readwrite", (store) => store.add(entry));
+}
+
+function dequeue(id) {
+  return tx("readwrite", (store) => store.delete(id));
+}
+
+function queued() {
+  return tx("readonly", (store) => store.getAll());
+}
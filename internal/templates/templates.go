@@ -1,48 +1,168 @@
 package templates
 
 import (
+	"embed"
 	"html/template"
 	"net/http"
-	"os"
 	"strings"
 )
 
+// files holds the page templates and their scripts and styles so the binary
+// runs from any directory.
+//
+//go:embed home.html game.html ladder.html overlay.html docs.html
+//go:embed manifest.webmanifest sw.js icon.svg
+//go:embed static
+var files embed.FS
+
 var commit = "dev"
 
 func SetVersion(c string) {
 	commit = c
 }
 
+// Version returns the build's version, as set by SetVersion.
+func Version() string {
+	return commit
+}
+
 // WriteHomeHTML serves the home page template
 func WriteHomeHTML(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Header().Set("Cache-Control", "no-store")
 	w.WriteHeader(http.StatusOK)
 
-	content, err := os.ReadFile("internal/templates/home.html")
+	content, err := files.ReadFile("home.html")
 	if err != nil {
 		http.Error(w, "Template not found", http.StatusInternalServerError)
 		return
 	}
 	html := strings.ReplaceAll(string(content), "{{COMMIT}}", commit)
-	_, _ = w.Write([]byte(html))
+	_, _ = w.Write([]byte(withAssets(brand(html, branding.Name, "/"))))
+}
+
+// WriteLadderHTML serves the live ladder page.
+func WriteLadderHTML(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	content, err := files.ReadFile("ladder.html")
+	if err != nil {
+		http.Error(w, "Template not found", http.StatusInternalServerError)
+		return
+	}
+	html := strings.ReplaceAll(string(content), "{{COMMIT}}", commit)
+	_, _ = w.Write([]byte(withAssets(brand(html, branding.Name+" – Ladder", "/ladder"))))
 }
 
 // WriteGameHTML serves the game page template with game ID substitution
 func WriteGameHTML(w http.ResponseWriter, gameID string) {
+	writeGameHTML(w, gameID, "", nil)
+}
+
+// WriteGameHTMLAt serves the game page opened at an earlier position of the
+// game. position is the JSON of a game.PlyPosition; it must be HTML-safe, as
+// encoding/json produces by default.
+func WriteGameHTMLAt(w http.ResponseWriter, gameID string, position []byte) {
+	writeGameHTML(w, gameID, "", position)
+}
+
+// WriteRemoteGameHTML serves the game page in read-only mode for a game
+// relayed from another instance.
+func WriteRemoteGameHTML(w http.ResponseWriter, host, gameID string) {
+	writeGameHTML(w, gameID, host, nil)
+}
+
+func writeGameHTML(w http.ResponseWriter, gameID, remoteHost string, position []byte) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Header().Set("Cache-Control", "no-store")
 	w.WriteHeader(http.StatusOK)
 
-	content, err := os.ReadFile("internal/templates/game.html")
+	content, err := files.ReadFile("game.html")
 	if err != nil {
 		http.Error(w, "Template not found", http.StatusInternalServerError)
 		return
 	}
 
 	html := strings.ReplaceAll(string(content), "{{GAME_ID}}", gameID)
+	html = strings.ReplaceAll(html, "{{REMOTE_HOST}}", remoteHost)
+	if position == nil {
+		position = []byte("null")
+	}
+	html = strings.ReplaceAll(html, "{{SHARED_PLY}}", string(position))
 	html = strings.ReplaceAll(html, "{{COMMIT}}", commit)
-	_, _ = w.Write([]byte(html))
+	path := "/" + gameID
+	if remoteHost != "" {
+		path = "/remote/" + remoteHost + "/" + gameID
+	}
+	_, _ = w.Write([]byte(withAssets(brand(html, branding.Name+" – Game "+gameID, path))))
+}
+
+// WriteOverlayHTML serves the stream overlay for a game. Its look is set
+// client-side from the query string.
+func WriteOverlayHTML(w http.ResponseWriter, gameID string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	content, err := files.ReadFile("overlay.html")
+	if err != nil {
+		http.Error(w, "Template not found", http.StatusInternalServerError)
+		return
+	}
+	html := strings.ReplaceAll(string(content), "{{GAME_ID}}", gameID)
+	_, _ = w.Write([]byte(withAssets(brand(html, branding.Name, "/overlay/"+gameID))))
+}
+
+// WriteAPIDocs serves the API explorer, which renders the OpenAPI
+// description at /api/openapi.json.
+func WriteAPIDocs(w http.ResponseWriter) {
+	content, err := files.ReadFile("docs.html")
+	if err != nil {
+		http.Error(w, "Template not found", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(withAssets(brand(string(content), branding.Name+" – API", "/api/docs"))))
+}
+
+// WriteManifest serves the web app manifest that makes the site installable,
+// under the instance's name.
+func WriteManifest(w http.ResponseWriter) {
+	content, err := files.ReadFile("manifest.webmanifest")
+	if err != nil {
+		http.Error(w, "Template not found", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/manifest+json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(strings.ReplaceAll(string(content), `"{{INSTANCE_NAME}}"`, manifestName())))
+}
+
+// WriteIcon serves the app icon.
+func WriteIcon(w http.ResponseWriter) {
+	writeAsset(w, "icon.svg", "image/svg+xml")
+}
+
+// WriteServiceWorker serves the service worker, versioned with the build so
+// a new release replaces the cached pages. It must not be cached itself, or
+// browsers would keep running an old worker.
+func WriteServiceWorker(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-cache")
+	writeAsset(w, "sw.js", "text/javascript; charset=utf-8")
+}
+
+func writeAsset(w http.ResponseWriter, name, contentType string) {
+	content, err := files.ReadFile(name)
+	if err != nil {
+		http.Error(w, "Template not found", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(withAssets(strings.ReplaceAll(string(content), "{{COMMIT}}", commit))))
 }
 
 // LoadTemplate loads and parses an HTML template
@@ -0,0 +1,200 @@
+// Package usage counts API requests per caller and enforces a daily quota.
+// Counters are kept in memory for the current UTC day and start over at
+// midnight or when the process restarts.
+package usage
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Report is a caller's usage for the current day.
+type Report struct {
+	Key string `json:"key"`
+	// Requests counts the requests served; Refused those turned away over
+	// quota. Tokens is the part of Requests made with an API token or key.
+	Requests int64 `json:"requests"`
+	Refused  int64 `json:"refused"`
+	Tokens   int64 `json:"tokens"`
+	// Limit is the daily quota, 0 when unlimited; Remaining is what is left
+	// of it.
+	Limit     int64            `json:"limit"`
+	Remaining int64            `json:"remaining"`
+	ResetAt   time.Time        `json:"resetAt"`
+	Routes    map[string]int64 `json:"routes"`
+}
+
+// Totals sums the day's usage across callers.
+type Totals struct {
+	Day      string    `json:"day"`
+	Callers  int       `json:"callers"`
+	Requests int64     `json:"requests"`
+	Refused  int64     `json:"refused"`
+	Tokens   int64     `json:"tokens"`
+	Limit    int64     `json:"limit"`
+	ResetAt  time.Time `json:"resetAt"`
+}
+
+type counter struct {
+	requests int64
+	refused  int64
+	tokens   int64
+	routes   map[string]int64
+}
+
+// Meter counts requests per caller key. A nil Meter counts nothing and
+// allows everything.
+type Meter struct {
+	// Limit is the number of requests a caller may make per day; 0 disables
+	// the quota while still counting.
+	Limit int64
+
+	mu       sync.Mutex
+	day      string
+	counters map[string]*counter
+	// totals counts each request once, however many keys it counted
+	// against.
+	totals counter
+	now    func() time.Time
+}
+
+// New returns a meter allowing limit requests per caller and day, or any
+// number when limit is 0.
+func New(limit int64) *Meter {
+	return &Meter{Limit: limit, counters: make(map[string]*counter), now: time.Now}
+}
+
+// Allow counts a request to route against each of keys, such as the
+// caller's address and their user, and reports whether it is within the
+// quota of all of them, along with the usage of the key with the least
+// left. Requests over any key's quota are counted as refused. token marks
+// requests made with an API token or key.
+func (m *Meter) Allow(keys []string, route string, token bool) (Report, bool) {
+	if m == nil {
+		return Report{Key: keys[0]}, true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.rollLocked()
+	counters := make([]*counter, len(keys))
+	tightest := 0
+	for i, key := range keys {
+		c := m.counters[key]
+		if c == nil {
+			c = &counter{routes: make(map[string]int64)}
+			m.counters[key] = c
+		}
+		counters[i] = c
+		if c.requests > counters[tightest].requests {
+			tightest = i
+		}
+	}
+	if m.Limit > 0 && counters[tightest].requests >= m.Limit {
+		for _, c := range counters {
+			c.refused++
+		}
+		m.totals.refused++
+		return m.reportLocked(keys[tightest], counters[tightest], now), false
+	}
+	for _, c := range append(counters, &m.totals) {
+		c.requests++
+		if token {
+			c.tokens++
+		}
+	}
+	for _, c := range counters {
+		c.routes[route]++
+	}
+	return m.reportLocked(keys[tightest], counters[tightest], now), true
+}
+
+// Usage returns the caller's usage for the day so far.
+func (m *Meter) Usage(key string) Report {
+	if m == nil {
+		return Report{Key: key, Routes: map[string]int64{}}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.rollLocked()
+	c := m.counters[key]
+	if c == nil {
+		c = &counter{routes: map[string]int64{}}
+	}
+	return m.reportLocked(key, c, now)
+}
+
+// Top returns the day's totals and up to n of the busiest callers, by
+// requests served and then refused.
+func (m *Meter) Top(n int) (Totals, []Report) {
+	reports := []Report{}
+	if m == nil {
+		return Totals{}, reports
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.rollLocked()
+	totals := Totals{
+		Day:      m.day,
+		Callers:  len(m.counters),
+		Requests: m.totals.requests,
+		Refused:  m.totals.refused,
+		Tokens:   m.totals.tokens,
+		Limit:    m.Limit,
+		ResetAt:  resetAt(now),
+	}
+	for key, c := range m.counters {
+		reports = append(reports, m.reportLocked(key, c, now))
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		a, b := reports[i], reports[j]
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		if a.Refused != b.Refused {
+			return a.Refused > b.Refused
+		}
+		return a.Key < b.Key
+	})
+	if len(reports) > n {
+		reports = reports[:n]
+	}
+	return totals, reports
+}
+
+// rollLocked starts a new day's counters once the UTC date changes.
+func (m *Meter) rollLocked() time.Time {
+	now := m.now().UTC()
+	if day := now.Format(time.DateOnly); day != m.day {
+		m.day = day
+		m.counters = make(map[string]*counter)
+		m.totals = counter{}
+	}
+	return now
+}
+
+func (m *Meter) reportLocked(key string, c *counter, now time.Time) Report {
+	routes := make(map[string]int64, len(c.routes))
+	for r, n := range c.routes {
+		routes[r] = n
+	}
+	rep := Report{
+		Key:      key,
+		Requests: c.requests,
+		Refused:  c.refused,
+		Tokens:   c.tokens,
+		Limit:    m.Limit,
+		ResetAt:  resetAt(now),
+		Routes:   routes,
+	}
+	if m.Limit > 0 {
+		rep.Remaining = max(m.Limit-c.requests, 0)
+	}
+	return rep
+}
+
+// resetAt is the next UTC midnight after now.
+func resetAt(now time.Time) time.Time {
+	y, mo, d := now.Date()
+	return time.Date(y, mo, d+1, 0, 0, 0, 0, time.UTC)
+}
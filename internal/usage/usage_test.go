@@ -0,0 +1,74 @@
+package usage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuotaAndRollover(t *testing.T) {
+	now := time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+	m := New(2)
+	m.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, ok := m.Allow([]string{"user:a"}, "GET /api/stats", false); !ok {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+	rep, ok := m.Allow([]string{"user:a"}, "GET /api/stats", false)
+	if ok {
+		t.Fatal("expected the third request to be refused")
+	}
+	if rep.Requests != 2 || rep.Refused != 1 || rep.Remaining != 0 {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+	if want := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC); !rep.ResetAt.Equal(want) {
+		t.Fatalf("expected reset at %v, got %v", want, rep.ResetAt)
+	}
+	if _, ok := m.Allow([]string{"user:b"}, "GET /api/stats", true); !ok {
+		t.Fatal("another caller has a quota of its own")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if rep, ok := m.Allow([]string{"user:a"}, "GET /api/stats", false); !ok || rep.Requests != 1 {
+		t.Fatalf("expected a fresh quota the next day, got %+v", rep)
+	}
+}
+
+func TestTop(t *testing.T) {
+	m := New(0)
+	for i := 0; i < 3; i++ {
+		m.Allow([]string{"user:busy"}, "POST /move/{id}", true)
+	}
+	m.Allow([]string{"user:busy"}, "GET /api/stats", false)
+	m.Allow([]string{"ip:192.0.2.1"}, "GET /api/stats", false)
+
+	totals, callers := m.Top(1)
+	if totals.Callers != 2 || totals.Requests != 5 || totals.Tokens != 3 {
+		t.Fatalf("unexpected totals %+v", totals)
+	}
+	if len(callers) != 1 || callers[0].Key != "user:busy" || callers[0].Routes["POST /move/{id}"] != 3 {
+		t.Fatalf("unexpected callers %+v", callers)
+	}
+	if rep := m.Usage("user:busy"); rep.Limit != 0 || rep.Remaining != 0 || rep.Requests != 4 {
+		t.Fatalf("unexpected unlimited report %+v", rep)
+	}
+}
+
+func TestAllowCountsEveryKey(t *testing.T) {
+	m := New(2)
+	m.Allow([]string{"ip:192.0.2.1", "user:a"}, "GET /api/stats", false)
+	m.Allow([]string{"ip:192.0.2.1", "user:b"}, "GET /api/stats", false)
+
+	// A new user from the same address still spends the address's quota.
+	rep, ok := m.Allow([]string{"ip:192.0.2.1", "user:c"}, "GET /api/stats", false)
+	if ok || rep.Key != "ip:192.0.2.1" || rep.Remaining != 0 {
+		t.Fatalf("expected the address's quota to refuse, got %v %+v", ok, rep)
+	}
+	if rep := m.Usage("user:c"); rep.Requests != 0 || rep.Refused != 1 {
+		t.Fatalf("unexpected usage %+v", rep)
+	}
+	if totals, _ := m.Top(10); totals.Requests != 2 || totals.Refused != 1 || totals.Callers != 4 {
+		t.Fatalf("each request must be totalled once, got %+v", totals)
+	}
+}
@@ -1,50 +1,255 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"tinychess/internal/config"
+	"tinychess/internal/engine"
+	"tinychess/internal/federation"
+	"tinychess/internal/fediverse"
 	"tinychess/internal/game"
 	"tinychess/internal/handlers"
 	"tinychess/internal/logging"
+	"tinychess/internal/outbox"
 	"tinychess/internal/storage"
+	"tinychess/internal/tablebase"
 	"tinychess/internal/templates"
+	"tinychess/internal/usage"
 )
 
 func main() {
 	debug := flag.Bool("debug", false, "enable debug logging")
+	standalone := flag.Bool("standalone", false, "use an embedded SQLite store and generated admin token")
+	dataDir := flag.String("data", "tinychess-data", "data directory for -standalone")
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML config file; environment variables override it")
+	addr := flag.String("addr", "", "listen address, overriding the config")
 	flag.Parse()
 	logging.Debug = *debug
 
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	if *addr != "" {
+		cfg.Addr = *addr
+	}
+
 	templates.SetVersion(commit)
+	game.SetInstance(cfg.InstanceName, cfg.BaseURL)
+	err = templates.SetBranding(templates.Branding{
+		Name:    cfg.InstanceName,
+		LogoURL: cfg.Branding.LogoURL,
+		Footer:  cfg.Branding.Footer,
+		Accents: cfg.Branding.Accents,
+		BaseURL: cfg.BaseURL,
+	})
+	if err != nil {
+		log.Fatalf("invalid branding: %v", err)
+	}
+	if cfg.Cleanup.Interval > 0 {
+		game.CleanupInterval = cfg.Cleanup.Interval
+	}
+	if cfg.Cleanup.IdleAfter > 0 {
+		game.IdleTTL = cfg.Cleanup.IdleAfter
+	}
+	if cfg.Cooldowns.React > 0 {
+		game.ReactCooldown = cfg.Cooldowns.React
+	}
+	if cfg.Cooldowns.Chat > 0 {
+		game.ChatCooldown = cfg.Cooldowns.Chat
+	}
+	if cfg.Cooldowns.Hint > 0 {
+		game.HintCooldown = cfg.Cooldowns.Hint
+	}
+	game.PostGameCoolOff = cfg.Cooldowns.PostGame
+
+	dsn := cfg.DatabaseURL
+	adminToken := cfg.AdminToken
+	if *standalone {
+		if dsn, adminToken, err = standaloneDefaults(*dataDir, dsn, adminToken); err != nil {
+			log.Fatalf("failed to prepare standalone mode: %v", err)
+		}
+	}
 
 	var store *storage.Store
-	if dsn := os.Getenv("DATABASE_URL"); dsn != "" {
-		db, err := storage.New(dsn)
+	if dsn != "" {
+		db, err := storage.New(dsn, cfg.MoveShards)
 		if err != nil {
 			log.Fatalf("failed to initialize database: %v", err)
 		}
 		store = storage.NewStore(db)
 	}
 
+	var retention time.Duration
+	if cfg.RetentionDays > 0 {
+		retention = time.Duration(cfg.RetentionDays) * 24 * time.Hour
+		go store.RunRetention(context.Background(), retention, time.Hour)
+	}
+	if store != nil && cfg.DBMaintenanceInterval > 0 {
+		go store.RunDatabaseMaintenance(context.Background(), cfg.DBMaintenanceInterval)
+	}
+
 	// Initialize game hub
 	hub := game.NewHub(store)
+	if cfg.EnginePath != "" {
+		eng, err := engine.New(cfg.EnginePath)
+		if err != nil {
+			log.Fatalf("failed to start engine: %v", err)
+		}
+		defer eng.Close()
+		hub.Engine = eng
+		go hub.RunAnalysis(context.Background())
+	}
+	hub.PostMortemSpectators = cfg.PostMortemSpectators
+	if cfg.TablebaseURL != "" {
+		hub.Tablebase = tablebase.NewClient(cfg.TablebaseURL, nil)
+	}
+	if cfg.ImageCacheBytes != nil {
+		hub.Images = game.NewImageCache(*cfg.ImageCacheBytes)
+	}
+	if cfg.SeatSecret != "" {
+		hub.Seats = game.NewSeatSigner([]byte(cfg.SeatSecret))
+	}
+	if cfg.TimeControls != "" {
+		presets, err := game.ParseTimeControls(cfg.TimeControls)
+		if err != nil {
+			log.Fatalf("invalid time controls: %v", err)
+		}
+		hub.Presets = presets
+	}
+
+	// Without a database, games survive restarts in the save file.
+	stopSaving, saved := func() {}, make(chan struct{})
+	if store == nil && cfg.SaveFile != "" && cfg.SaveInterval > 0 {
+		n, err := hub.LoadGames(cfg.SaveFile)
+		if err != nil {
+			log.Fatalf("failed to load saved games: %v", err)
+		}
+		log.Printf("loaded %d games from %s", n, cfg.SaveFile)
+		ctx, cancel := context.WithCancel(context.Background())
+		stopSaving = cancel
+		go func() {
+			defer close(saved)
+			hub.RunSaves(ctx, cfg.SaveFile, cfg.SaveInterval)
+		}()
+	} else {
+		close(saved)
+	}
+
+	if cfg.WarmHours > 0 {
+		n, err := hub.Warm(context.Background(), time.Duration(cfg.WarmHours)*time.Hour, game.WarmLimit)
+		if err != nil {
+			log.Printf("warming games failed: %v", err)
+		} else {
+			log.Printf("warmed %d games active in the last %dh", n, cfg.WarmHours)
+		}
+	}
 
 	// Initialize HTTP handlers
 	h := handlers.NewHandler(hub, store)
+	h.AdminToken = adminToken
+	h.RetentionAge = retention
+	h.LadderReach = cfg.LadderReach
+	h.Usage = usage.New(cfg.APIQuota)
+	h.Heartbeat = cfg.Heartbeat
+	if h.RateLimits, err = handlers.ParseRateLimits(cfg.RateLimits); err != nil {
+		log.Fatalf("invalid rate limits: %v", err)
+	}
+	if h.TrustedProxies, err = handlers.ParseProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("invalid trusted proxies: %v", err)
+	}
+	if h.Disabled, err = handlers.ParseFeatures(cfg.DisabledFeatures); err != nil {
+		log.Fatalf("invalid disabled features: %v", err)
+	}
+	if h.Coaches, err = handlers.ParseCoaches(cfg.Coaches); err != nil {
+		log.Fatalf("invalid coaches: %v", err)
+	}
+	if len(cfg.CORSOrigins) > 0 {
+		origins, err := handlers.ParseOrigins(strings.Join(cfg.CORSOrigins, ","))
+		if err != nil {
+			log.Fatalf("invalid CORS origins: %v", err)
+		}
+		h.CORSOrigins = origins
+	}
+	if len(cfg.FederationHosts) > 0 {
+		relay, err := federation.NewRelay(cfg.FederationHosts, nil)
+		if err != nil {
+			log.Fatalf("invalid federation hosts: %v", err)
+		}
+		h.Remote = relay
+	}
+	if cfg.Fediverse.Enabled {
+		inst := game.CurrentInstance()
+		if inst.BaseURL == "" {
+			log.Fatalf("the fediverse needs a base URL")
+		}
+		key, err := fediverse.LoadKey(cfg.Fediverse.Key)
+		if err != nil {
+			log.Fatalf("failed to load fediverse key: %v", err)
+		}
+		var followers fediverse.FollowerStore
+		if store != nil {
+			followers = store
+		}
+		pub := fediverse.New(inst.BaseURL, inst.Name, key, followers)
+		deliveries := outbox.New(store)
+		deliveries.Register(fediverse.DeliveryKind, pub.Deliver)
+		pub.Queue = deliveries
+		go deliveries.Run(context.Background(), outbox.DefaultInterval)
+		h.Fediverse = pub
+	}
+
+	h.AlternateURL = cfg.AlternateURL
+
+	srv := &http.Server{Addr: cfg.Addr, Handler: h.Routes()}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		// Event streams would hold shutdown open; end them first with a hint
+		// to reconnect, to the alternate deployment if there is one.
+		log.Printf("shutting down")
+		h.Drain()
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownGrace)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("shutdown: %v", err)
+		}
+	}()
 
-	// Register routes
-	http.HandleFunc("/new", h.HandleNew)
-	http.HandleFunc("/sse/", h.HandleSSE)
-	http.HandleFunc("/move/", h.HandleMove)
-	http.HandleFunc("/react/", h.HandleReact)
-	http.HandleFunc("/release/", h.HandleRelease)
-	http.HandleFunc("/forget/", h.HandleForget)
-	http.HandleFunc("/api/stats", h.HandleStats)
-	http.HandleFunc("/", h.HandlePage)
-
-	log.Printf("Tiny Chess listening on http://localhost:8080 …")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	scheme := "http"
+	if cfg.TLS.Cert != "" {
+		scheme = "https"
+	}
+	log.Printf("%s listening on %s://%s …", templates.CurrentBranding().Name, scheme, displayAddr(cfg.Addr))
+	if cfg.TLS.Cert != "" {
+		err = srv.ListenAndServeTLS(cfg.TLS.Cert, cfg.TLS.Key)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	<-stopped
+	stopSaving()
+	<-saved
+}
+
+// displayAddr turns a listen address such as ":8080" into one to browse to.
+func displayAddr(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "localhost" + addr
+	}
+	return addr
 }
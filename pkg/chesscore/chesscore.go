@@ -0,0 +1,248 @@
+// Package chesscore holds the position rules shared by the server and its
+// clients: parsing positions, squares and moves, and listing legal moves. It
+// depends only on the chess library so it also builds for WebAssembly (see
+// cmd/chesscore-wasm), letting a browser check moves offline with the same
+// code the server uses.
+package chesscore
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/corentings/chess/v2"
+)
+
+// ErrBadSquare is returned for square names other than "a1" to "h8".
+var ErrBadSquare = errors.New("invalid square")
+
+// fenMu serializes FEN decoding: chess v2.2.0 parses boards through a
+// package-level buffer, so concurrent parses race. Every new game, even from
+// the standard position, decodes a FEN.
+var fenMu sync.Mutex
+
+// GameFromFEN returns a new chess game from fen, or from the standard position
+// when fen is empty. Use it instead of chess.FEN and chess.NewGame, which are
+// not safe to call concurrently.
+func GameFromFEN(fen string) (*chess.Game, error) {
+	fenMu.Lock()
+	defer fenMu.Unlock()
+	if fen == "" {
+		return chess.NewGame(), nil
+	}
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	return chess.NewGame(opt), nil
+}
+
+// GameFromPGN reads the first game of pgn. Parsing decodes the FEN tag and
+// every position, so it is serialized with the rest of the FEN decoding.
+func GameFromPGN(pgn string) (*chess.Game, error) {
+	fenMu.Lock()
+	defer fenMu.Unlock()
+	opt, err := chess.PGN(strings.NewReader(pgn))
+	if err != nil {
+		return nil, err
+	}
+	return chess.NewGame(opt), nil
+}
+
+// ParseStartFEN validates a custom starting position and returns it in
+// canonical form. The standard starting position and empty input return "".
+// Positions must have one king per side, leave the side not to move out of
+// check, and still have a legal move.
+func ParseStartFEN(fen string) (string, error) {
+	fen = strings.TrimSpace(fen)
+	if fen == "" {
+		return "", nil
+	}
+	g, err := GameFromFEN(fen)
+	if err != nil {
+		return "", fmt.Errorf("invalid fen: %w", err)
+	}
+	pos := g.Position()
+
+	kings := map[chess.Color]int{}
+	for _, p := range pos.Board().SquareMap() {
+		if p.Type() == chess.King {
+			kings[p.Color()]++
+		}
+	}
+	if kings[chess.White] != 1 || kings[chess.Black] != 1 {
+		return "", errors.New("invalid fen: each side needs exactly one king")
+	}
+	if g.Outcome() != chess.NoOutcome || len(g.ValidMoves()) == 0 {
+		return "", errors.New("invalid fen: the game is already over")
+	}
+	if opponentInCheck(pos) {
+		return "", errors.New("invalid fen: the side not to move is in check")
+	}
+
+	start, _ := GameFromFEN("")
+	canonical := pos.String()
+	if canonical == start.Position().String() {
+		return "", nil
+	}
+	return canonical, nil
+}
+
+// Copy returns a private copy of pos. Positions fill a cache of their legal
+// moves the first time they are asked for them, so code that shares a
+// position with other readers, such as under a read lock, lists moves on a
+// copy instead.
+func Copy(pos *chess.Position) (*chess.Position, error) {
+	data, err := pos.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	cp := &chess.Position{}
+	if err := cp.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// WithTurn returns a copy of pos with c to move and no en passant square,
+// for looking at the moves of the side that is not to move. The copy is
+// decoded from a FEN, so it is serialized with the rest of the FEN
+// decoding.
+func WithTurn(pos *chess.Position, c chess.Color) (*chess.Position, error) {
+	fields := strings.Fields(pos.String())
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("unexpected position %q", pos.String())
+	}
+	fields[1] = c.String()
+	fields[3] = "-"
+	fenMu.Lock()
+	defer fenMu.Unlock()
+	other := &chess.Position{}
+	if err := other.UnmarshalText([]byte(strings.Join(fields, " "))); err != nil {
+		return nil, err
+	}
+	return other, nil
+}
+
+// opponentInCheck reports whether the side to move could capture the other
+// king.
+func opponentInCheck(pos *chess.Position) bool {
+	for _, m := range pos.ValidMoves() {
+		if p := pos.Board().Piece(m.S2()); p.Type() == chess.King {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSquare parses a square name such as "e2".
+func ParseSquare(s string) (chess.Square, error) {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return chess.NoSquare, ErrBadSquare
+	}
+	return chess.NewSquare(chess.File(s[0]-'a'), chess.Rank(s[1]-'1')), nil
+}
+
+// Target is a square the piece on a given square may legally move to.
+type Target struct {
+	To      string `json:"to"`
+	Capture bool   `json:"capture"`
+	// Promotion is set for pawn moves to the last rank, which need a
+	// promotion piece.
+	Promotion bool `json:"promotion"`
+	Castle    bool `json:"castle,omitempty"`
+}
+
+// LegalTargets lists where the piece on from may move in pos, sorted by
+// square name. It is empty when the square is empty or holds a piece of the
+// side not to move.
+func LegalTargets(pos *chess.Position, from chess.Square) []Target {
+	targets := []Target{}
+	seen := make(map[chess.Square]bool)
+	for _, m := range pos.ValidMoves() {
+		if m.S1() != from || seen[m.S2()] {
+			continue
+		}
+		// The four promotions to a square are one target.
+		seen[m.S2()] = true
+		targets = append(targets, Target{
+			To:        m.S2().String(),
+			Capture:   m.HasTag(chess.Capture) || m.HasTag(chess.EnPassant),
+			Promotion: m.Promo() != chess.NoPieceType,
+			Castle:    m.HasTag(chess.KingSideCastle) || m.HasTag(chess.QueenSideCastle),
+		})
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].To < targets[j].To })
+	return targets
+}
+
+// DecodeSAN converts a move in standard algebraic notation, such as "Nf3",
+// "O-O" or "exd8=Q+", to UCI in pos. Castling may also be written with
+// zeros.
+func DecodeSAN(pos *chess.Position, san string) (string, error) {
+	san = strings.TrimSpace(san)
+	if strings.HasPrefix(san, "0-0") {
+		san = strings.ReplaceAll(san, "0", "O")
+	}
+	m, err := chess.AlgebraicNotation{}.Decode(pos, san)
+	if err != nil {
+		return "", err
+	}
+	return chess.UCINotation{}.Encode(pos, m), nil
+}
+
+// ErrIllegalMove is returned for moves that cannot be played in a position.
+var ErrIllegalMove = errors.New("illegal move")
+
+// LegalMove finds the UCI move among the legal moves in pos. It returns the
+// decoding error for malformed input and ErrIllegalMove for moves that are
+// well formed but not legal.
+func LegalMove(pos *chess.Position, uci string) (*chess.Move, error) {
+	mv, err := chess.UCINotation{}.Decode(pos, uci)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range pos.ValidMoves() {
+		if m.S1() == mv.S1() && m.S2() == mv.S2() && m.Promo() == mv.Promo() {
+			return &m, nil
+		}
+	}
+	return nil, ErrIllegalMove
+}
+
+// Result is the position after a move.
+type Result struct {
+	FEN string `json:"fen"`
+	SAN string `json:"san"`
+	// Status is "checkmate" or "stalemate" when the move ends the game, and
+	// empty otherwise.
+	Status string `json:"status,omitempty"`
+}
+
+// Play applies the UCI move to the position fen, or to the standard
+// position when fen is empty, and returns the position that results.
+func Play(fen, uci string) (Result, error) {
+	g, err := GameFromFEN(strings.TrimSpace(fen))
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid fen: %w", err)
+	}
+	pos := g.Position()
+	m, err := LegalMove(pos, strings.TrimSpace(uci))
+	if err != nil {
+		return Result{}, ErrIllegalMove
+	}
+	san := chess.AlgebraicNotation{}.Encode(pos, m)
+	if err := g.Move(m, nil); err != nil {
+		return Result{}, err
+	}
+	res := Result{FEN: g.Position().String(), SAN: san}
+	switch g.Method() {
+	case chess.Checkmate:
+		res.Status = "checkmate"
+	case chess.Stalemate:
+		res.Status = "stalemate"
+	}
+	return res, nil
+}
@@ -0,0 +1,75 @@
+package chesscore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/corentings/chess/v2"
+)
+
+func TestPlay(t *testing.T) {
+	res, err := Play("", "g1f3")
+	if err != nil || res.SAN != "Nf3" || res.Status != "" {
+		t.Fatalf("Play(g1f3) = %+v, %v", res, err)
+	}
+	if res.FEN != "rnbqkbnr/pppppppp/8/8/8/5N2/PPPPPPPP/RNBQKB1R b KQkq - 1 1" {
+		t.Fatalf("unexpected fen %q", res.FEN)
+	}
+
+	res, err = Play("rnbqkbnr/ppppp2p/5p2/6p1/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 3", "d1h5")
+	if err != nil || res.SAN != "Qh5#" || res.Status != "checkmate" {
+		t.Fatalf("Play(d1h5) = %+v, %v", res, err)
+	}
+	res, err = Play("7k/4Q3/6K1/8/8/8/8/8 w - - 0 1", "e7f7")
+	if err != nil || res.Status != "stalemate" {
+		t.Fatalf("Play(e7f7) = %+v, %v", res, err)
+	}
+
+	for _, uci := range []string{"e2e5", "e7e5", "zz", ""} {
+		if _, err := Play("", uci); !errors.Is(err, ErrIllegalMove) {
+			t.Errorf("Play(%q) = %v, want ErrIllegalMove", uci, err)
+		}
+	}
+	if _, err := Play("not a fen", "e2e4"); err == nil {
+		t.Error("expected an invalid fen to be refused")
+	}
+}
+
+func TestCopy(t *testing.T) {
+	g, err := GameFromFEN("rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3")
+	if err != nil {
+		t.Fatalf("fen: %v", err)
+	}
+	pos := g.Position()
+	cp, err := Copy(pos)
+	if err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	if cp == pos || cp.String() != pos.String() {
+		t.Fatalf("expected an equal copy, got %s", cp)
+	}
+	if a, b := len(cp.ValidMoves()), len(pos.ValidMoves()); a != b {
+		t.Fatalf("copy has %d moves, original %d", a, b)
+	}
+}
+
+func TestWithTurn(t *testing.T) {
+	g, err := GameFromFEN("rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2")
+	if err != nil {
+		t.Fatalf("fen: %v", err)
+	}
+	pos := g.Position()
+	other, err := WithTurn(pos, chess.Black)
+	if err != nil {
+		t.Fatalf("WithTurn: %v", err)
+	}
+	if other.Turn() != chess.Black || other.EnPassantSquare() != chess.NoSquare {
+		t.Fatalf("unexpected position %s", other)
+	}
+	if pos.Turn() != chess.White {
+		t.Fatalf("expected the original position untouched")
+	}
+	if n := len(other.ValidMoves()); n != 29 {
+		t.Fatalf("expected 29 moves for black, got %d", n)
+	}
+}
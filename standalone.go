@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tinychess/pkg/utils"
+)
+
+// standaloneDefaults fills in the database and admin token for a zero-config
+// run: a SQLite file in dataDir and a token generated on first start. Values
+// already set in the environment win.
+func standaloneDefaults(dataDir, dsn, adminToken string) (string, string, error) {
+	if err := os.MkdirAll(dataDir, 0o700); err != nil {
+		return "", "", err
+	}
+	if dsn == "" {
+		dsn = "sqlite://" + filepath.Join(dataDir, "tinychess.db")
+	}
+	if adminToken == "" {
+		var err error
+		if adminToken, err = loadAdminToken(filepath.Join(dataDir, "admin-token")); err != nil {
+			return "", "", err
+		}
+	}
+	return dsn, adminToken, nil
+}
+
+// loadAdminToken reads the token stored at path, generating and saving a new
+// one if the file does not exist yet.
+func loadAdminToken(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err == nil {
+		return strings.TrimSpace(string(b)), nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	token := utils.RandomHex(24)
+	if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		return "", err
+	}
+	log.Printf("generated admin token %s (saved to %s)", token, path)
+	return token, nil
+}